@@ -0,0 +1,167 @@
+// Copyright 2017 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"golang.org/x/net/context"
+	"google.golang.org/api/iterator"
+)
+
+// AnonymousCleanup specifies the anonymous users deleted by DeleteStaleAnonymousUsers.
+type AnonymousCleanup struct {
+	// OlderThan is the minimum time elapsed since the creation of an anonymous user for it to be
+	// deleted. It must be positive.
+	OlderThan time.Duration
+
+	// BatchSize is the number of users deleted by each DeleteUsers call. Defaults to, and must not
+	// exceed, 1000.
+	BatchSize int
+
+	// Interval is the minimum time between the starts of two successive DeleteUsers calls, which
+	// limits the rate at which the deletion quota of the project is consumed. Defaults to no delay.
+	Interval time.Duration
+
+	// IsAnonymous confirms that a stale user without any email address, phone number or linked
+	// identity provider is anonymous, and may be deleted. It must not be nil. Users signed in with
+	// custom tokens have no email address, phone number or provider either, and the user management
+	// API cannot tell them apart from anonymous users. IsAnonymous must therefore rely on information
+	// of the app, such as a custom claim or a naming convention of the UIDs of custom token users.
+	IsAnonymous func(user *UserRecord) bool
+
+	// DryRun reports the users that would be deleted in the MatchedUIDs of the report, without
+	// deleting any of them.
+	DryRun bool
+
+	// Progress is called, if not nil, after each batch of users has been deleted, with the cumulative
+	// report so far.
+	Progress func(report *AnonymousCleanupReport)
+}
+
+// AnonymousCleanupReport summarizes the outcome of a DeleteStaleAnonymousUsers call.
+//
+// MatchedCount is the number of stale anonymous users found, and MatchedUIDs lists them in dry runs.
+// The Index of each error is the position of the failed user in its batch.
+type AnonymousCleanupReport struct {
+	ScannedCount int
+	MatchedCount int
+	MatchedUIDs  []string
+	DeletedCount int
+	FailureCount int
+	Errors       []*DeleteUsersErrorInfo
+}
+
+// DeleteStaleAnonymousUsers deletes the anonymous users of the project created longer ago than
+// cleanup.OlderThan.
+//
+// A user is anonymous if it has no email address, no phone number and no linked identity provider,
+// and cleanup.IsAnonymous confirms it: the user records alone do not tell anonymous users apart from
+// users signed in with custom tokens. Users are listed with ListUsers, and the stale anonymous users
+// are deleted in batches as described in DeleteUsers, waiting cleanup.Interval between batches.
+// Failures to delete individual users are reported in the returned AnonymousCleanupReport rather than
+// as an error. Deletion is irreversible; set cleanup.DryRun to review the matched users first.
+//
+// If listing or deleting users fails, or the context is done, DeleteStaleAnonymousUsers stops and
+// returns the report so far along with the error.
+func (c *Client) DeleteStaleAnonymousUsers(ctx context.Context, cleanup *AnonymousCleanup) (*AnonymousCleanupReport, error) {
+	if cleanup == nil || cleanup.OlderThan <= 0 {
+		return nil, errors.New("cleanup must not be nil and must specify a positive age")
+	}
+	if cleanup.IsAnonymous == nil {
+		return nil, errors.New("cleanup must specify an IsAnonymous function")
+	}
+	batchSize := cleanup.BatchSize
+	if batchSize == 0 {
+		batchSize = maxDeleteUsersBatchSize
+	}
+	if batchSize < 0 || batchSize > maxDeleteUsersBatchSize {
+		return nil, fmt.Errorf("batch size must be between 1 and %d", maxDeleteUsersBatchSize)
+	}
+	if cleanup.Interval < 0 {
+		return nil, errors.New("interval must not be negative")
+	}
+
+	cutoff := clk.Now().Add(-cleanup.OlderThan).UnixNano() / int64(time.Millisecond)
+	report := &AnonymousCleanupReport{}
+	var last time.Time
+	deleteBatch := func(uids []string) error {
+		if !last.IsZero() {
+			select {
+			case <-time.After(cleanup.Interval - time.Since(last)):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+		last = time.Now()
+		result, err := c.DeleteUsers(ctx, uids)
+		if err != nil {
+			return err
+		}
+		report.DeletedCount += result.SuccessCount
+		report.FailureCount += result.FailureCount
+		report.Errors = append(report.Errors, result.Errors...)
+		if cleanup.Progress != nil {
+			cleanup.Progress(report)
+		}
+		return nil
+	}
+
+	var batch []string
+	iter := c.ListUsers(ctx)
+	for {
+		user, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return report, err
+		}
+		report.ScannedCount++
+		if !hasNoIdentity(user) || user.UserMetadata == nil || user.UserMetadata.CreationTimestamp >= cutoff ||
+			!cleanup.IsAnonymous(user) {
+			continue
+		}
+		report.MatchedCount++
+		if cleanup.DryRun {
+			report.MatchedUIDs = append(report.MatchedUIDs, user.UID)
+			continue
+		}
+		batch = append(batch, user.UID)
+		if len(batch) == batchSize {
+			if err := deleteBatch(batch); err != nil {
+				return report, err
+			}
+			batch = nil
+		}
+	}
+	if len(batch) > 0 {
+		if err := deleteBatch(batch); err != nil {
+			return report, err
+		}
+	}
+	return report, nil
+}
+
+// hasNoIdentity checks if the given user has no email address, phone number or linked identity
+// provider. This is the case of anonymous users, but also of users signed in with custom tokens.
+func hasNoIdentity(user *UserRecord) bool {
+	if len(user.ProviderUserInfo) > 0 {
+		return false
+	}
+	return user.UserInfo == nil || (user.Email == "" && user.PhoneNumber == "")
+}
@@ -0,0 +1,188 @@
+// Copyright 2017 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+// Users created at 1000s (stale) and 9000s (recent), relative to a clock at 10000s.
+const anonymousUsersResponse = `{
+	"users": [
+		{"localId": "anon1", "createdAt": "1000000"},
+		{"localId": "anon2", "createdAt": "9000000"},
+		{"localId": "email1", "email": "user@example.com", "createdAt": "1000000"},
+		{"localId": "phone1", "phoneNumber": "+15555550100", "createdAt": "1000000"},
+		{"localId": "google1", "createdAt": "1000000", "providerUserInfo": [{"providerId": "google.com", "rawId": "g1"}]},
+		{"localId": "anon3", "createdAt": "2000000"},
+		{"localId": "anon4", "createdAt": "3000000"},
+		{"localId": "custom-token-user", "createdAt": "1000000"}
+	]
+}`
+
+// isAnonymous tells the anonymous users of the test response apart from custom token users, which
+// have no email, phone number or provider either.
+func isAnonymous(user *UserRecord) bool {
+	return strings.HasPrefix(user.UID, "anon")
+}
+
+// anonymousCleanupServer starts a mock server that lists the users of anonymousUsersResponse, and
+// responds to each request to delete users with deleteResp. It records the user IDs of each request.
+func anonymousCleanupServer(t *testing.T, deleteResp string) (*pagedUsersServer, *[][]string) {
+	var deleted [][]string
+	pages := map[string]string{"": anonymousUsersResponse}
+	s := newPagedUsersServer(t, pages, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/projects/mock-project-id/accounts:batchDelete" {
+			t.Errorf("Path = %q; want = %q", r.URL.Path, "/projects/mock-project-id/accounts:batchDelete")
+			return
+		}
+		var req struct {
+			LocalIDs []string `json:"localIds"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Error(err)
+		}
+		deleted = append(deleted, req.LocalIDs)
+		w.Write([]byte(deleteResp))
+	})
+	return s, &deleted
+}
+
+func TestDeleteStaleAnonymousUsers(t *testing.T) {
+	s, deleted := anonymousCleanupServer(t, `{}`)
+	defer s.Close()
+	clk = &mockClock{now: time.Unix(10000, 0)}
+	defer func() {
+		clk = &systemClock{}
+	}()
+
+	var progress []int
+	cleanup := &AnonymousCleanup{
+		OlderThan:   time.Hour,
+		BatchSize:   2,
+		Interval:    10 * time.Millisecond,
+		IsAnonymous: isAnonymous,
+		Progress: func(report *AnonymousCleanupReport) {
+			progress = append(progress, report.DeletedCount)
+		},
+	}
+	start := time.Now()
+	report, err := s.Client.DeleteStaleAnonymousUsers(context.Background(), cleanup)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < cleanup.Interval {
+		t.Errorf("DeleteStaleAnonymousUsers() took %v; want >= %v", elapsed, cleanup.Interval)
+	}
+
+	want := &AnonymousCleanupReport{ScannedCount: 8, MatchedCount: 3, DeletedCount: 3}
+	if !reflect.DeepEqual(report, want) {
+		t.Errorf("DeleteStaleAnonymousUsers() = %#v; want = %#v", report, want)
+	}
+	wantDeleted := [][]string{{"anon1", "anon3"}, {"anon4"}}
+	if !reflect.DeepEqual(*deleted, wantDeleted) {
+		t.Errorf("DeleteUsers() batches = %v; want = %v", *deleted, wantDeleted)
+	}
+	if !reflect.DeepEqual(progress, []int{2, 3}) {
+		t.Errorf("Progress() = %v; want = [2 3]", progress)
+	}
+}
+
+func TestDeleteStaleAnonymousUsersFailures(t *testing.T) {
+	s, _ := anonymousCleanupServer(t, `{"errors": [{"index": 0, "localId": "anon1", "message": "failed"}]}`)
+	defer s.Close()
+
+	cleanup := &AnonymousCleanup{OlderThan: time.Hour, IsAnonymous: isAnonymous}
+	report, err := s.Client.DeleteStaleAnonymousUsers(context.Background(), cleanup)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &AnonymousCleanupReport{
+		ScannedCount: 8,
+		MatchedCount: 4,
+		DeletedCount: 3,
+		FailureCount: 1,
+		Errors:       []*DeleteUsersErrorInfo{{Index: 0, UID: "anon1", Reason: "failed"}},
+	}
+	if !reflect.DeepEqual(report, want) {
+		t.Errorf("DeleteStaleAnonymousUsers() = %#v; want = %#v", report, want)
+	}
+}
+
+func TestDeleteStaleAnonymousUsersDryRun(t *testing.T) {
+	s, deleted := anonymousCleanupServer(t, `{}`)
+	defer s.Close()
+	clk = &mockClock{now: time.Unix(10000, 0)}
+	defer func() {
+		clk = &systemClock{}
+	}()
+
+	cleanup := &AnonymousCleanup{OlderThan: time.Hour, IsAnonymous: isAnonymous, DryRun: true}
+	report, err := s.Client.DeleteStaleAnonymousUsers(context.Background(), cleanup)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &AnonymousCleanupReport{
+		ScannedCount: 8,
+		MatchedCount: 3,
+		MatchedUIDs:  []string{"anon1", "anon3", "anon4"},
+	}
+	if !reflect.DeepEqual(report, want) {
+		t.Errorf("DeleteStaleAnonymousUsers() = %#v; want = %#v", report, want)
+	}
+	if len(*deleted) != 0 {
+		t.Errorf("DeleteUsers() batches = %v; want = none", *deleted)
+	}
+}
+
+func TestDeleteStaleAnonymousUsersInvalid(t *testing.T) {
+	cases := []*AnonymousCleanup{
+		nil,
+		{},
+		{OlderThan: time.Hour},
+		{OlderThan: -time.Hour, IsAnonymous: isAnonymous},
+		{OlderThan: time.Hour, IsAnonymous: isAnonymous, BatchSize: -1},
+		{OlderThan: time.Hour, IsAnonymous: isAnonymous, BatchSize: maxDeleteUsersBatchSize + 1},
+		{OlderThan: time.Hour, IsAnonymous: isAnonymous, Interval: -time.Second},
+	}
+	for _, tc := range cases {
+		report, err := client.DeleteStaleAnonymousUsers(context.Background(), tc)
+		if report != nil || err == nil {
+			t.Errorf("DeleteStaleAnonymousUsers(%#v) = (%v, %v); want = (nil, error)", tc, report, err)
+		}
+	}
+}
+
+func TestDeleteStaleAnonymousUsersError(t *testing.T) {
+	s := echoServer([]byte(`{"error": {"message": "QUOTA_EXCEEDED"}}`), t)
+	defer s.Close()
+	s.Status = http.StatusTooManyRequests
+
+	cleanup := &AnonymousCleanup{OlderThan: time.Hour, IsAnonymous: isAnonymous}
+	report, err := s.Client.DeleteStaleAnonymousUsers(context.Background(), cleanup)
+	if !IsQuotaExceeded(err) {
+		t.Errorf("DeleteStaleAnonymousUsers() = %v; want = quota-exceeded error", err)
+	}
+	if report == nil || report.DeletedCount != 0 {
+		t.Errorf("DeleteStaleAnonymousUsers() = %v; want = empty report", report)
+	}
+}
@@ -0,0 +1,68 @@
+// Copyright 2017 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// WithAudiences returns a copy of the Client that only accepts ID tokens whose 'aud' (audience) claim
+// is one of the keys of the given map. The Client on which it is called is not modified.
+//
+// Each audience maps to the issuer expected in the 'iss' claim of tokens issued for it. An empty
+// issuer stands for the default Firebase issuer of the audience, https://securetoken.google.com/<aud>.
+// This supports Identity Platform projects configured with additional audiences, such as apps fronted
+// by Identity-Aware Proxy, whose tokens are issued under a different issuer.
+//
+// The list is strict: the project of the Client and the accepted projects of the App are not implied,
+// and must be listed explicitly to remain accepted. The ProjectID field of the returned Token holds the
+// audience that matched.
+func (c *Client) WithAudiences(audiences map[string]string) (*Client, error) {
+	if len(audiences) == 0 {
+		return nil, errors.New("audiences must not be empty")
+	}
+	copied := make(map[string]string, len(audiences))
+	for aud, iss := range audiences {
+		if aud == "" {
+			return nil, errors.New("audiences must be non-empty strings")
+		}
+		if iss == "" {
+			iss = issuerPrefix + aud
+		}
+		copied[aud] = iss
+	}
+	client := *c
+	client.audiences = copied
+	return &client, nil
+}
+
+// checkStrictAudience checks the given audience against the audiences configured with WithAudiences,
+// and returns the issuer expected for it.
+func (c *Client) checkStrictAudience(aud string) (string, error) {
+	iss, ok := c.audiences[aud]
+	if !ok {
+		var accepted []string
+		for a := range c.audiences {
+			accepted = append(accepted, a)
+		}
+		sort.Strings(accepted)
+		return "", fmt.Errorf("ID token has invalid 'aud' (audience) claim. Expected one of [%s] but got %q. %s",
+			strings.Join(accepted, ", "), aud, verifyTokenMsg)
+	}
+	return iss, nil
+}
@@ -0,0 +1,107 @@
+// Copyright 2017 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"testing"
+
+	"golang.org/x/net/context"
+)
+
+func TestWithAudiences(t *testing.T) {
+	c, err := client.WithAudiences(map[string]string{
+		"mock-project-id":               "",
+		"/projects/123/apps/my-gae-app": "https://cloud.google.com/iap",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if client.audiences != nil {
+		t.Errorf("WithAudiences() modified the original Client")
+	}
+
+	cases := []struct {
+		aud string
+		iss string
+	}{
+		{"mock-project-id", "https://securetoken.google.com/mock-project-id"},
+		{"/projects/123/apps/my-gae-app", "https://cloud.google.com/iap"},
+	}
+	for _, tc := range cases {
+		token := getIDToken(mockIDTokenPayload{"aud": tc.aud, "iss": tc.iss})
+		ft, err := c.VerifyIDToken(token)
+		if err != nil {
+			t.Errorf("VerifyIDToken(%q) = %v; want = nil", tc.aud, err)
+		} else if ft.ProjectID != tc.aud {
+			t.Errorf("ProjectID = %q; want = %q", ft.ProjectID, tc.aud)
+		}
+	}
+}
+
+func TestWithAudiencesInvalidTokens(t *testing.T) {
+	c, err := client.WithAudiences(map[string]string{
+		"/projects/123/apps/my-gae-app": "https://cloud.google.com/iap",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name    string
+		payload mockIDTokenPayload
+	}{
+		{
+			"ProjectNotListed",
+			mockIDTokenPayload{},
+		},
+		{
+			"UnknownAudience",
+			mockIDTokenPayload{"aud": "other-app", "iss": "https://cloud.google.com/iap"},
+		},
+		{
+			"DefaultIssuer",
+			mockIDTokenPayload{
+				"aud": "/projects/123/apps/my-gae-app",
+				"iss": "https://securetoken.google.com//projects/123/apps/my-gae-app",
+			},
+		},
+	}
+	for _, tc := range cases {
+		token := getIDToken(tc.payload)
+		if ft, err := c.VerifyIDToken(token); ft != nil || err == nil {
+			t.Errorf("VerifyIDToken(%s) = (%v, %v); want = (nil, error)", tc.name, ft, err)
+		}
+		results, err := c.VerifyIDTokens(context.Background(), []string{token})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if results[0].Err == nil {
+			t.Errorf("VerifyIDTokens(%s)[0] = nil; want = error", tc.name)
+		}
+	}
+}
+
+func TestWithAudiencesInvalid(t *testing.T) {
+	cases := []map[string]string{
+		nil,
+		{},
+		{"": "https://cloud.google.com/iap"},
+	}
+	for _, audiences := range cases {
+		if c, err := client.WithAudiences(audiences); c != nil || err == nil {
+			t.Errorf("WithAudiences(%v) = (%v, %v); want = (nil, error)", audiences, c, err)
+		}
+	}
+}
@@ -12,20 +12,25 @@
 // See the License for the specific language governing permissions and
 // limitations under the License.
 
-// Package auth contains functions for minting custom authentication tokens, and verifying Firebase ID tokens.
+// Package auth contains functions for minting custom authentication tokens, verifying Firebase ID tokens,
+// and managing Firebase user accounts.
 package auth
 
 import (
 	"encoding/json"
-	"encoding/pem"
 	"errors"
 	"fmt"
+	"net/http"
+	"os"
 	"strings"
+	"sync"
 
 	"crypto/rsa"
-	"crypto/x509"
 
 	"firebase.google.com/go/internal"
+
+	"golang.org/x/net/context"
+	"google.golang.org/api/transport"
 )
 
 const firebaseAudience = "https://identitytoolkit.googleapis.com/google.identity.identitytoolkit.v1.IdentityToolkit"
@@ -33,6 +38,19 @@ const googleCertURL = "https://www.googleapis.com/robot/v1/metadata/x509/securet
 const issuerPrefix = "https://securetoken.google.com/"
 const tokenExpSeconds = 3600
 
+// authEmulatorHostEnv is the environment variable that holds the address of the Auth emulator, when
+// the SDK runs against it. emulatorEmail is the service account email used when none is available.
+const authEmulatorHostEnv = "FIREBASE_AUTH_EMULATOR_HOST"
+const emulatorEmail = "firebase-auth-emulator@example.com"
+
+// maxVerifyConcurrency is the maximum number of goroutines used by VerifyIDTokens.
+const maxVerifyConcurrency = 16
+
+const projectIDMsg = "Make sure the ID token comes from the same Firebase project as the credential used to" +
+	" authenticate this SDK."
+const verifyTokenMsg = "See https://firebase.google.com/docs/auth/admin/verify-id-tokens for details on how to " +
+	"retrieve a valid ID token."
+
 var reservedClaims = []string{
 	"acr", "amr", "at_hash", "aud", "auth_time", "azp", "cnf", "c_hash",
 	"exp", "firebase", "iat", "iss", "jti", "nbf", "nonce", "sub",
@@ -44,15 +62,17 @@ var clk clock = &systemClock{}
 //
 // Token provides typed accessors to the common JWT fields such as Audience (aud) and Expiry (exp).
 // Additionally it provides a UID field, which indicates the user ID of the account to which this token
-// belongs. Any additional JWT claims can be accessed via the Claims map of Token.
+// belongs. The ProjectID field indicates the Firebase project for which the token was issued. Any
+// additional JWT claims can be accessed via the Claims map of Token.
 type Token struct {
-	Issuer   string                 `json:"iss"`
-	Audience string                 `json:"aud"`
-	Expires  int64                  `json:"exp"`
-	IssuedAt int64                  `json:"iat"`
-	Subject  string                 `json:"sub,omitempty"`
-	UID      string                 `json:"uid,omitempty"`
-	Claims   map[string]interface{} `json:"-"`
+	Issuer    string                 `json:"iss"`
+	Audience  string                 `json:"aud"`
+	Expires   int64                  `json:"exp"`
+	IssuedAt  int64                  `json:"iat"`
+	Subject   string                 `json:"sub,omitempty"`
+	UID       string                 `json:"uid,omitempty"`
+	ProjectID string                 `json:"-"`
+	Claims    map[string]interface{} `json:"-"`
 }
 
 // Client is the interface for the Firebase auth service.
@@ -60,20 +80,70 @@ type Token struct {
 // Client facilitates generating custom JWT tokens for Firebase clients, and verifying ID tokens issued
 // by Firebase backend services.
 type Client struct {
-	ks        keySource
-	projectID string
-	email     string
-	pk        *rsa.PrivateKey
+	hc                 *internal.HTTPClient
+	ks                 keySource
+	projectID          string
+	email              string
+	pk                 *rsa.PrivateKey
+	userEndpoint       string
+	projectMgtEndpoint string
+	acceptedProjects   map[string]bool
+	audiences          map[string]string
+	tenantID           string
+	claimsHistory      ClaimsHistoryStore
+	pins               *keyPins
+	limiter            *rateLimiter
+	emulated           bool
 }
 
 // NewClient creates a new instance of the Firebase Auth Client.
 //
 // This function can only be invoked from within the SDK. Client applications should access the
 // the Auth service through firebase.App.
-func NewClient(c *internal.AuthConfig) (*Client, error) {
+func NewClient(ctx context.Context, c *internal.AuthConfig) (*Client, error) {
+	if c.StrictVerification && c.KeyGracePeriod > 0 {
+		return nil, errors.New("key grace period must not be set when strict verification is enabled")
+	}
+	accepted := make(map[string]bool)
+	for _, pid := range c.AcceptedProjectIDs {
+		if pid == "" {
+			return nil, errors.New("accepted project ids must be non-empty strings")
+		}
+		accepted[pid] = true
+	}
+	if host := os.Getenv(authEmulatorHostEnv); host != "" {
+		userEndpoint, projectMgtEndpoint := emulatorEndpoints(host)
+		return &Client{
+			hc:                 &internal.HTTPClient{Client: &http.Client{Transport: &emulatorTransport{}}},
+			ks:                 emulatorKeySource{},
+			projectID:          c.ProjectID,
+			userEndpoint:       userEndpoint,
+			projectMgtEndpoint: projectMgtEndpoint,
+			acceptedProjects:   accepted,
+			pins:               &keyPins{},
+			emulated:           true,
+		}, nil
+	}
+
+	hc, _, err := transport.NewHTTPClient(ctx, c.Opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	ks := newHTTPKeySource(googleCertURL)
+	ks.GracePeriod = c.KeyGracePeriod
+	if !c.StrictVerification {
+		// Strict verification only trusts keys fetched from Google by the Client itself.
+		ks.Cache = c.Cache
+	}
 	client := &Client{
-		ks:        newHTTPKeySource(googleCertURL),
-		projectID: c.ProjectID,
+		hc:                 &internal.HTTPClient{Client: hc},
+		ks:                 ks,
+		projectID:          c.ProjectID,
+		userEndpoint:       idToolkitV1Endpoint,
+		projectMgtEndpoint: idToolkitV2Endpoint,
+		acceptedProjects:   accepted,
+		pins:               &keyPins{},
 	}
 	if c.Creds == nil || len(c.Creds.JSON) == 0 {
 		return client, nil
@@ -83,13 +153,12 @@ func NewClient(c *internal.AuthConfig) (*Client, error) {
 		ClientEmail string `json:"client_email"`
 		PrivateKey  string `json:"private_key"`
 	}
-	err := json.Unmarshal(c.Creds.JSON, &svcAcct)
-	if err != nil {
+	if err := json.Unmarshal(c.Creds.JSON, &svcAcct); err != nil {
 		return nil, err
 	}
 
 	if svcAcct.PrivateKey != "" {
-		pk, err := parseKey(svcAcct.PrivateKey)
+		pk, err := internal.ParsePrivateKey(svcAcct.PrivateKey)
 		if err != nil {
 			return nil, err
 		}
@@ -103,6 +172,10 @@ func NewClient(c *internal.AuthConfig) (*Client, error) {
 // JWT can be used in a Firebase client SDK to trigger an authentication flow. See
 // https://firebase.google.com/docs/auth/admin/create-custom-tokens#sign_in_using_custom_tokens_on_clients
 // for more details on how to use custom tokens for client authentication.
+//
+// When the Client is routed to the Auth emulator, because the FIREBASE_AUTH_EMULATOR_HOST environment
+// variable was set when it was created, custom tokens are not signed, as expected by the emulator, and
+// no service account credential is required.
 func (c *Client) CustomToken(uid string) (string, error) {
 	return c.CustomTokenWithClaims(uid, nil)
 }
@@ -110,11 +183,19 @@ func (c *Client) CustomToken(uid string) (string, error) {
 // CustomTokenWithClaims is similar to CustomToken, but in addition to the user ID, it also encodes
 // all the key-value pairs in the provided map as claims in the resulting JWT.
 func (c *Client) CustomTokenWithClaims(uid string, devClaims map[string]interface{}) (string, error) {
-	if c.email == "" {
-		return "", errors.New("service account email not available")
-	}
-	if c.pk == nil {
-		return "", errors.New("private key not available")
+	email, header := c.email, defaultHeader()
+	if c.emulated {
+		if email == "" {
+			email = emulatorEmail
+		}
+		header.Algorithm = "none"
+	} else {
+		if email == "" {
+			return "", errors.New("service account email not available")
+		}
+		if c.pk == nil {
+			return "", errors.New("private key not available")
+		}
 	}
 
 	if len(uid) == 0 || len(uid) > 128 {
@@ -135,57 +216,192 @@ func (c *Client) CustomTokenWithClaims(uid string, devClaims map[string]interfac
 
 	now := clk.Now().Unix()
 	payload := &customToken{
-		Iss:    c.email,
-		Sub:    c.email,
+		Iss:    email,
+		Sub:    email,
 		Aud:    firebaseAudience,
 		UID:    uid,
 		Iat:    now,
 		Exp:    now + tokenExpSeconds,
 		Claims: devClaims,
 	}
-	return encodeToken(defaultHeader(), payload, c.pk)
+	return encodeToken(header, payload, c.pk)
 }
 
 // VerifyIDToken verifies the signature	and payload of the provided ID token.
 //
 // VerifyIDToken accepts a signed JWT token string, and verifies that it is current, issued for the
 // correct Firebase project, and signed by the Google Firebase services in the cloud. It returns
-// a Token containing the decoded claims in the input JWT. See
+// a Token containing the decoded claims in the input JWT. Tokens issued for any of the additional
+// projects accepted by the Client are also considered valid, in which case the ProjectID field of the
+// returned Token indicates the project that issued it. See
 // https://firebase.google.com/docs/auth/admin/verify-id-tokens#retrieve_id_tokens_on_clients for
 // more details on how to obtain an ID token in a client app.
 func (c *Client) VerifyIDToken(idToken string) (*Token, error) {
 	if c.projectID == "" {
 		return nil, errors.New("project id not available")
 	}
+	return verifyIDToken(idToken, c.keySource(), c.checkAudience)
+}
+
+// VerifyIDTokenAndCheckRevoked verifies the provided ID token, and additionally checks that the token
+// has not been revoked, and that the user account it belongs to is still active.
+//
+// VerifyIDTokenAndCheckRevoked performs the same checks as VerifyIDToken, and then looks up the user
+// account of the token. The returned error can be inspected to render an appropriate response:
+// IsUserNotFound returns true if the user account has been deleted, IsUserDisabled returns true if the
+// user account has been disabled, and IsIDTokenRevoked returns true if the tokens of the user have been
+// revoked since the token was issued. Unlike VerifyIDToken, this makes a call to the Firebase Auth
+// backend for every token.
+//
+// User accounts can only be looked up in the project of the Client. Tokens issued for an accepted
+// project, or for an audience configured with WithAudiences, are verified, but then rejected with an
+// error, as their revocation cannot be checked.
+func (c *Client) VerifyIDTokenAndCheckRevoked(ctx context.Context, idToken string) (*Token, error) {
+	p, err := c.VerifyIDToken(idToken)
+	if err != nil {
+		return nil, err
+	}
+	return c.checkRevoked(ctx, p)
+}
+
+// checkRevoked checks that the given verified token belongs to the project of the Client, that the
+// user account of the token is still active, and that the token has not been revoked.
+func (c *Client) checkRevoked(ctx context.Context, p *Token) (*Token, error) {
+	if p.ProjectID != c.projectID {
+		return nil, fmt.Errorf("cannot check revocation of ID token issued for %q; user accounts can only "+
+			"be looked up in project %q", p.ProjectID, c.projectID)
+	}
+
+	user, err := c.GetUser(ctx, p.UID)
+	if err != nil {
+		return nil, err
+	}
+	if user.Disabled {
+		return nil, internal.Errorf(userDisabled, "user account %q has been disabled", p.UID)
+	}
+	if p.IssuedAt*1000 < user.TokensValidAfterMillis {
+		return nil, internal.Error(idTokenRevoked, "ID token has been revoked")
+	}
+	return p, nil
+}
+
+// WarmUp fetches the public keys used to verify ID tokens, so that the first call to VerifyIDToken does
+// not incur the latency of fetching them. WarmUp returns when the keys are available, or the context is
+// done.
+func (c *Client) WarmUp(ctx context.Context) error {
+	errc := make(chan error, 1)
+	go func() {
+		_, err := c.keySource().Keys()
+		errc <- err
+	}()
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (c *Client) checkAudience(aud string) (string, error) {
+	if c.audiences != nil {
+		return c.checkStrictAudience(aud)
+	}
+	if aud != c.projectID && !c.acceptedProjects[aud] {
+		return "", fmt.Errorf("ID token has invalid 'aud' (audience) claim. Expected %q but got %q. %s %s",
+			c.projectID, aud, projectIDMsg, verifyTokenMsg)
+	}
+	return issuerPrefix + aud, nil
+}
+
+// VerifyIDTokenResult is the outcome of verifying a single ID token with VerifyIDTokens.
+//
+// Exactly one of Token and Err is set.
+type VerifyIDTokenResult struct {
+	Token *Token
+	Err   error
+}
+
+// VerifyIDTokens verifies a batch of ID tokens concurrently.
+//
+// The public keys required for verification are looked up once, and shared by all the tokens in the
+// batch. The results are returned in the same order as the input tokens. VerifyIDTokens only returns an
+// error when the batch as a whole cannot be processed, such as when the public keys cannot be fetched.
+// Failures of individual tokens are reported in the Err field of the corresponding result. If the
+// context is cancelled, tokens that have not been verified yet are reported with the context error.
+func (c *Client) VerifyIDTokens(ctx context.Context, idTokens []string) ([]*VerifyIDTokenResult, error) {
+	if c.projectID == "" {
+		return nil, errors.New("project id not available")
+	}
+	ks := c.keySource()
+	keys, err := ks.Keys()
+	if err != nil {
+		return nil, err
+	}
+	if !c.emulated {
+		ks = &staticKeySource{keys: keys}
+	}
+
+	results := make([]*VerifyIDTokenResult, len(idTokens))
+	indices := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < maxVerifyConcurrency && w < len(idTokens); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indices {
+				t, err := verifyIDToken(idTokens[i], ks, c.checkAudience)
+				results[i] = &VerifyIDTokenResult{Token: t, Err: err}
+			}
+		}()
+	}
+
+	i := 0
+loop:
+	for ; i < len(idTokens); i++ {
+		select {
+		case indices <- i:
+		case <-ctx.Done():
+			break loop
+		}
+	}
+	close(indices)
+	wg.Wait()
+
+	for ; i < len(idTokens); i++ {
+		results[i] = &VerifyIDTokenResult{Err: ctx.Err()}
+	}
+	return results, nil
+}
+
+// verifyIDToken decodes the given ID token, and verifies its signature and claims. The checkAudience
+// function decides whether the 'aud' claim of the token is acceptable, and returns the issuer expected
+// for it. The 'iss' claim of the token is then required to match that issuer.
+func verifyIDToken(idToken string, ks keySource, checkAudience func(aud string) (string, error)) (*Token, error) {
 	if idToken == "" {
 		return nil, fmt.Errorf("ID token must be a non-empty string")
 	}
 
 	h := &jwtHeader{}
 	p := &Token{}
-	if err := decodeToken(idToken, c.ks, h, p); err != nil {
+	if err := decodeToken(idToken, ks, h, p); err != nil {
 		return nil, err
 	}
 
-	projectIDMsg := "Make sure the ID token comes from the same Firebase project as the credential used to" +
-		" authenticate this SDK."
-	verifyTokenMsg := "See https://firebase.google.com/docs/auth/admin/verify-id-tokens for details on how to " +
-		"retrieve a valid ID token."
-	issuer := issuerPrefix + c.projectID
+	// Tokens issued by the Auth emulator have no signature, and no key ID.
+	_, unsigned := ks.(emulatorKeySource)
 
 	var err error
-	if h.KeyID == "" {
+	if !unsigned && h.KeyID == "" {
 		if p.Audience == firebaseAudience {
 			err = fmt.Errorf("VerifyIDToken() expects an ID token, but was given a custom token")
 		} else {
 			err = fmt.Errorf("ID token has no 'kid' header")
 		}
-	} else if h.Algorithm != "RS256" {
+	} else if !unsigned && h.Algorithm != "RS256" {
 		err = fmt.Errorf("ID token has invalid incorrect algorithm. Expected 'RS256' but got %q. %s",
 			h.Algorithm, verifyTokenMsg)
-	} else if p.Audience != c.projectID {
-		err = fmt.Errorf("ID token has invalid 'aud' (audience) claim. Expected %q but got %q. %s %s",
-			c.projectID, p.Audience, projectIDMsg, verifyTokenMsg)
+	} else if issuer, aerr := checkAudience(p.Audience); aerr != nil {
+		err = aerr
 	} else if p.Issuer != issuer {
 		err = fmt.Errorf("ID token has invalid 'iss' (issuer) claim. Expected %q but got %q. %s %s",
 			issuer, p.Issuer, projectIDMsg, verifyTokenMsg)
@@ -203,25 +419,6 @@ func (c *Client) VerifyIDToken(idToken string) (*Token, error) {
 		return nil, err
 	}
 	p.UID = p.Subject
+	p.ProjectID = p.Audience
 	return p, nil
 }
-
-func parseKey(key string) (*rsa.PrivateKey, error) {
-	block, _ := pem.Decode([]byte(key))
-	if block == nil {
-		return nil, fmt.Errorf("no private key data found in: %v", key)
-	}
-	k := block.Bytes
-	parsedKey, err := x509.ParsePKCS8PrivateKey(k)
-	if err != nil {
-		parsedKey, err = x509.ParsePKCS1PrivateKey(k)
-		if err != nil {
-			return nil, fmt.Errorf("private key should be a PEM or plain PKSC1 or PKCS8; parse error: %v", err)
-		}
-	}
-	parsed, ok := parsedKey.(*rsa.PrivateKey)
-	if !ok {
-		return nil, errors.New("private key is not an RSA key")
-	}
-	return parsed, nil
-}
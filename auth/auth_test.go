@@ -15,18 +15,22 @@
 package auth
 
 import (
+	"encoding/base64"
 	"errors"
 	"os"
+	"reflect"
 	"strings"
 	"testing"
 	"time"
 
 	"golang.org/x/net/context"
+	"golang.org/x/oauth2"
 	"golang.org/x/oauth2/google"
 
 	"google.golang.org/api/option"
 	"google.golang.org/api/transport"
 
+	"firebase.google.com/go/cache"
 	"firebase.google.com/go/internal"
 )
 
@@ -34,6 +38,18 @@ var creds *google.DefaultCredentials
 var client *Client
 var testIDToken string
 
+var testOpts = []option.ClientOption{
+	option.WithTokenSource(&mockTokenSource{"test.token"}),
+}
+
+type mockTokenSource struct {
+	AccessToken string
+}
+
+func (ts *mockTokenSource) Token() (*oauth2.Token, error) {
+	return &oauth2.Token{AccessToken: ts.AccessToken}, nil
+}
+
 func verifyCustomToken(t *testing.T, token string, expected map[string]interface{}) {
 	h := &jwtHeader{}
 	p := &customToken{}
@@ -101,7 +117,8 @@ func TestMain(m *testing.M) {
 		os.Exit(1)
 	}
 
-	client, err = NewClient(&internal.AuthConfig{
+	client, err = NewClient(context.Background(), &internal.AuthConfig{
+		Opts:      testOpts,
 		Creds:     creds,
 		ProjectID: "mock-project-id",
 	})
@@ -163,7 +180,7 @@ func TestCustomTokenError(t *testing.T) {
 }
 
 func TestCustomTokenInvalidCredential(t *testing.T) {
-	s, err := NewClient(&internal.AuthConfig{})
+	s, err := NewClient(context.Background(), &internal.AuthConfig{Opts: testOpts})
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -179,6 +196,42 @@ func TestCustomTokenInvalidCredential(t *testing.T) {
 	}
 }
 
+func TestCustomTokenEmulator(t *testing.T) {
+	os.Setenv(authEmulatorHostEnv, "localhost:9099")
+	defer os.Unsetenv(authEmulatorHostEnv)
+	c, err := NewClient(context.Background(), &internal.AuthConfig{Opts: testOpts})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	claims := map[string]interface{}{"premium": true}
+	token, err := c.CustomTokenWithClaims("user1", claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+	segments := strings.Split(token, ".")
+	if len(segments) != 3 || segments[2] != "" {
+		t.Fatalf("CustomTokenWithClaims() = %q; want = unsigned JWT", token)
+	}
+	var h jwtHeader
+	if err := decode(segments[0], &h); err != nil {
+		t.Fatal(err)
+	}
+	if h.Algorithm != "none" {
+		t.Errorf("Algorithm = %q; want = %q", h.Algorithm, "none")
+	}
+	var p customToken
+	if err := p.decode(segments[1]); err != nil {
+		t.Fatal(err)
+	}
+	if p.Iss != emulatorEmail || p.Sub != emulatorEmail || p.UID != "user1" || p.Aud != firebaseAudience {
+		t.Errorf("CustomTokenWithClaims() payload = %#v; want = emulator token for user1", p)
+	}
+	if !reflect.DeepEqual(p.Claims, claims) {
+		t.Errorf("Claims = %v; want = %v", p.Claims, claims)
+	}
+}
+
 func TestVerifyIDToken(t *testing.T) {
 	ft, err := client.VerifyIDToken(testIDToken)
 	if err != nil {
@@ -225,8 +278,54 @@ func TestVerifyIDTokenError(t *testing.T) {
 	}
 }
 
+func TestVerifyIDTokenDecodeError(t *testing.T) {
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"RS256","typ":"JWT","kid":"key1"}`))
+	cases := []struct {
+		name  string
+		token string
+		want  *TokenDecodeError
+	}{
+		{"Segments", "foo.bar", &TokenDecodeError{Kind: TokenDecodeSegments, Segment: -1, Offset: -1}},
+		{"HeaderBase64", "ab!c.payload.sig", &TokenDecodeError{Kind: TokenDecodeBase64, Segment: 0, Offset: 2}},
+		{
+			"HeaderJSON",
+			base64.RawURLEncoding.EncodeToString([]byte(`{"alg":}`)) + ".payload.sig",
+			&TokenDecodeError{Kind: TokenDecodeJSON, Segment: 0, Offset: 8},
+		},
+		{
+			"PayloadBase64",
+			header + ".pay*load.sig",
+			&TokenDecodeError{
+				Kind: TokenDecodeBase64, Segment: 1, Offset: 3, Algorithm: "RS256", Type: "JWT", KeyID: "key1",
+			},
+		},
+		{
+			"PayloadJSON",
+			header + "." + base64.RawURLEncoding.EncodeToString([]byte(`{"sub": 10}`)) + ".sig",
+			&TokenDecodeError{
+				Kind: TokenDecodeJSON, Segment: 1, Offset: 10, Algorithm: "RS256", Type: "JWT", KeyID: "key1",
+			},
+		},
+	}
+	for _, tc := range cases {
+		_, err := client.VerifyIDToken(tc.token)
+		de, ok := err.(*TokenDecodeError)
+		if !ok {
+			t.Errorf("VerifyIDToken(%s) = %v; want = TokenDecodeError", tc.name, err)
+			continue
+		}
+		if de.Err == nil || de.Error() == "" {
+			t.Errorf("VerifyIDToken(%s) = %#v; want non-empty cause", tc.name, de)
+		}
+		de.Err = nil
+		if !reflect.DeepEqual(de, tc.want) {
+			t.Errorf("VerifyIDToken(%s) = %#v; want = %#v", tc.name, de, tc.want)
+		}
+	}
+}
+
 func TestNoProjectID(t *testing.T) {
-	c, err := NewClient(&internal.AuthConfig{Creds: creds})
+	c, err := NewClient(context.Background(), &internal.AuthConfig{Opts: testOpts, Creds: creds})
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -257,3 +356,221 @@ func TestCertificateRequestError(t *testing.T) {
 		t.Error("VeridyIDToken() = nil; want error")
 	}
 }
+
+func TestVerifyIDTokens(t *testing.T) {
+	tokens := []string{
+		testIDToken,
+		getIDToken(mockIDTokenPayload{"aud": "bad-audience"}),
+		getIDToken(mockIDTokenPayload{"sub": "uid2"}),
+		"",
+	}
+	results, err := client.VerifyIDTokens(context.Background(), tokens)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != len(tokens) {
+		t.Fatalf("VerifyIDTokens() = %d results; want: %d", len(results), len(tokens))
+	}
+
+	wantUIDs := []string{"1234567890", "", "uid2", ""}
+	for i, r := range results {
+		if wantUIDs[i] == "" {
+			if r.Token != nil || r.Err == nil {
+				t.Errorf("results[%d] = (%v, %v); want: (nil, error)", i, r.Token, r.Err)
+			}
+		} else if r.Err != nil || r.Token.UID != wantUIDs[i] {
+			t.Errorf("results[%d] = (%v, %v); want: (%q, nil)", i, r.Token, r.Err, wantUIDs[i])
+		}
+	}
+}
+
+func TestVerifyIDTokensCancelledContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tokens := make([]string, 100)
+	for i := range tokens {
+		tokens[i] = testIDToken
+	}
+	results, err := client.VerifyIDTokens(ctx, tokens)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, r := range results {
+		if r == nil || (r.Token == nil && r.Err == nil) {
+			t.Fatalf("results[%d] = %v; want: non-empty result", i, r)
+		}
+	}
+	if last := results[len(results)-1]; last.Err != context.Canceled {
+		t.Errorf("results[last].Err = %v; want: %v", last.Err, context.Canceled)
+	}
+}
+
+func TestVerifyIDTokensKeySourceError(t *testing.T) {
+	ks := client.ks
+	client.ks = &mockKeySource{nil, errors.New("mock error")}
+	defer func() {
+		client.ks = ks
+	}()
+	if results, err := client.VerifyIDTokens(context.Background(), []string{testIDToken}); results != nil || err == nil {
+		t.Errorf("VerifyIDTokens() = (%v, %v); want: (nil, error)", results, err)
+	}
+}
+
+func TestWarmUp(t *testing.T) {
+	if err := client.WarmUp(context.Background()); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestWarmUpKeySourceError(t *testing.T) {
+	ks := client.ks
+	client.ks = &mockKeySource{nil, errors.New("mock error")}
+	defer func() {
+		client.ks = ks
+	}()
+	if err := client.WarmUp(context.Background()); err == nil {
+		t.Error("WarmUp() = nil; want error")
+	}
+}
+
+func TestNewClientStrictVerificationIgnoresCache(t *testing.T) {
+	shared := cache.NewMemoryCache()
+	for _, strict := range []bool{false, true} {
+		c, err := NewClient(context.Background(), &internal.AuthConfig{
+			Opts:               testOpts,
+			ProjectID:          "mock-project-id",
+			StrictVerification: strict,
+			Cache:              shared,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := c.ks.(*httpKeySource).Cache
+		if strict && got != nil {
+			t.Errorf("NewClient(strict) key cache = %v; want = nil", got)
+		} else if !strict && got != shared {
+			t.Errorf("NewClient() key cache = %v; want = %v", got, shared)
+		}
+	}
+}
+
+func TestVerifyIDTokenAcceptedProjects(t *testing.T) {
+	c, err := NewClient(context.Background(), &internal.AuthConfig{
+		Opts:               testOpts,
+		ProjectID:          "mock-project-id",
+		AcceptedProjectIDs: []string{"staging-project"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.ks = client.ks
+
+	for _, pid := range []string{"mock-project-id", "staging-project"} {
+		token := getIDToken(mockIDTokenPayload{
+			"aud": pid,
+			"iss": "https://securetoken.google.com/" + pid,
+		})
+		ft, err := c.VerifyIDToken(token)
+		if err != nil {
+			t.Errorf("VerifyIDToken(%q) = %v; want = nil", pid, err)
+		} else if ft.ProjectID != pid {
+			t.Errorf("ProjectID = %q; want = %q", ft.ProjectID, pid)
+		}
+	}
+
+	token := getIDToken(mockIDTokenPayload{
+		"aud": "other-project",
+		"iss": "https://securetoken.google.com/other-project",
+	})
+	if ft, err := c.VerifyIDToken(token); ft != nil || err == nil {
+		t.Errorf("VerifyIDToken(other-project) = (%v, %v); want = (nil, error)", ft, err)
+	}
+}
+
+func TestVerifyIDTokenAndCheckRevokedAcceptedProject(t *testing.T) {
+	s := echoServer(testGetUserResponse(t), t)
+	defer s.Close()
+	s.Client.ks = client.ks
+	s.Client.acceptedProjects = map[string]bool{"staging-project": true}
+
+	token := getIDToken(mockIDTokenPayload{
+		"aud": "staging-project",
+		"iss": "https://securetoken.google.com/staging-project",
+	})
+	if _, err := s.Client.VerifyIDToken(token); err != nil {
+		t.Fatal(err)
+	}
+	if ft, err := s.Client.VerifyIDTokenAndCheckRevoked(context.Background(), token); ft != nil || err == nil {
+		t.Errorf("VerifyIDTokenAndCheckRevoked(staging-project) = (%v, %v); want = (nil, error)", ft, err)
+	}
+	if len(s.Req) != 0 {
+		t.Errorf("Requests = %d; want = 0", len(s.Req))
+	}
+}
+
+func TestNewClientInvalidAcceptedProjects(t *testing.T) {
+	c, err := NewClient(context.Background(), &internal.AuthConfig{
+		Opts:               testOpts,
+		ProjectID:          "mock-project-id",
+		AcceptedProjectIDs: []string{""},
+	})
+	if c != nil || err == nil {
+		t.Errorf("NewClient() = (%v, %v); want = (nil, error)", c, err)
+	}
+}
+
+func TestVerifyIDTokenAndCheckRevoked(t *testing.T) {
+	s := echoServer(testGetUserResponse(t), t)
+	defer s.Close()
+	s.Client.ks = client.ks
+
+	ft, err := s.Client.VerifyIDTokenAndCheckRevoked(context.Background(), testIDToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ft.UID != "1234567890" {
+		t.Errorf("UID = %q; want = %q", ft.UID, "1234567890")
+	}
+	checkRequest(t, s, "/projects/mock-project-id/accounts:lookup", map[string]interface{}{
+		"localId": []interface{}{"1234567890"},
+	})
+}
+
+func TestVerifyIDTokenAndCheckRevokedErrors(t *testing.T) {
+	revoked := getIDToken(mockIDTokenPayload{"iat": 1494364392})
+	cases := []struct {
+		name  string
+		resp  string
+		token string
+		check func(error) bool
+	}{
+		{"Revoked", `{"users": [{"localId": "1234567890", "validSince": "1494364393"}]}`, revoked, IsIDTokenRevoked},
+		{"Disabled", `{"users": [{"localId": "1234567890", "disabled": true}]}`, testIDToken, IsUserDisabled},
+		{"Deleted", `{"users": []}`, testIDToken, IsUserNotFound},
+	}
+	for _, tc := range cases {
+		s := echoServer([]byte(tc.resp), t)
+		s.Client.ks = client.ks
+
+		ft, err := s.Client.VerifyIDTokenAndCheckRevoked(context.Background(), tc.token)
+		if ft != nil || !tc.check(err) {
+			t.Errorf("VerifyIDTokenAndCheckRevoked(%s) = (%v, %v); want = (nil, %s error)", tc.name, ft, err, tc.name)
+		}
+		s.Close()
+	}
+}
+
+func TestVerifyIDTokenAndCheckRevokedInvalidToken(t *testing.T) {
+	s := echoServer(testGetUserResponse(t), t)
+	defer s.Close()
+	s.Client.ks = client.ks
+
+	token := getIDToken(mockIDTokenPayload{"aud": "bad-audience"})
+	if ft, err := s.Client.VerifyIDTokenAndCheckRevoked(context.Background(), token); ft != nil || err == nil {
+		t.Errorf("VerifyIDTokenAndCheckRevoked() = (%v, %v); want = (nil, error)", ft, err)
+	}
+	if len(s.Req) != 0 {
+		t.Errorf("requests = %d; want = 0", len(s.Req))
+	}
+}
@@ -0,0 +1,97 @@
+// Copyright 2017 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"fmt"
+	"net/url"
+)
+
+const (
+	beforeCreateTriggerKey = "blockingFunctions.triggers.beforeCreate"
+	beforeSignInTriggerKey = "blockingFunctions.triggers.beforeSignIn"
+	forwardCredentialsKey  = "blockingFunctions.forwardInboundCredentials"
+)
+
+// BlockingFunctionsConfig represents the blocking functions of a project, which are HTTPS endpoints
+// called by the Auth service before a user is created or signed in, and which can reject the
+// operation or modify the user.
+//
+// BeforeCreateURI and BeforeSignInURI are empty when no function is registered for the event. The
+// Forward fields indicate which OAuth credentials of the identity provider are passed to the
+// functions.
+type BlockingFunctionsConfig struct {
+	BeforeCreateURI     string
+	BeforeSignInURI     string
+	ForwardIDToken      bool
+	ForwardAccessToken  bool
+	ForwardRefreshToken bool
+}
+
+// blockingFunctionTrigger is the JSON representation of the Trigger resource of the Identity Toolkit
+// service.
+type blockingFunctionTrigger struct {
+	FunctionURI string `json:"functionUri"`
+}
+
+// forwardInboundCredentials is the JSON representation of the ForwardInboundCredentials resource of
+// the Identity Toolkit service.
+type forwardInboundCredentials struct {
+	IDToken      bool `json:"idToken"`
+	AccessToken  bool `json:"accessToken"`
+	RefreshToken bool `json:"refreshToken"`
+}
+
+// blockingFunctionsResponse is the JSON representation of the BlockingFunctionsConfig resource of the
+// Identity Toolkit service.
+type blockingFunctionsResponse struct {
+	Triggers                  map[string]*blockingFunctionTrigger `json:"triggers"`
+	ForwardInboundCredentials forwardInboundCredentials           `json:"forwardInboundCredentials"`
+}
+
+func (r *blockingFunctionsResponse) makeBlockingFunctionsConfig() *BlockingFunctionsConfig {
+	config := &BlockingFunctionsConfig{
+		ForwardIDToken:      r.ForwardInboundCredentials.IDToken,
+		ForwardAccessToken:  r.ForwardInboundCredentials.AccessToken,
+		ForwardRefreshToken: r.ForwardInboundCredentials.RefreshToken,
+	}
+	if t, ok := r.Triggers["beforeCreate"]; ok && t != nil {
+		config.BeforeCreateURI = t.FunctionURI
+	}
+	if t, ok := r.Triggers["beforeSignIn"]; ok && t != nil {
+		config.BeforeSignInURI = t.FunctionURI
+	}
+	return config
+}
+
+// blockingFunctionTriggerFor returns the trigger for the given function URI, or nil to remove the
+// trigger when the URI is empty.
+func blockingFunctionTriggerFor(uri string) *blockingFunctionTrigger {
+	if uri == "" {
+		return nil
+	}
+	return &blockingFunctionTrigger{FunctionURI: uri}
+}
+
+func validateBlockingFunctionTrigger(trigger *blockingFunctionTrigger) error {
+	if trigger == nil {
+		return nil
+	}
+	u, err := url.Parse(trigger.FunctionURI)
+	if err != nil || u.Scheme != "https" || u.Host == "" {
+		return fmt.Errorf("blocking function URI must be a valid HTTPS URL: %q", trigger.FunctionURI)
+	}
+	return nil
+}
@@ -0,0 +1,216 @@
+// Copyright 2017 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"golang.org/x/net/context"
+	"google.golang.org/api/iterator"
+)
+
+// claimsRetryDelays are the delays between successive attempts to set the custom claims of a user, when
+// an attempt fails due to the request quota being exceeded, and the Client has no rate limit.
+var claimsRetryDelays = []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second}
+
+// ClaimsUpdateReport summarizes the outcome of a SetCustomUserClaimsBatch call.
+type ClaimsUpdateReport struct {
+	SuccessCount int
+	FailureCount int
+	Errors       []*ClaimsUpdateError
+}
+
+// ClaimsUpdateError describes the failure to set the custom claims of one user.
+type ClaimsUpdateError struct {
+	UID string
+	Err error
+}
+
+// SetCustomUserClaimsBatch sets custom claims on each user account whose user ID is received from the
+// uids channel, until the channel is closed.
+//
+// The claims for each user are obtained by calling the claims function with the user ID, and are
+// validated as described in SetCustomUserClaims. Up to concurrency users are updated in parallel.
+// Updates rejected because the request quota of the project was exceeded are retried with exponential
+// backoff, or, if the Client has a rate limit set with WithRateLimit, as configured by the MaxRetries
+// and backoff of that limit. Failures of individual users are reported in the returned
+// ClaimsUpdateReport rather than as an error.
+//
+// If the context is done before the uids channel is closed, SetCustomUserClaimsBatch stops receiving
+// user IDs, waits for the updates in progress, and returns the report so far along with the context
+// error.
+func (c *Client) SetCustomUserClaimsBatch(ctx context.Context, uids <-chan string,
+	claims func(uid string) map[string]interface{}, concurrency int) (*ClaimsUpdateReport, error) {
+	if uids == nil {
+		return nil, errors.New("uids channel must not be nil")
+	}
+	if claims == nil {
+		return nil, errors.New("claims function must not be nil")
+	}
+	if concurrency < 1 {
+		return nil, errors.New("concurrency must be at least 1")
+	}
+
+	report := &ClaimsUpdateReport{}
+	var mutex sync.Mutex
+	var wg sync.WaitGroup
+	work := make(chan string)
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for uid := range work {
+				err := c.setCustomUserClaimsWithRetry(ctx, uid, claims(uid))
+				mutex.Lock()
+				if err != nil {
+					report.FailureCount++
+					report.Errors = append(report.Errors, &ClaimsUpdateError{UID: uid, Err: err})
+				} else {
+					report.SuccessCount++
+				}
+				mutex.Unlock()
+			}
+		}()
+	}
+
+	var err error
+loop:
+	for {
+		select {
+		case uid, ok := <-uids:
+			if !ok {
+				break loop
+			}
+			select {
+			case work <- uid:
+			case <-ctx.Done():
+				err = ctx.Err()
+				break loop
+			}
+		case <-ctx.Done():
+			err = ctx.Err()
+			break loop
+		}
+	}
+	close(work)
+	wg.Wait()
+	return report, err
+}
+
+// ClaimsQuery specifies the users whose custom claims are updated by SetCustomUserClaimsByQuery, and how.
+type ClaimsQuery struct {
+	// Match selects the users to update. If nil, all users are updated.
+	Match func(user *UserRecord) bool
+
+	// Claims returns the new custom claims of a matching user, typically derived from the current
+	// claims of the user. It must not be nil.
+	Claims func(user *UserRecord) map[string]interface{}
+
+	// Concurrency is the number of users updated in parallel. Defaults to 1.
+	Concurrency int
+
+	// Checkpoint resumes a previous run from the checkpoint last reported to its Progress function.
+	// If empty, all users of the project are considered.
+	Checkpoint string
+
+	// Progress is called, if not nil, after each page of users has been processed, with the
+	// cumulative report so far and a checkpoint from which the run can be resumed. The checkpoint is
+	// empty once all users have been processed.
+	Progress func(report *ClaimsUpdateReport, checkpoint string)
+}
+
+// SetCustomUserClaimsByQuery sets the custom claims of all the users of the project that match the
+// query.
+//
+// Users are listed with ListUsers, one page at a time, and the matching users of each page are updated
+// as described in SetCustomUserClaimsBatch. A run that fails or is interrupted can be resumed by
+// passing the last checkpoint reported to the Progress function in a new query. Users of the page
+// being processed when the run stopped are updated again when the run is resumed, hence the Claims
+// function should produce the same claims when applied to already updated users.
+func (c *Client) SetCustomUserClaimsByQuery(ctx context.Context, query *ClaimsQuery) (*ClaimsUpdateReport, error) {
+	if query == nil || query.Claims == nil {
+		return nil, errors.New("query and its claims function must not be nil")
+	}
+	concurrency := query.Concurrency
+	if concurrency == 0 {
+		concurrency = 1
+	}
+	if concurrency < 0 {
+		return nil, errors.New("concurrency must not be negative")
+	}
+
+	report := &ClaimsUpdateReport{}
+	pager := iterator.NewPager(c.ListUsers(ctx), maxListUsersResults, query.Checkpoint)
+	for {
+		var users []*UserRecord
+		checkpoint, err := pager.NextPage(&users)
+		if err != nil {
+			return report, err
+		}
+
+		matched := make(map[string]*UserRecord)
+		uids := make(chan string, len(users))
+		for _, user := range users {
+			if query.Match == nil || query.Match(user) {
+				matched[user.UID] = user
+				uids <- user.UID
+			}
+		}
+		close(uids)
+
+		claims := func(uid string) map[string]interface{} {
+			return query.Claims(matched[uid])
+		}
+		pageReport, err := c.SetCustomUserClaimsBatch(ctx, uids, claims, concurrency)
+		if pageReport != nil {
+			report.SuccessCount += pageReport.SuccessCount
+			report.FailureCount += pageReport.FailureCount
+			report.Errors = append(report.Errors, pageReport.Errors...)
+		}
+		if err != nil {
+			return report, err
+		}
+		if query.Progress != nil {
+			query.Progress(report, checkpoint)
+		}
+		if checkpoint == "" {
+			return report, nil
+		}
+	}
+}
+
+// setCustomUserClaimsWithRetry sets the custom claims of a user, retrying when the request quota is
+// exceeded. Clients with a rate limit already retry quota errors in makeRequest, hence the claims are
+// set only once.
+func (c *Client) setCustomUserClaimsWithRetry(ctx context.Context, uid string, claims map[string]interface{}) error {
+	err := c.SetCustomUserClaims(ctx, uid, claims)
+	if c.limiter != nil {
+		return err
+	}
+	for _, d := range claimsRetryDelays {
+		if err == nil || !IsQuotaExceeded(err) {
+			break
+		}
+		select {
+		case <-time.After(d):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		err = c.SetCustomUserClaims(ctx, uid, claims)
+	}
+	return err
+}
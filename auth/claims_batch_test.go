@@ -0,0 +1,305 @@
+// Copyright 2017 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+func uidStream(uids ...string) <-chan string {
+	ch := make(chan string, len(uids))
+	for _, uid := range uids {
+		ch <- uid
+	}
+	close(ch)
+	return ch
+}
+
+func adminClaims(uid string) map[string]interface{} {
+	return map[string]interface{}{"admin": true}
+}
+
+func adminUserClaims(user *UserRecord) map[string]interface{} {
+	return map[string]interface{}{"admin": true}
+}
+
+// claimsServer starts a mock Identity Toolkit server that responds to each update request with a quota
+// error as many times as specified for the user in the request, and then with success. Requests for the
+// user ID "invalid" are rejected with a non-retryable error.
+func claimsServer(t *testing.T, quotaErrors map[string]int) (*Client, *httptest.Server) {
+	var mutex sync.Mutex
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		b, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var req struct {
+			UID string `json:"localId"`
+		}
+		if err := json.Unmarshal(b, &req); err != nil {
+			t.Fatal(err)
+		}
+
+		mutex.Lock()
+		defer mutex.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		if req.UID == "invalid" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error": {"message": "USER_NOT_FOUND"}}`))
+		} else if quotaErrors[req.UID] > 0 {
+			quotaErrors[req.UID]--
+			w.WriteHeader(http.StatusTooManyRequests)
+			w.Write([]byte(`{"error": {"message": "QUOTA_EXCEEDED : Exceeded quota for updating account information."}}`))
+		} else {
+			w.Write([]byte(fmt.Sprintf(`{"localId": %q}`, req.UID)))
+		}
+	}
+	s := httptest.NewServer(http.HandlerFunc(handler))
+	c := *client
+	c.userEndpoint = s.URL
+	return &c, s
+}
+
+func TestSetCustomUserClaimsBatch(t *testing.T) {
+	delays := claimsRetryDelays
+	claimsRetryDelays = []time.Duration{0, 0}
+	defer func() {
+		claimsRetryDelays = delays
+	}()
+
+	c, s := claimsServer(t, map[string]int{"uid2": 2, "uid3": 3})
+	defer s.Close()
+
+	uids := uidStream("uid1", "uid2", "uid3", "invalid")
+	report, err := c.SetCustomUserClaimsBatch(context.Background(), uids, adminClaims, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.SuccessCount != 2 || report.FailureCount != 2 || len(report.Errors) != 2 {
+		t.Fatalf("SetCustomUserClaimsBatch() = %v; want = 2 successes, 2 failures", report)
+	}
+	for _, e := range report.Errors {
+		if e.UID == "uid3" && !IsQuotaExceeded(e.Err) {
+			t.Errorf("Errors[uid3] = %v; want = quota-exceeded error", e.Err)
+		} else if e.UID == "invalid" && !IsUserNotFound(e.Err) {
+			t.Errorf("Errors[invalid] = %v; want = user-not-found error", e.Err)
+		} else if e.UID != "uid3" && e.UID != "invalid" {
+			t.Errorf("Errors = %v; want = errors for uid3 and invalid", e)
+		}
+	}
+}
+
+func TestSetCustomUserClaimsBatchRateLimit(t *testing.T) {
+	delays := claimsRetryDelays
+	claimsRetryDelays = []time.Duration{0, 0}
+	defer func() {
+		claimsRetryDelays = delays
+	}()
+
+	c, s := claimsServer(t, map[string]int{"uid1": 1, "uid2": 2})
+	defer s.Close()
+	limited, err := c.WithRateLimit(&RateLimit{MaxRetries: 1, InitialBackoff: time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	uids := uidStream("uid1", "uid2")
+	report, err := limited.SetCustomUserClaimsBatch(context.Background(), uids, adminClaims, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.SuccessCount != 1 || report.FailureCount != 1 || len(report.Errors) != 1 {
+		t.Fatalf("SetCustomUserClaimsBatch() = %v; want = 1 success, 1 failure", report)
+	}
+	if e := report.Errors[0]; e.UID != "uid2" || !IsQuotaExceeded(e.Err) {
+		t.Errorf("Errors = %v; want = quota-exceeded error for uid2", e)
+	}
+}
+
+func TestSetCustomUserClaimsBatchInvalidClaims(t *testing.T) {
+	c, s := claimsServer(t, nil)
+	defer s.Close()
+
+	claims := func(uid string) map[string]interface{} {
+		return map[string]interface{}{"sub": uid}
+	}
+	report, err := c.SetCustomUserClaimsBatch(context.Background(), uidStream("uid1"), claims, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.SuccessCount != 0 || report.FailureCount != 1 {
+		t.Errorf("SetCustomUserClaimsBatch() = %v; want = 1 failure", report)
+	}
+}
+
+func TestSetCustomUserClaimsBatchCancelledContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	uids := make(chan string)
+	report, err := client.SetCustomUserClaimsBatch(ctx, uids, adminClaims, 4)
+	if err != context.Canceled {
+		t.Errorf("SetCustomUserClaimsBatch() err = %v; want = %v", err, context.Canceled)
+	}
+	if report == nil || report.SuccessCount != 0 || report.FailureCount != 0 {
+		t.Errorf("SetCustomUserClaimsBatch() = %v; want = empty report", report)
+	}
+}
+
+func TestSetCustomUserClaimsBatchInvalidArgs(t *testing.T) {
+	ctx := context.Background()
+	cases := []struct {
+		uids        <-chan string
+		claims      func(string) map[string]interface{}
+		concurrency int
+	}{
+		{nil, adminClaims, 1},
+		{uidStream(), nil, 1},
+		{uidStream(), adminClaims, 0},
+	}
+	for _, tc := range cases {
+		report, err := client.SetCustomUserClaimsBatch(ctx, tc.uids, tc.claims, tc.concurrency)
+		if report != nil || err == nil {
+			t.Errorf("SetCustomUserClaimsBatch() = (%v, %v); want = (nil, error)", report, err)
+		}
+	}
+}
+
+// claimsQueryServer starts a mock Identity Toolkit server that lists users in the given pages, keyed
+// by page token, and records the custom claims set on each user.
+func claimsQueryServer(t *testing.T, pages map[string]string) (*pagedUsersServer, map[string]string) {
+	var mutex sync.Mutex
+	updated := make(map[string]string)
+	s := newPagedUsersServer(t, pages, func(w http.ResponseWriter, r *http.Request) {
+		b, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var req struct {
+			UID    string `json:"localId"`
+			Claims string `json:"customAttributes"`
+		}
+		if err := json.Unmarshal(b, &req); err != nil {
+			t.Fatal(err)
+		}
+		mutex.Lock()
+		defer mutex.Unlock()
+		updated[req.UID] = req.Claims
+		w.Write([]byte(fmt.Sprintf(`{"localId": %q}`, req.UID)))
+	})
+	return s, updated
+}
+
+var claimsQueryPages = map[string]string{
+	"": `{
+		"users": [
+			{"localId": "user1", "customAttributes": "{\"role\": \"editor\"}"},
+			{"localId": "user2", "customAttributes": "{\"role\": \"viewer\"}"}
+		],
+		"nextPageToken": "page2"
+	}`,
+	"page2": `{
+		"users": [
+			{"localId": "user3", "customAttributes": "{\"role\": \"editor\"}"},
+			{"localId": "user4"}
+		]
+	}`,
+}
+
+var editorsQuery = &ClaimsQuery{
+	Match: func(user *UserRecord) bool {
+		return user.CustomClaims["role"] == "editor"
+	},
+	Claims: func(user *UserRecord) map[string]interface{} {
+		return map[string]interface{}{"role": "writer"}
+	},
+	Concurrency: 2,
+}
+
+func TestSetCustomUserClaimsByQuery(t *testing.T) {
+	s, updated := claimsQueryServer(t, claimsQueryPages)
+	defer s.Close()
+
+	query := *editorsQuery
+	var checkpoints []string
+	query.Progress = func(report *ClaimsUpdateReport, checkpoint string) {
+		checkpoints = append(checkpoints, checkpoint)
+	}
+	report, err := s.Client.SetCustomUserClaimsByQuery(context.Background(), &query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.SuccessCount != 2 || report.FailureCount != 0 {
+		t.Errorf("SetCustomUserClaimsByQuery() = %#v; want = {SuccessCount: 2}", report)
+	}
+	want := map[string]string{
+		"user1": `{"role":"writer"}`,
+		"user3": `{"role":"writer"}`,
+	}
+	if !reflect.DeepEqual(updated, want) {
+		t.Errorf("Updated claims = %v; want = %v", updated, want)
+	}
+	wantCheckpoints := []string{"page2", ""}
+	if !reflect.DeepEqual(checkpoints, wantCheckpoints) {
+		t.Errorf("Checkpoints = %v; want = %v", checkpoints, wantCheckpoints)
+	}
+}
+
+func TestSetCustomUserClaimsByQueryResume(t *testing.T) {
+	s, updated := claimsQueryServer(t, claimsQueryPages)
+	defer s.Close()
+
+	query := *editorsQuery
+	query.Match = nil
+	query.Checkpoint = "page2"
+	report, err := s.Client.SetCustomUserClaimsByQuery(context.Background(), &query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.SuccessCount != 2 {
+		t.Errorf("SetCustomUserClaimsByQuery() = %#v; want = {SuccessCount: 2}", report)
+	}
+	want := map[string]string{
+		"user3": `{"role":"writer"}`,
+		"user4": `{"role":"writer"}`,
+	}
+	if !reflect.DeepEqual(updated, want) {
+		t.Errorf("Updated claims = %v; want = %v", updated, want)
+	}
+}
+
+func TestSetCustomUserClaimsByQueryInvalid(t *testing.T) {
+	cases := []*ClaimsQuery{
+		nil,
+		{},
+		{Claims: adminUserClaims, Concurrency: -1},
+	}
+	for _, tc := range cases {
+		report, err := client.SetCustomUserClaimsByQuery(context.Background(), tc)
+		if report != nil || err == nil {
+			t.Errorf("SetCustomUserClaimsByQuery(%v) = (%v, %v); want = (nil, error)", tc, report, err)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+// Copyright 2017 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"fmt"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+// ClaimsHistoryRecord describes an update of the custom claims of a user.
+//
+// Previous holds the claims of the user before the update, and is nil if the user had no custom
+// claims. New holds the claims set by the update, and is empty when the claims are removed.
+type ClaimsHistoryRecord struct {
+	UID       string
+	Previous  map[string]interface{}
+	New       map[string]interface{}
+	Timestamp time.Time
+}
+
+// ClaimsHistoryStore persists the custom claims of users before they are overwritten, so that claim
+// updates can be audited, and rolled back by setting the previous claims again.
+//
+// Implementations must be safe for concurrent use by multiple goroutines, as batch claim updates
+// record their changes concurrently.
+type ClaimsHistoryStore interface {
+	// SaveClaims records an update of the custom claims of a user. It is called before the update is
+	// sent to the server. The update is not made if SaveClaims returns an error, so that no claims are
+	// overwritten without a record. A record is therefore not a guarantee that the update succeeded.
+	SaveClaims(ctx context.Context, record *ClaimsHistoryRecord) error
+}
+
+// WithClaimsHistory returns a copy of the Client that records every update of custom claims in the
+// given store, before making it. The Client on which it is called is not modified.
+//
+// Claims updated through SetCustomUserClaims, SetCustomUserClaimsBatch, SetCustomUserClaimsByQuery and
+// UpdateUser are recorded. Each update first looks up the current claims of the user, which costs an
+// additional request to the server.
+func (c *Client) WithClaimsHistory(store ClaimsHistoryStore) *Client {
+	copied := *c
+	copied.claimsHistory = store
+	return &copied
+}
+
+// saveClaimsHistory records the update of the claims of the given user in the claims history store of
+// the Client, if any.
+func (c *Client) saveClaimsHistory(ctx context.Context, uid string, claims map[string]interface{}) error {
+	if c.claimsHistory == nil {
+		return nil
+	}
+	user, err := c.GetUser(ctx, uid)
+	if err != nil {
+		return err
+	}
+	record := &ClaimsHistoryRecord{
+		UID:       uid,
+		Previous:  user.CustomClaims,
+		New:       claims,
+		Timestamp: clk.Now(),
+	}
+	if err := c.claimsHistory.SaveClaims(ctx, record); err != nil {
+		return fmt.Errorf("failed to save claims history: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+// Copyright 2017 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"errors"
+	"net/http"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+type mockClaimsHistoryStore struct {
+	mutex   sync.Mutex
+	records []*ClaimsHistoryRecord
+	err     error
+}
+
+func (s *mockClaimsHistoryStore) SaveClaims(ctx context.Context, record *ClaimsHistoryRecord) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.err != nil {
+		return s.err
+	}
+	s.records = append(s.records, record)
+	return nil
+}
+
+func TestWithClaimsHistory(t *testing.T) {
+	s := echoServer(testGetUserResponse(t), t)
+	defer s.Close()
+
+	now := time.Unix(1000, 0)
+	clk = &mockClock{now: now}
+	defer func() {
+		clk = &systemClock{}
+	}()
+
+	store := &mockClaimsHistoryStore{}
+	c := s.Client.WithClaimsHistory(store)
+	if s.Client.claimsHistory != nil {
+		t.Errorf("WithClaimsHistory() modified the original Client")
+	}
+	claims := map[string]interface{}{"admin": false}
+	if err := c.SetCustomUserClaims(context.Background(), "testuser", claims); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []*ClaimsHistoryRecord{
+		{
+			UID:       "testuser",
+			Previous:  map[string]interface{}{"admin": true, "package": "gold"},
+			New:       claims,
+			Timestamp: now,
+		},
+	}
+	if !reflect.DeepEqual(store.records, want) {
+		t.Errorf("SaveClaims() records = %#v; want = %#v", store.records, want)
+	}
+	if len(s.Req) != 2 {
+		t.Fatalf("Requests = %d; want = 2", len(s.Req))
+	}
+	if path := s.Req[0].URL.Path; path != "/projects/mock-project-id/accounts:lookup" {
+		t.Errorf("Path = %q; want = %q", path, "/projects/mock-project-id/accounts:lookup")
+	}
+	checkRequest(t, s, "/projects/mock-project-id/accounts:update", map[string]interface{}{
+		"localId":          "testuser",
+		"customAttributes": `{"admin":false}`,
+	})
+}
+
+func TestWithClaimsHistoryOtherUpdates(t *testing.T) {
+	s := echoServer(testGetUserResponse(t), t)
+	defer s.Close()
+
+	store := &mockClaimsHistoryStore{}
+	c := s.Client.WithClaimsHistory(store)
+	if _, err := c.UpdateUser(context.Background(), "testuser", (&UserToUpdate{}).DisplayName("name")); err != nil {
+		t.Fatal(err)
+	}
+	if len(store.records) != 0 {
+		t.Errorf("SaveClaims() records = %d; want = 0", len(store.records))
+	}
+}
+
+func TestWithClaimsHistoryStoreError(t *testing.T) {
+	s := echoServer(testGetUserResponse(t), t)
+	defer s.Close()
+
+	store := &mockClaimsHistoryStore{err: errors.New("store unavailable")}
+	c := s.Client.WithClaimsHistory(store)
+	if err := c.SetCustomUserClaims(context.Background(), "testuser", nil); err == nil {
+		t.Errorf("SetCustomUserClaims() = nil; want = error")
+	}
+	for _, r := range s.Req {
+		if r.URL.Path == "/projects/mock-project-id/accounts:update" {
+			t.Errorf("SetCustomUserClaims() updated the user despite the store error")
+		}
+	}
+}
+
+func TestWithClaimsHistoryUserNotFound(t *testing.T) {
+	s := echoServer([]byte(`{"error": {"message": "USER_NOT_FOUND"}}`), t)
+	defer s.Close()
+	s.Status = http.StatusBadRequest
+
+	store := &mockClaimsHistoryStore{}
+	c := s.Client.WithClaimsHistory(store)
+	if err := c.SetCustomUserClaims(context.Background(), "testuser", nil); !IsUserNotFound(err) {
+		t.Errorf("SetCustomUserClaims() = %v; want = user-not-found error", err)
+	}
+	if len(store.records) != 0 {
+		t.Errorf("SaveClaims() records = %d; want = 0", len(store.records))
+	}
+}
@@ -23,12 +23,15 @@ import (
 	"encoding/json"
 	"encoding/pem"
 	"errors"
+	"fmt"
 	"io/ioutil"
 	"net/http"
 	"strconv"
 	"strings"
 	"sync"
 	"time"
+
+	"firebase.google.com/go/cache"
 )
 
 type publicKey struct {
@@ -58,16 +61,52 @@ type keySource interface {
 	Keys() ([]*publicKey, error)
 }
 
+// keyFetchRetryDelays are the delays between successive attempts to fetch public keys, when an attempt
+// fails due to a transient error.
+var keyFetchRetryDelays = []time.Duration{100 * time.Millisecond, 500 * time.Millisecond}
+
+// keyRefreshBackoff is the minimum time between the start of a failed attempt to refresh the public keys,
+// and the next attempt.
+var keyRefreshBackoff = 5 * time.Second
+
+// closedChan is a channel that is always closed.
+var closedChan = func() chan struct{} {
+	ch := make(chan struct{})
+	close(ch)
+	return ch
+}()
+
 // httpKeySource fetches RSA public keys from a remote HTTP server, and caches them in
 // memory. It also handles cache! invalidation and refresh based on the standard HTTP
 // cache-control headers.
+//
+// Fetches that fail due to network errors or server errors are retried a small number of times. Once
+// the keys have expired, they continue to be served until GracePeriod has elapsed past their expiry
+// time, while they are refreshed in the background (stale-while-revalidate). After a failed refresh,
+// the keys are not fetched again for keyRefreshBackoff, and the error of the failed refresh is returned
+// to callers that cannot be served stale keys.
+//
+// If a shared Cache is set, keys fetched from the remote server are also written to it, and keys
+// written to it by other key sources are used in preference to fetching them again.
 type httpKeySource struct {
-	KeyURI     string
-	HTTPClient *http.Client
-	CachedKeys []*publicKey
-	ExpiryTime time.Time
-	Clock      clock
-	Mutex      *sync.Mutex
+	KeyURI      string
+	HTTPClient  *http.Client
+	CachedKeys  []*publicKey
+	ExpiryTime  time.Time
+	GracePeriod time.Duration
+	Cache       cache.Cache
+	Clock       clock
+	Mutex       *sync.Mutex
+
+	refreshing  chan struct{} // closed when the refresh in progress completes; nil if none
+	refreshErr  error         // error of the last refresh, if it failed
+	lastFailure time.Time     // start of the last refresh, if it failed
+}
+
+// cachedKeys is the representation of the public keys stored in a shared Cache.
+type cachedKeys struct {
+	Certs  json.RawMessage `json:"certs"`
+	Expiry int64           `json:"expiry"`
 }
 
 func newHTTPKeySource(uri string) *httpKeySource {
@@ -80,51 +119,178 @@ func newHTTPKeySource(uri string) *httpKeySource {
 
 // Keys returns the RSA Public Keys hosted at this key source's URI. Refreshes the data if
 // the cache is stale.
+//
+// Keys only waits for the refresh when no keys can be served: expired keys still within the grace
+// period are returned immediately, while they are refreshed in the background.
 func (k *httpKeySource) Keys() ([]*publicKey, error) {
 	k.Mutex.Lock()
-	defer k.Mutex.Unlock()
 	if len(k.CachedKeys) == 0 || k.hasExpired() {
-		err := k.refreshKeys()
-		if err != nil && len(k.CachedKeys) == 0 {
-			return nil, err
+		k.loadSharedKeys()
+	}
+	if len(k.CachedKeys) > 0 && !k.pastGracePeriod() {
+		if k.hasExpired() {
+			k.startRefresh()
 		}
+		keys := k.CachedKeys
+		k.Mutex.Unlock()
+		return keys, nil
+	}
+	done := k.startRefresh()
+	k.Mutex.Unlock()
+
+	<-done
+	k.Mutex.Lock()
+	defer k.Mutex.Unlock()
+	if k.refreshErr != nil && (len(k.CachedKeys) == 0 || k.pastGracePeriod()) {
+		return nil, k.refreshErr
 	}
 	return k.CachedKeys, nil
 }
 
+// startRefresh starts refreshing the keys in a new goroutine, unless a refresh is already in progress,
+// or the last one failed less than keyRefreshBackoff ago. It returns a channel that is closed when the
+// refresh in progress completes, or a closed channel when no refresh is started. The Mutex of the key
+// source must be held by the caller.
+func (k *httpKeySource) startRefresh() <-chan struct{} {
+	if k.refreshing != nil {
+		return k.refreshing
+	}
+	if k.refreshErr != nil && k.Clock.Now().Before(k.lastFailure.Add(keyRefreshBackoff)) {
+		return closedChan
+	}
+	done := make(chan struct{})
+	k.refreshing = done
+	start := k.Clock.Now()
+	go func() {
+		err := k.refreshKeysWithRetry()
+		k.Mutex.Lock()
+		k.refreshing = nil
+		k.refreshErr = err
+		if err != nil {
+			k.lastFailure = start
+		}
+		k.Mutex.Unlock()
+		close(done)
+	}()
+	return done
+}
+
 // hasExpired indicates whether the cache has expired.
 func (k *httpKeySource) hasExpired() bool {
 	return k.Clock.Now().After(k.ExpiryTime)
 }
 
-func (k *httpKeySource) refreshKeys() error {
-	k.CachedKeys = nil
-	if k.HTTPClient == nil {
-		k.HTTPClient = http.DefaultClient
+// pastGracePeriod indicates whether the cache has expired, and can no longer be served while stale.
+func (k *httpKeySource) pastGracePeriod() bool {
+	return k.Clock.Now().After(k.ExpiryTime.Add(k.GracePeriod))
+}
+
+func (k *httpKeySource) cacheKey() string {
+	return "firebase.auth.publicKeys:" + k.KeyURI
+}
+
+// loadSharedKeys replaces the cached keys with the keys in the shared Cache, if the latter expire later.
+func (k *httpKeySource) loadSharedKeys() {
+	if k.Cache == nil {
+		return
+	}
+	b, ok := k.Cache.Get(k.cacheKey())
+	if !ok {
+		return
+	}
+	var ck cachedKeys
+	if err := json.Unmarshal(b, &ck); err != nil {
+		return
+	}
+	expiry := time.Unix(ck.Expiry, 0)
+	if len(k.CachedKeys) > 0 && !expiry.After(k.ExpiryTime) {
+		return
+	}
+	keys, err := parsePublicKeys(ck.Certs)
+	if err != nil {
+		return
+	}
+	k.CachedKeys = keys
+	k.ExpiryTime = expiry
+}
+
+// storeSharedKeys writes the given certificates to the shared Cache. They are retained in the Cache
+// until the grace period has elapsed past their expiry time.
+func (k *httpKeySource) storeSharedKeys(certs []byte) {
+	if k.Cache == nil {
+		return
 	}
-	resp, err := k.HTTPClient.Get(k.KeyURI)
+	b, err := json.Marshal(&cachedKeys{
+		Certs:  certs,
+		Expiry: k.ExpiryTime.Unix(),
+	})
 	if err != nil {
-		return err
+		return
+	}
+	if ttl := k.ExpiryTime.Add(k.GracePeriod).Sub(k.Clock.Now()); ttl > 0 {
+		k.Cache.Set(k.cacheKey(), b, ttl)
+	}
+}
+
+func (k *httpKeySource) refreshKeysWithRetry() error {
+	retry, err := k.refreshKeys()
+	for _, d := range keyFetchRetryDelays {
+		if err == nil || !retry {
+			break
+		}
+		time.Sleep(d)
+		retry, err = k.refreshKeys()
+	}
+	return err
+}
+
+// refreshKeys fetches the public keys from the remote server, and replaces the cached keys on success.
+// In case of an error, it also indicates whether the error is transient, and the fetch may be retried.
+// The Mutex of the key source is only held while the cached keys are replaced.
+func (k *httpKeySource) refreshKeys() (bool, error) {
+	hc := k.HTTPClient
+	if hc == nil {
+		hc = http.DefaultClient
+	}
+	resp, err := hc.Get(k.KeyURI)
+	if err != nil {
+		return true, err
 	}
 	defer resp.Body.Close()
 	contents, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
-		return err
+		return true, err
+	}
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return true, fmt.Errorf("failed to fetch public keys; status: %d", resp.StatusCode)
 	}
 
 	newKeys, err := parsePublicKeys(contents)
 	if err != nil {
-		return err
+		return false, err
 	}
 
 	maxAge, err := findMaxAge(resp)
 	if err != nil {
-		return err
+		return false, err
 	}
 
+	k.Mutex.Lock()
+	defer k.Mutex.Unlock()
 	k.CachedKeys = append([]*publicKey(nil), newKeys...)
 	k.ExpiryTime = k.Clock.Now().Add(*maxAge)
-	return nil
+	k.storeSharedKeys(contents)
+	return false, nil
+}
+
+// staticKeySource serves a fixed set of public keys. It is used to share a single key lookup across
+// several verifications.
+type staticKeySource struct {
+	keys []*publicKey
+}
+
+func (s *staticKeySource) Keys() ([]*publicKey, error) {
+	return s.keys, nil
 }
 
 type fileKeySource struct {
@@ -176,6 +342,9 @@ func parsePublicKeys(keys []byte) ([]*publicKey, error) {
 	var result []*publicKey
 	for kid, key := range m {
 		block, _ := pem.Decode([]byte(key))
+		if block == nil {
+			return nil, fmt.Errorf("Certificate %q is not PEM encoded", kid)
+		}
 		cert, err := x509.ParseCertificate(block.Bytes)
 		if err != nil {
 			return nil, err
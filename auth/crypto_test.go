@@ -15,11 +15,16 @@
 package auth
 
 import (
+	"errors"
+	"fmt"
 	"io"
 	"io/ioutil"
 	"net/http"
+	"sync"
 	"testing"
 	"time"
+
+	"firebase.google.com/go/cache"
 )
 
 type mockHTTPResponse struct {
@@ -109,3 +114,253 @@ func TestHTTPKeySource(t *testing.T) {
 		t.Errorf("HTTP calls: %d; want: 2", rc.closeCount)
 	}
 }
+
+func TestHTTPKeySourceGracePeriod(t *testing.T) {
+	data, err := ioutil.ReadFile("../testdata/public_certs.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	delays := keyFetchRetryDelays
+	keyFetchRetryDelays = []time.Duration{0, 0}
+	defer func() {
+		keyFetchRetryDelays = delays
+	}()
+
+	mc := &mockClock{now: time.Unix(0, 0)}
+	mock := &mockHTTPResponse{
+		Response: http.Response{
+			Status:     "200 OK",
+			StatusCode: 200,
+			Header: http.Header{
+				"Cache-Control": {"public, max-age=100"},
+			},
+			Body: &mockReadCloser{data: string(data)},
+		},
+	}
+	ks := newHTTPKeySource("http://mock.url")
+	ks.HTTPClient = &http.Client{Transport: mock}
+	ks.Clock = mc
+	ks.GracePeriod = 50 * time.Second
+
+	if _, err := ks.Keys(); err != nil {
+		t.Fatal(err)
+	}
+
+	rc := &mockReadCloser{data: "{}"}
+	mock.Response = http.Response{
+		Status:     "503 Service Unavailable",
+		StatusCode: 503,
+		Body:       rc,
+	}
+
+	mc.now = time.Unix(150, 0)
+	keys, err := ks.Keys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 3 {
+		t.Errorf("Keys: %d; want: 3", len(keys))
+	}
+	waitRefresh(ks)
+	if rc.closeCount != 3 {
+		t.Errorf("HTTP calls: %d; want: 3", rc.closeCount)
+	}
+
+	// No refresh is attempted within the backoff interval of the failed one.
+	if keys, err := ks.Keys(); len(keys) != 3 || err != nil {
+		t.Errorf("Keys() = (%d, %v); want = (3, nil)", len(keys), err)
+	}
+	mc.now = time.Unix(151, 0)
+	if keys, err := ks.Keys(); keys != nil || err == nil {
+		t.Errorf("Keys() = (%v, %v); want = (nil, error)", keys, err)
+	}
+	waitRefresh(ks)
+	if rc.closeCount != 3 {
+		t.Errorf("HTTP calls: %d; want: 3", rc.closeCount)
+	}
+
+	mock.Response = http.Response{
+		Status:     "200 OK",
+		StatusCode: 200,
+		Header: http.Header{
+			"Cache-Control": {"public, max-age=100"},
+		},
+		Body: &mockReadCloser{data: string(data)},
+	}
+	mc.now = mc.now.Add(keyRefreshBackoff)
+	if keys, err := ks.Keys(); len(keys) != 3 || err != nil {
+		t.Errorf("Keys() = (%d, %v); want = (3, nil)", len(keys), err)
+	}
+}
+
+// blockingTransport serves a response once it is released, and counts the requests made.
+type blockingTransport struct {
+	release  chan struct{}
+	response func() *http.Response
+	mutex    sync.Mutex
+	calls    int
+}
+
+func (b *blockingTransport) RoundTrip(*http.Request) (*http.Response, error) {
+	b.mutex.Lock()
+	b.calls++
+	b.mutex.Unlock()
+	<-b.release
+	return b.response(), nil
+}
+
+func TestHTTPKeySourceStaleWhileRevalidate(t *testing.T) {
+	data, err := ioutil.ReadFile("../testdata/public_certs.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mc := &mockClock{now: time.Unix(0, 0)}
+	bt := &blockingTransport{
+		release: make(chan struct{}),
+		response: func() *http.Response {
+			return &http.Response{
+				Status:     "200 OK",
+				StatusCode: 200,
+				Header: http.Header{
+					"Cache-Control": {"public, max-age=100"},
+				},
+				Body: &mockReadCloser{data: string(data)},
+			}
+		},
+	}
+	ks := newHTTPKeySource("http://mock.url")
+	ks.HTTPClient = &http.Client{Transport: bt}
+	ks.Clock = mc
+	ks.GracePeriod = 50 * time.Second
+	close(bt.release)
+	if _, err := ks.Keys(); err != nil {
+		t.Fatal(err)
+	}
+
+	// While the refresh is blocked, the stale keys are served without waiting for it, and no other
+	// refresh is started.
+	bt.release = make(chan struct{})
+	mc.now = time.Unix(120, 0)
+	for i := 0; i < 10; i++ {
+		if keys, err := ks.Keys(); len(keys) != 3 || err != nil {
+			t.Fatalf("Keys() = (%d, %v); want = (3, nil)", len(keys), err)
+		}
+	}
+	close(bt.release)
+	waitRefresh(ks)
+
+	bt.mutex.Lock()
+	defer bt.mutex.Unlock()
+	if bt.calls != 2 {
+		t.Errorf("HTTP calls: %d; want: 2", bt.calls)
+	}
+	if want := time.Unix(220, 0); !ks.ExpiryTime.Equal(want) {
+		t.Errorf("Expiry: %v; want: %v", ks.ExpiryTime, want)
+	}
+}
+
+// waitRefresh waits for the background refresh of the key source, if any, to complete.
+func waitRefresh(ks *httpKeySource) {
+	ks.Mutex.Lock()
+	done := ks.refreshing
+	ks.Mutex.Unlock()
+	if done != nil {
+		<-done
+	}
+}
+
+func TestHTTPKeySourceSharedCache(t *testing.T) {
+	data, err := ioutil.ReadFile("../testdata/public_certs.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	shared := cache.NewMemoryCache()
+	mc := &mockClock{now: time.Now()}
+	rc := &mockReadCloser{data: string(data)}
+	ks1 := newHTTPKeySource("http://mock.url")
+	ks1.HTTPClient = &http.Client{
+		Transport: &mockHTTPResponse{
+			Response: http.Response{
+				Status:     "200 OK",
+				StatusCode: 200,
+				Header: http.Header{
+					"Cache-Control": {"public, max-age=100"},
+				},
+				Body: rc,
+			},
+		},
+	}
+	ks1.Clock = mc
+	ks1.Cache = shared
+	if _, err := ks1.Keys(); err != nil {
+		t.Fatal(err)
+	}
+
+	ks2 := newHTTPKeySource("http://mock.url")
+	ks2.HTTPClient = &http.Client{
+		Transport: &mockHTTPResponse{Err: errors.New("unexpected request")},
+	}
+	ks2.Clock = mc
+	ks2.Cache = shared
+	keys, err := ks2.Keys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 3 {
+		t.Errorf("Keys: %d; want: 3", len(keys))
+	}
+	if rc.closeCount != 1 {
+		t.Errorf("HTTP calls: %d; want: 1", rc.closeCount)
+	}
+	if ks2.ExpiryTime.Unix() != ks1.ExpiryTime.Unix() {
+		t.Errorf("Expiry: %v; want: %v", ks2.ExpiryTime, ks1.ExpiryTime)
+	}
+}
+
+func TestHTTPKeySourceCorruptSharedCache(t *testing.T) {
+	data, err := ioutil.ReadFile("../testdata/public_certs.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mc := &mockClock{now: time.Now()}
+	shared := cache.NewMemoryCache()
+	corrupt := fmt.Sprintf(`{"certs": {"kid": "not a certificate"}, "expiry": %d}`, mc.now.Add(time.Hour).Unix())
+	shared.Set("firebase.auth.publicKeys:http://mock.url", []byte(corrupt), time.Hour)
+
+	rc := &mockReadCloser{data: string(data)}
+	ks := newHTTPKeySource("http://mock.url")
+	ks.HTTPClient = &http.Client{
+		Transport: &mockHTTPResponse{
+			Response: http.Response{
+				Status:     "200 OK",
+				StatusCode: 200,
+				Header: http.Header{
+					"Cache-Control": {"public, max-age=100"},
+				},
+				Body: rc,
+			},
+		},
+	}
+	ks.Clock = mc
+	ks.Cache = shared
+	keys, err := ks.Keys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 3 {
+		t.Errorf("Keys: %d; want: 3", len(keys))
+	}
+	if rc.closeCount != 1 {
+		t.Errorf("HTTP calls: %d; want: 1", rc.closeCount)
+	}
+}
+
+func TestParsePublicKeysNotPEM(t *testing.T) {
+	if keys, err := parsePublicKeys([]byte(`{"kid": "not a certificate"}`)); keys != nil || err == nil {
+		t.Errorf("parsePublicKeys() = (%v, %v); want = (nil, error)", keys, err)
+	}
+}
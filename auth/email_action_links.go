@@ -0,0 +1,296 @@
+// Copyright 2017 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+// ActionCodeSettings specifies the required continue/state URL with optional Android and iOS settings.
+// Used when invoking the email action link generation APIs.
+type ActionCodeSettings struct {
+	URL                   string `json:"continueUrl"`
+	HandleCodeInApp       bool   `json:"canHandleCodeInApp"`
+	IOSBundleID           string `json:"iOSBundleId,omitempty"`
+	AndroidPackageName    string `json:"androidPackageName,omitempty"`
+	AndroidMinimumVersion string `json:"androidMinimumVersion,omitempty"`
+	AndroidInstallApp     bool   `json:"androidInstallApp,omitempty"`
+	DynamicLinkDomain     string `json:"dynamicLinkDomain,omitempty"`
+}
+
+func (settings *ActionCodeSettings) toMap() (map[string]interface{}, error) {
+	if settings.URL == "" {
+		return nil, errors.New("URL must not be empty")
+	}
+	u, err := url.ParseRequestURI(settings.URL)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("malformed url string: %q", settings.URL)
+	}
+	if settings.AndroidMinimumVersion != "" || settings.AndroidInstallApp {
+		if settings.AndroidPackageName == "" {
+			return nil, errors.New("Android package name is required when specifying other Android settings")
+		}
+	}
+
+	b, err := json.Marshal(settings)
+	if err != nil {
+		return nil, err
+	}
+	var result map[string]interface{}
+	if err := json.Unmarshal(b, &result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+type linkType string
+
+const (
+	emailVerification linkType = "VERIFY_EMAIL"
+	passwordReset     linkType = "PASSWORD_RESET"
+	emailLinkSignIn   linkType = "EMAIL_SIGNIN"
+)
+
+// EmailVerificationLink generates the out-of-band email action link for email verification flows for
+// the specified email address.
+//
+// The returned link can be sent to the user through any channel, such as a custom email provider.
+func (c *Client) EmailVerificationLink(ctx context.Context, email string) (string, error) {
+	return c.EmailVerificationLinkWithSettings(ctx, email, nil)
+}
+
+// EmailVerificationLinkWithSettings generates the out-of-band email action link for email verification
+// flows for the specified email address, using the action code settings provided.
+//
+// The action code settings specify the URL the user is redirected to after verifying their email
+// address, and whether the link should be opened in a mobile app. Settings may be nil.
+func (c *Client) EmailVerificationLinkWithSettings(ctx context.Context, email string,
+	settings *ActionCodeSettings) (string, error) {
+	return c.generateEmailActionLink(ctx, emailVerification, email, settings)
+}
+
+// PasswordResetLink generates the out-of-band email action link for password reset flows for the
+// specified email address.
+//
+// The returned link can be embedded in a custom-branded email, and sent through any email provider.
+func (c *Client) PasswordResetLink(ctx context.Context, email string) (string, error) {
+	return c.PasswordResetLinkWithSettings(ctx, email, nil)
+}
+
+// PasswordResetLinkWithSettings generates the out-of-band email action link for password reset flows
+// for the specified email address, using the action code settings provided. Settings may be nil.
+func (c *Client) PasswordResetLinkWithSettings(ctx context.Context, email string,
+	settings *ActionCodeSettings) (string, error) {
+	return c.generateEmailActionLink(ctx, passwordReset, email, settings)
+}
+
+// EmailSignInLink generates the out-of-band email action link for email link sign-in flows for the
+// specified email address, using the action code settings provided.
+//
+// Settings must not be nil, and must have HandleCodeInApp set to true, since email link sign-in is
+// always completed in the app. Use IOSBundleID and AndroidPackageName to have the link opened in a
+// mobile app.
+func (c *Client) EmailSignInLink(ctx context.Context, email string, settings *ActionCodeSettings) (string, error) {
+	if settings == nil {
+		return "", errors.New("action code settings must not be nil when generating sign-in links")
+	}
+	if !settings.HandleCodeInApp {
+		return "", errors.New("HandleCodeInApp must be true when generating sign-in links")
+	}
+	return c.generateEmailActionLink(ctx, emailLinkSignIn, email, settings)
+}
+
+// EmailVerificationCode generates the raw out-of-band code for email verification flows for the
+// specified email address, using the action code settings provided. Settings may be nil.
+//
+// Unlike EmailVerificationLinkWithSettings, it returns only the code, which can be delivered through
+// channels other than email, such as SMS or in-app messages. The app completes the verification by
+// passing the code to the applyActionCode API of the Firebase client SDKs.
+func (c *Client) EmailVerificationCode(ctx context.Context, email string,
+	settings *ActionCodeSettings) (string, error) {
+	return c.generateEmailActionCode(ctx, emailVerification, email, settings)
+}
+
+// PasswordResetCode generates the raw out-of-band code for password reset flows for the specified
+// email address, using the action code settings provided. Settings may be nil.
+//
+// The app completes the password reset by passing the code, along with the new password, to the
+// confirmPasswordReset API of the Firebase client SDKs.
+func (c *Client) PasswordResetCode(ctx context.Context, email string, settings *ActionCodeSettings) (string, error) {
+	return c.generateEmailActionCode(ctx, passwordReset, email, settings)
+}
+
+// emailActionResponse is the response of a sendOobCode request made with returnOobLink set.
+type emailActionResponse struct {
+	OOBLink string `json:"oobLink"`
+	OOBCode string `json:"oobCode"`
+}
+
+func (c *Client) generateEmailActionLink(ctx context.Context, linkType linkType, email string,
+	settings *ActionCodeSettings) (string, error) {
+	resp, err := c.sendEmailAction(ctx, linkType, email, settings)
+	if err != nil {
+		return "", err
+	}
+	return resp.OOBLink, nil
+}
+
+// generateEmailActionCode returns the out-of-band code of an email action. The code is taken from the
+// oobCode query parameter of the action link if the server does not return it separately.
+func (c *Client) generateEmailActionCode(ctx context.Context, linkType linkType, email string,
+	settings *ActionCodeSettings) (string, error) {
+	resp, err := c.sendEmailAction(ctx, linkType, email, settings)
+	if err != nil {
+		return "", err
+	}
+	if resp.OOBCode != "" {
+		return resp.OOBCode, nil
+	}
+	if u, err := url.Parse(resp.OOBLink); err == nil {
+		if code := u.Query().Get("oobCode"); code != "" {
+			return code, nil
+		}
+	}
+	return "", errors.New("no out-of-band code in the response of the server")
+}
+
+func (c *Client) sendEmailAction(ctx context.Context, linkType linkType, email string,
+	settings *ActionCodeSettings) (*emailActionResponse, error) {
+	if err := validateEmail(email); err != nil {
+		return nil, err
+	}
+
+	payload := map[string]interface{}{
+		"requestType":   linkType,
+		"email":         email,
+		"returnOobLink": true,
+	}
+	if settings != nil {
+		settingsMap, err := settings.toMap()
+		if err != nil {
+			return nil, err
+		}
+		for k, v := range settingsMap {
+			payload[k] = v
+		}
+	}
+
+	var parsed emailActionResponse
+	if err := c.post(ctx, "/accounts:sendOobCode", payload, &parsed); err != nil {
+		return nil, err
+	}
+	return &parsed, nil
+}
+
+// EmailLinkSignInResult is the result of completing an email link sign-in with SignInWithEmailLink.
+type EmailLinkSignInResult struct {
+	UID          string
+	Email        string
+	IDToken      string
+	RefreshToken string
+	ExpiresIn    time.Duration
+	IsNewUser    bool
+}
+
+// IsSignInWithEmailLink checks if the given link is an email link sign-in link, as generated by
+// EmailSignInLink.
+//
+// Links wrapped by Firebase Dynamic Links are unwrapped before checking.
+func IsSignInWithEmailLink(link string) bool {
+	_, err := signInOOBCode(link)
+	return err == nil
+}
+
+// SignInWithEmailLink completes an email link sign-in on behalf of the user that received the link, and
+// returns the ID token and refresh token of the signed in user.
+//
+// The link must have been generated for the given email address by EmailSignInLink, or sent by the
+// Firebase client SDKs. The user is created if no user exists with the email address. If the link
+// has expired or was already used, SignInWithEmailLink returns an error for which IsInvalidActionCode
+// returns true.
+func (c *Client) SignInWithEmailLink(ctx context.Context, email, link string) (*EmailLinkSignInResult, error) {
+	if err := validateEmail(email); err != nil {
+		return nil, err
+	}
+	oobCode, err := signInOOBCode(link)
+	if err != nil {
+		return nil, err
+	}
+
+	payload := map[string]interface{}{
+		"email":             email,
+		"oobCode":           oobCode,
+		"returnSecureToken": true,
+	}
+	if c.tenantID != "" {
+		payload["tenantId"] = c.tenantID
+	}
+	var parsed struct {
+		LocalID      string `json:"localId"`
+		Email        string `json:"email"`
+		IDToken      string `json:"idToken"`
+		RefreshToken string `json:"refreshToken"`
+		ExpiresIn    string `json:"expiresIn"`
+		IsNewUser    bool   `json:"isNewUser"`
+	}
+	endpoint := c.userEndpoint + "/accounts:signInWithEmailLink"
+	if err := c.makeRequest(ctx, http.MethodPost, endpoint, payload, &parsed); err != nil {
+		return nil, err
+	}
+
+	result := &EmailLinkSignInResult{
+		UID:          parsed.LocalID,
+		Email:        parsed.Email,
+		IDToken:      parsed.IDToken,
+		RefreshToken: parsed.RefreshToken,
+		IsNewUser:    parsed.IsNewUser,
+	}
+	if parsed.ExpiresIn != "" {
+		seconds, err := strconv.ParseInt(parsed.ExpiresIn, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid token expiry: %q", parsed.ExpiresIn)
+		}
+		result.ExpiresIn = time.Duration(seconds) * time.Second
+	}
+	return result, nil
+}
+
+// signInOOBCode extracts the out-of-band code from an email link sign-in link. The link may be wrapped
+// by a Firebase Dynamic Link, in which case the sign-in link is carried in its link or deep_link_id
+// query parameter.
+func signInOOBCode(link string) (string, error) {
+	u, err := url.Parse(link)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return "", fmt.Errorf("malformed email link: %q", link)
+	}
+	q := u.Query()
+	if q.Get("mode") == "signIn" && q.Get("oobCode") != "" {
+		return q.Get("oobCode"), nil
+	}
+	for _, param := range []string{"link", "deep_link_id"} {
+		if inner := q.Get(param); inner != "" {
+			return signInOOBCode(inner)
+		}
+	}
+	return "", fmt.Errorf("not an email link sign-in link: %q", link)
+}
@@ -0,0 +1,416 @@
+// Copyright 2017 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"net/http"
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+const (
+	testActionLink     = "https://test.link"
+	testEmail          = "user@domain.com"
+	testActionLinkJSON = `{"oobLink": "https://test.link"}`
+)
+
+var testActionCodeSettings = &ActionCodeSettings{
+	URL:                   "https://example.dynamic.link",
+	HandleCodeInApp:       true,
+	DynamicLinkDomain:     "custom.page.link",
+	IOSBundleID:           "com.example.ios",
+	AndroidPackageName:    "com.example.android",
+	AndroidInstallApp:     true,
+	AndroidMinimumVersion: "6",
+}
+
+var testActionCodeSettingsMap = map[string]interface{}{
+	"continueUrl":           "https://example.dynamic.link",
+	"canHandleCodeInApp":    true,
+	"dynamicLinkDomain":     "custom.page.link",
+	"iOSBundleId":           "com.example.ios",
+	"androidPackageName":    "com.example.android",
+	"androidInstallApp":     true,
+	"androidMinimumVersion": "6",
+}
+
+var invalidActionCodeSettings = []struct {
+	name     string
+	settings *ActionCodeSettings
+}{
+	{"no-url", &ActionCodeSettings{}},
+	{"malformed-url", &ActionCodeSettings{URL: "not a url"}},
+	{"no-scheme", &ActionCodeSettings{URL: "example.com/path"}},
+	{"no-android-package-name", &ActionCodeSettings{
+		URL:               "https://example.dynamic.link",
+		AndroidInstallApp: true,
+	}},
+	{"no-android-package-name-with-version", &ActionCodeSettings{
+		URL:                   "https://example.dynamic.link",
+		AndroidMinimumVersion: "6",
+	}},
+}
+
+func TestEmailVerificationLink(t *testing.T) {
+	s := echoServer([]byte(testActionLinkJSON), t)
+	defer s.Close()
+
+	link, err := s.Client.EmailVerificationLink(context.Background(), testEmail)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if link != testActionLink {
+		t.Errorf("EmailVerificationLink() = %q; want = %q", link, testActionLink)
+	}
+
+	want := map[string]interface{}{
+		"requestType":   "VERIFY_EMAIL",
+		"email":         testEmail,
+		"returnOobLink": true,
+	}
+	checkRequest(t, s, "/projects/mock-project-id/accounts:sendOobCode", want)
+}
+
+func TestEmailVerificationLinkWithSettings(t *testing.T) {
+	s := echoServer([]byte(testActionLinkJSON), t)
+	defer s.Close()
+
+	link, err := s.Client.EmailVerificationLinkWithSettings(context.Background(), testEmail, testActionCodeSettings)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if link != testActionLink {
+		t.Errorf("EmailVerificationLinkWithSettings() = %q; want = %q", link, testActionLink)
+	}
+
+	want := map[string]interface{}{
+		"requestType":   "VERIFY_EMAIL",
+		"email":         testEmail,
+		"returnOobLink": true,
+	}
+	for k, v := range testActionCodeSettingsMap {
+		want[k] = v
+	}
+	checkRequest(t, s, "/projects/mock-project-id/accounts:sendOobCode", want)
+}
+
+func TestEmailVerificationLinkInvalidEmail(t *testing.T) {
+	for _, email := range []string{"", "not-an-email"} {
+		link, err := client.EmailVerificationLink(context.Background(), email)
+		if link != "" || err == nil {
+			t.Errorf("EmailVerificationLink(%q) = (%q, %v); want = (\"\", error)", email, link, err)
+		}
+	}
+}
+
+func TestEmailVerificationLinkInvalidSettings(t *testing.T) {
+	for _, tc := range invalidActionCodeSettings {
+		link, err := client.EmailVerificationLinkWithSettings(context.Background(), testEmail, tc.settings)
+		if link != "" || err == nil {
+			t.Errorf("EmailVerificationLinkWithSettings(%s) = (%q, %v); want = (\"\", error)", tc.name, link, err)
+		}
+	}
+}
+
+func TestEmailVerificationLinkError(t *testing.T) {
+	s := echoServer([]byte(`{"error": {"message": "USER_NOT_FOUND"}}`), t)
+	defer s.Close()
+	s.Status = http.StatusBadRequest
+
+	link, err := s.Client.EmailVerificationLink(context.Background(), testEmail)
+	if link != "" || !IsUserNotFound(err) {
+		t.Errorf("EmailVerificationLink() = (%q, %v); want = (\"\", user-not-found error)", link, err)
+	}
+}
+
+func TestPasswordResetLink(t *testing.T) {
+	s := echoServer([]byte(testActionLinkJSON), t)
+	defer s.Close()
+
+	link, err := s.Client.PasswordResetLink(context.Background(), testEmail)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if link != testActionLink {
+		t.Errorf("PasswordResetLink() = %q; want = %q", link, testActionLink)
+	}
+
+	want := map[string]interface{}{
+		"requestType":   "PASSWORD_RESET",
+		"email":         testEmail,
+		"returnOobLink": true,
+	}
+	checkRequest(t, s, "/projects/mock-project-id/accounts:sendOobCode", want)
+}
+
+func TestPasswordResetLinkWithSettings(t *testing.T) {
+	s := echoServer([]byte(testActionLinkJSON), t)
+	defer s.Close()
+
+	link, err := s.Client.PasswordResetLinkWithSettings(context.Background(), testEmail, testActionCodeSettings)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if link != testActionLink {
+		t.Errorf("PasswordResetLinkWithSettings() = %q; want = %q", link, testActionLink)
+	}
+
+	want := map[string]interface{}{
+		"requestType":   "PASSWORD_RESET",
+		"email":         testEmail,
+		"returnOobLink": true,
+	}
+	for k, v := range testActionCodeSettingsMap {
+		want[k] = v
+	}
+	checkRequest(t, s, "/projects/mock-project-id/accounts:sendOobCode", want)
+}
+
+func TestPasswordResetLinkInvalidSettings(t *testing.T) {
+	for _, tc := range invalidActionCodeSettings {
+		link, err := client.PasswordResetLinkWithSettings(context.Background(), testEmail, tc.settings)
+		if link != "" || err == nil {
+			t.Errorf("PasswordResetLinkWithSettings(%s) = (%q, %v); want = (\"\", error)", tc.name, link, err)
+		}
+	}
+}
+
+func TestEmailSignInLink(t *testing.T) {
+	s := echoServer([]byte(testActionLinkJSON), t)
+	defer s.Close()
+
+	link, err := s.Client.EmailSignInLink(context.Background(), testEmail, testActionCodeSettings)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if link != testActionLink {
+		t.Errorf("EmailSignInLink() = %q; want = %q", link, testActionLink)
+	}
+
+	want := map[string]interface{}{
+		"requestType":   "EMAIL_SIGNIN",
+		"email":         testEmail,
+		"returnOobLink": true,
+	}
+	for k, v := range testActionCodeSettingsMap {
+		want[k] = v
+	}
+	checkRequest(t, s, "/projects/mock-project-id/accounts:sendOobCode", want)
+}
+
+func TestEmailSignInLinkNoSettings(t *testing.T) {
+	link, err := client.EmailSignInLink(context.Background(), testEmail, nil)
+	if link != "" || err == nil {
+		t.Errorf("EmailSignInLink(nil) = (%q, %v); want = (\"\", error)", link, err)
+	}
+}
+
+func TestEmailSignInLinkNotHandledInApp(t *testing.T) {
+	settings := &ActionCodeSettings{URL: "https://example.dynamic.link"}
+	link, err := client.EmailSignInLink(context.Background(), testEmail, settings)
+	if link != "" || err == nil {
+		t.Errorf("EmailSignInLink() = (%q, %v); want = (\"\", error)", link, err)
+	}
+}
+
+func TestEmailSignInLinkInvalidSettings(t *testing.T) {
+	for _, tc := range invalidActionCodeSettings {
+		settings := *tc.settings
+		settings.HandleCodeInApp = true
+		link, err := client.EmailSignInLink(context.Background(), testEmail, &settings)
+		if link != "" || err == nil {
+			t.Errorf("EmailSignInLink(%s) = (%q, %v); want = (\"\", error)", tc.name, link, err)
+		}
+	}
+}
+
+const (
+	testSignInLink = "https://mock-project-id.firebaseapp.com/__/auth/action?apiKey=key&mode=signIn" +
+		"&oobCode=code&continueUrl=https%3A%2F%2Fexample.com"
+	testSignInJSON = `{
+		"localId": "testuser",
+		"email": "user@domain.com",
+		"idToken": "id-token",
+		"refreshToken": "refresh-token",
+		"expiresIn": "3600",
+		"isNewUser": true
+	}`
+)
+
+func TestIsSignInWithEmailLink(t *testing.T) {
+	cases := []struct {
+		link string
+		want bool
+	}{
+		{testSignInLink, true},
+		{"https://custom.page.link/?link=" + url.QueryEscape(testSignInLink), true},
+		{"https://custom.page.link/?deep_link_id=" + url.QueryEscape(testSignInLink), true},
+		{"https://mock-project-id.firebaseapp.com/__/auth/action?mode=resetPassword&oobCode=code", false},
+		{"https://mock-project-id.firebaseapp.com/__/auth/action?mode=signIn", false},
+		{"https://custom.page.link/?link=not-a-link", false},
+		{"not a link", false},
+		{"", false},
+	}
+	for _, tc := range cases {
+		if got := IsSignInWithEmailLink(tc.link); got != tc.want {
+			t.Errorf("IsSignInWithEmailLink(%q) = %v; want = %v", tc.link, got, tc.want)
+		}
+	}
+}
+
+func TestSignInWithEmailLink(t *testing.T) {
+	s := echoServer([]byte(testSignInJSON), t)
+	defer s.Close()
+
+	result, err := s.Client.SignInWithEmailLink(context.Background(), testEmail, testSignInLink)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &EmailLinkSignInResult{
+		UID:          "testuser",
+		Email:        testEmail,
+		IDToken:      "id-token",
+		RefreshToken: "refresh-token",
+		ExpiresIn:    time.Hour,
+		IsNewUser:    true,
+	}
+	if !reflect.DeepEqual(result, want) {
+		t.Errorf("SignInWithEmailLink() = %#v; want = %#v", result, want)
+	}
+
+	wantBody := map[string]interface{}{
+		"email":             testEmail,
+		"oobCode":           "code",
+		"returnSecureToken": true,
+	}
+	checkRequest(t, s, "/accounts:signInWithEmailLink", wantBody)
+}
+
+func TestSignInWithEmailLinkTenant(t *testing.T) {
+	s := echoServer([]byte(testSignInJSON), t)
+	defer s.Close()
+
+	tc, err := s.Client.TenantManager().AuthForTenant("tenantID")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tc.SignInWithEmailLink(context.Background(), testEmail, testSignInLink); err != nil {
+		t.Fatal(err)
+	}
+	wantBody := map[string]interface{}{
+		"email":             testEmail,
+		"oobCode":           "code",
+		"returnSecureToken": true,
+		"tenantId":          "tenantID",
+	}
+	checkRequest(t, s, "/accounts:signInWithEmailLink", wantBody)
+}
+
+func TestSignInWithEmailLinkInvalid(t *testing.T) {
+	cases := []struct {
+		email string
+		link  string
+	}{
+		{"", testSignInLink},
+		{"not-an-email", testSignInLink},
+		{testEmail, ""},
+		{testEmail, "https://mock-project-id.firebaseapp.com/__/auth/action?mode=verifyEmail&oobCode=code"},
+	}
+	for _, tc := range cases {
+		result, err := client.SignInWithEmailLink(context.Background(), tc.email, tc.link)
+		if result != nil || err == nil {
+			t.Errorf("SignInWithEmailLink(%q, %q) = (%v, %v); want = (nil, error)", tc.email, tc.link, result, err)
+		}
+	}
+}
+
+func TestSignInWithEmailLinkExpired(t *testing.T) {
+	s := echoServer([]byte(`{"error": {"message": "EXPIRED_OOB_CODE"}}`), t)
+	defer s.Close()
+	s.Status = http.StatusBadRequest
+
+	result, err := s.Client.SignInWithEmailLink(context.Background(), testEmail, testSignInLink)
+	if result != nil || !IsInvalidActionCode(err) {
+		t.Errorf("SignInWithEmailLink() = (%v, %v); want = (nil, invalid-action-code error)", result, err)
+	}
+}
+
+func TestEmailVerificationCode(t *testing.T) {
+	s := echoServer([]byte(`{"oobLink": "https://test.link?mode=verifyEmail&oobCode=code", "oobCode": "code"}`), t)
+	defer s.Close()
+
+	code, err := s.Client.EmailVerificationCode(context.Background(), testEmail, testActionCodeSettings)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if code != "code" {
+		t.Errorf("EmailVerificationCode() = %q; want = %q", code, "code")
+	}
+
+	want := map[string]interface{}{
+		"requestType":   "VERIFY_EMAIL",
+		"email":         testEmail,
+		"returnOobLink": true,
+	}
+	for k, v := range testActionCodeSettingsMap {
+		want[k] = v
+	}
+	checkRequest(t, s, "/projects/mock-project-id/accounts:sendOobCode", want)
+}
+
+func TestPasswordResetCodeFromLink(t *testing.T) {
+	s := echoServer([]byte(`{"oobLink": "https://test.link?mode=resetPassword&oobCode=code"}`), t)
+	defer s.Close()
+
+	code, err := s.Client.PasswordResetCode(context.Background(), testEmail, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if code != "code" {
+		t.Errorf("PasswordResetCode() = %q; want = %q", code, "code")
+	}
+
+	want := map[string]interface{}{
+		"requestType":   "PASSWORD_RESET",
+		"email":         testEmail,
+		"returnOobLink": true,
+	}
+	checkRequest(t, s, "/projects/mock-project-id/accounts:sendOobCode", want)
+}
+
+func TestPasswordResetCodeMissing(t *testing.T) {
+	s := echoServer([]byte(testActionLinkJSON), t)
+	defer s.Close()
+
+	if code, err := s.Client.PasswordResetCode(context.Background(), testEmail, nil); code != "" || err == nil {
+		t.Errorf("PasswordResetCode() = (%q, %v); want = (\"\", error)", code, err)
+	}
+}
+
+func TestEmailActionCodeInvalid(t *testing.T) {
+	if code, err := client.EmailVerificationCode(context.Background(), "", nil); code != "" || err == nil {
+		t.Errorf("EmailVerificationCode(\"\") = (%q, %v); want = (\"\", error)", code, err)
+	}
+	for _, tc := range invalidActionCodeSettings {
+		code, err := client.PasswordResetCode(context.Background(), testEmail, tc.settings)
+		if code != "" || err == nil {
+			t.Errorf("PasswordResetCode(%s) = (%q, %v); want = (\"\", error)", tc.name, code, err)
+		}
+	}
+}
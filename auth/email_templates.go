@@ -0,0 +1,126 @@
+// Copyright 2017 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	passwordResetTemplateKey     = "notification.sendEmail.resetPasswordTemplate"
+	emailVerificationTemplateKey = "notification.sendEmail.verifyEmailTemplate"
+)
+
+// EmailTemplate represents a template of the emails sent by the Auth service to users.
+//
+// Emails are sent from the address SenderLocalPart@<project domain>, which is shown to recipients as
+// SenderDisplayName. Body is plain text unless HTMLBody is set. Customized is set by the server, and
+// indicates whether the template differs from the default template.
+type EmailTemplate struct {
+	SenderDisplayName string
+	SenderLocalPart   string
+	ReplyTo           string
+	Subject           string
+	Body              string
+	HTMLBody          bool
+	Customized        bool
+}
+
+// EmailTemplatesConfig represents the templates of the password reset and email verification emails
+// of a project. A template is nil if the server returned none.
+type EmailTemplatesConfig struct {
+	PasswordReset     *EmailTemplate
+	EmailVerification *EmailTemplate
+}
+
+// emailTemplateResponse is the JSON representation of the EmailTemplate resource of the Identity
+// Toolkit service.
+type emailTemplateResponse struct {
+	SenderLocalPart   string `json:"senderLocalPart,omitempty"`
+	Subject           string `json:"subject,omitempty"`
+	SenderDisplayName string `json:"senderDisplayName,omitempty"`
+	Body              string `json:"body,omitempty"`
+	BodyFormat        string `json:"bodyFormat,omitempty"`
+	ReplyTo           string `json:"replyTo,omitempty"`
+	Customized        bool   `json:"customized,omitempty"`
+}
+
+func (r *emailTemplateResponse) makeEmailTemplate() *EmailTemplate {
+	if r == nil {
+		return nil
+	}
+	return &EmailTemplate{
+		SenderDisplayName: r.SenderDisplayName,
+		SenderLocalPart:   r.SenderLocalPart,
+		ReplyTo:           r.ReplyTo,
+		Subject:           r.Subject,
+		Body:              r.Body,
+		HTMLBody:          r.BodyFormat == "HTML",
+		Customized:        r.Customized,
+	}
+}
+
+// notificationConfigResponse is the JSON representation of the NotificationConfig resource of the
+// Identity Toolkit service.
+type notificationConfigResponse struct {
+	SendEmail struct {
+		ResetPasswordTemplate *emailTemplateResponse `json:"resetPasswordTemplate"`
+		VerifyEmailTemplate   *emailTemplateResponse `json:"verifyEmailTemplate"`
+	} `json:"sendEmail"`
+}
+
+func (r *notificationConfigResponse) makeEmailTemplatesConfig() *EmailTemplatesConfig {
+	return &EmailTemplatesConfig{
+		PasswordReset:     r.SendEmail.ResetPasswordTemplate.makeEmailTemplate(),
+		EmailVerification: r.SendEmail.VerifyEmailTemplate.makeEmailTemplate(),
+	}
+}
+
+// emailTemplateFor converts the given template into the JSON representation of an update request.
+// The Customized field is set by the server, and hence ignored.
+func emailTemplateFor(t *EmailTemplate) *emailTemplateResponse {
+	if t == nil {
+		return nil
+	}
+	format := "PLAIN_TEXT"
+	if t.HTMLBody {
+		format = "HTML"
+	}
+	return &emailTemplateResponse{
+		SenderLocalPart:   t.SenderLocalPart,
+		Subject:           t.Subject,
+		SenderDisplayName: t.SenderDisplayName,
+		Body:              t.Body,
+		BodyFormat:        format,
+		ReplyTo:           t.ReplyTo,
+	}
+}
+
+func validateEmailTemplate(t *emailTemplateResponse) error {
+	if t == nil {
+		return errors.New("email template must not be nil")
+	}
+	if strings.ContainsAny(t.SenderLocalPart, "@ ") {
+		return fmt.Errorf("invalid sender local part: %q; must not contain '@' or spaces", t.SenderLocalPart)
+	}
+	if t.ReplyTo != "" {
+		if err := validateEmail(t.ReplyTo); err != nil {
+			return fmt.Errorf("invalid reply-to address: %v", err)
+		}
+	}
+	return nil
+}
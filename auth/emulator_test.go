@@ -0,0 +1,116 @@
+// Copyright 2017 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+
+	"firebase.google.com/go/internal"
+)
+
+func emulatorClient(t *testing.T, host string) *Client {
+	os.Setenv(authEmulatorHostEnv, host)
+	defer os.Unsetenv(authEmulatorHostEnv)
+	c, err := NewClient(context.Background(), &internal.AuthConfig{
+		Opts:      testOpts,
+		ProjectID: "mock-project-id",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestEmulatorEndpoints(t *testing.T) {
+	c := emulatorClient(t, "localhost:9099")
+	if want := "http://localhost:9099/identitytoolkit.googleapis.com/v1"; c.userEndpoint != want {
+		t.Errorf("userEndpoint = %q; want = %q", c.userEndpoint, want)
+	}
+	if want := "http://localhost:9099/identitytoolkit.googleapis.com/v2"; c.projectMgtEndpoint != want {
+		t.Errorf("projectMgtEndpoint = %q; want = %q", c.projectMgtEndpoint, want)
+	}
+
+	if client.emulated || client.userEndpoint != idToolkitV1Endpoint {
+		t.Errorf("Client created without %s is routed to the emulator", authEmulatorHostEnv)
+	}
+}
+
+func TestEmulatorUserManagement(t *testing.T) {
+	var got *http.Request
+	resp := testGetUserResponse(t)
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(resp)
+	}))
+	defer s.Close()
+	u, err := url.Parse(s.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := emulatorClient(t, u.Host)
+	if _, err := c.GetUser(context.Background(), "testuser"); err != nil {
+		t.Fatal(err)
+	}
+	if want := "/identitytoolkit.googleapis.com/v1/projects/mock-project-id/accounts:lookup"; got.URL.Path != want {
+		t.Errorf("Path = %q; want = %q", got.URL.Path, want)
+	}
+	if auth := got.Header.Get("Authorization"); auth != "Bearer owner" {
+		t.Errorf("Authorization = %q; want = %q", auth, "Bearer owner")
+	}
+}
+
+func TestEmulatorVerifyIDToken(t *testing.T) {
+	c := emulatorClient(t, "localhost:9099")
+	now := time.Now().Unix()
+	token, err := encodeToken(jwtHeader{Algorithm: "none", Type: "JWT"}, mockIDTokenPayload{
+		"aud": "mock-project-id",
+		"iss": "https://securetoken.google.com/mock-project-id",
+		"iat": now - 100,
+		"exp": now + 3600,
+		"sub": "1234567890",
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ft, err := c.VerifyIDToken(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ft.UID != "1234567890" {
+		t.Errorf("UID = %q; want = %q", ft.UID, "1234567890")
+	}
+	results, err := c.VerifyIDTokens(context.Background(), []string{token})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if results[0].Err != nil {
+		t.Errorf("VerifyIDTokens()[0] = %v; want = nil", results[0].Err)
+	}
+
+	// Unsigned tokens are only accepted by Clients routed to the emulator.
+	if _, err := client.VerifyIDToken(token); err == nil {
+		t.Errorf("VerifyIDToken(unsigned) = nil; want = error")
+	}
+}
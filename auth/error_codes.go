@@ -0,0 +1,110 @@
+// Copyright 2017 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"net/http"
+	"sync"
+
+	"firebase.google.com/go/internal"
+)
+
+// defaultErrorStatus maps SDK error codes to the HTTP statuses returned by ErrorStatusMapper, unless
+// a handler is registered for the code.
+var defaultErrorStatus = map[string]int{
+	configurationNotFound: http.StatusNotFound,
+	emailAlreadyExists:    http.StatusConflict,
+	idTokenRevoked:        http.StatusUnauthorized,
+	invalidActionCode:     http.StatusBadRequest,
+	invalidCredential:     http.StatusUnauthorized,
+	passwordPolicyFailed:  http.StatusBadRequest,
+	quotaExceeded:         http.StatusTooManyRequests,
+	tenantIDMismatch:      http.StatusUnauthorized,
+	tenantNotFound:        http.StatusNotFound,
+	unknown:               http.StatusInternalServerError,
+	userDisabled:          http.StatusForbidden,
+	userNotFound:          http.StatusNotFound,
+}
+
+// ServerErrorCodes returns the mapping from the error codes of the Identity Toolkit service, such as
+// "EMAIL_EXISTS" or "USER_NOT_FOUND", to the SDK error codes, such as "email-already-exists" or
+// "user-not-found". Server error codes missing from the mapping result in the "unknown-error" code.
+//
+// The returned map is a copy, and may be modified by the caller.
+func ServerErrorCodes() map[string]string {
+	codes := make(map[string]string, len(serverError))
+	for k, v := range serverError {
+		codes[k] = v
+	}
+	return codes
+}
+
+// ErrorCode returns the SDK error code of the given error, such as "user-not-found", or an empty string
+// if the error was not returned by the Auth service.
+func ErrorCode(err error) string {
+	if fe, ok := err.(*internal.FirebaseError); ok {
+		return fe.Code
+	}
+	return ""
+}
+
+// ErrorStatusMapper translates the errors of the Auth service into HTTP statuses, so that services
+// acting as gateways to the Auth service can respond to their clients consistently.
+//
+// By default, errors are translated into the status that best matches their SDK error code, such as
+// 404 for "user-not-found" and 409 for "email-already-exists". Other errors are translated into 500.
+// Handlers registered for an SDK error code override the default translation of the code.
+//
+// An ErrorStatusMapper is safe for concurrent use by multiple goroutines.
+type ErrorStatusMapper struct {
+	mutex    sync.RWMutex
+	handlers map[string]func(err error) int
+}
+
+// NewErrorStatusMapper creates an ErrorStatusMapper with the default translation.
+func NewErrorStatusMapper() *ErrorStatusMapper {
+	return &ErrorStatusMapper{handlers: make(map[string]func(err error) int)}
+}
+
+// Handle registers a handler that translates the errors with the given SDK error code into HTTP
+// statuses, replacing any handler previously registered for the code. Pass a nil handler to restore
+// the default translation of the code.
+func (m *ErrorStatusMapper) Handle(code string, handler func(err error) int) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if handler == nil {
+		delete(m.handlers, code)
+		return
+	}
+	m.handlers[code] = handler
+}
+
+// Status returns the HTTP status into which the given error is translated, or 200 if the error is nil.
+func (m *ErrorStatusMapper) Status(err error) int {
+	if err == nil {
+		return http.StatusOK
+	}
+	code := ErrorCode(err)
+	m.mutex.RLock()
+	handler, ok := m.handlers[code]
+	m.mutex.RUnlock()
+	if ok {
+		return handler(err)
+	}
+	if status, ok := defaultErrorStatus[code]; ok {
+		return status
+	}
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,95 @@
+// Copyright 2017 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"golang.org/x/net/context"
+)
+
+func TestServerErrorCodes(t *testing.T) {
+	codes := ServerErrorCodes()
+	want := map[string]string{
+		"EMAIL_EXISTS":                "email-already-exists",
+		"USER_NOT_FOUND":              "user-not-found",
+		"TOO_MANY_ATTEMPTS_TRY_LATER": "quota-exceeded",
+	}
+	for k, v := range want {
+		if codes[k] != v {
+			t.Errorf("ServerErrorCodes()[%q] = %q; want = %q", k, codes[k], v)
+		}
+	}
+
+	codes["USER_NOT_FOUND"] = "modified"
+	if serverError["USER_NOT_FOUND"] != userNotFound {
+		t.Errorf("ServerErrorCodes() returned the internal mapping")
+	}
+}
+
+func TestErrorCode(t *testing.T) {
+	s := echoServer([]byte(`{"error": {"message": "EMAIL_EXISTS"}}`), t)
+	defer s.Close()
+	s.Status = http.StatusBadRequest
+
+	_, err := s.Client.UpdateUser(context.Background(), "testuser", (&UserToUpdate{}).Email("user@example.com"))
+	if !IsEmailAlreadyExists(err) {
+		t.Errorf("UpdateUser() = %v; want = email-already-exists error", err)
+	}
+	if code := ErrorCode(err); code != "email-already-exists" {
+		t.Errorf("ErrorCode() = %q; want = %q", code, "email-already-exists")
+	}
+	for _, err := range []error{nil, errors.New("not a firebase error")} {
+		if code := ErrorCode(err); code != "" {
+			t.Errorf("ErrorCode(%v) = %q; want = \"\"", err, code)
+		}
+	}
+}
+
+func TestErrorStatusMapper(t *testing.T) {
+	s := echoServer([]byte(`{"error": {"message": "USER_NOT_FOUND"}}`), t)
+	defer s.Close()
+	s.Status = http.StatusBadRequest
+	_, notFound := s.Client.GetUser(context.Background(), "testuser")
+
+	m := NewErrorStatusMapper()
+	cases := []struct {
+		err  error
+		want int
+	}{
+		{nil, http.StatusOK},
+		{notFound, http.StatusNotFound},
+		{errors.New("not a firebase error"), http.StatusInternalServerError},
+	}
+	for _, tc := range cases {
+		if got := m.Status(tc.err); got != tc.want {
+			t.Errorf("Status(%v) = %d; want = %d", tc.err, got, tc.want)
+		}
+	}
+
+	// Gateways may hide the existence of users.
+	m.Handle("user-not-found", func(err error) int {
+		return http.StatusUnauthorized
+	})
+	if got := m.Status(notFound); got != http.StatusUnauthorized {
+		t.Errorf("Status() with handler = %d; want = %d", got, http.StatusUnauthorized)
+	}
+	m.Handle("user-not-found", nil)
+	if got := m.Status(notFound); got != http.StatusNotFound {
+		t.Errorf("Status() after removing handler = %d; want = %d", got, http.StatusNotFound)
+	}
+}
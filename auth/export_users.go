@@ -0,0 +1,166 @@
+// Copyright 2017 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+
+	"golang.org/x/net/context"
+)
+
+// ExportFormat is the output format of ExportUsers.
+type ExportFormat int
+
+const (
+	// ExportJSON writes one JSON object per line (newline-delimited JSON), using the field names of
+	// the user accounts exported by the Firebase CLI. The output can be imported with
+	// ImportUsersFromReader.
+	ExportJSON ExportFormat = iota
+
+	// ExportCSV writes one CSV record per user, with the columns produced by the Firebase CLI, and no
+	// header record.
+	ExportCSV
+)
+
+// csvExportProviders are the identity providers whose user info occupies dedicated columns of CSV
+// exports, in column order.
+var csvExportProviders = []string{"google.com", "facebook.com", "twitter.com", "github.com"}
+
+// userExportRecord is the JSON representation of a user account, as returned by the accounts:batchGet
+// endpoint.
+type userExportRecord struct {
+	userImportRecord
+	LastLoginAt int64 `json:"lastLoginAt,string,omitempty"`
+}
+
+// ExportUsers writes all the user accounts of the project to w, in the same formats as the
+// "firebase auth:export" command of the Firebase CLI.
+//
+// Users are fetched from the server in pages of up to 1000 accounts, and written as they are fetched,
+// so that projects of any size can be exported without holding all users in memory. Password hashes
+// and salts are only included when the credentials of the Client are permitted to read them. Use the
+// hash configuration of the project to import them elsewhere. ExportUsers returns the number of users
+// written, which reflects the users written so far if an error occurs.
+func (c *Client) ExportUsers(ctx context.Context, w io.Writer, format ExportFormat) (int, error) {
+	if w == nil {
+		return 0, errors.New("writer must not be nil")
+	}
+	var write func(*userExportRecord) error
+	var flush func() error
+	switch format {
+	case ExportJSON:
+		enc := json.NewEncoder(w)
+		write = func(r *userExportRecord) error {
+			rec := r.userImportRecord
+			rec.LastSignedInAt = r.LastLoginAt
+			return enc.Encode(&rec)
+		}
+		flush = func() error { return nil }
+	case ExportCSV:
+		cw := csv.NewWriter(w)
+		write = func(r *userExportRecord) error {
+			return cw.Write(r.csvRecord())
+		}
+		flush = func() error {
+			cw.Flush()
+			return cw.Error()
+		}
+	default:
+		return 0, fmt.Errorf("unsupported export format: %d", format)
+	}
+
+	count := 0
+	pageToken := ""
+	for {
+		users, next, err := c.fetchExportPage(ctx, pageToken)
+		if err != nil {
+			return count, err
+		}
+		for _, u := range users {
+			if err := write(u); err != nil {
+				return count, err
+			}
+			count++
+		}
+		if err := flush(); err != nil {
+			return count, err
+		}
+		if next == "" {
+			return count, nil
+		}
+		pageToken = next
+	}
+}
+
+// fetchExportPage fetches a page of user accounts with fetchUsersPage, and decodes them for export.
+func (c *Client) fetchExportPage(ctx context.Context, pageToken string) ([]*userExportRecord, string, error) {
+	raw, nextPageToken, err := c.fetchUsersPage(ctx, maxListUsersResults, pageToken)
+	if err != nil {
+		return nil, "", err
+	}
+	users := make([]*userExportRecord, len(raw))
+	for i, r := range raw {
+		users[i] = &userExportRecord{}
+		if err := json.Unmarshal(r, users[i]); err != nil {
+			return nil, "", err
+		}
+	}
+	return users, nextPageToken, nil
+}
+
+// csvRecord returns the CSV columns of the user account: the UID, email, email verified flag, password
+// hash, salt, display name and photo URL, followed by the UID, email, display name and photo URL for
+// each of the Google, Facebook, Twitter and GitHub providers, then the creation time, last sign-in
+// time, phone number, disabled flag and custom claims.
+func (r *userExportRecord) csvRecord() []string {
+	rec := []string{
+		r.UID,
+		r.Email,
+		strconv.FormatBool(r.EmailVerified),
+		r.PasswordHash,
+		r.Salt,
+		r.DisplayName,
+		r.PhotoURL,
+	}
+	for _, providerID := range csvExportProviders {
+		info := &UserInfo{}
+		for _, p := range r.ProviderUserInfo {
+			if p.ProviderID == providerID {
+				info = p
+				break
+			}
+		}
+		rec = append(rec, info.UID, info.Email, info.DisplayName, info.PhotoURL)
+	}
+	return append(rec,
+		formatTimestamp(r.CreatedAt),
+		formatTimestamp(r.LastLoginAt),
+		r.PhoneNumber,
+		strconv.FormatBool(r.Disabled),
+		r.CustomAttributes,
+	)
+}
+
+func formatTimestamp(millis int64) string {
+	if millis == 0 {
+		return ""
+	}
+	return strconv.FormatInt(millis, 10)
+}
@@ -0,0 +1,128 @@
+// Copyright 2017 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"bytes"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/context"
+)
+
+var exportPages = map[string]string{
+	"": `{
+		"users": [{
+			"localId": "uid1",
+			"email": "user1@example.com",
+			"emailVerified": true,
+			"passwordHash": "aGFzaA==",
+			"salt": "c2FsdA==",
+			"createdAt": "1000",
+			"lastLoginAt": "2000",
+			"passwordUpdatedAt": 1000,
+			"providerUserInfo": [
+				{"providerId": "github.com", "rawId": "gh1", "email": "user1@github.com"}
+			]
+		}],
+		"nextPageToken": "page2"
+	}`,
+	"page2": `{
+		"users": [{
+			"localId": "uid2",
+			"phoneNumber": "+15555550100",
+			"disabled": true,
+			"customAttributes": "{\"admin\": true}"
+		}]
+	}`,
+}
+
+func TestExportUsersJSON(t *testing.T) {
+	s := newPagedUsersServer(t, exportPages, nil)
+	defer s.Close()
+
+	var buf bytes.Buffer
+	count, err := s.Client.ExportUsers(context.Background(), &buf, ExportJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 2 {
+		t.Errorf("ExportUsers() = %d; want = 2", count)
+	}
+
+	want := `{"localId":"uid1","email":"user1@example.com","emailVerified":true,"passwordHash":"aGFzaA==",` +
+		`"salt":"c2FsdA==","createdAt":"1000","lastSignedInAt":"2000","providerUserInfo":` +
+		`[{"email":"user1@github.com","providerId":"github.com","rawId":"gh1"}]}` + "\n" +
+		`{"localId":"uid2","phoneNumber":"+15555550100","disabled":true,"customAttributes":"{\"admin\": true}"}` +
+		"\n"
+	if got := buf.String(); got != want {
+		t.Errorf("ExportUsers() output = %s; want = %s", got, want)
+	}
+	wantQueries := []string{"maxResults=1000", "maxResults=1000&nextPageToken=page2"}
+	if !reflect.DeepEqual(s.Queries, wantQueries) {
+		t.Errorf("ExportUsers() queries = %v; want = %v", s.Queries, wantQueries)
+	}
+
+	// The output can be read back by the importer.
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if _, err := parseImportRecord(line); err != nil {
+			t.Errorf("parseImportRecord(%s) = %v", line, err)
+		}
+	}
+}
+
+func TestExportUsersCSV(t *testing.T) {
+	s := newPagedUsersServer(t, exportPages, nil)
+	defer s.Close()
+
+	var buf bytes.Buffer
+	count, err := s.Client.ExportUsers(context.Background(), &buf, ExportCSV)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 2 {
+		t.Errorf("ExportUsers() = %d; want = 2", count)
+	}
+
+	want := "uid1,user1@example.com,true,aGFzaA==,c2FsdA==,,,,,,,,,,,,,,,gh1,user1@github.com,,,1000,2000,,false,\n" +
+		"uid2,,false,,,,,,,,,,,,,,,,,,,,,,,+15555550100,true,\"{\"\"admin\"\": true}\"\n"
+	if got := buf.String(); got != want {
+		t.Errorf("ExportUsers() output = %s; want = %s", got, want)
+	}
+}
+
+func TestExportUsersInvalid(t *testing.T) {
+	if count, err := client.ExportUsers(context.Background(), nil, ExportJSON); count != 0 || err == nil {
+		t.Errorf("ExportUsers(nil) = (%d, %v); want = (0, error)", count, err)
+	}
+	var buf bytes.Buffer
+	if count, err := client.ExportUsers(context.Background(), &buf, ExportFormat(5)); count != 0 || err == nil {
+		t.Errorf("ExportUsers(5) = (%d, %v); want = (0, error)", count, err)
+	}
+}
+
+func TestExportUsersError(t *testing.T) {
+	s := echoServer([]byte(`{"error": {"message": "QUOTA_EXCEEDED"}}`), t)
+	defer s.Close()
+	s.Status = http.StatusTooManyRequests
+
+	var buf bytes.Buffer
+	count, err := s.Client.ExportUsers(context.Background(), &buf, ExportJSON)
+	if count != 0 || !IsQuotaExceeded(err) {
+		t.Errorf("ExportUsers() = (%d, %v); want = (0, quota-exceeded error)", count, err)
+	}
+}
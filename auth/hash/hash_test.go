@@ -0,0 +1,169 @@
+// Copyright 2017 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hash
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"firebase.google.com/go/internal"
+)
+
+type userImportHash interface {
+	Config() (internal.HashConfig, error)
+}
+
+var validHashes = []struct {
+	alg  userImportHash
+	want internal.HashConfig
+}{
+	{
+		alg:  Bcrypt{},
+		want: internal.HashConfig{"hashAlgorithm": "BCRYPT"},
+	},
+	{
+		alg: StandardScrypt{
+			BlockSize:        1,
+			DerivedKeyLength: 2,
+			MemoryCost:       3,
+			Parallelization:  4,
+		},
+		want: internal.HashConfig{
+			"hashAlgorithm":   "STANDARD_SCRYPT",
+			"blockSize":       1,
+			"dkLen":           2,
+			"cpuMemCost":      3,
+			"parallelization": 4,
+		},
+	},
+	{
+		alg: Scrypt{
+			Key:           []byte("key"),
+			SaltSeparator: []byte("sep"),
+			Rounds:        8,
+			MemoryCost:    14,
+		},
+		want: internal.HashConfig{
+			"hashAlgorithm": "SCRYPT",
+			"signerKey":     "a2V5",
+			"saltSeparator": "c2Vw",
+			"rounds":        8,
+			"memoryCost":    14,
+		},
+	},
+	{
+		alg:  HMACMD5{Key: []byte("key")},
+		want: internal.HashConfig{"hashAlgorithm": "HMAC_MD5", "signerKey": "a2V5"},
+	},
+	{
+		alg:  HMACSHA1{Key: []byte("key")},
+		want: internal.HashConfig{"hashAlgorithm": "HMAC_SHA1", "signerKey": "a2V5"},
+	},
+	{
+		alg:  HMACSHA256{Key: []byte("key")},
+		want: internal.HashConfig{"hashAlgorithm": "HMAC_SHA256", "signerKey": "a2V5"},
+	},
+	{
+		alg:  HMACSHA512{Key: []byte("key")},
+		want: internal.HashConfig{"hashAlgorithm": "HMAC_SHA512", "signerKey": "a2V5"},
+	},
+	{
+		alg:  MD5{Rounds: 0},
+		want: internal.HashConfig{"hashAlgorithm": "MD5", "rounds": 0},
+	},
+	{
+		alg:  SHA1{Rounds: 1},
+		want: internal.HashConfig{"hashAlgorithm": "SHA1", "rounds": 1},
+	},
+	{
+		alg:  SHA256{Rounds: 8192},
+		want: internal.HashConfig{"hashAlgorithm": "SHA256", "rounds": 8192},
+	},
+	{
+		alg:  SHA512{Rounds: 100},
+		want: internal.HashConfig{"hashAlgorithm": "SHA512", "rounds": 100},
+	},
+	{
+		alg:  PBKDFSHA1{Rounds: 120000},
+		want: internal.HashConfig{"hashAlgorithm": "PBKDF_SHA1", "rounds": 120000},
+	},
+	{
+		alg:  PBKDF2SHA256{Rounds: 0},
+		want: internal.HashConfig{"hashAlgorithm": "PBKDF2_SHA256", "rounds": 0},
+	},
+}
+
+var invalidHashes = []userImportHash{
+	Scrypt{Rounds: 8, MemoryCost: 14},
+	Scrypt{Key: []byte("key"), Rounds: 0, MemoryCost: 14},
+	Scrypt{Key: []byte("key"), Rounds: 9, MemoryCost: 14},
+	Scrypt{Key: []byte("key"), Rounds: 8, MemoryCost: 0},
+	Scrypt{Key: []byte("key"), Rounds: 8, MemoryCost: 15},
+	HMACMD5{},
+	HMACSHA1{},
+	HMACSHA256{},
+	HMACSHA512{},
+	MD5{Rounds: -1},
+	MD5{Rounds: 8193},
+	SHA1{Rounds: 0},
+	SHA256{Rounds: 8193},
+	SHA512{Rounds: 0},
+	PBKDFSHA1{Rounds: -1},
+	PBKDF2SHA256{Rounds: 120001},
+}
+
+func TestValidHash(t *testing.T) {
+	for idx, tc := range validHashes {
+		got, err := tc.alg.Config()
+		if err != nil {
+			t.Errorf("[%d] Config() = %v", idx, err)
+		} else if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("[%d] Config() = %#v; want = %#v", idx, got, tc.want)
+		}
+	}
+}
+
+func TestInvalidHash(t *testing.T) {
+	for idx, alg := range invalidHashes {
+		if got, err := alg.Config(); got != nil || err == nil {
+			t.Errorf("[%d] Config() = (%v, %v); want = (nil, error)", idx, got, err)
+		}
+	}
+}
+
+func TestHashString(t *testing.T) {
+	cases := []struct {
+		alg  fmt.Stringer
+		want string
+	}{
+		{
+			Scrypt{Key: []byte("key"), SaltSeparator: []byte("sep"), Rounds: 8, MemoryCost: 14},
+			"hash.Scrypt{Key: REDACTED, SaltSeparator: REDACTED, Rounds: 8, MemoryCost: 14}",
+		},
+		{Scrypt{Key: []byte("key")}, "hash.Scrypt{Key: REDACTED, SaltSeparator: [], Rounds: 0, MemoryCost: 0}"},
+		{HMACMD5{Key: []byte("key")}, "hash.HMACMD5{Key: REDACTED}"},
+		{HMACSHA1{Key: []byte("key")}, "hash.HMACSHA1{Key: REDACTED}"},
+		{HMACSHA256{Key: []byte("key")}, "hash.HMACSHA256{Key: REDACTED}"},
+		{HMACSHA512{}, "hash.HMACSHA512{Key: []}"},
+	}
+	for _, tc := range cases {
+		for _, format := range []string{"%v", "%+v", "%#v"} {
+			if got := fmt.Sprintf(format, tc.alg); got != tc.want {
+				t.Errorf("Sprintf(%q) = %s; want = %s", format, got, tc.want)
+			}
+		}
+	}
+}
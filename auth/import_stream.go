@@ -0,0 +1,208 @@
+// Copyright 2017 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"golang.org/x/net/context"
+)
+
+// maxImportRecordSize is the maximum size of a single line of input to ImportUsersFromReader, in bytes.
+const maxImportRecordSize = 1 << 20
+
+// userImportRecord is the JSON representation of a user account, as read by ImportUsersFromReader. It
+// uses the same field names as the user accounts exported by the Firebase CLI.
+type userImportRecord struct {
+	UID              string      `json:"localId"`
+	Email            string      `json:"email,omitempty"`
+	EmailVerified    bool        `json:"emailVerified,omitempty"`
+	DisplayName      string      `json:"displayName,omitempty"`
+	PhotoURL         string      `json:"photoUrl,omitempty"`
+	PhoneNumber      string      `json:"phoneNumber,omitempty"`
+	Disabled         bool        `json:"disabled,omitempty"`
+	PasswordHash     string      `json:"passwordHash,omitempty"`
+	Salt             string      `json:"salt,omitempty"`
+	CreatedAt        int64       `json:"createdAt,string,omitempty"`
+	LastSignedInAt   int64       `json:"lastSignedInAt,string,omitempty"`
+	CustomAttributes string      `json:"customAttributes,omitempty"`
+	ProviderUserInfo []*UserInfo `json:"providerUserInfo,omitempty"`
+}
+
+func parseImportRecord(line string) (*UserToImport, error) {
+	var rec userImportRecord
+	if err := json.Unmarshal([]byte(line), &rec); err != nil {
+		return nil, err
+	}
+	return rec.makeUserToImport()
+}
+
+func (r *userImportRecord) makeUserToImport() (*UserToImport, error) {
+	u := (&UserToImport{}).UID(r.UID)
+	if r.Email != "" {
+		u.Email(r.Email)
+	}
+	if r.EmailVerified {
+		u.EmailVerified(true)
+	}
+	if r.DisplayName != "" {
+		u.DisplayName(r.DisplayName)
+	}
+	if r.PhotoURL != "" {
+		u.PhotoURL(r.PhotoURL)
+	}
+	if r.PhoneNumber != "" {
+		u.PhoneNumber(r.PhoneNumber)
+	}
+	if r.Disabled {
+		u.Disabled(true)
+	}
+	if r.PasswordHash != "" {
+		b, err := decodeBase64(r.PasswordHash)
+		if err != nil {
+			return nil, fmt.Errorf("malformed password hash: %v", err)
+		}
+		u.PasswordHash(b)
+	}
+	if r.Salt != "" {
+		b, err := decodeBase64(r.Salt)
+		if err != nil {
+			return nil, fmt.Errorf("malformed password salt: %v", err)
+		}
+		u.PasswordSalt(b)
+	}
+	if r.CreatedAt > 0 || r.LastSignedInAt > 0 {
+		u.Metadata(&UserMetadata{
+			CreationTimestamp:  r.CreatedAt,
+			LastLogInTimestamp: r.LastSignedInAt,
+		})
+	}
+	if r.CustomAttributes != "" {
+		var claims map[string]interface{}
+		if err := json.Unmarshal([]byte(r.CustomAttributes), &claims); err != nil {
+			return nil, fmt.Errorf("malformed custom attributes: %v", err)
+		}
+		u.CustomClaims(claims)
+	}
+	if len(r.ProviderUserInfo) > 0 {
+		u.ProviderData(r.ProviderUserInfo)
+	}
+	if _, err := u.validatedUserInfo(); err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+// decodeBase64 decodes a string encoded in either the standard or the URL-safe base64 alphabet, with or
+// without padding.
+func decodeBase64(s string) ([]byte, error) {
+	s = strings.TrimRight(s, "=")
+	if strings.ContainsAny(s, "+/") {
+		return base64.RawStdEncoding.DecodeString(s)
+	}
+	return base64.RawURLEncoding.DecodeString(s)
+}
+
+// ImportUsersFromReader imports the user accounts read from r into Firebase Auth.
+//
+// The input must contain one JSON object per line (newline-delimited JSON), using the same field names
+// as the user accounts exported by the Firebase CLI (localId, email, passwordHash, providerUserInfo and
+// so on). Blank lines are ignored. Users are read and imported in batches of up to 1000, so that inputs
+// of any size can be imported without holding them in memory. The options are applied to each batch.
+//
+// Records that cannot be parsed or fail validation, as well as users rejected by the server, are
+// reported in the Errors of the returned ImportUsersResult, where Index is the zero-based position of
+// the record in the input. If progress is not nil, it is called after each batch with the cumulative
+// result so far. ImportUsersFromReader returns an error if the input cannot be read, or a batch as a
+// whole cannot be imported, in which case the returned result reflects the batches imported so far.
+func (c *Client) ImportUsersFromReader(ctx context.Context, r io.Reader, progress func(*ImportUsersResult),
+	opts ...UserImportOption) (*ImportUsersResult, error) {
+	if r == nil {
+		return nil, errors.New("reader must not be nil")
+	}
+
+	result := &ImportUsersResult{}
+	var batch []*UserToImport
+	var indices []int
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		br, err := c.ImportUsers(ctx, batch, opts...)
+		if err != nil {
+			return err
+		}
+		for _, e := range br.Errors {
+			if e.Index < 0 || e.Index >= len(indices) {
+				return fmt.Errorf("server reported an error for user at index %d; batch size: %d",
+					e.Index, len(indices))
+			}
+		}
+		result.SuccessCount += br.SuccessCount
+		result.FailureCount += br.FailureCount
+		for _, e := range br.Errors {
+			result.Errors = append(result.Errors, &ImportUsersErrorInfo{
+				Index:  indices[e.Index],
+				Reason: e.Reason,
+			})
+		}
+		batch, indices = nil, nil
+		if progress != nil {
+			progress(result)
+		}
+		return nil
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxImportRecordSize)
+	index := 0
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		u, err := parseImportRecord(line)
+		if err != nil {
+			result.FailureCount++
+			result.Errors = append(result.Errors, &ImportUsersErrorInfo{
+				Index:  index,
+				Reason: err.Error(),
+			})
+		} else {
+			batch = append(batch, u)
+			indices = append(indices, index)
+		}
+		index++
+
+		if len(batch) == maxImportUsersBatchSize {
+			if err := flush(); err != nil {
+				return result, err
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return result, err
+	}
+	if err := flush(); err != nil {
+		return result, err
+	}
+	return result, nil
+}
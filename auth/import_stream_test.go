@@ -0,0 +1,160 @@
+// Copyright 2017 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"firebase.google.com/go/auth/hash"
+
+	"golang.org/x/net/context"
+)
+
+func TestImportUsersFromReader(t *testing.T) {
+	resp := `{"error": [{"index": 1, "message": "duplicate email"}]}`
+	s := echoServer([]byte(resp), t)
+	defer s.Close()
+
+	input := strings.Join([]string{
+		`{"localId": "user1", "email": "user1@example.com", "passwordHash": "cGFzc3dvcmQ=", "salt": "c2FsdA=="}`,
+		`not json`,
+		``,
+		`{"localId": "user2", "createdAt": "1000", "customAttributes": "{\"admin\": true}"}`,
+		`{"email": "no-uid@example.com"}`,
+		`{"localId": "user3", "providerUserInfo": [{"providerId": "google.com", "rawId": "google_uid3"}]}`,
+	}, "\n")
+
+	var calls int
+	result, err := s.Client.ImportUsersFromReader(context.Background(), strings.NewReader(input),
+		func(*ImportUsersResult) { calls++ }, WithHash(hash.Bcrypt{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.SuccessCount != 2 || result.FailureCount != 3 || len(result.Errors) != 3 {
+		t.Fatalf("ImportUsersFromReader() = %#v; want = 2 successes, 3 failures", result)
+	}
+	var indices []int
+	for _, e := range result.Errors {
+		indices = append(indices, e.Index)
+	}
+	if want := []int{1, 3, 2}; !reflect.DeepEqual(indices, want) {
+		t.Errorf("Errors indices = %v; want = %v", indices, want)
+	}
+	if calls != 1 {
+		t.Errorf("progress calls = %d; want = 1", calls)
+	}
+
+	wantBody := map[string]interface{}{
+		"users": []interface{}{
+			map[string]interface{}{
+				"localId":      "user1",
+				"email":        "user1@example.com",
+				"passwordHash": "cGFzc3dvcmQ",
+				"salt":         "c2FsdA",
+			},
+			map[string]interface{}{
+				"localId":          "user2",
+				"createdAt":        "1000",
+				"customAttributes": `{"admin":true}`,
+			},
+			map[string]interface{}{
+				"localId": "user3",
+				"providerUserInfo": []interface{}{
+					map[string]interface{}{"providerId": "google.com", "rawId": "google_uid3"},
+				},
+			},
+		},
+		"hashAlgorithm": "BCRYPT",
+	}
+	checkRequest(t, s, "/projects/mock-project-id/accounts:batchCreate", wantBody)
+}
+
+func TestImportUsersFromReaderBatches(t *testing.T) {
+	s := echoServer([]byte(`{}`), t)
+	defer s.Close()
+
+	var lines []string
+	for i := 0; i < maxImportUsersBatchSize+1; i++ {
+		lines = append(lines, fmt.Sprintf(`{"localId": "user%d"}`, i))
+	}
+	var progress []int
+	result, err := s.Client.ImportUsersFromReader(context.Background(), strings.NewReader(strings.Join(lines, "\n")),
+		func(r *ImportUsersResult) { progress = append(progress, r.SuccessCount) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.SuccessCount != maxImportUsersBatchSize+1 || result.FailureCount != 0 {
+		t.Errorf("ImportUsersFromReader() = %#v; want = %d successes", result, maxImportUsersBatchSize+1)
+	}
+	if want := []int{maxImportUsersBatchSize, maxImportUsersBatchSize + 1}; !reflect.DeepEqual(progress, want) {
+		t.Errorf("progress = %v; want = %v", progress, want)
+	}
+	if len(s.Req) != 2 {
+		t.Fatalf("requests = %d; want = 2", len(s.Req))
+	}
+	var body struct {
+		Users []interface{} `json:"users"`
+	}
+	if err := json.Unmarshal(s.Rbody, &body); err != nil {
+		t.Fatal(err)
+	}
+	if len(body.Users) != 1 {
+		t.Errorf("last batch = %d users; want = 1", len(body.Users))
+	}
+}
+
+func TestImportUsersFromReaderHTTPError(t *testing.T) {
+	s := echoServer([]byte(`{"error": {"message": "INTERNAL_ERROR"}}`), t)
+	defer s.Close()
+	s.Status = http.StatusInternalServerError
+
+	input := `{"localId": "user1"}`
+	result, err := s.Client.ImportUsersFromReader(context.Background(), strings.NewReader(input), nil)
+	if err == nil {
+		t.Fatal("ImportUsersFromReader() = nil; want = error")
+	}
+	if result == nil || result.SuccessCount != 0 {
+		t.Errorf("ImportUsersFromReader() = %#v; want = empty result", result)
+	}
+}
+
+func TestImportUsersFromReaderInvalidErrorIndex(t *testing.T) {
+	for _, index := range []int{-1, 2} {
+		resp := fmt.Sprintf(`{"error": [{"index": %d, "message": "duplicate email"}]}`, index)
+		s := echoServer([]byte(resp), t)
+
+		input := "{\"localId\": \"user1\"}\n{\"localId\": \"user2\"}"
+		result, err := s.Client.ImportUsersFromReader(context.Background(), strings.NewReader(input), nil)
+		s.Close()
+		if err == nil {
+			t.Errorf("ImportUsersFromReader(index: %d) = nil; want = error", index)
+		}
+		if result == nil || result.SuccessCount != 0 || len(result.Errors) != 0 {
+			t.Errorf("ImportUsersFromReader(index: %d) = %#v; want = empty result", index, result)
+		}
+	}
+}
+
+func TestImportUsersFromReaderNil(t *testing.T) {
+	result, err := client.ImportUsersFromReader(context.Background(), nil, nil)
+	if result != nil || err == nil {
+		t.Errorf("ImportUsersFromReader(nil) = (%v, %v); want = (nil, error)", result, err)
+	}
+}
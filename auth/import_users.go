@@ -0,0 +1,392 @@
+// Copyright 2017 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"firebase.google.com/go/internal"
+
+	"golang.org/x/net/context"
+)
+
+// maxImportUsersBatchSize is the maximum number of users that can be imported in one ImportUsers call.
+const maxImportUsersBatchSize = 1000
+
+// UserToImport represents a user account that can be bulk imported into Firebase Auth.
+//
+// Only the UID is required. All other attributes are optional.
+type UserToImport struct {
+	params map[string]interface{}
+}
+
+func (u *UserToImport) set(key string, value interface{}) *UserToImport {
+	if u.params == nil {
+		u.params = make(map[string]interface{})
+	}
+	u.params[key] = value
+	return u
+}
+
+// UID setter. This field is required.
+func (u *UserToImport) UID(uid string) *UserToImport {
+	return u.set("localId", uid)
+}
+
+// CustomClaims setter.
+func (u *UserToImport) CustomClaims(claims map[string]interface{}) *UserToImport {
+	return u.set("customClaims", claims)
+}
+
+// Disabled setter.
+func (u *UserToImport) Disabled(disabled bool) *UserToImport {
+	return u.set("disabled", disabled)
+}
+
+// DisplayName setter.
+func (u *UserToImport) DisplayName(name string) *UserToImport {
+	return u.set("displayName", name)
+}
+
+// Email setter.
+func (u *UserToImport) Email(email string) *UserToImport {
+	return u.set("email", email)
+}
+
+// EmailVerified setter.
+func (u *UserToImport) EmailVerified(verified bool) *UserToImport {
+	return u.set("emailVerified", verified)
+}
+
+// Metadata setter.
+func (u *UserToImport) Metadata(metadata *UserMetadata) *UserToImport {
+	return u.set("metadata", metadata)
+}
+
+// PasswordHash setter. When set, a UserImportHash must be specified as an option to ImportUsers.
+func (u *UserToImport) PasswordHash(hash []byte) *UserToImport {
+	return u.set("passwordHash", base64.RawURLEncoding.EncodeToString(hash))
+}
+
+// PasswordSalt setter.
+func (u *UserToImport) PasswordSalt(salt []byte) *UserToImport {
+	return u.set("salt", base64.RawURLEncoding.EncodeToString(salt))
+}
+
+// PhoneNumber setter.
+func (u *UserToImport) PhoneNumber(phone string) *UserToImport {
+	return u.set("phoneNumber", phone)
+}
+
+// PhotoURL setter.
+func (u *UserToImport) PhotoURL(url string) *UserToImport {
+	return u.set("photoUrl", url)
+}
+
+// ProviderData setter. Sets the federated identity providers linked to the user account.
+func (u *UserToImport) ProviderData(providers []*UserInfo) *UserToImport {
+	return u.set("providerUserInfo", providers)
+}
+
+// redactedPasswordHash is the password hash returned to credentials that are not permitted to read
+// password hashes, which is the base64 encoding of "REDACTED".
+const redactedPasswordHash = "UkVEQUNURUQ="
+
+// NewUserToImport creates a UserToImport from a user account retrieved from another project, for
+// instance with GetUser or ListUsers, so that users can be copied between projects without mapping
+// their attributes one by one.
+//
+// The UID, email, phone number, display name, photo URL, verification and disabled flags, custom
+// claims, metadata and federated identity providers of the user are copied. The password hash and salt
+// are copied when present, in which case the hash configuration of the source project must be passed
+// to ImportUsers with the WithHash option. NewUserToImport returns an error if the password hash of the
+// user is redacted, because the credentials used to retrieve the user were not permitted to read it.
+// Second factors are not copied.
+func NewUserToImport(user *UserRecord) (*UserToImport, error) {
+	if user == nil || user.UserInfo == nil {
+		return nil, errors.New("user must not be nil")
+	}
+	u := (&UserToImport{}).UID(user.UID)
+	if user.Email != "" {
+		u.Email(user.Email)
+	}
+	if user.EmailVerified {
+		u.EmailVerified(true)
+	}
+	if user.PhoneNumber != "" {
+		u.PhoneNumber(user.PhoneNumber)
+	}
+	if user.DisplayName != "" {
+		u.DisplayName(user.DisplayName)
+	}
+	if user.PhotoURL != "" {
+		u.PhotoURL(user.PhotoURL)
+	}
+	if user.Disabled {
+		u.Disabled(true)
+	}
+	if len(user.CustomClaims) > 0 {
+		u.CustomClaims(user.CustomClaims)
+	}
+	if user.UserMetadata != nil {
+		u.Metadata(&UserMetadata{
+			CreationTimestamp:  user.UserMetadata.CreationTimestamp,
+			LastLogInTimestamp: user.UserMetadata.LastLogInTimestamp,
+		})
+	}
+	if user.PasswordHash == redactedPasswordHash {
+		return nil, fmt.Errorf("password hash of user %q is redacted; retrieve the user with credentials "+
+			"permitted to read password hashes", user.UID)
+	}
+	if user.PasswordHash != "" {
+		b, err := decodeBase64(user.PasswordHash)
+		if err != nil {
+			return nil, fmt.Errorf("malformed password hash: %v", err)
+		}
+		u.PasswordHash(b)
+	}
+	if user.PasswordSalt != "" {
+		b, err := decodeBase64(user.PasswordSalt)
+		if err != nil {
+			return nil, fmt.Errorf("malformed password salt: %v", err)
+		}
+		u.PasswordSalt(b)
+	}
+
+	// The password and phone providers are derived from the password hash and the phone number of the
+	// user, and cannot be imported as provider data.
+	var providers []*UserInfo
+	for _, p := range user.ProviderUserInfo {
+		if p.ProviderID != "password" && p.ProviderID != "phone" {
+			providers = append(providers, p)
+		}
+	}
+	if len(providers) > 0 {
+		u.ProviderData(providers)
+	}
+	if _, err := u.validatedUserInfo(); err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+// validatedUserInfo validates the parameters set on the UserToImport, and builds the corresponding
+// entry of the accounts:batchCreate request.
+func (u *UserToImport) validatedUserInfo() (map[string]interface{}, error) {
+	if len(u.params) == 0 {
+		return nil, errors.New("no parameters are set on the user to import")
+	}
+
+	info := make(map[string]interface{})
+	for k, v := range u.params {
+		info[k] = v
+	}
+	uid, _ := info["localId"].(string)
+	if err := validateUID(uid); err != nil {
+		return nil, err
+	}
+	if email, ok := info["email"]; ok {
+		if err := validateEmail(email.(string)); err != nil {
+			return nil, err
+		}
+	}
+	if phone, ok := info["phoneNumber"]; ok {
+		if err := ValidatePhoneNumber(phone.(string)); err != nil {
+			return nil, err
+		}
+	}
+	if claims, ok := info["customClaims"]; ok {
+		delete(info, "customClaims")
+		if attrs, err := serializeCustomClaims(claims.(map[string]interface{})); err != nil {
+			return nil, err
+		} else if attrs != "{}" {
+			info["customAttributes"] = attrs
+		}
+	}
+	if metadata, ok := info["metadata"]; ok {
+		delete(info, "metadata")
+		if m := metadata.(*UserMetadata); m != nil {
+			if m.CreationTimestamp > 0 {
+				info["createdAt"] = strconv.FormatInt(m.CreationTimestamp, 10)
+			}
+			if m.LastLogInTimestamp > 0 {
+				info["lastLoginAt"] = strconv.FormatInt(m.LastLogInTimestamp, 10)
+			}
+		}
+	}
+	if providers, ok := info["providerUserInfo"]; ok {
+		for _, p := range providers.([]*UserInfo) {
+			if p == nil || p.ProviderID == "" || p.UID == "" {
+				return nil, errors.New("provider data must specify a provider id and a uid")
+			}
+		}
+	}
+	return info, nil
+}
+
+// UserImportHash represents a hash algorithm and its associated configuration that can be used to
+// hash user passwords.
+//
+// Implementations of this interface are available in the auth/hash package.
+type UserImportHash interface {
+	Config() (internal.HashConfig, error)
+}
+
+// UserImportOption is an option for the ImportUsers function.
+type UserImportOption interface {
+	applyTo(req map[string]interface{}) error
+}
+
+type withHash struct {
+	hash UserImportHash
+}
+
+func (w withHash) applyTo(req map[string]interface{}) error {
+	if w.hash == nil {
+		return errors.New("hash must not be nil")
+	}
+	conf, err := w.hash.Config()
+	if err != nil {
+		return err
+	}
+	for k, v := range conf {
+		req[k] = v
+	}
+	return nil
+}
+
+// WithHash returns a UserImportOption that specifies a hash configuration.
+//
+// The hash configuration is required when importing users with password hashes.
+func WithHash(hash UserImportHash) UserImportOption {
+	return withHash{hash}
+}
+
+// ImportUsersResult is the result of the ImportUsers function.
+type ImportUsersResult struct {
+	SuccessCount int
+	FailureCount int
+	Errors       []*ImportUsersErrorInfo
+}
+
+// ImportUsersErrorInfo describes the failure to import one of the users in an ImportUsers call.
+//
+// Index is the position of the failed user in the list passed to ImportUsers.
+type ImportUsersErrorInfo struct {
+	Index  int    `json:"index"`
+	Reason string `json:"message"`
+}
+
+// ImportUsers imports an array of users to Firebase Auth.
+//
+// ImportUsers accepts up to 1000 users in a single call. If any of the users contain a password hash,
+// a hash configuration must be specified using the WithHash option. No more than one user may share an
+// identifier such as a UID or an email. Failures to import individual users are reported in the
+// returned ImportUsersResult rather than as an error. ImportUsers only returns an error when the batch
+// as a whole cannot be processed.
+func (c *Client) ImportUsers(ctx context.Context, users []*UserToImport, opts ...UserImportOption) (*ImportUsersResult, error) {
+	if len(users) == 0 {
+		return nil, errors.New("users list must not be empty")
+	}
+	if len(users) > maxImportUsersBatchSize {
+		return nil, fmt.Errorf("users list must not contain more than %d elements", maxImportUsersBatchSize)
+	}
+
+	var infos []map[string]interface{}
+	hashRequired := false
+	for _, u := range users {
+		if u == nil {
+			return nil, errors.New("users list must not contain nil elements")
+		}
+		info, err := u.validatedUserInfo()
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := info["passwordHash"]; ok {
+			hashRequired = true
+		}
+		infos = append(infos, info)
+	}
+
+	req := map[string]interface{}{"users": infos}
+	hashSpecified := false
+	for _, opt := range opts {
+		if opt == nil {
+			return nil, errors.New("options must not contain nil elements")
+		}
+		if err := opt.applyTo(req); err != nil {
+			return nil, err
+		}
+		if _, ok := opt.(withHash); ok {
+			hashSpecified = true
+		}
+	}
+	if hashRequired && !hashSpecified {
+		return nil, errors.New("hash option is required to import users with passwords")
+	}
+
+	var parsed struct {
+		Error []*ImportUsersErrorInfo `json:"error"`
+	}
+	if err := c.post(ctx, "/accounts:batchCreate", req, &parsed); err != nil {
+		return nil, err
+	}
+	return &ImportUsersResult{
+		SuccessCount: len(users) - len(parsed.Error),
+		FailureCount: len(parsed.Error),
+		Errors:       parsed.Error,
+	}, nil
+}
+
+// VerifyPassword checks that the given password is the password of the user with the given email
+// address, and returns the UID of the user. It is meant for migration tools, to confirm that the
+// password hashes of imported users were imported with the right hash configuration before switching
+// over to Firebase Auth.
+//
+// VerifyPassword signs the user in, which updates the last sign-in time of the user, and is subject to
+// the sign-in quota and brute force protection of the project. The ID and refresh tokens issued by the
+// sign-in are discarded, so that the credentials of users are never exposed to the caller. Hence it
+// should only be used with test accounts, or with passwords that the caller already knows.
+//
+// If the password does not match, VerifyPassword returns an error for which IsInvalidCredential
+// returns true.
+func (c *Client) VerifyPassword(ctx context.Context, email, password string) (string, error) {
+	if err := validateEmail(email); err != nil {
+		return "", err
+	}
+	if password == "" {
+		return "", errors.New("password must be a non-empty string")
+	}
+
+	payload := map[string]interface{}{
+		"email":    email,
+		"password": password,
+	}
+	if c.tenantID != "" {
+		payload["tenantId"] = c.tenantID
+	}
+	var parsed struct {
+		LocalID string `json:"localId"`
+	}
+	endpoint := c.userEndpoint + "/accounts:signInWithPassword"
+	if err := c.makeRequest(ctx, http.MethodPost, endpoint, payload, &parsed); err != nil {
+		return "", err
+	}
+	return parsed.LocalID, nil
+}
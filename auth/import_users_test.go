@@ -0,0 +1,267 @@
+// Copyright 2017 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"firebase.google.com/go/auth/hash"
+
+	"golang.org/x/net/context"
+)
+
+func TestImportUsers(t *testing.T) {
+	resp := `{
+		"error": [
+			{"index": 1, "message": "duplicate email"}
+		]
+	}`
+	s := echoServer([]byte(resp), t)
+	defer s.Close()
+
+	users := []*UserToImport{
+		(&UserToImport{}).UID("user1"),
+		(&UserToImport{}).UID("user2").Email("user2@example.com"),
+	}
+	result, err := s.Client.ImportUsers(context.Background(), users)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &ImportUsersResult{
+		SuccessCount: 1,
+		FailureCount: 1,
+		Errors:       []*ImportUsersErrorInfo{{Index: 1, Reason: "duplicate email"}},
+	}
+	if !reflect.DeepEqual(result, want) {
+		t.Errorf("ImportUsers() = %#v; want = %#v", result, want)
+	}
+
+	wantBody := map[string]interface{}{
+		"users": []interface{}{
+			map[string]interface{}{"localId": "user1"},
+			map[string]interface{}{"localId": "user2", "email": "user2@example.com"},
+		},
+	}
+	checkRequest(t, s, "/projects/mock-project-id/accounts:batchCreate", wantBody)
+}
+
+func TestImportUsersWithHash(t *testing.T) {
+	s := echoServer([]byte(`{}`), t)
+	defer s.Close()
+
+	users := []*UserToImport{
+		(&UserToImport{}).
+			UID("user1").
+			Email("user1@example.com").
+			EmailVerified(true).
+			DisplayName("User One").
+			PhotoURL("http://www.example.com/user1/photo.png").
+			PhoneNumber("+15555550001").
+			Disabled(true).
+			CustomClaims(map[string]interface{}{"admin": true}).
+			Metadata(&UserMetadata{CreationTimestamp: 1000, LastLogInTimestamp: 2000}).
+			ProviderData([]*UserInfo{{ProviderID: "google.com", UID: "google_uid1"}}).
+			PasswordHash([]byte("password")).
+			PasswordSalt([]byte("salt")),
+	}
+	scrypt := hash.Scrypt{
+		Key:           []byte("key"),
+		SaltSeparator: []byte("sep"),
+		Rounds:        8,
+		MemoryCost:    14,
+	}
+	result, err := s.Client.ImportUsers(context.Background(), users, WithHash(scrypt))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.SuccessCount != 1 || result.FailureCount != 0 || len(result.Errors) != 0 {
+		t.Errorf("ImportUsers() = %#v; want = 1 success", result)
+	}
+
+	wantBody := map[string]interface{}{
+		"users": []interface{}{
+			map[string]interface{}{
+				"localId":          "user1",
+				"email":            "user1@example.com",
+				"emailVerified":    true,
+				"displayName":      "User One",
+				"photoUrl":         "http://www.example.com/user1/photo.png",
+				"phoneNumber":      "+15555550001",
+				"disabled":         true,
+				"customAttributes": `{"admin":true}`,
+				"createdAt":        "1000",
+				"lastLoginAt":      "2000",
+				"providerUserInfo": []interface{}{
+					map[string]interface{}{"providerId": "google.com", "rawId": "google_uid1"},
+				},
+				"passwordHash": "cGFzc3dvcmQ",
+				"salt":         "c2FsdA",
+			},
+		},
+		"hashAlgorithm": "SCRYPT",
+		"signerKey":     "a2V5",
+		"saltSeparator": "c2Vw",
+		"rounds":        float64(8),
+		"memoryCost":    float64(14),
+	}
+	checkRequest(t, s, "/projects/mock-project-id/accounts:batchCreate", wantBody)
+}
+
+func TestImportUsersInvalid(t *testing.T) {
+	tooMany := make([]*UserToImport, maxImportUsersBatchSize+1)
+	for i := range tooMany {
+		tooMany[i] = (&UserToImport{}).UID("user")
+	}
+	cases := []struct {
+		users []*UserToImport
+		opts  []UserImportOption
+	}{
+		{nil, nil},
+		{tooMany, nil},
+		{[]*UserToImport{nil}, nil},
+		{[]*UserToImport{{}}, nil},
+		{[]*UserToImport{(&UserToImport{}).Email("user@example.com")}, nil},
+		{[]*UserToImport{(&UserToImport{}).UID(strings.Repeat("a", 129))}, nil},
+		{[]*UserToImport{(&UserToImport{}).UID("user").Email("not-an-email")}, nil},
+		{[]*UserToImport{(&UserToImport{}).UID("user").PhoneNumber("1234")}, nil},
+		{[]*UserToImport{(&UserToImport{}).UID("user").CustomClaims(map[string]interface{}{"sub": "x"})}, nil},
+		{[]*UserToImport{(&UserToImport{}).UID("user").ProviderData([]*UserInfo{{ProviderID: "google.com"}})}, nil},
+		{[]*UserToImport{(&UserToImport{}).UID("user").PasswordHash([]byte("password"))}, nil},
+		{[]*UserToImport{(&UserToImport{}).UID("user")}, []UserImportOption{nil}},
+		{[]*UserToImport{(&UserToImport{}).UID("user")}, []UserImportOption{WithHash(nil)}},
+		{[]*UserToImport{(&UserToImport{}).UID("user")}, []UserImportOption{WithHash(hash.HMACSHA256{})}},
+	}
+	for idx, tc := range cases {
+		result, err := client.ImportUsers(context.Background(), tc.users, tc.opts...)
+		if result != nil || err == nil {
+			t.Errorf("[%d] ImportUsers() = (%v, %v); want = (nil, error)", idx, result, err)
+		}
+	}
+}
+
+func TestNewUserToImport(t *testing.T) {
+	user := *testUser
+	user.PasswordHash = "cGFzc3dvcmQ="
+	user.PasswordSalt = "c2FsdA=="
+	user.ProviderUserInfo = append(user.ProviderUserInfo, &UserInfo{ProviderID: "google.com", UID: "google_uid"})
+
+	u, err := NewUserToImport(&user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := u.validatedUserInfo()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{
+		"localId":          "testuser",
+		"email":            "testuser@example.com",
+		"emailVerified":    true,
+		"phoneNumber":      "+1234567890",
+		"displayName":      "Test User",
+		"photoUrl":         "http://www.example.com/testuser/photo.png",
+		"customAttributes": `{"admin":true,"package":"gold"}`,
+		"createdAt":        "1234567890000",
+		"lastLoginAt":      "1233211232000",
+		"passwordHash":     "cGFzc3dvcmQ",
+		"salt":             "c2FsdA",
+		"providerUserInfo": []*UserInfo{{ProviderID: "google.com", UID: "google_uid"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("NewUserToImport() = %#v; want = %#v", got, want)
+	}
+}
+
+func TestNewUserToImportInvalid(t *testing.T) {
+	redacted := *testUser
+	redacted.PasswordHash = redactedPasswordHash
+	malformed := *testUser
+	malformed.PasswordHash = "not base64!"
+
+	for _, user := range []*UserRecord{nil, {}, &redacted, &malformed} {
+		if u, err := NewUserToImport(user); u != nil || err == nil {
+			t.Errorf("NewUserToImport(%v) = (%v, %v); want = (nil, error)", user, u, err)
+		}
+	}
+}
+
+func TestVerifyPassword(t *testing.T) {
+	s := echoServer([]byte(`{"localId": "testuser", "idToken": "id-token", "refreshToken": "refresh-token"}`), t)
+	defer s.Close()
+
+	uid, err := s.Client.VerifyPassword(context.Background(), testEmail, "password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if uid != "testuser" {
+		t.Errorf("VerifyPassword() = %q; want = %q", uid, "testuser")
+	}
+	want := map[string]interface{}{
+		"email":    testEmail,
+		"password": "password",
+	}
+	checkRequest(t, s, "/accounts:signInWithPassword", want)
+}
+
+func TestVerifyPasswordTenant(t *testing.T) {
+	s := echoServer([]byte(`{"localId": "testuser"}`), t)
+	defer s.Close()
+
+	tc, err := s.Client.TenantManager().AuthForTenant("tenantID")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tc.VerifyPassword(context.Background(), testEmail, "password"); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{
+		"email":    testEmail,
+		"password": "password",
+		"tenantId": "tenantID",
+	}
+	checkRequest(t, s, "/accounts:signInWithPassword", want)
+}
+
+func TestVerifyPasswordInvalidCredential(t *testing.T) {
+	for _, code := range []string{"INVALID_PASSWORD", "INVALID_LOGIN_CREDENTIALS"} {
+		s := echoServer([]byte(`{"error": {"message": "`+code+`"}}`), t)
+		s.Status = http.StatusBadRequest
+
+		uid, err := s.Client.VerifyPassword(context.Background(), testEmail, "wrong")
+		if uid != "" || !IsInvalidCredential(err) {
+			t.Errorf("VerifyPassword(%s) = (%q, %v); want = (\"\", invalid-credential error)", code, uid, err)
+		}
+		s.Close()
+	}
+}
+
+func TestVerifyPasswordInvalid(t *testing.T) {
+	cases := []struct {
+		email    string
+		password string
+	}{
+		{"", "password"},
+		{"not-an-email", "password"},
+		{testEmail, ""},
+	}
+	for _, tc := range cases {
+		if uid, err := client.VerifyPassword(context.Background(), tc.email, tc.password); uid != "" || err == nil {
+			t.Errorf("VerifyPassword(%q) = (%q, %v); want = (\"\", error)", tc.email, uid, err)
+		}
+	}
+}
@@ -27,6 +27,80 @@ import (
 	"strings"
 )
 
+// TokenDecodeErrorKind identifies the way in which a JWT is malformed.
+type TokenDecodeErrorKind string
+
+const (
+	// TokenDecodeSegments indicates that the token does not consist of three dot-separated segments.
+	TokenDecodeSegments TokenDecodeErrorKind = "segments"
+
+	// TokenDecodeBase64 indicates that a segment of the token is not valid unpadded base64url.
+	TokenDecodeBase64 TokenDecodeErrorKind = "base64"
+
+	// TokenDecodeJSON indicates that a segment of the token does not decode into a valid JSON object
+	// of the expected shape.
+	TokenDecodeJSON TokenDecodeErrorKind = "json"
+)
+
+// TokenDecodeError is returned when a JWT cannot be decoded, and describes where decoding failed to
+// help debug malformed tokens.
+//
+// Segment is the index of the segment that failed to decode: 0 for the header and 1 for the payload.
+// It is -1 for TokenDecodeSegments errors. Offset is the position of the malformed input, counted in
+// the base64 segment for TokenDecodeBase64 errors, and in the decoded JSON for TokenDecodeJSON errors.
+// It is -1 when the position is not known. Algorithm, Type and KeyID hold the corresponding header
+// values of the token, when the header was decoded before the failure.
+type TokenDecodeError struct {
+	Kind      TokenDecodeErrorKind
+	Segment   int
+	Offset    int64
+	Algorithm string
+	Type      string
+	KeyID     string
+	Err       error
+}
+
+func (e *TokenDecodeError) Error() string {
+	if e.Kind == TokenDecodeSegments {
+		return e.Err.Error()
+	}
+	segment := "header"
+	if e.Segment == 1 {
+		segment = "payload"
+	}
+	msg := fmt.Sprintf("failed to decode token %s: invalid %s", segment, e.Kind)
+	if e.Offset >= 0 {
+		msg = fmt.Sprintf("%s at offset %d", msg, e.Offset)
+	}
+	return fmt.Sprintf("%s: %v", msg, e.Err)
+}
+
+// newTokenDecodeError classifies an error returned by decode, which is either a base64 error or a JSON
+// error, for the segment at the given index.
+func newTokenDecodeError(segment int, h *jwtHeader, err error) *TokenDecodeError {
+	e := &TokenDecodeError{
+		Kind:    TokenDecodeJSON,
+		Segment: segment,
+		Offset:  -1,
+		Err:     err,
+	}
+	switch err := err.(type) {
+	case base64.CorruptInputError:
+		e.Kind = TokenDecodeBase64
+		e.Offset = int64(err)
+	case *json.SyntaxError:
+		e.Offset = err.Offset
+	case *json.UnmarshalTypeError:
+		e.Offset = err.Offset
+	}
+	if segment > 0 {
+		e.Algorithm = h.Algorithm
+		e.Type = h.Type
+		e.KeyID = h.KeyID
+	}
+	return e
+}
+
 type jwtHeader struct {
 	Algorithm string `json:"alg"`
 	Type      string `json:"typ"`
@@ -101,6 +175,9 @@ func encodeToken(h jwtHeader, p jwtPayload, pk *rsa.PrivateKey) (string, error)
 	}
 
 	ss := fmt.Sprintf("%s.%s", header, payload)
+	if h.Algorithm == "none" {
+		return ss + ".", nil
+	}
 	hash := sha256.New()
 	hash.Write([]byte(ss))
 	sig, err := rsa.SignPKCS1v15(rand.Reader, pk, crypto.SHA256, hash.Sum(nil))
@@ -110,19 +187,29 @@ func encodeToken(h jwtHeader, p jwtPayload, pk *rsa.PrivateKey) (string, error)
 	return fmt.Sprintf("%s.%s", ss, base64.RawURLEncoding.EncodeToString(sig)), nil
 }
 
+// decodeToken decodes the header and payload of the given token, and verifies its signature with the
+// keys of the keySource. The signature of tokens decoded for the Auth emulator is not verified.
 func decodeToken(token string, ks keySource, h *jwtHeader, p jwtPayload) error {
 	s := strings.Split(token, ".")
 	if len(s) != 3 {
-		return errors.New("incorrect number of segments")
+		return &TokenDecodeError{
+			Kind:    TokenDecodeSegments,
+			Segment: -1,
+			Offset:  -1,
+			Err:     fmt.Errorf("incorrect number of segments: %d; want 3", len(s)),
+		}
 	}
 
 	if err := decode(s[0], h); err != nil {
-		return err
+		return newTokenDecodeError(0, h, err)
 	}
 	if err := p.decode(s[1]); err != nil {
-		return err
+		return newTokenDecodeError(1, h, err)
 	}
 
+	if _, unsigned := ks.(emulatorKeySource); unsigned {
+		return nil
+	}
 	keys, err := ks.Keys()
 	if err != nil {
 		return err
@@ -0,0 +1,101 @@
+// Copyright 2017 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import "sync"
+
+// keyPins is the set of key IDs to which ID token verification is restricted. It is shared by a
+// Client and all its copies, so that pins can be updated at any time.
+type keyPins struct {
+	mutex      sync.Mutex
+	kids       map[string]bool
+	onUnpinned func(kid string)
+	reported   map[string]bool
+}
+
+// PinKeyIDs restricts the verification of ID tokens to the public keys with the given key IDs, which
+// appear in the "kid" header of ID tokens. Tokens signed with any other key fail verification, even
+// when the key is published by Google.
+//
+// If onUnpinned is not nil, it is called once for each key ID published by Google that is not pinned,
+// when the public keys are next fetched or used, and again after the next call to PinKeyIDs. This
+// signals that Google has rotated to a new key, and that the pins should be updated through a trusted
+// channel. The callback may be called concurrently from multiple goroutines.
+//
+// PinKeyIDs may be called at any time, and replaces any previous pins. It applies to the Client and to
+// all the clients derived from it. Pass an empty list to remove the pins.
+func (c *Client) PinKeyIDs(kids []string, onUnpinned func(kid string)) {
+	c.pins.mutex.Lock()
+	defer c.pins.mutex.Unlock()
+	if len(kids) == 0 {
+		c.pins.kids = nil
+		c.pins.onUnpinned = nil
+		c.pins.reported = nil
+		return
+	}
+	c.pins.kids = make(map[string]bool, len(kids))
+	for _, kid := range kids {
+		c.pins.kids[kid] = true
+	}
+	c.pins.onUnpinned = onUnpinned
+	c.pins.reported = make(map[string]bool)
+}
+
+// keySource returns the key source used to verify ID tokens, which applies the pins of the Client.
+// Pins do not apply to Clients routed to the Auth emulator, whose tokens are not signed.
+func (c *Client) keySource() keySource {
+	if c.pins == nil || c.emulated {
+		return c.ks
+	}
+	return &pinnedKeySource{ks: c.ks, pins: c.pins}
+}
+
+// pinnedKeySource is a keySource that only returns the pinned keys of another keySource.
+type pinnedKeySource struct {
+	ks   keySource
+	pins *keyPins
+}
+
+func (k *pinnedKeySource) Keys() ([]*publicKey, error) {
+	keys, err := k.ks.Keys()
+	if err != nil {
+		return nil, err
+	}
+
+	k.pins.mutex.Lock()
+	if k.pins.kids == nil {
+		k.pins.mutex.Unlock()
+		return keys, nil
+	}
+	var pinned []*publicKey
+	var unseen []string
+	for _, key := range keys {
+		if k.pins.kids[key.Kid] {
+			pinned = append(pinned, key)
+		} else if !k.pins.reported[key.Kid] {
+			k.pins.reported[key.Kid] = true
+			unseen = append(unseen, key.Kid)
+		}
+	}
+	onUnpinned := k.pins.onUnpinned
+	k.pins.mutex.Unlock()
+
+	if onUnpinned != nil {
+		for _, kid := range unseen {
+			onUnpinned(kid)
+		}
+	}
+	return pinned, nil
+}
@@ -0,0 +1,113 @@
+// Copyright 2017 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+
+	"golang.org/x/net/context"
+)
+
+// pinnedClient returns a copy of the test client with its own key pins.
+func pinnedClient() *Client {
+	c := *client
+	c.pins = &keyPins{}
+	return &c
+}
+
+func TestPinKeyIDs(t *testing.T) {
+	c := pinnedClient()
+	var mutex sync.Mutex
+	var unpinned []string
+	c.PinKeyIDs([]string{"mock-key-id-1", "mock-key-id-2"}, func(kid string) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		unpinned = append(unpinned, kid)
+	})
+
+	if _, err := c.VerifyIDToken(testIDToken); err != nil {
+		t.Errorf("VerifyIDToken(pinned) = %v; want = nil", err)
+	}
+
+	c.PinKeyIDs([]string{"mock-key-id-2"}, func(kid string) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		unpinned = append(unpinned, kid)
+	})
+	for i := 0; i < 2; i++ {
+		if _, err := c.VerifyIDToken(testIDToken); err == nil {
+			t.Errorf("VerifyIDToken(unpinned) = nil; want = error")
+		}
+	}
+
+	// Each unpinned key is reported once per call to PinKeyIDs, however many times the keys are used.
+	sort.Strings(unpinned)
+	want := []string{"mock-key-id-1", "mock-key-id-3", "mock-key-id-3"}
+	if !reflect.DeepEqual(unpinned, want) {
+		t.Errorf("onUnpinned() = %v; want = %v", unpinned, want)
+	}
+}
+
+func TestPinKeyIDsDerivedClients(t *testing.T) {
+	c := pinnedClient()
+	tc, err := c.TenantManager().AuthForTenant("tenantID")
+	if err != nil {
+		t.Fatal(err)
+	}
+	derived := c.WithClaimsHistory(&mockClaimsHistoryStore{})
+
+	c.PinKeyIDs([]string{"mock-key-id-2"}, nil)
+	if tc.client.pins != c.pins {
+		t.Errorf("TenantClient does not share the pins of its Client")
+	}
+	if _, err := derived.VerifyIDToken(testIDToken); err == nil {
+		t.Errorf("VerifyIDToken() = nil; want = error")
+	}
+}
+
+func TestPinKeyIDsUpdate(t *testing.T) {
+	c := pinnedClient()
+
+	c.PinKeyIDs([]string{"mock-key-id-3"}, nil)
+	if _, err := c.VerifyIDToken(testIDToken); err == nil {
+		t.Errorf("VerifyIDToken() = nil; want = error")
+	}
+
+	c.PinKeyIDs([]string{"mock-key-id-1", "mock-key-id-3"}, nil)
+	if _, err := c.VerifyIDToken(testIDToken); err != nil {
+		t.Errorf("VerifyIDToken() = %v; want = nil", err)
+	}
+
+	c.PinKeyIDs(nil, nil)
+	if _, err := c.VerifyIDToken(testIDToken); err != nil {
+		t.Errorf("VerifyIDToken() after unpinning = %v; want = nil", err)
+	}
+}
+
+func TestPinKeyIDsBatch(t *testing.T) {
+	c := pinnedClient()
+	c.PinKeyIDs([]string{"mock-key-id-2"}, nil)
+
+	results, err := c.VerifyIDTokens(context.Background(), []string{testIDToken})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if results[0].Err == nil {
+		t.Errorf("VerifyIDTokens()[0] = nil; want = error")
+	}
+}
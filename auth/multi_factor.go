@@ -0,0 +1,214 @@
+// Copyright 2017 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	phoneMultiFactorID = "phone"
+	totpMultiFactorID  = "totp"
+)
+
+// MultiFactorInfo describes a second factor enrolled by a user.
+//
+// FactorID is "phone" for SMS second factors, in which case PhoneNumber holds the phone number that
+// receives the verification codes, or "totp" for time-based one-time password second factors.
+// EnrollmentTimestamp is in milliseconds since epoch.
+type MultiFactorInfo struct {
+	UID                 string
+	DisplayName         string
+	EnrollmentTimestamp int64
+	FactorID            string
+	PhoneNumber         string
+}
+
+// MultiFactorSettings describes the multi-factor authentication settings of a user.
+type MultiFactorSettings struct {
+	EnrolledFactors []*MultiFactorInfo
+}
+
+// multiFactorInfoResponse is the JSON representation of an MFA enrollment returned by the Identity
+// Toolkit service.
+type multiFactorInfoResponse struct {
+	MFAEnrollmentID string    `json:"mfaEnrollmentId,omitempty"`
+	DisplayName     string    `json:"displayName,omitempty"`
+	PhoneInfo       string    `json:"phoneInfo,omitempty"`
+	TOTPInfo        *struct{} `json:"totpInfo,omitempty"`
+	EnrolledAt      string    `json:"enrolledAt,omitempty"`
+}
+
+func (r *multiFactorInfoResponse) makeMultiFactorInfo() (*MultiFactorInfo, error) {
+	info := &MultiFactorInfo{
+		UID:         r.MFAEnrollmentID,
+		DisplayName: r.DisplayName,
+	}
+	switch {
+	case r.PhoneInfo != "":
+		info.FactorID = phoneMultiFactorID
+		info.PhoneNumber = r.PhoneInfo
+	case r.TOTPInfo != nil:
+		info.FactorID = totpMultiFactorID
+	default:
+		return nil, fmt.Errorf("unsupported multi-factor enrollment: %q", r.MFAEnrollmentID)
+	}
+	if r.EnrolledAt != "" {
+		t, err := time.Parse(time.RFC3339Nano, r.EnrolledAt)
+		if err != nil {
+			return nil, err
+		}
+		info.EnrollmentTimestamp = t.UnixNano() / int64(time.Millisecond)
+	}
+	return info, nil
+}
+
+func makeMultiFactorSettings(enrollments []*multiFactorInfoResponse) (*MultiFactorSettings, error) {
+	if len(enrollments) == 0 {
+		return nil, nil
+	}
+	settings := &MultiFactorSettings{}
+	for _, e := range enrollments {
+		info, err := e.makeMultiFactorInfo()
+		if err != nil {
+			return nil, err
+		}
+		settings.EnrolledFactors = append(settings.EnrolledFactors, info)
+	}
+	return settings, nil
+}
+
+// multiFactorEnrollments validates the given settings, and converts them into the MFA enrollments of
+// an accounts:update request.
+//
+// New phone second factors may be enrolled by leaving their UID empty. TOTP second factors can only be
+// enrolled by the user, and hence must refer to an existing enrollment by UID.
+func multiFactorEnrollments(settings *MultiFactorSettings) ([]*multiFactorInfoResponse, error) {
+	var enrollments []*multiFactorInfoResponse
+	for _, factor := range settings.EnrolledFactors {
+		if factor == nil {
+			return nil, fmt.Errorf("enrolled factors must not be nil")
+		}
+		e := &multiFactorInfoResponse{
+			MFAEnrollmentID: factor.UID,
+			DisplayName:     factor.DisplayName,
+		}
+		switch factor.FactorID {
+		case phoneMultiFactorID:
+			if err := ValidatePhoneNumber(factor.PhoneNumber); err != nil {
+				return nil, err
+			}
+			e.PhoneInfo = factor.PhoneNumber
+		case totpMultiFactorID:
+			if factor.UID == "" {
+				return nil, fmt.Errorf("TOTP second factors cannot be enrolled by the SDK")
+			}
+			e.TOTPInfo = &struct{}{}
+		default:
+			return nil, fmt.Errorf("unsupported second factor id: %q", factor.FactorID)
+		}
+		if factor.EnrollmentTimestamp > 0 {
+			t := time.Unix(0, factor.EnrollmentTimestamp*int64(time.Millisecond)).UTC()
+			e.EnrolledAt = t.Format(time.RFC3339Nano)
+		}
+		enrollments = append(enrollments, e)
+	}
+	return enrollments, nil
+}
+
+// maxTOTPAdjacentIntervals is the maximum number of adjacent time intervals for which TOTP codes are
+// accepted.
+const maxTOTPAdjacentIntervals = 10
+
+// smsMultiFactorProvider is the identifier of SMS second factors in MultiFactorAuthConfig resources.
+const smsMultiFactorProvider = "PHONE_SMS"
+
+// MultiFactorConfig represents the multi-factor authentication configuration of a project or tenant.
+//
+// SMSEnabled and TOTPEnabled indicate whether users may enroll and sign in with SMS and TOTP second
+// factors respectively. Neither is enabled when multi-factor authentication is disabled as a whole.
+// TOTPAdjacentIntervals is the number of time intervals, before and after the current one, for which
+// TOTP codes are accepted, to allow for clock skew between the server and the devices of users.
+type MultiFactorConfig struct {
+	SMSEnabled            bool
+	TOTPEnabled           bool
+	TOTPAdjacentIntervals int
+}
+
+// multiFactorConfigResponse is the JSON representation of the MultiFactorAuthConfig resource of the
+// Identity Toolkit service.
+type multiFactorConfigResponse struct {
+	State            string   `json:"state"`
+	EnabledProviders []string `json:"enabledProviders"`
+	ProviderConfigs  []struct {
+		State              string `json:"state"`
+		TOTPProviderConfig *struct {
+			AdjacentIntervals int `json:"adjacentIntervals"`
+		} `json:"totpProviderConfig"`
+	} `json:"providerConfigs"`
+}
+
+func (r *multiFactorConfigResponse) makeMultiFactorConfig() *MultiFactorConfig {
+	config := &MultiFactorConfig{}
+	if r.State == "ENABLED" {
+		for _, p := range r.EnabledProviders {
+			if p == smsMultiFactorProvider {
+				config.SMSEnabled = true
+			}
+		}
+	}
+	for _, pc := range r.ProviderConfigs {
+		if pc.TOTPProviderConfig != nil {
+			config.TOTPEnabled = pc.State == "ENABLED" && r.State != "DISABLED"
+			config.TOTPAdjacentIntervals = pc.TOTPProviderConfig.AdjacentIntervals
+		}
+	}
+	return config
+}
+
+// totpProviderConfigs converts the given TOTP settings into the provider configs of a
+// MultiFactorAuthConfig update.
+func totpProviderConfigs(enabled bool, adjacentIntervals int) []interface{} {
+	state := "DISABLED"
+	if enabled {
+		state = "ENABLED"
+	}
+	return []interface{}{
+		map[string]interface{}{
+			"state": state,
+			"totpProviderConfig": map[string]interface{}{
+				"adjacentIntervals": adjacentIntervals,
+			},
+		},
+	}
+}
+
+// validateTOTPProviderConfigs validates the TOTP settings set at the specified key of an update
+// request, if any.
+func validateTOTPProviderConfigs(params nestedMap, key string) error {
+	val, ok := params.Get(key)
+	if !ok {
+		return nil
+	}
+	for _, pc := range val.([]interface{}) {
+		totp := pc.(map[string]interface{})["totpProviderConfig"].(map[string]interface{})
+		intervals := totp["adjacentIntervals"].(int)
+		if intervals < 0 || intervals > maxTOTPAdjacentIntervals {
+			return fmt.Errorf("TOTP adjacent intervals must be between 0 and %d", maxTOTPAdjacentIntervals)
+		}
+	}
+	return nil
+}
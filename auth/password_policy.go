@@ -0,0 +1,247 @@
+// Copyright 2017 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	passwordPolicyStateKey        = "passwordPolicyConfig.passwordPolicyEnforcementState"
+	passwordPolicyForceUpgradeKey = "passwordPolicyConfig.forceUpgradeOnSignin"
+	passwordPolicyVersionsKey     = "passwordPolicyConfig.passwordPolicyVersions"
+
+	minPasswordLengthLowerBound = 6
+	minPasswordLengthUpperBound = 30
+	maxPasswordLengthUpperBound = 4096
+
+	missingRequirementsPrefix = "Missing password requirements: ["
+)
+
+// PasswordPolicyEnforcementState is the enforcement state of the password policy of a project.
+type PasswordPolicyEnforcementState string
+
+const (
+	// PasswordPolicyEnforcementOff does not check passwords against the password policy.
+	PasswordPolicyEnforcementOff PasswordPolicyEnforcementState = "OFF"
+
+	// PasswordPolicyEnforcementEnforce rejects new passwords that do not satisfy the password policy.
+	PasswordPolicyEnforcementEnforce PasswordPolicyEnforcementState = "ENFORCE"
+)
+
+// PasswordConstraints specifies the requirements passwords must satisfy under a password policy.
+//
+// MinLength must be between 6 and 30. MaxLength must be at least MinLength, and at most 4096. A
+// MaxLength of zero indicates that no maximum length other than 4096 applies.
+type PasswordConstraints struct {
+	MinLength              int
+	MaxLength              int
+	RequireUppercase       bool
+	RequireLowercase       bool
+	RequireNumeric         bool
+	RequireNonAlphanumeric bool
+}
+
+// PasswordPolicyConfig represents the password policy of a project.
+//
+// When ForceUpgradeOnSignIn is true, users whose passwords do not satisfy the policy must change their
+// passwords when they next sign in. Otherwise only new passwords are checked against the policy.
+type PasswordPolicyConfig struct {
+	EnforcementState     PasswordPolicyEnforcementState
+	ForceUpgradeOnSignIn bool
+	Constraints          *PasswordConstraints
+}
+
+// Validate checks the given password against the password policy, so that passwords can be rejected
+// with a descriptive error before they are sent to the server, for instance with UpdateUser.
+//
+// The returned error lists all the requirements that the password does not satisfy. Validate returns
+// nil if the policy is not enforced. Characters are counted as Unicode code points, and only the ASCII
+// letters and digits are considered alphanumeric, as by the server.
+func (p *PasswordPolicyConfig) Validate(password string) error {
+	if p.EnforcementState != PasswordPolicyEnforcementEnforce || p.Constraints == nil {
+		return nil
+	}
+	c := p.Constraints
+	var violations []string
+	length := utf8.RuneCountInString(password)
+	if length < c.MinLength {
+		violations = append(violations, fmt.Sprintf("must contain at least %d characters", c.MinLength))
+	}
+	maxLength := c.MaxLength
+	if maxLength == 0 {
+		maxLength = maxPasswordLengthUpperBound
+	}
+	if length > maxLength {
+		violations = append(violations, fmt.Sprintf("must contain at most %d characters", maxLength))
+	}
+
+	var upper, lower, numeric, other bool
+	for _, r := range password {
+		switch {
+		case r >= 'A' && r <= 'Z':
+			upper = true
+		case r >= 'a' && r <= 'z':
+			lower = true
+		case r >= '0' && r <= '9':
+			numeric = true
+		default:
+			other = true
+		}
+	}
+	if c.RequireUppercase && !upper {
+		violations = append(violations, "must contain an uppercase character")
+	}
+	if c.RequireLowercase && !lower {
+		violations = append(violations, "must contain a lowercase character")
+	}
+	if c.RequireNumeric && !numeric {
+		violations = append(violations, "must contain a numeric character")
+	}
+	if c.RequireNonAlphanumeric && !other {
+		violations = append(violations, "must contain a non-alphanumeric character")
+	}
+	if len(violations) > 0 {
+		return fmt.Errorf("password does not satisfy the password policy: %s", strings.Join(violations, "; "))
+	}
+	return nil
+}
+
+// PasswordPolicyFailures returns the requirements of the password policy that a password rejected by
+// the server does not satisfy, such as "Password must contain a numeric character", as reported by the
+// server. They can be displayed as is to the user who chose the password.
+//
+// PasswordPolicyFailures returns nil if the error was not due to a password that does not satisfy the
+// password policy, or if the server did not report the requirements.
+func PasswordPolicyFailures(err error) []string {
+	if !IsPasswordPolicyFailed(err) {
+		return nil
+	}
+	msg := err.Error()
+	start := strings.Index(msg, missingRequirementsPrefix)
+	if start < 0 {
+		return nil
+	}
+	msg = msg[start+len(missingRequirementsPrefix):]
+	end := strings.LastIndex(msg, "]")
+	if end < 0 {
+		return nil
+	}
+	var failures []string
+	for _, f := range strings.Split(msg[:end], ",") {
+		if f = strings.TrimSpace(f); f != "" {
+			failures = append(failures, f)
+		}
+	}
+	return failures
+}
+
+// customStrengthOptions is the JSON representation of the CustomStrengthOptions resource of the
+// Identity Toolkit service.
+type customStrengthOptions struct {
+	MinPasswordLength                int  `json:"minPasswordLength,omitempty"`
+	MaxPasswordLength                int  `json:"maxPasswordLength,omitempty"`
+	ContainsUppercaseCharacter       bool `json:"containsUppercaseCharacter"`
+	ContainsLowercaseCharacter       bool `json:"containsLowercaseCharacter"`
+	ContainsNumericCharacter         bool `json:"containsNumericCharacter"`
+	ContainsNonAlphanumericCharacter bool `json:"containsNonAlphanumericCharacter"`
+}
+
+// passwordPolicyVersion is the JSON representation of the PasswordPolicyVersion resource of the
+// Identity Toolkit service.
+type passwordPolicyVersion struct {
+	CustomStrengthOptions customStrengthOptions `json:"customStrengthOptions"`
+}
+
+// passwordPolicyConfigResponse is the JSON representation of the PasswordPolicyConfig resource of the
+// Identity Toolkit service.
+type passwordPolicyConfigResponse struct {
+	EnforcementState       string                   `json:"passwordPolicyEnforcementState"`
+	ForceUpgradeOnSignin   bool                     `json:"forceUpgradeOnSignin"`
+	PasswordPolicyVersions []*passwordPolicyVersion `json:"passwordPolicyVersions"`
+}
+
+func (r *passwordPolicyConfigResponse) makePasswordPolicyConfig() *PasswordPolicyConfig {
+	state := PasswordPolicyEnforcementState(r.EnforcementState)
+	if state == "PASSWORD_POLICY_ENFORCEMENT_STATE_UNSPECIFIED" {
+		state = ""
+	}
+	constraints := &PasswordConstraints{}
+	if len(r.PasswordPolicyVersions) > 0 {
+		opts := r.PasswordPolicyVersions[0].CustomStrengthOptions
+		constraints = &PasswordConstraints{
+			MinLength:              opts.MinPasswordLength,
+			MaxLength:              opts.MaxPasswordLength,
+			RequireUppercase:       opts.ContainsUppercaseCharacter,
+			RequireLowercase:       opts.ContainsLowercaseCharacter,
+			RequireNumeric:         opts.ContainsNumericCharacter,
+			RequireNonAlphanumeric: opts.ContainsNonAlphanumericCharacter,
+		}
+	}
+	return &PasswordPolicyConfig{
+		EnforcementState:     state,
+		ForceUpgradeOnSignIn: r.ForceUpgradeOnSignin,
+		Constraints:          constraints,
+	}
+}
+
+// passwordPolicyVersions converts the given constraints into the policy versions of a
+// PasswordPolicyConfig update.
+func passwordPolicyVersions(c *PasswordConstraints) []*passwordPolicyVersion {
+	if c == nil {
+		return nil
+	}
+	return []*passwordPolicyVersion{
+		{
+			CustomStrengthOptions: customStrengthOptions{
+				MinPasswordLength:                c.MinLength,
+				MaxPasswordLength:                c.MaxLength,
+				ContainsUppercaseCharacter:       c.RequireUppercase,
+				ContainsLowercaseCharacter:       c.RequireLowercase,
+				ContainsNumericCharacter:         c.RequireNumeric,
+				ContainsNonAlphanumericCharacter: c.RequireNonAlphanumeric,
+			},
+		},
+	}
+}
+
+func validatePasswordPolicyEnforcementState(state PasswordPolicyEnforcementState) error {
+	switch state {
+	case PasswordPolicyEnforcementOff, PasswordPolicyEnforcementEnforce:
+		return nil
+	}
+	return fmt.Errorf("invalid password policy enforcement state: %q", state)
+}
+
+func validatePasswordPolicyVersions(versions []*passwordPolicyVersion) error {
+	if len(versions) == 0 {
+		return errors.New("password constraints must not be nil")
+	}
+	opts := versions[0].CustomStrengthOptions
+	if opts.MinPasswordLength < minPasswordLengthLowerBound ||
+		opts.MinPasswordLength > minPasswordLengthUpperBound {
+		return fmt.Errorf("minimum password length must be between %d and %d",
+			minPasswordLengthLowerBound, minPasswordLengthUpperBound)
+	}
+	if opts.MaxPasswordLength != 0 && (opts.MaxPasswordLength < opts.MinPasswordLength ||
+		opts.MaxPasswordLength > maxPasswordLengthUpperBound) {
+		return fmt.Errorf("maximum password length must be between the minimum length and %d",
+			maxPasswordLengthUpperBound)
+	}
+	return nil
+}
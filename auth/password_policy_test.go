@@ -0,0 +1,123 @@
+// Copyright 2017 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"errors"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"firebase.google.com/go/internal"
+
+	"golang.org/x/net/context"
+)
+
+func TestPasswordPolicyValidate(t *testing.T) {
+	policy := &PasswordPolicyConfig{
+		EnforcementState: PasswordPolicyEnforcementEnforce,
+		Constraints: &PasswordConstraints{
+			MinLength:              8,
+			MaxLength:              12,
+			RequireUppercase:       true,
+			RequireLowercase:       true,
+			RequireNumeric:         true,
+			RequireNonAlphanumeric: true,
+		},
+	}
+	for _, pw := range []string{"Passw0rd!", "Pässw0rd", "Ab1!Ab1!Ab1!"} {
+		if err := policy.Validate(pw); err != nil {
+			t.Errorf("Validate(%q) = %v; want = nil", pw, err)
+		}
+	}
+
+	cases := map[string][]string{
+		"Pa0!":          {"at least 8 characters"},
+		"Ab1!Ab1!Ab1!A": {"at most 12 characters"},
+		"password":      {"uppercase", "numeric", "non-alphanumeric"},
+		"PASSW0RD!":     {"lowercase"},
+		"":              {"at least 8 characters", "uppercase", "lowercase", "numeric", "non-alphanumeric"},
+	}
+	for pw, want := range cases {
+		err := policy.Validate(pw)
+		if err == nil {
+			t.Errorf("Validate(%q) = nil; want = error", pw)
+			continue
+		}
+		for _, w := range want {
+			if !strings.Contains(err.Error(), w) {
+				t.Errorf("Validate(%q) = %q; want to contain %q", pw, err, w)
+			}
+		}
+	}
+}
+
+func TestPasswordPolicyValidateDefaults(t *testing.T) {
+	long := strings.Repeat("a", maxPasswordLengthUpperBound+1)
+	policy := &PasswordPolicyConfig{
+		EnforcementState: PasswordPolicyEnforcementEnforce,
+		Constraints:      &PasswordConstraints{MinLength: 6},
+	}
+	if err := policy.Validate(long[:maxPasswordLengthUpperBound]); err != nil {
+		t.Errorf("Validate(4096 characters) = %v; want = nil", err)
+	}
+	if err := policy.Validate(long); err == nil {
+		t.Errorf("Validate(4097 characters) = nil; want = error")
+	}
+
+	unenforced := []*PasswordPolicyConfig{
+		{EnforcementState: PasswordPolicyEnforcementOff, Constraints: &PasswordConstraints{MinLength: 30}},
+		{EnforcementState: PasswordPolicyEnforcementEnforce},
+		{},
+	}
+	for _, p := range unenforced {
+		if err := p.Validate("a"); err != nil {
+			t.Errorf("Validate() = %v; want = nil", err)
+		}
+	}
+}
+
+func TestUpdateUserPasswordPolicyFailed(t *testing.T) {
+	resp := `{"error": {"message": "PASSWORD_DOES_NOT_MEET_REQUIREMENTS : Missing password requirements: ` +
+		`[Password must contain at least 8 characters, Password must contain a numeric character]"}}`
+	s := echoServer([]byte(resp), t)
+	defer s.Close()
+	s.Status = http.StatusBadRequest
+
+	_, err := s.Client.UpdateUser(context.Background(), "testuser", (&UserToUpdate{}).Password("secret"))
+	if !IsPasswordPolicyFailed(err) {
+		t.Fatalf("UpdateUser() = %v; want = password-policy-failed error", err)
+	}
+	want := []string{"Password must contain at least 8 characters", "Password must contain a numeric character"}
+	if got := PasswordPolicyFailures(err); !reflect.DeepEqual(got, want) {
+		t.Errorf("PasswordPolicyFailures() = %v; want = %v", got, want)
+	}
+}
+
+func TestPasswordPolicyFailuresUnavailable(t *testing.T) {
+	cases := []error{
+		nil,
+		errors.New("Missing password requirements: [Password must contain a numeric character]"),
+		internal.Error(userNotFound, "Missing password requirements: [Password must contain a numeric character]"),
+		internal.Error(passwordPolicyFailed, "http error status: 400; reason: PASSWORD_DOES_NOT_MEET_REQUIREMENTS"),
+		internal.Error(passwordPolicyFailed, "reason: Missing password requirements: [unterminated"),
+	}
+	for _, err := range cases {
+		if got := PasswordPolicyFailures(err); got != nil {
+			t.Errorf("PasswordPolicyFailures(%v) = %v; want = nil", err, got)
+		}
+	}
+}
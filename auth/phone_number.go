@@ -0,0 +1,77 @@
+// Copyright 2017 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	minPhoneDigits = 7
+	maxPhoneDigits = 15
+)
+
+// phoneFormatting removes the formatting characters commonly used when writing phone numbers.
+var phoneFormatting = strings.NewReplacer(" ", "", "-", "", ".", "", "(", "", ")", "", "/", "")
+
+// ValidatePhoneNumber checks that the given phone number is E.164 compliant, that is, a "+" sign
+// followed by a country code and a subscriber number of 7 to 15 digits in total, without any formatting
+// characters.
+//
+// The returned error describes what is wrong with the phone number. Use NormalizePhoneNumber to accept
+// phone numbers that contain formatting characters.
+func ValidatePhoneNumber(phone string) error {
+	if phone == "" {
+		return errors.New("phone number must be a non-empty string")
+	}
+	if !strings.HasPrefix(phone, "+") {
+		return fmt.Errorf("phone number %q must start with a \"+\" sign followed by the country code", phone)
+	}
+	digits := phone[1:]
+	for i, r := range digits {
+		if r < '0' || r > '9' {
+			return fmt.Errorf("phone number %q contains the invalid character %q at position %d; only "+
+				"digits may follow the \"+\" sign", phone, r, i+1)
+		}
+	}
+	if strings.HasPrefix(digits, "0") {
+		return fmt.Errorf("phone number %q must not have a country code starting with 0", phone)
+	}
+	if len(digits) < minPhoneDigits || len(digits) > maxPhoneDigits {
+		return fmt.Errorf("phone number %q has %d digits; E.164 phone numbers have %d to %d digits",
+			phone, len(digits), minPhoneDigits, maxPhoneDigits)
+	}
+	return nil
+}
+
+// NormalizePhoneNumber converts the given phone number to E.164 format, and validates it as described
+// in ValidatePhoneNumber.
+//
+// Spaces, hyphens, dots, parentheses and slashes are removed, and a leading "00" international call
+// prefix is replaced by a "+" sign. For example, "+1 (650) 555-0100" and "0044 20 7946 0000" are
+// normalized to "+16505550100" and "+442079460000" respectively. Phone numbers without a country code
+// cannot be normalized, as the country they belong to is unknown.
+func NormalizePhoneNumber(phone string) (string, error) {
+	normalized := phoneFormatting.Replace(strings.TrimSpace(phone))
+	if strings.HasPrefix(normalized, "00") {
+		normalized = "+" + normalized[2:]
+	}
+	if err := ValidatePhoneNumber(normalized); err != nil {
+		return "", err
+	}
+	return normalized, nil
+}
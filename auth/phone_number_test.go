@@ -0,0 +1,88 @@
+// Copyright 2017 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"testing"
+
+	"golang.org/x/net/context"
+)
+
+func TestValidatePhoneNumber(t *testing.T) {
+	for _, phone := range []string{"+16505550100", "+442079460000", "+6831234", "+123456789012345"} {
+		if err := ValidatePhoneNumber(phone); err != nil {
+			t.Errorf("ValidatePhoneNumber(%q) = %v; want = nil", phone, err)
+		}
+	}
+
+	invalid := []string{
+		"",
+		"16505550100",
+		"+1 650 555 0100",
+		"+1650555010a",
+		"+06505550100",
+		"+123456",
+		"+1234567890123456",
+	}
+	for _, phone := range invalid {
+		if err := ValidatePhoneNumber(phone); err == nil {
+			t.Errorf("ValidatePhoneNumber(%q) = nil; want = error", phone)
+		}
+	}
+}
+
+func TestNormalizePhoneNumber(t *testing.T) {
+	cases := map[string]string{
+		"+16505550100":       "+16505550100",
+		"+1 (650) 555-0100":  "+16505550100",
+		" +1.650.555.0100 ":  "+16505550100",
+		"0044 20 7946 0000":  "+442079460000",
+		"+44 (0)20/7946 000": "+440207946000",
+	}
+	for phone, want := range cases {
+		got, err := NormalizePhoneNumber(phone)
+		if err != nil || got != want {
+			t.Errorf("NormalizePhoneNumber(%q) = (%q, %v); want = (%q, nil)", phone, got, err, want)
+		}
+	}
+
+	for _, phone := range []string{"", "(650) 555-0100", "+1 650 CALL NOW", "+0 650 555 0100"} {
+		if got, err := NormalizePhoneNumber(phone); got != "" || err == nil {
+			t.Errorf("NormalizePhoneNumber(%q) = (%q, %v); want = (\"\", error)", phone, got, err)
+		}
+	}
+}
+
+func TestGetUsersNormalizesPhoneNumbers(t *testing.T) {
+	s := echoServer([]byte(`{"users": [{"localId": "uid1", "phoneNumber": "+15555550003"}]}`), t)
+	defer s.Close()
+
+	result, err := s.Client.GetUsers(context.Background(), []UserIdentifier{PhoneIdentifier{"+1 555-555-0003"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Users) != 1 || len(result.NotFound) != 0 {
+		t.Errorf("GetUsers() = (%d users, %d not found); want = (1, 0)", len(result.Users), len(result.NotFound))
+	}
+	checkRequest(t, s, "/projects/mock-project-id/accounts:lookup", map[string]interface{}{
+		"phoneNumber": []interface{}{"+15555550003"},
+	})
+}
+
+func TestGetUsersInvalidPhoneNumber(t *testing.T) {
+	if _, err := client.GetUsers(context.Background(), []UserIdentifier{PhoneIdentifier{"5555550003"}}); err == nil {
+		t.Errorf("GetUsers() = nil; want = error")
+	}
+}
@@ -0,0 +1,571 @@
+// Copyright 2017 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+
+	"golang.org/x/net/context"
+)
+
+const (
+	idToolkitV2Endpoint = "https://identitytoolkit.googleapis.com/v2"
+
+	testPhoneNumbersKey   = "signIn.phoneNumber.testPhoneNumbers"
+	projectMFAProviderKey = "mfa.providerConfigs"
+	smsRegionConfigKey    = "smsRegionConfig"
+	maxTestPhoneNumbers   = 10
+)
+
+// regionCodePattern matches ISO 3166 alpha-2 region codes.
+var regionCodePattern = regexp.MustCompile("^[A-Z]{2}$")
+
+// ProjectConfig represents the Firebase Auth configuration of a project.
+type ProjectConfig struct {
+	SignIn            *SignInConfig
+	MultiFactor       *MultiFactorConfig
+	SMSRegion         *SMSRegionConfig
+	Recaptcha         *RecaptchaConfig
+	PasswordPolicy    *PasswordPolicyConfig
+	EmailPrivacy      *EmailPrivacyConfig
+	BlockingFunctions *BlockingFunctionsConfig
+	EmailTemplates    *EmailTemplatesConfig
+}
+
+// SignInConfig indicates which sign-in providers are enabled for a project.
+//
+// Email link (passwordless) sign-in is only available when the email provider is enabled. Hence
+// EmailLinkEnabled is never true when EmailPasswordEnabled is false.
+type SignInConfig struct {
+	EmailPasswordEnabled bool
+	EmailLinkEnabled     bool
+	AnonymousEnabled     bool
+	PhoneNumberEnabled   bool
+
+	// TestPhoneNumbers maps fictional phone numbers to the verification codes that are accepted for
+	// them. No SMS message is sent when signing in with a test phone number.
+	TestPhoneNumbers map[string]string
+}
+
+// EmailPrivacyConfig represents the email privacy settings of a project.
+//
+// When ImprovedEmailPrivacy is enabled, the Auth service protects against email enumeration: sign-in
+// and password reset requests no longer reveal whether an account exists for an email address, and
+// the list of sign-in methods of an email address is not available to clients.
+type EmailPrivacyConfig struct {
+	ImprovedEmailPrivacy bool
+}
+
+// SMSRegionConfig specifies the regions to which SMS verification codes may be sent, identified by
+// their ISO 3166 alpha-2 codes.
+//
+// When AllowlistOnly is true, SMS messages may only be sent to phone numbers of the AllowedRegions.
+// Otherwise SMS messages may be sent to phone numbers of all regions except the DisallowedRegions.
+type SMSRegionConfig struct {
+	AllowlistOnly     bool
+	AllowedRegions    []string
+	DisallowedRegions []string
+}
+
+// smsRegionConfig is the JSON representation of the SmsRegionConfig resource of the Identity Toolkit
+// service. Exactly one of its fields is set.
+type smsRegionConfig struct {
+	AllowByDefault *smsAllowByDefault `json:"allowByDefault,omitempty"`
+	AllowlistOnly  *smsAllowlistOnly  `json:"allowlistOnly,omitempty"`
+}
+
+type smsAllowByDefault struct {
+	DisallowedRegions []string `json:"disallowedRegions,omitempty"`
+}
+
+type smsAllowlistOnly struct {
+	AllowedRegions []string `json:"allowedRegions,omitempty"`
+}
+
+func (r *smsRegionConfig) makeSMSRegionConfig() *SMSRegionConfig {
+	config := &SMSRegionConfig{}
+	if r.AllowlistOnly != nil {
+		config.AllowlistOnly = true
+		config.AllowedRegions = r.AllowlistOnly.AllowedRegions
+	} else if r.AllowByDefault != nil {
+		config.DisallowedRegions = r.AllowByDefault.DisallowedRegions
+	}
+	return config
+}
+
+// ProjectConfigToUpdate is the parameter struct for the UpdateProjectConfig function.
+//
+// Only the settings explicitly specified on a ProjectConfigToUpdate are changed. All other settings
+// retain their current values.
+type ProjectConfigToUpdate struct {
+	params nestedMap
+}
+
+func (p *ProjectConfigToUpdate) set(key string, value interface{}) *ProjectConfigToUpdate {
+	if p.params == nil {
+		p.params = make(nestedMap)
+	}
+	p.params.Set(key, value)
+	return p
+}
+
+// EmailPasswordSignIn enables or disables the email/password sign-in provider.
+func (p *ProjectConfigToUpdate) EmailPasswordSignIn(enabled bool) *ProjectConfigToUpdate {
+	return p.set("signIn.email.enabled", enabled)
+}
+
+// EmailLinkSignIn enables or disables email link (passwordless) sign-in.
+//
+// Email link sign-in only takes effect when the email/password sign-in provider is also enabled.
+func (p *ProjectConfigToUpdate) EmailLinkSignIn(enabled bool) *ProjectConfigToUpdate {
+	return p.set("signIn.email.passwordRequired", !enabled)
+}
+
+// AnonymousSignIn enables or disables anonymous sign-in.
+func (p *ProjectConfigToUpdate) AnonymousSignIn(enabled bool) *ProjectConfigToUpdate {
+	return p.set("signIn.anonymous.enabled", enabled)
+}
+
+// PhoneNumberSignIn enables or disables the phone number sign-in provider.
+func (p *ProjectConfigToUpdate) PhoneNumberSignIn(enabled bool) *ProjectConfigToUpdate {
+	return p.set("signIn.phoneNumber.enabled", enabled)
+}
+
+// TestPhoneNumbers replaces the test phone numbers of the project with the given map of phone numbers
+// to verification codes. Phone numbers must be E.164 compliant, and verification codes must consist of
+// 6 digits. Pass an empty map to remove all test phone numbers.
+func (p *ProjectConfigToUpdate) TestPhoneNumbers(numbers map[string]string) *ProjectConfigToUpdate {
+	copied := make(map[string]string, len(numbers))
+	for k, v := range numbers {
+		copied[k] = v
+	}
+	return p.set(testPhoneNumbersKey, copied)
+}
+
+// SMSMultiFactor enables or disables SMS second factors for the project.
+//
+// Enabling SMS second factors also enables multi-factor authentication for the project. Disabling them
+// leaves it enabled, so that other second factors, such as TOTP, keep working.
+func (p *ProjectConfigToUpdate) SMSMultiFactor(enabled bool) *ProjectConfigToUpdate {
+	if enabled {
+		p.set("mfa.state", "ENABLED")
+		return p.set("mfa.enabledProviders", []string{smsMultiFactorProvider})
+	}
+	return p.set("mfa.enabledProviders", []string{})
+}
+
+// TOTPMultiFactor enables or disables TOTP second factors for the project, and sets the number of
+// adjacent time intervals for which TOTP codes are accepted, between 0 and 10.
+func (p *ProjectConfigToUpdate) TOTPMultiFactor(enabled bool, adjacentIntervals int) *ProjectConfigToUpdate {
+	return p.set(projectMFAProviderKey, totpProviderConfigs(enabled, adjacentIntervals))
+}
+
+// ImprovedEmailPrivacy enables or disables email enumeration protection.
+func (p *ProjectConfigToUpdate) ImprovedEmailPrivacy(enabled bool) *ProjectConfigToUpdate {
+	return p.set("emailPrivacyConfig.enableImprovedEmailPrivacy", enabled)
+}
+
+// SMSRegionsAllowByDefault allows SMS verification codes to be sent to all regions except the given
+// ones. This replaces any allowlist set with SMSRegionsAllowlistOnly.
+func (p *ProjectConfigToUpdate) SMSRegionsAllowByDefault(disallowedRegions ...string) *ProjectConfigToUpdate {
+	return p.set(smsRegionConfigKey, &smsRegionConfig{
+		AllowByDefault: &smsAllowByDefault{append([]string(nil), disallowedRegions...)},
+	})
+}
+
+// SMSRegionsAllowlistOnly only allows SMS verification codes to be sent to the given regions, of which
+// there must be at least one. This replaces any deny list set with SMSRegionsAllowByDefault.
+func (p *ProjectConfigToUpdate) SMSRegionsAllowlistOnly(allowedRegions ...string) *ProjectConfigToUpdate {
+	return p.set(smsRegionConfigKey, &smsRegionConfig{
+		AllowlistOnly: &smsAllowlistOnly{append([]string(nil), allowedRegions...)},
+	})
+}
+
+// RecaptchaEmailPasswordEnforcement sets the reCAPTCHA Enterprise enforcement state of the
+// email/password sign-in provider.
+func (p *ProjectConfigToUpdate) RecaptchaEmailPasswordEnforcement(
+	state RecaptchaEnforcementState) *ProjectConfigToUpdate {
+	return p.set(recaptchaEmailPasswordStateKey, state)
+}
+
+// RecaptchaPhoneEnforcement sets the reCAPTCHA Enterprise enforcement state of the phone sign-in
+// provider.
+func (p *ProjectConfigToUpdate) RecaptchaPhoneEnforcement(state RecaptchaEnforcementState) *ProjectConfigToUpdate {
+	return p.set(recaptchaPhoneStateKey, state)
+}
+
+// RecaptchaManagedRules replaces the reCAPTCHA Enterprise managed rules of the project. Call with no
+// arguments to remove all managed rules.
+func (p *ProjectConfigToUpdate) RecaptchaManagedRules(rules ...*RecaptchaManagedRule) *ProjectConfigToUpdate {
+	return p.set(recaptchaManagedRulesKey, append([]*RecaptchaManagedRule{}, rules...))
+}
+
+// RecaptchaAccountDefender enables or disables reCAPTCHA Enterprise account defender, which detects
+// account takeover attempts.
+func (p *ProjectConfigToUpdate) RecaptchaAccountDefender(enabled bool) *ProjectConfigToUpdate {
+	return p.set(recaptchaAccountDefenderKey, enabled)
+}
+
+// PasswordPolicyEnforcement sets the enforcement state of the password policy of the project.
+func (p *ProjectConfigToUpdate) PasswordPolicyEnforcement(
+	state PasswordPolicyEnforcementState) *ProjectConfigToUpdate {
+	return p.set(passwordPolicyStateKey, state)
+}
+
+// PasswordPolicyForceUpgradeOnSignIn sets whether users whose passwords do not satisfy the password
+// policy must change their passwords when they next sign in.
+func (p *ProjectConfigToUpdate) PasswordPolicyForceUpgradeOnSignIn(force bool) *ProjectConfigToUpdate {
+	return p.set(passwordPolicyForceUpgradeKey, force)
+}
+
+// PasswordPolicyConstraints replaces the requirements of the password policy of the project.
+func (p *ProjectConfigToUpdate) PasswordPolicyConstraints(c *PasswordConstraints) *ProjectConfigToUpdate {
+	return p.set(passwordPolicyVersionsKey, passwordPolicyVersions(c))
+}
+
+// BeforeCreateFunction registers the blocking function called before a user is created, given the
+// HTTPS URI of the function. Pass an empty URI to remove the blocking function.
+func (p *ProjectConfigToUpdate) BeforeCreateFunction(uri string) *ProjectConfigToUpdate {
+	return p.set(beforeCreateTriggerKey, blockingFunctionTriggerFor(uri))
+}
+
+// BeforeSignInFunction registers the blocking function called before a user is signed in, given the
+// HTTPS URI of the function. Pass an empty URI to remove the blocking function.
+func (p *ProjectConfigToUpdate) BeforeSignInFunction(uri string) *ProjectConfigToUpdate {
+	return p.set(beforeSignInTriggerKey, blockingFunctionTriggerFor(uri))
+}
+
+// ForwardInboundCredentials sets which OAuth credentials of the identity provider, through which a user
+// signs in, are passed to the blocking functions of the project.
+func (p *ProjectConfigToUpdate) ForwardInboundCredentials(idToken, accessToken,
+	refreshToken bool) *ProjectConfigToUpdate {
+	return p.set(forwardCredentialsKey, &forwardInboundCredentials{
+		IDToken:      idToken,
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+	})
+}
+
+// PasswordResetEmailTemplate replaces the template of the password reset emails of the project. Empty
+// fields of the template revert to their default values.
+//
+// The server may reject changes to the body of the template, which is only customizable for some
+// projects, in order to prevent abuse.
+func (p *ProjectConfigToUpdate) PasswordResetEmailTemplate(t *EmailTemplate) *ProjectConfigToUpdate {
+	return p.set(passwordResetTemplateKey, emailTemplateFor(t))
+}
+
+// EmailVerificationTemplate replaces the template of the email verification emails of the project.
+// Empty fields of the template revert to their default values.
+//
+// As with PasswordResetEmailTemplate, the server may reject changes to the body of the template.
+func (p *ProjectConfigToUpdate) EmailVerificationTemplate(t *EmailTemplate) *ProjectConfigToUpdate {
+	return p.set(emailVerificationTemplateKey, emailTemplateFor(t))
+}
+
+func (p *ProjectConfigToUpdate) validate() error {
+	if p == nil || len(p.params) == 0 {
+		return errors.New("project config must not be nil or empty")
+	}
+	if numbers, ok := p.params.Get(testPhoneNumbersKey); ok {
+		if err := validateTestPhoneNumbers(numbers.(map[string]string)); err != nil {
+			return err
+		}
+	}
+	if config, ok := p.params.Get(smsRegionConfigKey); ok {
+		if err := validateSMSRegionConfig(config.(*smsRegionConfig)); err != nil {
+			return err
+		}
+	}
+	if state, ok := p.params.Get(recaptchaEmailPasswordStateKey); ok {
+		if err := validateRecaptchaEnforcementState(state.(RecaptchaEnforcementState)); err != nil {
+			return err
+		}
+	}
+	if state, ok := p.params.Get(recaptchaPhoneStateKey); ok {
+		if err := validateRecaptchaEnforcementState(state.(RecaptchaEnforcementState)); err != nil {
+			return err
+		}
+	}
+	if rules, ok := p.params.Get(recaptchaManagedRulesKey); ok {
+		if err := validateRecaptchaManagedRules(rules.([]*RecaptchaManagedRule)); err != nil {
+			return err
+		}
+	}
+	if state, ok := p.params.Get(passwordPolicyStateKey); ok {
+		if err := validatePasswordPolicyEnforcementState(state.(PasswordPolicyEnforcementState)); err != nil {
+			return err
+		}
+	}
+	if versions, ok := p.params.Get(passwordPolicyVersionsKey); ok {
+		if err := validatePasswordPolicyVersions(versions.([]*passwordPolicyVersion)); err != nil {
+			return err
+		}
+	}
+	for _, key := range []string{beforeCreateTriggerKey, beforeSignInTriggerKey} {
+		if trigger, ok := p.params.Get(key); ok {
+			if err := validateBlockingFunctionTrigger(trigger.(*blockingFunctionTrigger)); err != nil {
+				return err
+			}
+		}
+	}
+	for _, key := range []string{passwordResetTemplateKey, emailVerificationTemplateKey} {
+		if t, ok := p.params.Get(key); ok {
+			if err := validateEmailTemplate(t.(*emailTemplateResponse)); err != nil {
+				return err
+			}
+		}
+	}
+	return validateTOTPProviderConfigs(p.params, projectMFAProviderKey)
+}
+
+func validateSMSRegionConfig(config *smsRegionConfig) error {
+	var regions []string
+	if config.AllowlistOnly != nil {
+		regions = config.AllowlistOnly.AllowedRegions
+		if len(regions) == 0 {
+			return errors.New("at least one allowed region must be specified")
+		}
+	} else {
+		regions = config.AllowByDefault.DisallowedRegions
+	}
+	for _, r := range regions {
+		if !regionCodePattern.MatchString(r) {
+			return fmt.Errorf("invalid region code: %q; must be an ISO 3166 alpha-2 code", r)
+		}
+	}
+	return nil
+}
+
+// GetProjectConfig returns the Firebase Auth configuration of the current project.
+func (c *Client) GetProjectConfig(ctx context.Context) (*ProjectConfig, error) {
+	endpoint, err := c.projectConfigURL()
+	if err != nil {
+		return nil, err
+	}
+
+	var parsed projectConfigResponse
+	if err := c.makeRequest(ctx, http.MethodGet, endpoint, nil, &parsed); err != nil {
+		return nil, err
+	}
+	return parsed.makeProjectConfig(), nil
+}
+
+// UpdateProjectConfig updates the Firebase Auth configuration of the current project, and returns the
+// resulting configuration.
+func (c *Client) UpdateProjectConfig(ctx context.Context, config *ProjectConfigToUpdate) (*ProjectConfig, error) {
+	if err := config.validate(); err != nil {
+		return nil, err
+	}
+	base, err := c.projectConfigURL()
+	if err != nil {
+		return nil, err
+	}
+	mask := config.params.UpdateMask()
+	endpoint := fmt.Sprintf("%s?updateMask=%s", base, url.QueryEscape(strings.Join(mask, ",")))
+
+	var parsed projectConfigResponse
+	if err := c.makeRequest(ctx, http.MethodPatch, endpoint, config.params, &parsed); err != nil {
+		return nil, err
+	}
+	return parsed.makeProjectConfig(), nil
+}
+
+// TestPhoneNumbers returns the test phone numbers of the current project, mapped to their
+// verification codes.
+func (c *Client) TestPhoneNumbers(ctx context.Context) (map[string]string, error) {
+	config, err := c.GetProjectConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return config.SignIn.TestPhoneNumbers, nil
+}
+
+// AddTestPhoneNumber adds a test phone number to the current project, or changes the verification code
+// of an existing test phone number.
+//
+// The test phone numbers of the project are read and written back as a whole. Hence concurrent changes
+// to the test phone numbers of a project may be lost.
+func (c *Client) AddTestPhoneNumber(ctx context.Context, phone, code string) error {
+	if err := validateTestPhoneNumbers(map[string]string{phone: code}); err != nil {
+		return err
+	}
+	numbers, err := c.TestPhoneNumbers(ctx)
+	if err != nil {
+		return err
+	}
+	numbers[phone] = code
+	_, err = c.UpdateProjectConfig(ctx, (&ProjectConfigToUpdate{}).TestPhoneNumbers(numbers))
+	return err
+}
+
+// RemoveTestPhoneNumber removes a test phone number from the current project. Removing a phone number
+// that is not a test phone number of the project is not an error.
+//
+// The test phone numbers of the project are read and written back as a whole. Hence concurrent changes
+// to the test phone numbers of a project may be lost.
+func (c *Client) RemoveTestPhoneNumber(ctx context.Context, phone string) error {
+	if err := ValidatePhoneNumber(phone); err != nil {
+		return err
+	}
+	numbers, err := c.TestPhoneNumbers(ctx)
+	if err != nil {
+		return err
+	}
+	if _, ok := numbers[phone]; !ok {
+		return nil
+	}
+	delete(numbers, phone)
+	_, err = c.UpdateProjectConfig(ctx, (&ProjectConfigToUpdate{}).TestPhoneNumbers(numbers))
+	return err
+}
+
+func validateTestPhoneNumbers(numbers map[string]string) error {
+	if len(numbers) > maxTestPhoneNumbers {
+		return fmt.Errorf("a project must not have more than %d test phone numbers", maxTestPhoneNumbers)
+	}
+	for phone, code := range numbers {
+		if err := ValidatePhoneNumber(phone); err != nil {
+			return err
+		}
+		if len(code) != 6 || strings.Trim(code, "0123456789") != "" {
+			return fmt.Errorf("verification code for %q must consist of 6 digits", phone)
+		}
+	}
+	return nil
+}
+
+func (c *Client) projectConfigURL() (string, error) {
+	if c.projectID == "" {
+		return "", errors.New("project id not available")
+	}
+	return fmt.Sprintf("%s/projects/%s/config", c.projectMgtEndpoint, c.projectID), nil
+}
+
+// projectConfigResponse is the JSON representation of the Config resource of the Identity Toolkit
+// service.
+type projectConfigResponse struct {
+	SignIn struct {
+		Email struct {
+			Enabled          bool `json:"enabled"`
+			PasswordRequired bool `json:"passwordRequired"`
+		} `json:"email"`
+		PhoneNumber struct {
+			Enabled          bool              `json:"enabled"`
+			TestPhoneNumbers map[string]string `json:"testPhoneNumbers"`
+		} `json:"phoneNumber"`
+		Anonymous struct {
+			Enabled bool `json:"enabled"`
+		} `json:"anonymous"`
+	} `json:"signIn"`
+	MFA            multiFactorConfigResponse    `json:"mfa"`
+	SMSRegion      smsRegionConfig              `json:"smsRegionConfig"`
+	Recaptcha      recaptchaConfigResponse      `json:"recaptchaConfig"`
+	PasswordPolicy passwordPolicyConfigResponse `json:"passwordPolicyConfig"`
+	EmailPrivacy   struct {
+		EnableImprovedEmailPrivacy bool `json:"enableImprovedEmailPrivacy"`
+	} `json:"emailPrivacyConfig"`
+	BlockingFunctions blockingFunctionsResponse  `json:"blockingFunctions"`
+	Notification      notificationConfigResponse `json:"notification"`
+}
+
+func (r *projectConfigResponse) makeProjectConfig() *ProjectConfig {
+	email := r.SignIn.Email
+	numbers := r.SignIn.PhoneNumber.TestPhoneNumbers
+	if numbers == nil {
+		numbers = make(map[string]string)
+	}
+	return &ProjectConfig{
+		SignIn: &SignInConfig{
+			EmailPasswordEnabled: email.Enabled,
+			EmailLinkEnabled:     email.Enabled && !email.PasswordRequired,
+			AnonymousEnabled:     r.SignIn.Anonymous.Enabled,
+			PhoneNumberEnabled:   r.SignIn.PhoneNumber.Enabled,
+			TestPhoneNumbers:     numbers,
+		},
+		MultiFactor:    r.MFA.makeMultiFactorConfig(),
+		SMSRegion:      r.SMSRegion.makeSMSRegionConfig(),
+		Recaptcha:      r.Recaptcha.makeRecaptchaConfig(),
+		PasswordPolicy: r.PasswordPolicy.makePasswordPolicyConfig(),
+		EmailPrivacy: &EmailPrivacyConfig{
+			ImprovedEmailPrivacy: r.EmailPrivacy.EnableImprovedEmailPrivacy,
+		},
+		BlockingFunctions: r.BlockingFunctions.makeBlockingFunctionsConfig(),
+		EmailTemplates:    r.Notification.makeEmailTemplatesConfig(),
+	}
+}
+
+// nestedMap is a JSON object whose fields are set using dot-separated paths. It is used to build the
+// bodies of partial update requests, along with the corresponding field masks.
+type nestedMap map[string]interface{}
+
+// Set sets the value at the specified dot-separated path, creating any intermediate objects.
+func (nm nestedMap) Set(key string, value interface{}) {
+	segments := strings.Split(key, ".")
+	curr := map[string]interface{}(nm)
+	for _, segment := range segments[:len(segments)-1] {
+		child, ok := curr[segment].(map[string]interface{})
+		if !ok {
+			child = make(map[string]interface{})
+			curr[segment] = child
+		}
+		curr = child
+	}
+	curr[segments[len(segments)-1]] = value
+}
+
+// Get returns the value at the specified dot-separated path, if any.
+func (nm nestedMap) Get(key string) (interface{}, bool) {
+	segments := strings.Split(key, ".")
+	curr := map[string]interface{}(nm)
+	for _, segment := range segments[:len(segments)-1] {
+		child, ok := curr[segment].(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		curr = child
+	}
+	v, ok := curr[segments[len(segments)-1]]
+	return v, ok
+}
+
+// UpdateMask returns the sorted list of dot-separated paths to all the leaf values in the map.
+func (nm nestedMap) UpdateMask() []string {
+	mask := leafPaths("", nm)
+	sort.Strings(mask)
+	return mask
+}
+
+func leafPaths(prefix string, m map[string]interface{}) []string {
+	var paths []string
+	for k, v := range m {
+		path := k
+		if prefix != "" {
+			path = prefix + "." + k
+		}
+		if child, ok := v.(map[string]interface{}); ok {
+			paths = append(paths, leafPaths(path, child)...)
+		} else {
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}
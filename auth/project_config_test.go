@@ -0,0 +1,779 @@
+// Copyright 2017 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"golang.org/x/net/context"
+)
+
+const projectConfigResponseJSON = `{
+	"name": "projects/mock-project-id/config",
+	"signIn": {
+		"email": {"enabled": true, "passwordRequired": false},
+		"phoneNumber": {"enabled": true, "testPhoneNumbers": {"+16505550101": "123456"}},
+		"anonymous": {"enabled": false}
+	},
+	"mfa": {
+		"state": "ENABLED",
+		"enabledProviders": ["PHONE_SMS"],
+		"providerConfigs": [{"state": "ENABLED", "totpProviderConfig": {"adjacentIntervals": 5}}]
+	},
+	"smsRegionConfig": {
+		"allowByDefault": {"disallowedRegions": ["CU", "KP"]}
+	},
+	"recaptchaConfig": {
+		"emailPasswordEnforcementState": "AUDIT",
+		"phoneEnforcementState": "ENFORCE",
+		"managedRules": [{"endScore": 0.3, "action": "BLOCK"}],
+		"useAccountDefender": true
+	},
+	"passwordPolicyConfig": {
+		"passwordPolicyEnforcementState": "ENFORCE",
+		"forceUpgradeOnSignin": true,
+		"passwordPolicyVersions": [{
+			"customStrengthOptions": {
+				"minPasswordLength": 8,
+				"maxPasswordLength": 64,
+				"containsUppercaseCharacter": true,
+				"containsNumericCharacter": true
+			},
+			"schemaVersion": 1
+		}]
+	},
+	"emailPrivacyConfig": {"enableImprovedEmailPrivacy": true},
+	"blockingFunctions": {
+		"triggers": {
+			"beforeCreate": {
+				"functionUri": "https://us-central1-mock-project-id.cloudfunctions.net/beforeCreate",
+				"updateTime": "2023-01-01T00:00:00Z"
+			}
+		},
+		"forwardInboundCredentials": {"idToken": true}
+	},
+	"notification": {
+		"sendEmail": {
+			"resetPasswordTemplate": {
+				"senderLocalPart": "noreply",
+				"subject": "Reset your password for %APP_NAME%",
+				"senderDisplayName": "Example",
+				"body": "<p>Follow this link to reset your password: %LINK%</p>",
+				"bodyFormat": "HTML",
+				"replyTo": "support@example.com",
+				"customized": true
+			},
+			"verifyEmailTemplate": {
+				"senderLocalPart": "noreply",
+				"subject": "Verify your email for %APP_NAME%",
+				"body": "Follow this link to verify your email address: %LINK%",
+				"bodyFormat": "PLAIN_TEXT"
+			}
+		}
+	}
+}`
+
+var testProjectConfig = &ProjectConfig{
+	SignIn: &SignInConfig{
+		EmailPasswordEnabled: true,
+		EmailLinkEnabled:     true,
+		AnonymousEnabled:     false,
+		PhoneNumberEnabled:   true,
+		TestPhoneNumbers:     map[string]string{"+16505550101": "123456"},
+	},
+	MultiFactor: &MultiFactorConfig{
+		SMSEnabled:            true,
+		TOTPEnabled:           true,
+		TOTPAdjacentIntervals: 5,
+	},
+	SMSRegion: &SMSRegionConfig{
+		DisallowedRegions: []string{"CU", "KP"},
+	},
+	Recaptcha: &RecaptchaConfig{
+		EmailPasswordEnforcementState: RecaptchaEnforcementAudit,
+		PhoneEnforcementState:         RecaptchaEnforcementEnforce,
+		ManagedRules: []*RecaptchaManagedRule{
+			{EndScore: 0.3, Action: RecaptchaActionBlock},
+		},
+		UseAccountDefender: true,
+	},
+	PasswordPolicy: &PasswordPolicyConfig{
+		EnforcementState:     PasswordPolicyEnforcementEnforce,
+		ForceUpgradeOnSignIn: true,
+		Constraints: &PasswordConstraints{
+			MinLength:        8,
+			MaxLength:        64,
+			RequireUppercase: true,
+			RequireNumeric:   true,
+		},
+	},
+	EmailPrivacy: &EmailPrivacyConfig{
+		ImprovedEmailPrivacy: true,
+	},
+	BlockingFunctions: &BlockingFunctionsConfig{
+		BeforeCreateURI: "https://us-central1-mock-project-id.cloudfunctions.net/beforeCreate",
+		ForwardIDToken:  true,
+	},
+	EmailTemplates: &EmailTemplatesConfig{
+		PasswordReset: &EmailTemplate{
+			SenderDisplayName: "Example",
+			SenderLocalPart:   "noreply",
+			ReplyTo:           "support@example.com",
+			Subject:           "Reset your password for %APP_NAME%",
+			Body:              "<p>Follow this link to reset your password: %LINK%</p>",
+			HTMLBody:          true,
+			Customized:        true,
+		},
+		EmailVerification: &EmailTemplate{
+			SenderLocalPart: "noreply",
+			Subject:         "Verify your email for %APP_NAME%",
+			Body:            "Follow this link to verify your email address: %LINK%",
+		},
+	},
+}
+
+func TestGetProjectConfig(t *testing.T) {
+	s := echoServer([]byte(projectConfigResponseJSON), t)
+	defer s.Close()
+
+	config, err := s.Client.GetProjectConfig(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(config, testProjectConfig) {
+		t.Errorf("GetProjectConfig() = %#v; want = %#v", config, testProjectConfig)
+	}
+
+	req := s.Req[0]
+	if req.Method != http.MethodGet {
+		t.Errorf("Method = %q; want = %q", req.Method, http.MethodGet)
+	}
+	checkRequest(t, s, "/projects/mock-project-id/config", nil)
+}
+
+func TestGetProjectConfigError(t *testing.T) {
+	s := echoServer([]byte(`{"error": {"message": "CONFIGURATION_NOT_FOUND"}}`), t)
+	defer s.Close()
+	s.Status = http.StatusNotFound
+
+	if config, err := s.Client.GetProjectConfig(context.Background()); config != nil || err == nil {
+		t.Errorf("GetProjectConfig() = (%v, %v); want = (nil, error)", config, err)
+	}
+}
+
+func TestUpdateProjectConfig(t *testing.T) {
+	s := echoServer([]byte(projectConfigResponseJSON), t)
+	defer s.Close()
+
+	update := (&ProjectConfigToUpdate{}).
+		EmailPasswordSignIn(true).
+		EmailLinkSignIn(true).
+		AnonymousSignIn(false).
+		PhoneNumberSignIn(true)
+	config, err := s.Client.UpdateProjectConfig(context.Background(), update)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(config, testProjectConfig) {
+		t.Errorf("UpdateProjectConfig() = %#v; want = %#v", config, testProjectConfig)
+	}
+
+	req := s.Req[0]
+	if req.Method != http.MethodPatch {
+		t.Errorf("Method = %q; want = %q", req.Method, http.MethodPatch)
+	}
+	wantMask := "signIn.anonymous.enabled,signIn.email.enabled,signIn.email.passwordRequired," +
+		"signIn.phoneNumber.enabled"
+	if mask := req.URL.Query().Get("updateMask"); mask != wantMask {
+		t.Errorf("updateMask = %q; want = %q", mask, wantMask)
+	}
+	want := map[string]interface{}{
+		"signIn": map[string]interface{}{
+			"email": map[string]interface{}{
+				"enabled":          true,
+				"passwordRequired": false,
+			},
+			"anonymous":   map[string]interface{}{"enabled": false},
+			"phoneNumber": map[string]interface{}{"enabled": true},
+		},
+	}
+	checkRequest(t, s, "/projects/mock-project-id/config", want)
+}
+
+func TestUpdateProjectConfigTOTPMultiFactor(t *testing.T) {
+	s := echoServer([]byte(projectConfigResponseJSON), t)
+	defer s.Close()
+
+	update := (&ProjectConfigToUpdate{}).TOTPMultiFactor(true, 5)
+	if _, err := s.Client.UpdateProjectConfig(context.Background(), update); err != nil {
+		t.Fatal(err)
+	}
+
+	if mask := s.Req[0].URL.Query().Get("updateMask"); mask != "mfa.providerConfigs" {
+		t.Errorf("updateMask = %q; want = %q", mask, "mfa.providerConfigs")
+	}
+	want := map[string]interface{}{
+		"mfa": map[string]interface{}{
+			"providerConfigs": []interface{}{
+				map[string]interface{}{
+					"state":              "ENABLED",
+					"totpProviderConfig": map[string]interface{}{"adjacentIntervals": float64(5)},
+				},
+			},
+		},
+	}
+	checkRequest(t, s, "/projects/mock-project-id/config", want)
+}
+
+func TestUpdateProjectConfigSMSMultiFactor(t *testing.T) {
+	s := echoServer([]byte(projectConfigResponseJSON), t)
+	defer s.Close()
+
+	update := (&ProjectConfigToUpdate{}).SMSMultiFactor(true)
+	if _, err := s.Client.UpdateProjectConfig(context.Background(), update); err != nil {
+		t.Fatal(err)
+	}
+
+	if mask := s.Req[0].URL.Query().Get("updateMask"); mask != "mfa.enabledProviders,mfa.state" {
+		t.Errorf("updateMask = %q; want = %q", mask, "mfa.enabledProviders,mfa.state")
+	}
+	want := map[string]interface{}{
+		"mfa": map[string]interface{}{
+			"state":            "ENABLED",
+			"enabledProviders": []interface{}{"PHONE_SMS"},
+		},
+	}
+	checkRequest(t, s, "/projects/mock-project-id/config", want)
+}
+
+func TestUpdateProjectConfigDisableSMSMultiFactor(t *testing.T) {
+	s := echoServer([]byte(projectConfigResponseJSON), t)
+	defer s.Close()
+
+	update := (&ProjectConfigToUpdate{}).SMSMultiFactor(false)
+	if _, err := s.Client.UpdateProjectConfig(context.Background(), update); err != nil {
+		t.Fatal(err)
+	}
+
+	if mask := s.Req[0].URL.Query().Get("updateMask"); mask != "mfa.enabledProviders" {
+		t.Errorf("updateMask = %q; want = %q", mask, "mfa.enabledProviders")
+	}
+	want := map[string]interface{}{
+		"mfa": map[string]interface{}{
+			"enabledProviders": []interface{}{},
+		},
+	}
+	checkRequest(t, s, "/projects/mock-project-id/config", want)
+}
+
+func TestMultiFactorConfigDisabled(t *testing.T) {
+	var r multiFactorConfigResponse
+	resp := `{
+		"state": "DISABLED",
+		"enabledProviders": ["PHONE_SMS"],
+		"providerConfigs": [{"state": "ENABLED", "totpProviderConfig": {"adjacentIntervals": 5}}]
+	}`
+	if err := json.Unmarshal([]byte(resp), &r); err != nil {
+		t.Fatal(err)
+	}
+	want := &MultiFactorConfig{TOTPAdjacentIntervals: 5}
+	if got := r.makeMultiFactorConfig(); !reflect.DeepEqual(got, want) {
+		t.Errorf("makeMultiFactorConfig() = %#v; want = %#v", got, want)
+	}
+}
+
+func TestUpdateProjectConfigInvalidTOTPMultiFactor(t *testing.T) {
+	for _, intervals := range []int{-1, maxTOTPAdjacentIntervals + 1} {
+		update := (&ProjectConfigToUpdate{}).TOTPMultiFactor(true, intervals)
+		if config, err := client.UpdateProjectConfig(context.Background(), update); config != nil || err == nil {
+			t.Errorf("UpdateProjectConfig(%d) = (%v, %v); want = (nil, error)", intervals, config, err)
+		}
+	}
+}
+
+func TestUpdateProjectConfigSMSRegions(t *testing.T) {
+	s := echoServer([]byte(projectConfigResponseJSON), t)
+	defer s.Close()
+
+	cases := []struct {
+		update *ProjectConfigToUpdate
+		want   map[string]interface{}
+	}{
+		{
+			(&ProjectConfigToUpdate{}).SMSRegionsAllowByDefault("CU", "KP"),
+			map[string]interface{}{
+				"allowByDefault": map[string]interface{}{
+					"disallowedRegions": []interface{}{"CU", "KP"},
+				},
+			},
+		},
+		{
+			(&ProjectConfigToUpdate{}).SMSRegionsAllowByDefault(),
+			map[string]interface{}{
+				"allowByDefault": map[string]interface{}{},
+			},
+		},
+		{
+			(&ProjectConfigToUpdate{}).SMSRegionsAllowlistOnly("US", "CA"),
+			map[string]interface{}{
+				"allowlistOnly": map[string]interface{}{
+					"allowedRegions": []interface{}{"US", "CA"},
+				},
+			},
+		},
+	}
+	for _, tc := range cases {
+		if _, err := s.Client.UpdateProjectConfig(context.Background(), tc.update); err != nil {
+			t.Fatal(err)
+		}
+
+		req := s.Req[len(s.Req)-1]
+		if mask := req.URL.Query().Get("updateMask"); mask != "smsRegionConfig" {
+			t.Errorf("updateMask = %q; want = %q", mask, "smsRegionConfig")
+		}
+		want := map[string]interface{}{"smsRegionConfig": tc.want}
+		checkRequest(t, s, "/projects/mock-project-id/config", want)
+	}
+}
+
+func TestGetProjectConfigSMSRegionsAllowlistOnly(t *testing.T) {
+	s := echoServer([]byte(`{"smsRegionConfig": {"allowlistOnly": {"allowedRegions": ["US"]}}}`), t)
+	defer s.Close()
+
+	config, err := s.Client.GetProjectConfig(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &SMSRegionConfig{
+		AllowlistOnly:  true,
+		AllowedRegions: []string{"US"},
+	}
+	if !reflect.DeepEqual(config.SMSRegion, want) {
+		t.Errorf("SMSRegion = %#v; want = %#v", config.SMSRegion, want)
+	}
+}
+
+func TestUpdateProjectConfigInvalidSMSRegions(t *testing.T) {
+	cases := []*ProjectConfigToUpdate{
+		(&ProjectConfigToUpdate{}).SMSRegionsAllowlistOnly(),
+		(&ProjectConfigToUpdate{}).SMSRegionsAllowlistOnly("us"),
+		(&ProjectConfigToUpdate{}).SMSRegionsAllowlistOnly("USA"),
+		(&ProjectConfigToUpdate{}).SMSRegionsAllowByDefault(""),
+	}
+	for _, tc := range cases {
+		if config, err := client.UpdateProjectConfig(context.Background(), tc); config != nil || err == nil {
+			t.Errorf("UpdateProjectConfig(%v) = (%v, %v); want = (nil, error)", tc, config, err)
+		}
+	}
+}
+
+func TestUpdateProjectConfigRecaptcha(t *testing.T) {
+	s := echoServer([]byte(projectConfigResponseJSON), t)
+	defer s.Close()
+
+	update := (&ProjectConfigToUpdate{}).
+		RecaptchaEmailPasswordEnforcement(RecaptchaEnforcementEnforce).
+		RecaptchaPhoneEnforcement(RecaptchaEnforcementAudit).
+		RecaptchaManagedRules(&RecaptchaManagedRule{EndScore: 0.3, Action: RecaptchaActionBlock}).
+		RecaptchaAccountDefender(true)
+	if _, err := s.Client.UpdateProjectConfig(context.Background(), update); err != nil {
+		t.Fatal(err)
+	}
+
+	wantMask := "recaptchaConfig.emailPasswordEnforcementState,recaptchaConfig.managedRules," +
+		"recaptchaConfig.phoneEnforcementState,recaptchaConfig.useAccountDefender"
+	if mask := s.Req[0].URL.Query().Get("updateMask"); mask != wantMask {
+		t.Errorf("updateMask = %q; want = %q", mask, wantMask)
+	}
+	want := map[string]interface{}{
+		"recaptchaConfig": map[string]interface{}{
+			"emailPasswordEnforcementState": "ENFORCE",
+			"phoneEnforcementState":         "AUDIT",
+			"managedRules": []interface{}{
+				map[string]interface{}{"endScore": 0.3, "action": "BLOCK"},
+			},
+			"useAccountDefender": true,
+		},
+	}
+	checkRequest(t, s, "/projects/mock-project-id/config", want)
+}
+
+func TestUpdateProjectConfigRemoveRecaptchaManagedRules(t *testing.T) {
+	s := echoServer([]byte(projectConfigResponseJSON), t)
+	defer s.Close()
+
+	update := (&ProjectConfigToUpdate{}).RecaptchaManagedRules()
+	if _, err := s.Client.UpdateProjectConfig(context.Background(), update); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{
+		"recaptchaConfig": map[string]interface{}{
+			"managedRules": []interface{}{},
+		},
+	}
+	checkRequest(t, s, "/projects/mock-project-id/config", want)
+}
+
+func TestUpdateProjectConfigInvalidRecaptcha(t *testing.T) {
+	cases := []*ProjectConfigToUpdate{
+		(&ProjectConfigToUpdate{}).RecaptchaEmailPasswordEnforcement(""),
+		(&ProjectConfigToUpdate{}).RecaptchaEmailPasswordEnforcement("BLOCK"),
+		(&ProjectConfigToUpdate{}).RecaptchaPhoneEnforcement(""),
+		(&ProjectConfigToUpdate{}).RecaptchaPhoneEnforcement("ENFORCED"),
+		(&ProjectConfigToUpdate{}).RecaptchaManagedRules(nil),
+		(&ProjectConfigToUpdate{}).RecaptchaManagedRules(&RecaptchaManagedRule{EndScore: -0.1, Action: "BLOCK"}),
+		(&ProjectConfigToUpdate{}).RecaptchaManagedRules(&RecaptchaManagedRule{EndScore: 1.1, Action: "BLOCK"}),
+		(&ProjectConfigToUpdate{}).RecaptchaManagedRules(&RecaptchaManagedRule{EndScore: 0.25, Action: "BLOCK"}),
+		(&ProjectConfigToUpdate{}).RecaptchaManagedRules(&RecaptchaManagedRule{EndScore: 0.5, Action: "ALLOW"}),
+	}
+	for _, tc := range cases {
+		if config, err := client.UpdateProjectConfig(context.Background(), tc); config != nil || err == nil {
+			t.Errorf("UpdateProjectConfig(%v) = (%v, %v); want = (nil, error)", tc, config, err)
+		}
+	}
+}
+
+func TestUpdateProjectConfigPasswordPolicy(t *testing.T) {
+	s := echoServer([]byte(projectConfigResponseJSON), t)
+	defer s.Close()
+
+	update := (&ProjectConfigToUpdate{}).
+		PasswordPolicyEnforcement(PasswordPolicyEnforcementEnforce).
+		PasswordPolicyForceUpgradeOnSignIn(true).
+		PasswordPolicyConstraints(&PasswordConstraints{
+			MinLength:              8,
+			RequireLowercase:       true,
+			RequireNonAlphanumeric: true,
+		})
+	if _, err := s.Client.UpdateProjectConfig(context.Background(), update); err != nil {
+		t.Fatal(err)
+	}
+
+	wantMask := "passwordPolicyConfig.forceUpgradeOnSignin,passwordPolicyConfig.passwordPolicyEnforcementState," +
+		"passwordPolicyConfig.passwordPolicyVersions"
+	if mask := s.Req[0].URL.Query().Get("updateMask"); mask != wantMask {
+		t.Errorf("updateMask = %q; want = %q", mask, wantMask)
+	}
+	want := map[string]interface{}{
+		"passwordPolicyConfig": map[string]interface{}{
+			"passwordPolicyEnforcementState": "ENFORCE",
+			"forceUpgradeOnSignin":           true,
+			"passwordPolicyVersions": []interface{}{
+				map[string]interface{}{
+					"customStrengthOptions": map[string]interface{}{
+						"minPasswordLength":                float64(8),
+						"containsUppercaseCharacter":       false,
+						"containsLowercaseCharacter":       true,
+						"containsNumericCharacter":         false,
+						"containsNonAlphanumericCharacter": true,
+					},
+				},
+			},
+		},
+	}
+	checkRequest(t, s, "/projects/mock-project-id/config", want)
+}
+
+func TestUpdateProjectConfigInvalidPasswordPolicy(t *testing.T) {
+	cases := []*ProjectConfigToUpdate{
+		(&ProjectConfigToUpdate{}).PasswordPolicyEnforcement(""),
+		(&ProjectConfigToUpdate{}).PasswordPolicyEnforcement("AUDIT"),
+		(&ProjectConfigToUpdate{}).PasswordPolicyConstraints(nil),
+		(&ProjectConfigToUpdate{}).PasswordPolicyConstraints(&PasswordConstraints{}),
+		(&ProjectConfigToUpdate{}).PasswordPolicyConstraints(&PasswordConstraints{MinLength: 5}),
+		(&ProjectConfigToUpdate{}).PasswordPolicyConstraints(&PasswordConstraints{MinLength: 31}),
+		(&ProjectConfigToUpdate{}).PasswordPolicyConstraints(&PasswordConstraints{MinLength: 8, MaxLength: 7}),
+		(&ProjectConfigToUpdate{}).PasswordPolicyConstraints(&PasswordConstraints{MinLength: 8, MaxLength: 4097}),
+	}
+	for _, tc := range cases {
+		if config, err := client.UpdateProjectConfig(context.Background(), tc); config != nil || err == nil {
+			t.Errorf("UpdateProjectConfig(%v) = (%v, %v); want = (nil, error)", tc, config, err)
+		}
+	}
+}
+
+func TestUpdateProjectConfigImprovedEmailPrivacy(t *testing.T) {
+	s := echoServer([]byte(projectConfigResponseJSON), t)
+	defer s.Close()
+
+	for _, enabled := range []bool{true, false} {
+		update := (&ProjectConfigToUpdate{}).ImprovedEmailPrivacy(enabled)
+		if _, err := s.Client.UpdateProjectConfig(context.Background(), update); err != nil {
+			t.Fatal(err)
+		}
+
+		req := s.Req[len(s.Req)-1]
+		wantMask := "emailPrivacyConfig.enableImprovedEmailPrivacy"
+		if mask := req.URL.Query().Get("updateMask"); mask != wantMask {
+			t.Errorf("updateMask = %q; want = %q", mask, wantMask)
+		}
+		want := map[string]interface{}{
+			"emailPrivacyConfig": map[string]interface{}{"enableImprovedEmailPrivacy": enabled},
+		}
+		checkRequest(t, s, "/projects/mock-project-id/config", want)
+	}
+}
+
+func TestUpdateProjectConfigBlockingFunctions(t *testing.T) {
+	s := echoServer([]byte(projectConfigResponseJSON), t)
+	defer s.Close()
+
+	update := (&ProjectConfigToUpdate{}).
+		BeforeCreateFunction("https://example.com/beforeCreate").
+		BeforeSignInFunction("").
+		ForwardInboundCredentials(true, false, true)
+	if _, err := s.Client.UpdateProjectConfig(context.Background(), update); err != nil {
+		t.Fatal(err)
+	}
+
+	wantMask := "blockingFunctions.forwardInboundCredentials,blockingFunctions.triggers.beforeCreate," +
+		"blockingFunctions.triggers.beforeSignIn"
+	if mask := s.Req[0].URL.Query().Get("updateMask"); mask != wantMask {
+		t.Errorf("updateMask = %q; want = %q", mask, wantMask)
+	}
+	want := map[string]interface{}{
+		"blockingFunctions": map[string]interface{}{
+			"triggers": map[string]interface{}{
+				"beforeCreate": map[string]interface{}{"functionUri": "https://example.com/beforeCreate"},
+				"beforeSignIn": nil,
+			},
+			"forwardInboundCredentials": map[string]interface{}{
+				"idToken":      true,
+				"accessToken":  false,
+				"refreshToken": true,
+			},
+		},
+	}
+	checkRequest(t, s, "/projects/mock-project-id/config", want)
+}
+
+func TestUpdateProjectConfigInvalidBlockingFunctions(t *testing.T) {
+	cases := []*ProjectConfigToUpdate{
+		(&ProjectConfigToUpdate{}).BeforeCreateFunction("not a url"),
+		(&ProjectConfigToUpdate{}).BeforeCreateFunction("http://example.com/beforeCreate"),
+		(&ProjectConfigToUpdate{}).BeforeSignInFunction("https://"),
+	}
+	for _, tc := range cases {
+		if config, err := client.UpdateProjectConfig(context.Background(), tc); config != nil || err == nil {
+			t.Errorf("UpdateProjectConfig(%v) = (%v, %v); want = (nil, error)", tc, config, err)
+		}
+	}
+}
+
+func TestUpdateProjectConfigEmailTemplates(t *testing.T) {
+	s := echoServer([]byte(projectConfigResponseJSON), t)
+	defer s.Close()
+
+	update := (&ProjectConfigToUpdate{}).
+		PasswordResetEmailTemplate(&EmailTemplate{
+			SenderDisplayName: "Example",
+			SenderLocalPart:   "noreply",
+			ReplyTo:           "support@example.com",
+			Subject:           "Reset your password",
+			Customized:        true,
+		}).
+		EmailVerificationTemplate(&EmailTemplate{
+			Subject:  "Verify your email",
+			Body:     "<p>%LINK%</p>",
+			HTMLBody: true,
+		})
+	if _, err := s.Client.UpdateProjectConfig(context.Background(), update); err != nil {
+		t.Fatal(err)
+	}
+
+	wantMask := "notification.sendEmail.resetPasswordTemplate,notification.sendEmail.verifyEmailTemplate"
+	if mask := s.Req[0].URL.Query().Get("updateMask"); mask != wantMask {
+		t.Errorf("updateMask = %q; want = %q", mask, wantMask)
+	}
+	want := map[string]interface{}{
+		"notification": map[string]interface{}{
+			"sendEmail": map[string]interface{}{
+				"resetPasswordTemplate": map[string]interface{}{
+					"senderDisplayName": "Example",
+					"senderLocalPart":   "noreply",
+					"replyTo":           "support@example.com",
+					"subject":           "Reset your password",
+					"bodyFormat":        "PLAIN_TEXT",
+				},
+				"verifyEmailTemplate": map[string]interface{}{
+					"subject":    "Verify your email",
+					"body":       "<p>%LINK%</p>",
+					"bodyFormat": "HTML",
+				},
+			},
+		},
+	}
+	checkRequest(t, s, "/projects/mock-project-id/config", want)
+}
+
+func TestUpdateProjectConfigInvalidEmailTemplates(t *testing.T) {
+	cases := []*ProjectConfigToUpdate{
+		(&ProjectConfigToUpdate{}).PasswordResetEmailTemplate(nil),
+		(&ProjectConfigToUpdate{}).PasswordResetEmailTemplate(&EmailTemplate{SenderLocalPart: "a@b"}),
+		(&ProjectConfigToUpdate{}).EmailVerificationTemplate(&EmailTemplate{SenderLocalPart: "no reply"}),
+		(&ProjectConfigToUpdate{}).EmailVerificationTemplate(&EmailTemplate{ReplyTo: "not an email"}),
+	}
+	for _, tc := range cases {
+		if config, err := client.UpdateProjectConfig(context.Background(), tc); config != nil || err == nil {
+			t.Errorf("UpdateProjectConfig(%v) = (%v, %v); want = (nil, error)", tc, config, err)
+		}
+	}
+}
+
+func TestUpdateProjectConfigEmpty(t *testing.T) {
+	cases := []*ProjectConfigToUpdate{nil, {}}
+	for _, tc := range cases {
+		if config, err := client.UpdateProjectConfig(context.Background(), tc); config != nil || err == nil {
+			t.Errorf("UpdateProjectConfig(%v) = (%v, %v); want = (nil, error)", tc, config, err)
+		}
+	}
+}
+
+func TestUpdateProjectConfigInvalidTestPhoneNumbers(t *testing.T) {
+	tooMany := make(map[string]string)
+	for i := 0; i <= maxTestPhoneNumbers; i++ {
+		tooMany[fmt.Sprintf("+1650555%04d", i)] = "123456"
+	}
+	cases := []map[string]string{
+		{"16505550101": "123456"},
+		{"": "123456"},
+		{"+16505550101": "12345"},
+		{"+16505550101": "12345a"},
+		tooMany,
+	}
+	for _, tc := range cases {
+		update := (&ProjectConfigToUpdate{}).TestPhoneNumbers(tc)
+		if config, err := client.UpdateProjectConfig(context.Background(), update); config != nil || err == nil {
+			t.Errorf("UpdateProjectConfig(%v) = (%v, %v); want = (nil, error)", tc, config, err)
+		}
+	}
+}
+
+func TestTestPhoneNumbers(t *testing.T) {
+	s := echoServer([]byte(projectConfigResponseJSON), t)
+	defer s.Close()
+
+	numbers, err := s.Client.TestPhoneNumbers(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"+16505550101": "123456"}
+	if !reflect.DeepEqual(numbers, want) {
+		t.Errorf("TestPhoneNumbers() = %v; want = %v", numbers, want)
+	}
+}
+
+func TestAddTestPhoneNumber(t *testing.T) {
+	s := echoServer([]byte(projectConfigResponseJSON), t)
+	defer s.Close()
+
+	if err := s.Client.AddTestPhoneNumber(context.Background(), "+16505550102", "654321"); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(s.Req) != 2 {
+		t.Fatalf("Requests = %d; want = 2", len(s.Req))
+	}
+	req := s.Req[1]
+	if req.Method != http.MethodPatch {
+		t.Errorf("Method = %q; want = %q", req.Method, http.MethodPatch)
+	}
+	if mask := req.URL.Query().Get("updateMask"); mask != testPhoneNumbersKey {
+		t.Errorf("updateMask = %q; want = %q", mask, testPhoneNumbersKey)
+	}
+	want := map[string]interface{}{
+		"signIn": map[string]interface{}{
+			"phoneNumber": map[string]interface{}{
+				"testPhoneNumbers": map[string]interface{}{
+					"+16505550101": "123456",
+					"+16505550102": "654321",
+				},
+			},
+		},
+	}
+	checkRequest(t, s, "/projects/mock-project-id/config", want)
+}
+
+func TestAddTestPhoneNumberInvalid(t *testing.T) {
+	s := echoServer([]byte(projectConfigResponseJSON), t)
+	defer s.Close()
+
+	if err := s.Client.AddTestPhoneNumber(context.Background(), "+16505550102", "abc"); err == nil {
+		t.Errorf("AddTestPhoneNumber() = nil; want = error")
+	}
+	if len(s.Req) != 0 {
+		t.Errorf("Requests = %d; want = 0", len(s.Req))
+	}
+}
+
+func TestRemoveTestPhoneNumber(t *testing.T) {
+	s := echoServer([]byte(projectConfigResponseJSON), t)
+	defer s.Close()
+
+	if err := s.Client.RemoveTestPhoneNumber(context.Background(), "+16505550101"); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(s.Req) != 2 {
+		t.Fatalf("Requests = %d; want = 2", len(s.Req))
+	}
+	want := map[string]interface{}{
+		"signIn": map[string]interface{}{
+			"phoneNumber": map[string]interface{}{
+				"testPhoneNumbers": map[string]interface{}{},
+			},
+		},
+	}
+	checkRequest(t, s, "/projects/mock-project-id/config", want)
+}
+
+func TestRemoveTestPhoneNumberNotFound(t *testing.T) {
+	s := echoServer([]byte(projectConfigResponseJSON), t)
+	defer s.Close()
+
+	if err := s.Client.RemoveTestPhoneNumber(context.Background(), "+16505550199"); err != nil {
+		t.Fatal(err)
+	}
+	if len(s.Req) != 1 {
+		t.Errorf("Requests = %d; want = 1", len(s.Req))
+	}
+}
+
+func TestNestedMap(t *testing.T) {
+	nm := make(nestedMap)
+	nm.Set("a", 1)
+	nm.Set("b.c", 2)
+	nm.Set("b.d.e", 3)
+
+	want := nestedMap{
+		"a": 1,
+		"b": map[string]interface{}{
+			"c": 2,
+			"d": map[string]interface{}{"e": 3},
+		},
+	}
+	if !reflect.DeepEqual(nm, want) {
+		t.Errorf("nestedMap = %v; want = %v", nm, want)
+	}
+
+	wantMask := []string{"a", "b.c", "b.d.e"}
+	if mask := nm.UpdateMask(); !reflect.DeepEqual(mask, wantMask) {
+		t.Errorf("UpdateMask() = %v; want = %v", mask, wantMask)
+	}
+}
@@ -0,0 +1,850 @@
+// Copyright 2017 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/context"
+	"google.golang.org/api/iterator"
+)
+
+// maxProviderConfigs is the maximum number of provider configs that can be retrieved in one page.
+const maxProviderConfigs = 100
+
+const (
+	oidcProviderIDPrefix = "oidc."
+	samlProviderIDPrefix = "saml."
+
+	clientIDKey            = "clientId"
+	clientSecretKey        = "clientSecret"
+	displayNameKey         = "displayName"
+	enabledKey             = "enabled"
+	issuerKey              = "issuer"
+	codeResponseTypeKey    = "responseType.code"
+	idTokenResponseTypeKey = "responseType.idToken"
+
+	idpEntityIDKey     = "idpConfig.idpEntityId"
+	ssoURLKey          = "idpConfig.ssoUrl"
+	signRequestKey     = "idpConfig.signRequest"
+	idpCertsKey        = "idpConfig.idpCertificates"
+	spEntityIDKey      = "spConfig.spEntityId"
+	spCallbackURLKey   = "spConfig.callbackUri"
+	x509CertificateKey = "x509Certificate"
+)
+
+// OIDCProviderConfig is the OIDC auth provider configuration.
+// See https://openid.net/specs/openid-connect-core-1_0-final.html.
+type OIDCProviderConfig struct {
+	ID                  string
+	DisplayName         string
+	Enabled             bool
+	ClientID            string
+	Issuer              string
+	ClientSecret        string
+	CodeResponseType    bool
+	IDTokenResponseType bool
+}
+
+// OIDCProviderConfigToCreate represents the options used to create a new OIDCProviderConfig.
+//
+// The ID of the provider must be specified, and must start with the "oidc." prefix. ClientID and
+// Issuer are also required.
+type OIDCProviderConfigToCreate struct {
+	id     string
+	params nestedMap
+}
+
+func (config *OIDCProviderConfigToCreate) set(key string, value interface{}) *OIDCProviderConfigToCreate {
+	if config.params == nil {
+		config.params = make(nestedMap)
+	}
+	config.params.Set(key, value)
+	return config
+}
+
+// ID sets the provider ID of the new config.
+func (config *OIDCProviderConfigToCreate) ID(id string) *OIDCProviderConfigToCreate {
+	config.id = id
+	return config
+}
+
+// ClientID sets the client ID of the new config.
+func (config *OIDCProviderConfigToCreate) ClientID(clientID string) *OIDCProviderConfigToCreate {
+	return config.set(clientIDKey, clientID)
+}
+
+// ClientSecret sets the client secret of the new config. The client secret is required when
+// CodeResponseType is enabled.
+func (config *OIDCProviderConfigToCreate) ClientSecret(secret string) *OIDCProviderConfigToCreate {
+	return config.set(clientSecretKey, secret)
+}
+
+// DisplayName sets the DisplayName field of the new config.
+func (config *OIDCProviderConfigToCreate) DisplayName(name string) *OIDCProviderConfigToCreate {
+	return config.set(displayNameKey, name)
+}
+
+// Enabled enables or disables the new config.
+func (config *OIDCProviderConfigToCreate) Enabled(enabled bool) *OIDCProviderConfigToCreate {
+	return config.set(enabledKey, enabled)
+}
+
+// Issuer sets the issuer of the new config. The issuer must be a valid URL.
+func (config *OIDCProviderConfigToCreate) Issuer(issuer string) *OIDCProviderConfigToCreate {
+	return config.set(issuerKey, issuer)
+}
+
+// CodeResponseType sets whether to enable the code response flow for the new config.
+func (config *OIDCProviderConfigToCreate) CodeResponseType(enabled bool) *OIDCProviderConfigToCreate {
+	return config.set(codeResponseTypeKey, enabled)
+}
+
+// IDTokenResponseType sets whether to enable the ID token response flow for the new config.
+//
+// Unless CodeResponseType is enabled, the ID token response flow is used by default.
+func (config *OIDCProviderConfigToCreate) IDTokenResponseType(enabled bool) *OIDCProviderConfigToCreate {
+	return config.set(idTokenResponseTypeKey, enabled)
+}
+
+func (config *OIDCProviderConfigToCreate) buildRequest() (nestedMap, string, error) {
+	if config == nil || len(config.params) == 0 {
+		return nil, "", errors.New("config must not be nil")
+	}
+	if err := validateOIDCConfigID(config.id); err != nil {
+		return nil, "", err
+	}
+	if val, ok := config.params.Get(clientIDKey); !ok || val.(string) == "" {
+		return nil, "", errors.New("ClientID must not be empty")
+	}
+	if val, ok := config.params.Get(issuerKey); !ok || val.(string) == "" {
+		return nil, "", errors.New("Issuer must not be empty")
+	}
+	if err := validateOIDCParams(config.params); err != nil {
+		return nil, "", err
+	}
+	return config.params, config.id, nil
+}
+
+// OIDCProviderConfigToUpdate represents the options used to update an existing OIDCProviderConfig.
+//
+// Only the fields explicitly specified on an OIDCProviderConfigToUpdate are changed.
+type OIDCProviderConfigToUpdate struct {
+	params nestedMap
+}
+
+func (config *OIDCProviderConfigToUpdate) set(key string, value interface{}) *OIDCProviderConfigToUpdate {
+	if config.params == nil {
+		config.params = make(nestedMap)
+	}
+	config.params.Set(key, value)
+	return config
+}
+
+// ClientID updates the client ID of the config.
+func (config *OIDCProviderConfigToUpdate) ClientID(clientID string) *OIDCProviderConfigToUpdate {
+	return config.set(clientIDKey, clientID)
+}
+
+// ClientSecret updates the client secret of the config.
+func (config *OIDCProviderConfigToUpdate) ClientSecret(secret string) *OIDCProviderConfigToUpdate {
+	return config.set(clientSecretKey, secret)
+}
+
+// DisplayName updates the DisplayName field of the config. An empty string removes the display name.
+func (config *OIDCProviderConfigToUpdate) DisplayName(name string) *OIDCProviderConfigToUpdate {
+	return config.set(displayNameKey, name)
+}
+
+// Enabled enables or disables the config.
+func (config *OIDCProviderConfigToUpdate) Enabled(enabled bool) *OIDCProviderConfigToUpdate {
+	return config.set(enabledKey, enabled)
+}
+
+// Issuer updates the issuer of the config. The issuer must be a valid URL.
+func (config *OIDCProviderConfigToUpdate) Issuer(issuer string) *OIDCProviderConfigToUpdate {
+	return config.set(issuerKey, issuer)
+}
+
+// CodeResponseType sets whether to enable the code response flow for the config.
+func (config *OIDCProviderConfigToUpdate) CodeResponseType(enabled bool) *OIDCProviderConfigToUpdate {
+	return config.set(codeResponseTypeKey, enabled)
+}
+
+// IDTokenResponseType sets whether to enable the ID token response flow for the config.
+func (config *OIDCProviderConfigToUpdate) IDTokenResponseType(enabled bool) *OIDCProviderConfigToUpdate {
+	return config.set(idTokenResponseTypeKey, enabled)
+}
+
+func (config *OIDCProviderConfigToUpdate) buildRequest() (nestedMap, error) {
+	if config == nil || len(config.params) == 0 {
+		return nil, errors.New("config must not be nil or empty")
+	}
+	if val, ok := config.params.Get(clientIDKey); ok && val.(string) == "" {
+		return nil, errors.New("ClientID must not be empty")
+	}
+	if val, ok := config.params.Get(issuerKey); ok && val.(string) == "" {
+		return nil, errors.New("Issuer must not be empty")
+	}
+	if err := validateOIDCParams(config.params); err != nil {
+		return nil, err
+	}
+	return config.params, nil
+}
+
+// OIDCProviderConfig returns the OIDCProviderConfig with the given ID.
+//
+// If no config exists for the given ID, OIDCProviderConfig returns an error for which
+// IsConfigurationNotFound returns true.
+func (c *Client) OIDCProviderConfig(ctx context.Context, id string) (*OIDCProviderConfig, error) {
+	if err := validateOIDCConfigID(id); err != nil {
+		return nil, err
+	}
+	base, err := c.projectResourcesURL("oauthIdpConfigs")
+	if err != nil {
+		return nil, err
+	}
+
+	var parsed oidcProviderConfigResponse
+	if err := c.makeRequest(ctx, http.MethodGet, base+"/"+url.PathEscape(id), nil, &parsed); err != nil {
+		return nil, err
+	}
+	return parsed.makeOIDCProviderConfig(), nil
+}
+
+// CreateOIDCProviderConfig creates a new OIDC provider config from the given parameters, and returns
+// the resulting config.
+func (c *Client) CreateOIDCProviderConfig(ctx context.Context,
+	config *OIDCProviderConfigToCreate) (*OIDCProviderConfig, error) {
+	body, id, err := config.buildRequest()
+	if err != nil {
+		return nil, err
+	}
+	base, err := c.projectResourcesURL("oauthIdpConfigs")
+	if err != nil {
+		return nil, err
+	}
+	endpoint := fmt.Sprintf("%s?oauthIdpConfigId=%s", base, url.QueryEscape(id))
+
+	var parsed oidcProviderConfigResponse
+	if err := c.makeRequest(ctx, http.MethodPost, endpoint, body, &parsed); err != nil {
+		return nil, err
+	}
+	return parsed.makeOIDCProviderConfig(), nil
+}
+
+// UpdateOIDCProviderConfig updates an existing OIDC provider config with the given parameters, and
+// returns the resulting config.
+func (c *Client) UpdateOIDCProviderConfig(ctx context.Context, id string,
+	config *OIDCProviderConfigToUpdate) (*OIDCProviderConfig, error) {
+	if err := validateOIDCConfigID(id); err != nil {
+		return nil, err
+	}
+	body, err := config.buildRequest()
+	if err != nil {
+		return nil, err
+	}
+	base, err := c.projectResourcesURL("oauthIdpConfigs")
+	if err != nil {
+		return nil, err
+	}
+	mask := body.UpdateMask()
+	endpoint := fmt.Sprintf("%s/%s?updateMask=%s", base, url.PathEscape(id),
+		url.QueryEscape(strings.Join(mask, ",")))
+
+	var parsed oidcProviderConfigResponse
+	if err := c.makeRequest(ctx, http.MethodPatch, endpoint, body, &parsed); err != nil {
+		return nil, err
+	}
+	return parsed.makeOIDCProviderConfig(), nil
+}
+
+// DeleteOIDCProviderConfig deletes the OIDCProviderConfig with the given ID.
+func (c *Client) DeleteOIDCProviderConfig(ctx context.Context, id string) error {
+	if err := validateOIDCConfigID(id); err != nil {
+		return err
+	}
+	base, err := c.projectResourcesURL("oauthIdpConfigs")
+	if err != nil {
+		return err
+	}
+
+	var parsed map[string]interface{}
+	return c.makeRequest(ctx, http.MethodDelete, base+"/"+url.PathEscape(id), nil, &parsed)
+}
+
+// OIDCProviderConfigIterator is an iterator over the OIDC provider configs of a project.
+//
+// OIDCProviderConfigIterator is compatible with the google.golang.org/api/iterator package.
+type OIDCProviderConfigIterator struct {
+	client   *Client
+	ctx      context.Context
+	nextFunc func() error
+	pageInfo *iterator.PageInfo
+	configs  []*OIDCProviderConfig
+}
+
+// ListOIDCProviderConfigs returns an iterator over the OIDC provider configs of the project.
+//
+// Configs are fetched from the server in pages of up to 100 configs, as the iterator advances.
+func (c *Client) ListOIDCProviderConfigs(ctx context.Context) *OIDCProviderConfigIterator {
+	it := &OIDCProviderConfigIterator{
+		client: c,
+		ctx:    ctx,
+	}
+	it.pageInfo, it.nextFunc = iterator.NewPageInfo(
+		it.fetch,
+		func() int { return len(it.configs) },
+		func() interface{} { b := it.configs; it.configs = nil; return b })
+	it.pageInfo.MaxSize = maxProviderConfigs
+	return it
+}
+
+// PageSize sets the number of configs retrieved from the server in each page. It must be between 1
+// and 100. PageSize should be called before the first call to Next.
+func (it *OIDCProviderConfigIterator) PageSize(size int) *OIDCProviderConfigIterator {
+	it.pageInfo.MaxSize = size
+	return it
+}
+
+// PageInfo supports pagination. See the google.golang.org/api/iterator package for details.
+func (it *OIDCProviderConfigIterator) PageInfo() *iterator.PageInfo {
+	return it.pageInfo
+}
+
+// Next returns the next OIDCProviderConfig. Its second return value is iterator.Done if there are no
+// more results. Once Next returns iterator.Done, all subsequent calls will return iterator.Done.
+func (it *OIDCProviderConfigIterator) Next() (*OIDCProviderConfig, error) {
+	if err := it.nextFunc(); err != nil {
+		return nil, err
+	}
+	config := it.configs[0]
+	it.configs = it.configs[1:]
+	return config, nil
+}
+
+func (it *OIDCProviderConfigIterator) fetch(pageSize int, pageToken string) (string, error) {
+	var parsed struct {
+		Configs       []*oidcProviderConfigResponse `json:"oauthIdpConfigs"`
+		NextPageToken string                        `json:"nextPageToken"`
+	}
+	err := it.client.listProviderConfigs(it.ctx, "oauthIdpConfigs", pageSize, pageToken, &parsed)
+	if err != nil {
+		return "", err
+	}
+	for _, config := range parsed.Configs {
+		it.configs = append(it.configs, config.makeOIDCProviderConfig())
+	}
+	return parsed.NextPageToken, nil
+}
+
+// listProviderConfigs retrieves one page of the specified collection of provider configs, and
+// unmarshals the JSON response into the variable pointed by v.
+func (c *Client) listProviderConfigs(ctx context.Context, collection string, pageSize int,
+	pageToken string, v interface{}) error {
+	if pageSize < 1 || pageSize > maxProviderConfigs {
+		return fmt.Errorf("page size must be between 1 and %d", maxProviderConfigs)
+	}
+	base, err := c.projectResourcesURL(collection)
+	if err != nil {
+		return err
+	}
+
+	query := url.Values{}
+	query.Set("pageSize", strconv.Itoa(pageSize))
+	if pageToken != "" {
+		query.Set("pageToken", pageToken)
+	}
+	return c.makeRequest(ctx, http.MethodGet, fmt.Sprintf("%s?%s", base, query.Encode()), nil, v)
+}
+
+// projectResourcesURL returns the URL of the specified collection of resources of the project, such as
+// provider configs or tenants, in the Identity Toolkit v2 API.
+func (c *Client) projectResourcesURL(collection string) (string, error) {
+	if c.projectID == "" {
+		return "", errors.New("project id not available")
+	}
+	return fmt.Sprintf("%s/projects/%s/%s", c.projectMgtEndpoint, c.projectID, collection), nil
+}
+
+// oidcProviderConfigResponse is the JSON representation of the OAuthIdpConfig resource of the
+// Identity Toolkit service.
+type oidcProviderConfigResponse struct {
+	Name         string `json:"name"`
+	ClientID     string `json:"clientId"`
+	Issuer       string `json:"issuer"`
+	DisplayName  string `json:"displayName"`
+	Enabled      bool   `json:"enabled"`
+	ClientSecret string `json:"clientSecret"`
+	ResponseType struct {
+		Code    bool `json:"code"`
+		IDToken bool `json:"idToken"`
+	} `json:"responseType"`
+}
+
+func (r *oidcProviderConfigResponse) makeOIDCProviderConfig() *OIDCProviderConfig {
+	return &OIDCProviderConfig{
+		ID:                  extractResourceID(r.Name),
+		DisplayName:         r.DisplayName,
+		Enabled:             r.Enabled,
+		ClientID:            r.ClientID,
+		Issuer:              r.Issuer,
+		ClientSecret:        r.ClientSecret,
+		CodeResponseType:    r.ResponseType.Code,
+		IDTokenResponseType: r.ResponseType.IDToken,
+	}
+}
+
+// extractResourceID returns the last segment of a resource name of the form
+// "projects/project-id/collection/resource-id".
+func extractResourceID(name string) string {
+	segments := strings.Split(name, "/")
+	return segments[len(segments)-1]
+}
+
+func validateOIDCConfigID(id string) error {
+	if !strings.HasPrefix(id, oidcProviderIDPrefix) {
+		return fmt.Errorf("invalid OIDC provider id: %q", id)
+	}
+	return nil
+}
+
+// validateOIDCParams validates the issuer and the response types set on an OIDC provider config
+// request. The response types default to the ID token flow when neither is set.
+func validateOIDCParams(params nestedMap) error {
+	if val, ok := params.Get(issuerKey); ok && !isValidURL(val.(string)) {
+		return fmt.Errorf("failed to parse Issuer: %q", val)
+	}
+	code, codeOK := params.Get(codeResponseTypeKey)
+	idToken, idTokenOK := params.Get(idTokenResponseTypeKey)
+	if codeOK && idTokenOK && !code.(bool) && !idToken.(bool) {
+		return errors.New("at least one response type must be enabled")
+	}
+	if codeOK && code.(bool) {
+		if val, ok := params.Get(clientSecretKey); !ok || val.(string) == "" {
+			return errors.New("ClientSecret must not be empty when CodeResponseType is enabled")
+		}
+	}
+	return nil
+}
+
+// SAMLProviderConfig is the SAML auth provider configuration.
+// See http://docs.oasis-open.org/security/saml/Post2.0/sstc-saml-tech-overview-2.0.html.
+type SAMLProviderConfig struct {
+	ID                    string
+	DisplayName           string
+	Enabled               bool
+	IDPEntityID           string
+	SSOURL                string
+	RequestSigningEnabled bool
+	X509Certificates      []string
+	RPEntityID            string
+	CallbackURL           string
+}
+
+// SAMLProviderConfigToCreate represents the options used to create a new SAMLProviderConfig.
+//
+// The ID of the provider must be specified, and must start with the "saml." prefix. IDPEntityID,
+// SSOURL, X509Certificates, RPEntityID and CallbackURL are also required.
+type SAMLProviderConfigToCreate struct {
+	id     string
+	params nestedMap
+}
+
+func (config *SAMLProviderConfigToCreate) set(key string, value interface{}) *SAMLProviderConfigToCreate {
+	if config.params == nil {
+		config.params = make(nestedMap)
+	}
+	config.params.Set(key, value)
+	return config
+}
+
+// ID sets the provider ID of the new config.
+func (config *SAMLProviderConfigToCreate) ID(id string) *SAMLProviderConfigToCreate {
+	config.id = id
+	return config
+}
+
+// DisplayName sets the DisplayName field of the new config.
+func (config *SAMLProviderConfigToCreate) DisplayName(name string) *SAMLProviderConfigToCreate {
+	return config.set(displayNameKey, name)
+}
+
+// Enabled enables or disables the new config.
+func (config *SAMLProviderConfigToCreate) Enabled(enabled bool) *SAMLProviderConfigToCreate {
+	return config.set(enabledKey, enabled)
+}
+
+// IDPEntityID sets the entity ID of the identity provider of the new config.
+func (config *SAMLProviderConfigToCreate) IDPEntityID(entityID string) *SAMLProviderConfigToCreate {
+	return config.set(idpEntityIDKey, entityID)
+}
+
+// SSOURL sets the SSO URL of the identity provider of the new config. The SSO URL must be a valid URL.
+func (config *SAMLProviderConfigToCreate) SSOURL(url string) *SAMLProviderConfigToCreate {
+	return config.set(ssoURLKey, url)
+}
+
+// RequestSigningEnabled sets whether requests to the identity provider should be signed.
+func (config *SAMLProviderConfigToCreate) RequestSigningEnabled(enabled bool) *SAMLProviderConfigToCreate {
+	return config.set(signRequestKey, enabled)
+}
+
+// X509Certificates sets the PEM encoded x509 certificates of the identity provider of the new config.
+func (config *SAMLProviderConfigToCreate) X509Certificates(certs []string) *SAMLProviderConfigToCreate {
+	return config.set(idpCertsKey, makeX509Certificates(certs))
+}
+
+// RPEntityID sets the entity ID of the relying party (service provider) of the new config.
+func (config *SAMLProviderConfigToCreate) RPEntityID(entityID string) *SAMLProviderConfigToCreate {
+	return config.set(spEntityIDKey, entityID)
+}
+
+// CallbackURL sets the callback URL of the relying party of the new config. The callback URL must be
+// a valid URL.
+func (config *SAMLProviderConfigToCreate) CallbackURL(url string) *SAMLProviderConfigToCreate {
+	return config.set(spCallbackURLKey, url)
+}
+
+func (config *SAMLProviderConfigToCreate) buildRequest() (nestedMap, string, error) {
+	if config == nil || len(config.params) == 0 {
+		return nil, "", errors.New("config must not be nil")
+	}
+	if err := validateSAMLConfigID(config.id); err != nil {
+		return nil, "", err
+	}
+	required := []struct {
+		key, name string
+	}{
+		{idpEntityIDKey, "IDPEntityID"},
+		{ssoURLKey, "SSOURL"},
+		{spEntityIDKey, "RPEntityID"},
+		{spCallbackURLKey, "CallbackURL"},
+	}
+	for _, r := range required {
+		if val, ok := config.params.Get(r.key); !ok || val.(string) == "" {
+			return nil, "", fmt.Errorf("%s must not be empty", r.name)
+		}
+	}
+	if _, ok := config.params.Get(idpCertsKey); !ok {
+		return nil, "", errors.New("X509Certificates must not be empty")
+	}
+	if err := validateSAMLParams(config.params); err != nil {
+		return nil, "", err
+	}
+	return config.params, config.id, nil
+}
+
+// SAMLProviderConfigToUpdate represents the options used to update an existing SAMLProviderConfig.
+//
+// Only the fields explicitly specified on a SAMLProviderConfigToUpdate are changed.
+type SAMLProviderConfigToUpdate struct {
+	params nestedMap
+}
+
+func (config *SAMLProviderConfigToUpdate) set(key string, value interface{}) *SAMLProviderConfigToUpdate {
+	if config.params == nil {
+		config.params = make(nestedMap)
+	}
+	config.params.Set(key, value)
+	return config
+}
+
+// DisplayName updates the DisplayName field of the config. An empty string removes the display name.
+func (config *SAMLProviderConfigToUpdate) DisplayName(name string) *SAMLProviderConfigToUpdate {
+	return config.set(displayNameKey, name)
+}
+
+// Enabled enables or disables the config.
+func (config *SAMLProviderConfigToUpdate) Enabled(enabled bool) *SAMLProviderConfigToUpdate {
+	return config.set(enabledKey, enabled)
+}
+
+// IDPEntityID updates the entity ID of the identity provider of the config.
+func (config *SAMLProviderConfigToUpdate) IDPEntityID(entityID string) *SAMLProviderConfigToUpdate {
+	return config.set(idpEntityIDKey, entityID)
+}
+
+// SSOURL updates the SSO URL of the identity provider of the config.
+func (config *SAMLProviderConfigToUpdate) SSOURL(url string) *SAMLProviderConfigToUpdate {
+	return config.set(ssoURLKey, url)
+}
+
+// RequestSigningEnabled sets whether requests to the identity provider should be signed.
+func (config *SAMLProviderConfigToUpdate) RequestSigningEnabled(enabled bool) *SAMLProviderConfigToUpdate {
+	return config.set(signRequestKey, enabled)
+}
+
+// X509Certificates replaces the x509 certificates of the identity provider of the config.
+func (config *SAMLProviderConfigToUpdate) X509Certificates(certs []string) *SAMLProviderConfigToUpdate {
+	return config.set(idpCertsKey, makeX509Certificates(certs))
+}
+
+// RPEntityID updates the entity ID of the relying party of the config.
+func (config *SAMLProviderConfigToUpdate) RPEntityID(entityID string) *SAMLProviderConfigToUpdate {
+	return config.set(spEntityIDKey, entityID)
+}
+
+// CallbackURL updates the callback URL of the relying party of the config.
+func (config *SAMLProviderConfigToUpdate) CallbackURL(url string) *SAMLProviderConfigToUpdate {
+	return config.set(spCallbackURLKey, url)
+}
+
+func (config *SAMLProviderConfigToUpdate) buildRequest() (nestedMap, error) {
+	if config == nil || len(config.params) == 0 {
+		return nil, errors.New("config must not be nil or empty")
+	}
+	if val, ok := config.params.Get(idpEntityIDKey); ok && val.(string) == "" {
+		return nil, errors.New("IDPEntityID must not be empty")
+	}
+	if val, ok := config.params.Get(spEntityIDKey); ok && val.(string) == "" {
+		return nil, errors.New("RPEntityID must not be empty")
+	}
+	if err := validateSAMLParams(config.params); err != nil {
+		return nil, err
+	}
+	return config.params, nil
+}
+
+// SAMLProviderConfig returns the SAMLProviderConfig with the given ID.
+//
+// If no config exists for the given ID, SAMLProviderConfig returns an error for which
+// IsConfigurationNotFound returns true.
+func (c *Client) SAMLProviderConfig(ctx context.Context, id string) (*SAMLProviderConfig, error) {
+	if err := validateSAMLConfigID(id); err != nil {
+		return nil, err
+	}
+	base, err := c.projectResourcesURL("inboundSamlConfigs")
+	if err != nil {
+		return nil, err
+	}
+
+	var parsed samlProviderConfigResponse
+	if err := c.makeRequest(ctx, http.MethodGet, base+"/"+url.PathEscape(id), nil, &parsed); err != nil {
+		return nil, err
+	}
+	return parsed.makeSAMLProviderConfig(), nil
+}
+
+// CreateSAMLProviderConfig creates a new SAML provider config from the given parameters, and returns
+// the resulting config.
+func (c *Client) CreateSAMLProviderConfig(ctx context.Context,
+	config *SAMLProviderConfigToCreate) (*SAMLProviderConfig, error) {
+	body, id, err := config.buildRequest()
+	if err != nil {
+		return nil, err
+	}
+	base, err := c.projectResourcesURL("inboundSamlConfigs")
+	if err != nil {
+		return nil, err
+	}
+	endpoint := fmt.Sprintf("%s?inboundSamlConfigId=%s", base, url.QueryEscape(id))
+
+	var parsed samlProviderConfigResponse
+	if err := c.makeRequest(ctx, http.MethodPost, endpoint, body, &parsed); err != nil {
+		return nil, err
+	}
+	return parsed.makeSAMLProviderConfig(), nil
+}
+
+// UpdateSAMLProviderConfig updates an existing SAML provider config with the given parameters, and
+// returns the resulting config.
+func (c *Client) UpdateSAMLProviderConfig(ctx context.Context, id string,
+	config *SAMLProviderConfigToUpdate) (*SAMLProviderConfig, error) {
+	if err := validateSAMLConfigID(id); err != nil {
+		return nil, err
+	}
+	body, err := config.buildRequest()
+	if err != nil {
+		return nil, err
+	}
+	base, err := c.projectResourcesURL("inboundSamlConfigs")
+	if err != nil {
+		return nil, err
+	}
+	mask := body.UpdateMask()
+	endpoint := fmt.Sprintf("%s/%s?updateMask=%s", base, url.PathEscape(id),
+		url.QueryEscape(strings.Join(mask, ",")))
+
+	var parsed samlProviderConfigResponse
+	if err := c.makeRequest(ctx, http.MethodPatch, endpoint, body, &parsed); err != nil {
+		return nil, err
+	}
+	return parsed.makeSAMLProviderConfig(), nil
+}
+
+// DeleteSAMLProviderConfig deletes the SAMLProviderConfig with the given ID.
+func (c *Client) DeleteSAMLProviderConfig(ctx context.Context, id string) error {
+	if err := validateSAMLConfigID(id); err != nil {
+		return err
+	}
+	base, err := c.projectResourcesURL("inboundSamlConfigs")
+	if err != nil {
+		return err
+	}
+
+	var parsed map[string]interface{}
+	return c.makeRequest(ctx, http.MethodDelete, base+"/"+url.PathEscape(id), nil, &parsed)
+}
+
+// SAMLProviderConfigIterator is an iterator over the SAML provider configs of a project.
+//
+// SAMLProviderConfigIterator is compatible with the google.golang.org/api/iterator package.
+type SAMLProviderConfigIterator struct {
+	client   *Client
+	ctx      context.Context
+	nextFunc func() error
+	pageInfo *iterator.PageInfo
+	configs  []*SAMLProviderConfig
+}
+
+// ListSAMLProviderConfigs returns an iterator over the SAML provider configs of the project.
+//
+// Configs are fetched from the server in pages of up to 100 configs, as the iterator advances.
+func (c *Client) ListSAMLProviderConfigs(ctx context.Context) *SAMLProviderConfigIterator {
+	it := &SAMLProviderConfigIterator{
+		client: c,
+		ctx:    ctx,
+	}
+	it.pageInfo, it.nextFunc = iterator.NewPageInfo(
+		it.fetch,
+		func() int { return len(it.configs) },
+		func() interface{} { b := it.configs; it.configs = nil; return b })
+	it.pageInfo.MaxSize = maxProviderConfigs
+	return it
+}
+
+// PageSize sets the number of configs retrieved from the server in each page. It must be between 1
+// and 100. PageSize should be called before the first call to Next.
+func (it *SAMLProviderConfigIterator) PageSize(size int) *SAMLProviderConfigIterator {
+	it.pageInfo.MaxSize = size
+	return it
+}
+
+// PageInfo supports pagination. See the google.golang.org/api/iterator package for details.
+func (it *SAMLProviderConfigIterator) PageInfo() *iterator.PageInfo {
+	return it.pageInfo
+}
+
+// Next returns the next SAMLProviderConfig. Its second return value is iterator.Done if there are no
+// more results. Once Next returns iterator.Done, all subsequent calls will return iterator.Done.
+func (it *SAMLProviderConfigIterator) Next() (*SAMLProviderConfig, error) {
+	if err := it.nextFunc(); err != nil {
+		return nil, err
+	}
+	config := it.configs[0]
+	it.configs = it.configs[1:]
+	return config, nil
+}
+
+func (it *SAMLProviderConfigIterator) fetch(pageSize int, pageToken string) (string, error) {
+	var parsed struct {
+		Configs       []*samlProviderConfigResponse `json:"inboundSamlConfigs"`
+		NextPageToken string                        `json:"nextPageToken"`
+	}
+	err := it.client.listProviderConfigs(it.ctx, "inboundSamlConfigs", pageSize, pageToken, &parsed)
+	if err != nil {
+		return "", err
+	}
+	for _, config := range parsed.Configs {
+		it.configs = append(it.configs, config.makeSAMLProviderConfig())
+	}
+	return parsed.NextPageToken, nil
+}
+
+// samlProviderConfigResponse is the JSON representation of the InboundSamlConfig resource of the
+// Identity Toolkit service.
+type samlProviderConfigResponse struct {
+	Name        string `json:"name"`
+	DisplayName string `json:"displayName"`
+	Enabled     bool   `json:"enabled"`
+	IDPConfig   struct {
+		IDPEntityID     string `json:"idpEntityId"`
+		SSOURL          string `json:"ssoUrl"`
+		SignRequest     bool   `json:"signRequest"`
+		IDPCertificates []struct {
+			X509Certificate string `json:"x509Certificate"`
+		} `json:"idpCertificates"`
+	} `json:"idpConfig"`
+	SPConfig struct {
+		SPEntityID  string `json:"spEntityId"`
+		CallbackURI string `json:"callbackUri"`
+	} `json:"spConfig"`
+}
+
+func (r *samlProviderConfigResponse) makeSAMLProviderConfig() *SAMLProviderConfig {
+	var certs []string
+	for _, cert := range r.IDPConfig.IDPCertificates {
+		certs = append(certs, cert.X509Certificate)
+	}
+	return &SAMLProviderConfig{
+		ID:                    extractResourceID(r.Name),
+		DisplayName:           r.DisplayName,
+		Enabled:               r.Enabled,
+		IDPEntityID:           r.IDPConfig.IDPEntityID,
+		SSOURL:                r.IDPConfig.SSOURL,
+		RequestSigningEnabled: r.IDPConfig.SignRequest,
+		X509Certificates:      certs,
+		RPEntityID:            r.SPConfig.SPEntityID,
+		CallbackURL:           r.SPConfig.CallbackURI,
+	}
+}
+
+func makeX509Certificates(certs []string) []interface{} {
+	result := make([]interface{}, len(certs))
+	for i, cert := range certs {
+		result[i] = map[string]interface{}{x509CertificateKey: cert}
+	}
+	return result
+}
+
+func validateSAMLConfigID(id string) error {
+	if !strings.HasPrefix(id, samlProviderIDPrefix) {
+		return fmt.Errorf("invalid SAML provider id: %q", id)
+	}
+	return nil
+}
+
+// validateSAMLParams validates the URLs and certificates set on a SAML provider config request.
+func validateSAMLParams(params nestedMap) error {
+	if val, ok := params.Get(ssoURLKey); ok && !isValidURL(val.(string)) {
+		return fmt.Errorf("failed to parse SSOURL: %q", val)
+	}
+	if val, ok := params.Get(spCallbackURLKey); ok && !isValidURL(val.(string)) {
+		return fmt.Errorf("failed to parse CallbackURL: %q", val)
+	}
+	if val, ok := params.Get(idpCertsKey); ok {
+		certs := val.([]interface{})
+		if len(certs) == 0 {
+			return errors.New("X509Certificates must not be empty")
+		}
+		for _, cert := range certs {
+			if cert.(map[string]interface{})[x509CertificateKey].(string) == "" {
+				return errors.New("X509Certificates must not contain empty strings")
+			}
+		}
+	}
+	return nil
+}
+
+func isValidURL(s string) bool {
+	u, err := url.ParseRequestURI(s)
+	return err == nil && u.Scheme != "" && u.Host != ""
+}
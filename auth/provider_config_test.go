@@ -0,0 +1,648 @@
+// Copyright 2017 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"golang.org/x/net/context"
+	"google.golang.org/api/iterator"
+)
+
+const oidcConfigResponse = `{
+	"name": "projects/mock-project-id/oauthIdpConfigs/oidc.provider",
+	"clientId": "CLIENT_ID",
+	"issuer": "https://oidc.com/issuer",
+	"displayName": "oidcProviderName",
+	"enabled": true,
+	"clientSecret": "CLIENT_SECRET",
+	"responseType": {
+		"code": true,
+		"idToken": true
+	}
+}`
+
+var oidcProviderConfig = &OIDCProviderConfig{
+	ID:                  "oidc.provider",
+	DisplayName:         "oidcProviderName",
+	Enabled:             true,
+	ClientID:            "CLIENT_ID",
+	Issuer:              "https://oidc.com/issuer",
+	ClientSecret:        "CLIENT_SECRET",
+	CodeResponseType:    true,
+	IDTokenResponseType: true,
+}
+
+var invalidOIDCConfigIDs = []string{
+	"",
+	"invalid.id",
+	"saml.config",
+}
+
+func TestOIDCProviderConfig(t *testing.T) {
+	s := echoServer([]byte(oidcConfigResponse), t)
+	defer s.Close()
+
+	config, err := s.Client.OIDCProviderConfig(context.Background(), "oidc.provider")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(config, oidcProviderConfig) {
+		t.Errorf("OIDCProviderConfig() = %#v; want = %#v", config, oidcProviderConfig)
+	}
+
+	req := s.Req[0]
+	if req.Method != http.MethodGet {
+		t.Errorf("Method = %q; want = %q", req.Method, http.MethodGet)
+	}
+	checkRequest(t, s, "/projects/mock-project-id/oauthIdpConfigs/oidc.provider", nil)
+}
+
+func TestOIDCProviderConfigInvalidID(t *testing.T) {
+	for _, id := range invalidOIDCConfigIDs {
+		config, err := client.OIDCProviderConfig(context.Background(), id)
+		if config != nil || err == nil {
+			t.Errorf("OIDCProviderConfig(%q) = (%v, %v); want = (nil, error)", id, config, err)
+		}
+	}
+}
+
+func TestOIDCProviderConfigError(t *testing.T) {
+	s := echoServer([]byte(`{"error": {"message": "CONFIGURATION_NOT_FOUND"}}`), t)
+	defer s.Close()
+	s.Status = http.StatusNotFound
+
+	config, err := s.Client.OIDCProviderConfig(context.Background(), "oidc.provider")
+	if config != nil || err == nil {
+		t.Errorf("OIDCProviderConfig() = (%v, %v); want = (nil, error)", config, err)
+	}
+	if !IsConfigurationNotFound(err) {
+		t.Errorf("IsConfigurationNotFound(%v) = false; want = true", err)
+	}
+}
+
+func TestCreateOIDCProviderConfig(t *testing.T) {
+	s := echoServer([]byte(oidcConfigResponse), t)
+	defer s.Close()
+
+	options := (&OIDCProviderConfigToCreate{}).
+		ID(oidcProviderConfig.ID).
+		DisplayName(oidcProviderConfig.DisplayName).
+		Enabled(oidcProviderConfig.Enabled).
+		ClientID(oidcProviderConfig.ClientID).
+		Issuer(oidcProviderConfig.Issuer).
+		ClientSecret(oidcProviderConfig.ClientSecret).
+		CodeResponseType(true).
+		IDTokenResponseType(false)
+	config, err := s.Client.CreateOIDCProviderConfig(context.Background(), options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(config, oidcProviderConfig) {
+		t.Errorf("CreateOIDCProviderConfig() = %#v; want = %#v", config, oidcProviderConfig)
+	}
+
+	req := s.Req[0]
+	if req.Method != http.MethodPost {
+		t.Errorf("Method = %q; want = %q", req.Method, http.MethodPost)
+	}
+	if id := req.URL.Query().Get("oauthIdpConfigId"); id != "oidc.provider" {
+		t.Errorf("oauthIdpConfigId = %q; want = %q", id, "oidc.provider")
+	}
+	want := map[string]interface{}{
+		"displayName":  oidcProviderConfig.DisplayName,
+		"enabled":      true,
+		"clientId":     oidcProviderConfig.ClientID,
+		"issuer":       oidcProviderConfig.Issuer,
+		"clientSecret": oidcProviderConfig.ClientSecret,
+		"responseType": map[string]interface{}{
+			"code":    true,
+			"idToken": false,
+		},
+	}
+	checkRequest(t, s, "/projects/mock-project-id/oauthIdpConfigs", want)
+}
+
+func TestCreateOIDCProviderConfigInvalid(t *testing.T) {
+	valid := func() *OIDCProviderConfigToCreate {
+		return (&OIDCProviderConfigToCreate{}).
+			ID("oidc.provider").
+			ClientID("CLIENT_ID").
+			Issuer("https://oidc.com/issuer")
+	}
+	cases := []struct {
+		name   string
+		config *OIDCProviderConfigToCreate
+	}{
+		{"nil", nil},
+		{"empty", &OIDCProviderConfigToCreate{}},
+		{"no-id", (&OIDCProviderConfigToCreate{}).ClientID("CLIENT_ID").Issuer("https://oidc.com/issuer")},
+		{"invalid-id", valid().ID("saml.provider")},
+		{"no-client-id", (&OIDCProviderConfigToCreate{}).ID("oidc.provider").Issuer("https://oidc.com/issuer")},
+		{"empty-client-id", valid().ClientID("")},
+		{"no-issuer", (&OIDCProviderConfigToCreate{}).ID("oidc.provider").ClientID("CLIENT_ID")},
+		{"invalid-issuer", valid().Issuer("not a url")},
+		{"no-response-types", valid().CodeResponseType(false).IDTokenResponseType(false)},
+		{"code-without-secret", valid().CodeResponseType(true)},
+	}
+	for _, tc := range cases {
+		config, err := client.CreateOIDCProviderConfig(context.Background(), tc.config)
+		if config != nil || err == nil {
+			t.Errorf("CreateOIDCProviderConfig(%s) = (%v, %v); want = (nil, error)", tc.name, config, err)
+		}
+	}
+}
+
+func TestUpdateOIDCProviderConfig(t *testing.T) {
+	s := echoServer([]byte(oidcConfigResponse), t)
+	defer s.Close()
+
+	options := (&OIDCProviderConfigToUpdate{}).
+		DisplayName(oidcProviderConfig.DisplayName).
+		Enabled(true).
+		Issuer(oidcProviderConfig.Issuer).
+		IDTokenResponseType(true)
+	config, err := s.Client.UpdateOIDCProviderConfig(context.Background(), "oidc.provider", options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(config, oidcProviderConfig) {
+		t.Errorf("UpdateOIDCProviderConfig() = %#v; want = %#v", config, oidcProviderConfig)
+	}
+
+	req := s.Req[0]
+	if req.Method != http.MethodPatch {
+		t.Errorf("Method = %q; want = %q", req.Method, http.MethodPatch)
+	}
+	wantMask := "displayName,enabled,issuer,responseType.idToken"
+	if mask := req.URL.Query().Get("updateMask"); mask != wantMask {
+		t.Errorf("updateMask = %q; want = %q", mask, wantMask)
+	}
+	want := map[string]interface{}{
+		"displayName":  oidcProviderConfig.DisplayName,
+		"enabled":      true,
+		"issuer":       oidcProviderConfig.Issuer,
+		"responseType": map[string]interface{}{"idToken": true},
+	}
+	checkRequest(t, s, "/projects/mock-project-id/oauthIdpConfigs/oidc.provider", want)
+}
+
+func TestUpdateOIDCProviderConfigInvalid(t *testing.T) {
+	cases := []struct {
+		name   string
+		config *OIDCProviderConfigToUpdate
+	}{
+		{"nil", nil},
+		{"empty", &OIDCProviderConfigToUpdate{}},
+		{"empty-client-id", (&OIDCProviderConfigToUpdate{}).ClientID("")},
+		{"empty-issuer", (&OIDCProviderConfigToUpdate{}).Issuer("")},
+		{"invalid-issuer", (&OIDCProviderConfigToUpdate{}).Issuer("not a url")},
+		{"no-response-types", (&OIDCProviderConfigToUpdate{}).CodeResponseType(false).IDTokenResponseType(false)},
+		{"code-without-secret", (&OIDCProviderConfigToUpdate{}).CodeResponseType(true)},
+	}
+	for _, tc := range cases {
+		config, err := client.UpdateOIDCProviderConfig(context.Background(), "oidc.provider", tc.config)
+		if config != nil || err == nil {
+			t.Errorf("UpdateOIDCProviderConfig(%s) = (%v, %v); want = (nil, error)", tc.name, config, err)
+		}
+	}
+
+	options := (&OIDCProviderConfigToUpdate{}).Enabled(true)
+	for _, id := range invalidOIDCConfigIDs {
+		config, err := client.UpdateOIDCProviderConfig(context.Background(), id, options)
+		if config != nil || err == nil {
+			t.Errorf("UpdateOIDCProviderConfig(%q) = (%v, %v); want = (nil, error)", id, config, err)
+		}
+	}
+}
+
+func TestDeleteOIDCProviderConfig(t *testing.T) {
+	s := echoServer([]byte("{}"), t)
+	defer s.Close()
+
+	if err := s.Client.DeleteOIDCProviderConfig(context.Background(), "oidc.provider"); err != nil {
+		t.Fatal(err)
+	}
+
+	req := s.Req[0]
+	if req.Method != http.MethodDelete {
+		t.Errorf("Method = %q; want = %q", req.Method, http.MethodDelete)
+	}
+	checkRequest(t, s, "/projects/mock-project-id/oauthIdpConfigs/oidc.provider", nil)
+}
+
+func TestDeleteOIDCProviderConfigInvalidID(t *testing.T) {
+	for _, id := range invalidOIDCConfigIDs {
+		if err := client.DeleteOIDCProviderConfig(context.Background(), id); err == nil {
+			t.Errorf("DeleteOIDCProviderConfig(%q) = nil; want = error", id)
+		}
+	}
+}
+
+const samlConfigResponse = `{
+	"name": "projects/mock-project-id/inboundSamlConfigs/saml.provider",
+	"idpConfig": {
+		"idpEntityId": "IDP_ENTITY_ID",
+		"ssoUrl": "https://example.com/login",
+		"signRequest": true,
+		"idpCertificates": [
+			{"x509Certificate": "CERT1"},
+			{"x509Certificate": "CERT2"}
+		]
+	},
+	"spConfig": {
+		"spEntityId": "RP_ENTITY_ID",
+		"callbackUri": "https://projectId.firebaseapp.com/__/auth/handler"
+	},
+	"displayName": "samlProviderName",
+	"enabled": true
+}`
+
+var samlProviderConfig = &SAMLProviderConfig{
+	ID:                    "saml.provider",
+	DisplayName:           "samlProviderName",
+	Enabled:               true,
+	IDPEntityID:           "IDP_ENTITY_ID",
+	SSOURL:                "https://example.com/login",
+	RequestSigningEnabled: true,
+	X509Certificates:      []string{"CERT1", "CERT2"},
+	RPEntityID:            "RP_ENTITY_ID",
+	CallbackURL:           "https://projectId.firebaseapp.com/__/auth/handler",
+}
+
+var invalidSAMLConfigIDs = []string{
+	"",
+	"invalid.id",
+	"oidc.config",
+}
+
+func TestOIDCProviderConfigIDEscaped(t *testing.T) {
+	s := echoServer([]byte(oidcConfigResponse), t)
+	defer s.Close()
+
+	const id = "oidc.provider/../other?x#y"
+	ctx := context.Background()
+	if _, err := s.Client.OIDCProviderConfig(ctx, id); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Client.UpdateOIDCProviderConfig(ctx, id, (&OIDCProviderConfigToUpdate{}).Enabled(true)); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Client.DeleteOIDCProviderConfig(ctx, id); err != nil {
+		t.Fatal(err)
+	}
+
+	want := "/projects/mock-project-id/oauthIdpConfigs/oidc.provider%2F..%2Fother%3Fx%23y"
+	if len(s.Req) != 3 {
+		t.Fatalf("Requests = %d; want = 3", len(s.Req))
+	}
+	for _, req := range s.Req {
+		if got := req.URL.EscapedPath(); got != want {
+			t.Errorf("%s Path = %q; want = %q", req.Method, got, want)
+		}
+	}
+}
+
+func TestSAMLProviderConfig(t *testing.T) {
+	s := echoServer([]byte(samlConfigResponse), t)
+	defer s.Close()
+
+	config, err := s.Client.SAMLProviderConfig(context.Background(), "saml.provider")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(config, samlProviderConfig) {
+		t.Errorf("SAMLProviderConfig() = %#v; want = %#v", config, samlProviderConfig)
+	}
+
+	req := s.Req[0]
+	if req.Method != http.MethodGet {
+		t.Errorf("Method = %q; want = %q", req.Method, http.MethodGet)
+	}
+	checkRequest(t, s, "/projects/mock-project-id/inboundSamlConfigs/saml.provider", nil)
+}
+
+func TestSAMLProviderConfigInvalidID(t *testing.T) {
+	for _, id := range invalidSAMLConfigIDs {
+		config, err := client.SAMLProviderConfig(context.Background(), id)
+		if config != nil || err == nil {
+			t.Errorf("SAMLProviderConfig(%q) = (%v, %v); want = (nil, error)", id, config, err)
+		}
+	}
+}
+
+func TestSAMLProviderConfigError(t *testing.T) {
+	s := echoServer([]byte(`{"error": {"message": "CONFIGURATION_NOT_FOUND"}}`), t)
+	defer s.Close()
+	s.Status = http.StatusNotFound
+
+	config, err := s.Client.SAMLProviderConfig(context.Background(), "saml.provider")
+	if config != nil || err == nil {
+		t.Errorf("SAMLProviderConfig() = (%v, %v); want = (nil, error)", config, err)
+	}
+	if !IsConfigurationNotFound(err) {
+		t.Errorf("IsConfigurationNotFound(%v) = false; want = true", err)
+	}
+}
+
+func TestCreateSAMLProviderConfig(t *testing.T) {
+	s := echoServer([]byte(samlConfigResponse), t)
+	defer s.Close()
+
+	options := (&SAMLProviderConfigToCreate{}).
+		ID(samlProviderConfig.ID).
+		DisplayName(samlProviderConfig.DisplayName).
+		Enabled(samlProviderConfig.Enabled).
+		IDPEntityID(samlProviderConfig.IDPEntityID).
+		SSOURL(samlProviderConfig.SSOURL).
+		RequestSigningEnabled(samlProviderConfig.RequestSigningEnabled).
+		X509Certificates(samlProviderConfig.X509Certificates).
+		RPEntityID(samlProviderConfig.RPEntityID).
+		CallbackURL(samlProviderConfig.CallbackURL)
+	config, err := s.Client.CreateSAMLProviderConfig(context.Background(), options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(config, samlProviderConfig) {
+		t.Errorf("CreateSAMLProviderConfig() = %#v; want = %#v", config, samlProviderConfig)
+	}
+
+	req := s.Req[0]
+	if req.Method != http.MethodPost {
+		t.Errorf("Method = %q; want = %q", req.Method, http.MethodPost)
+	}
+	if id := req.URL.Query().Get("inboundSamlConfigId"); id != "saml.provider" {
+		t.Errorf("inboundSamlConfigId = %q; want = %q", id, "saml.provider")
+	}
+	want := map[string]interface{}{
+		"displayName": samlProviderConfig.DisplayName,
+		"enabled":     true,
+		"idpConfig": map[string]interface{}{
+			"idpEntityId": samlProviderConfig.IDPEntityID,
+			"ssoUrl":      samlProviderConfig.SSOURL,
+			"signRequest": true,
+			"idpCertificates": []interface{}{
+				map[string]interface{}{"x509Certificate": "CERT1"},
+				map[string]interface{}{"x509Certificate": "CERT2"},
+			},
+		},
+		"spConfig": map[string]interface{}{
+			"spEntityId":  samlProviderConfig.RPEntityID,
+			"callbackUri": samlProviderConfig.CallbackURL,
+		},
+	}
+	checkRequest(t, s, "/projects/mock-project-id/inboundSamlConfigs", want)
+}
+
+func TestCreateSAMLProviderConfigInvalid(t *testing.T) {
+	valid := func() *SAMLProviderConfigToCreate {
+		return (&SAMLProviderConfigToCreate{}).
+			ID("saml.provider").
+			IDPEntityID("IDP_ENTITY_ID").
+			SSOURL("https://example.com/login").
+			X509Certificates([]string{"CERT1"}).
+			RPEntityID("RP_ENTITY_ID").
+			CallbackURL("https://projectId.firebaseapp.com/__/auth/handler")
+	}
+	noCerts := &SAMLProviderConfigToCreate{}
+	noCerts.ID("saml.provider").
+		IDPEntityID("IDP_ENTITY_ID").
+		SSOURL("https://example.com/login").
+		RPEntityID("RP_ENTITY_ID").
+		CallbackURL("https://projectId.firebaseapp.com/__/auth/handler")
+	cases := []struct {
+		name   string
+		config *SAMLProviderConfigToCreate
+	}{
+		{"nil", nil},
+		{"empty", &SAMLProviderConfigToCreate{}},
+		{"invalid-id", valid().ID("oidc.provider")},
+		{"empty-idp-entity-id", valid().IDPEntityID("")},
+		{"empty-sso-url", valid().SSOURL("")},
+		{"invalid-sso-url", valid().SSOURL("not a url")},
+		{"no-certs", noCerts},
+		{"empty-certs", valid().X509Certificates(nil)},
+		{"empty-cert", valid().X509Certificates([]string{""})},
+		{"empty-rp-entity-id", valid().RPEntityID("")},
+		{"invalid-callback-url", valid().CallbackURL("not a url")},
+	}
+	for _, tc := range cases {
+		config, err := client.CreateSAMLProviderConfig(context.Background(), tc.config)
+		if config != nil || err == nil {
+			t.Errorf("CreateSAMLProviderConfig(%s) = (%v, %v); want = (nil, error)", tc.name, config, err)
+		}
+	}
+}
+
+func TestUpdateSAMLProviderConfig(t *testing.T) {
+	s := echoServer([]byte(samlConfigResponse), t)
+	defer s.Close()
+
+	options := (&SAMLProviderConfigToUpdate{}).
+		Enabled(true).
+		SSOURL(samlProviderConfig.SSOURL).
+		X509Certificates(samlProviderConfig.X509Certificates)
+	config, err := s.Client.UpdateSAMLProviderConfig(context.Background(), "saml.provider", options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(config, samlProviderConfig) {
+		t.Errorf("UpdateSAMLProviderConfig() = %#v; want = %#v", config, samlProviderConfig)
+	}
+
+	req := s.Req[0]
+	if req.Method != http.MethodPatch {
+		t.Errorf("Method = %q; want = %q", req.Method, http.MethodPatch)
+	}
+	wantMask := "enabled,idpConfig.idpCertificates,idpConfig.ssoUrl"
+	if mask := req.URL.Query().Get("updateMask"); mask != wantMask {
+		t.Errorf("updateMask = %q; want = %q", mask, wantMask)
+	}
+	want := map[string]interface{}{
+		"enabled": true,
+		"idpConfig": map[string]interface{}{
+			"ssoUrl": samlProviderConfig.SSOURL,
+			"idpCertificates": []interface{}{
+				map[string]interface{}{"x509Certificate": "CERT1"},
+				map[string]interface{}{"x509Certificate": "CERT2"},
+			},
+		},
+	}
+	checkRequest(t, s, "/projects/mock-project-id/inboundSamlConfigs/saml.provider", want)
+}
+
+func TestUpdateSAMLProviderConfigInvalid(t *testing.T) {
+	cases := []struct {
+		name   string
+		config *SAMLProviderConfigToUpdate
+	}{
+		{"nil", nil},
+		{"empty", &SAMLProviderConfigToUpdate{}},
+		{"empty-idp-entity-id", (&SAMLProviderConfigToUpdate{}).IDPEntityID("")},
+		{"invalid-sso-url", (&SAMLProviderConfigToUpdate{}).SSOURL("not a url")},
+		{"empty-certs", (&SAMLProviderConfigToUpdate{}).X509Certificates([]string{})},
+		{"empty-rp-entity-id", (&SAMLProviderConfigToUpdate{}).RPEntityID("")},
+		{"invalid-callback-url", (&SAMLProviderConfigToUpdate{}).CallbackURL("")},
+	}
+	for _, tc := range cases {
+		config, err := client.UpdateSAMLProviderConfig(context.Background(), "saml.provider", tc.config)
+		if config != nil || err == nil {
+			t.Errorf("UpdateSAMLProviderConfig(%s) = (%v, %v); want = (nil, error)", tc.name, config, err)
+		}
+	}
+
+	options := (&SAMLProviderConfigToUpdate{}).Enabled(true)
+	for _, id := range invalidSAMLConfigIDs {
+		config, err := client.UpdateSAMLProviderConfig(context.Background(), id, options)
+		if config != nil || err == nil {
+			t.Errorf("UpdateSAMLProviderConfig(%q) = (%v, %v); want = (nil, error)", id, config, err)
+		}
+	}
+}
+
+func TestDeleteSAMLProviderConfig(t *testing.T) {
+	s := echoServer([]byte("{}"), t)
+	defer s.Close()
+
+	if err := s.Client.DeleteSAMLProviderConfig(context.Background(), "saml.provider"); err != nil {
+		t.Fatal(err)
+	}
+
+	req := s.Req[0]
+	if req.Method != http.MethodDelete {
+		t.Errorf("Method = %q; want = %q", req.Method, http.MethodDelete)
+	}
+	checkRequest(t, s, "/projects/mock-project-id/inboundSamlConfigs/saml.provider", nil)
+}
+
+func TestDeleteSAMLProviderConfigInvalidID(t *testing.T) {
+	for _, id := range invalidSAMLConfigIDs {
+		if err := client.DeleteSAMLProviderConfig(context.Background(), id); err == nil {
+			t.Errorf("DeleteSAMLProviderConfig(%q) = nil; want = error", id)
+		}
+	}
+}
+
+func TestSAMLProviderConfigIDEscaped(t *testing.T) {
+	s := echoServer([]byte(samlConfigResponse), t)
+	defer s.Close()
+
+	const id = "saml.provider/../other?x#y"
+	ctx := context.Background()
+	if _, err := s.Client.SAMLProviderConfig(ctx, id); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Client.UpdateSAMLProviderConfig(ctx, id, (&SAMLProviderConfigToUpdate{}).Enabled(true)); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Client.DeleteSAMLProviderConfig(ctx, id); err != nil {
+		t.Fatal(err)
+	}
+
+	want := "/projects/mock-project-id/inboundSamlConfigs/saml.provider%2F..%2Fother%3Fx%23y"
+	if len(s.Req) != 3 {
+		t.Fatalf("Requests = %d; want = 3", len(s.Req))
+	}
+	for _, req := range s.Req {
+		if got := req.URL.EscapedPath(); got != want {
+			t.Errorf("%s Path = %q; want = %q", req.Method, got, want)
+		}
+	}
+}
+
+func TestListOIDCProviderConfigs(t *testing.T) {
+	pages := map[string]string{
+		"": `{
+			"oauthIdpConfigs": [
+				{"name": "projects/mock-project-id/oauthIdpConfigs/oidc.provider1"},
+				{"name": "projects/mock-project-id/oauthIdpConfigs/oidc.provider2"}
+			],
+			"nextPageToken": "page2"
+		}`,
+		"page2": `{
+			"oauthIdpConfigs": [
+				{"name": "projects/mock-project-id/oauthIdpConfigs/oidc.provider3"}
+			]
+		}`,
+	}
+	var queries []string
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		queries = append(queries, r.URL.RawQuery)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(pages[r.URL.Query().Get("pageToken")]))
+	}))
+	defer s.Close()
+	c := *client
+	c.projectMgtEndpoint = s.URL
+
+	it := c.ListOIDCProviderConfigs(context.Background()).PageSize(2)
+	var ids []string
+	for {
+		config, err := it.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, config.ID)
+	}
+
+	wantIDs := []string{"oidc.provider1", "oidc.provider2", "oidc.provider3"}
+	if !reflect.DeepEqual(ids, wantIDs) {
+		t.Errorf("ListOIDCProviderConfigs() = %v; want = %v", ids, wantIDs)
+	}
+	wantQueries := []string{"pageSize=2", "pageSize=2&pageToken=page2"}
+	if !reflect.DeepEqual(queries, wantQueries) {
+		t.Errorf("ListOIDCProviderConfigs() queries = %v; want = %v", queries, wantQueries)
+	}
+}
+
+func TestListSAMLProviderConfigsPager(t *testing.T) {
+	resp := `{
+		"inboundSamlConfigs": [` + samlConfigResponse + `],
+		"nextPageToken": "page3"
+	}`
+	s := echoServer([]byte(resp), t)
+	defer s.Close()
+
+	pager := iterator.NewPager(s.Client.ListSAMLProviderConfigs(context.Background()), 10, "page2")
+	var configs []*SAMLProviderConfig
+	token, err := pager.NextPage(&configs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(configs) != 1 || !reflect.DeepEqual(configs[0], samlProviderConfig) || token != "page3" {
+		t.Errorf("NextPage() = (%v, %q); want = ([%v], %q)", configs, token, samlProviderConfig, "page3")
+	}
+	if q := s.Req[0].URL.RawQuery; q != "pageSize=10&pageToken=page2" {
+		t.Errorf("Query = %q; want = %q", q, "pageSize=10&pageToken=page2")
+	}
+	checkRequest(t, s, "/projects/mock-project-id/inboundSamlConfigs", nil)
+}
+
+func TestListProviderConfigsInvalidPageSize(t *testing.T) {
+	for _, size := range []int{0, maxProviderConfigs + 1} {
+		if _, err := client.ListOIDCProviderConfigs(context.Background()).PageSize(size).Next(); err == nil {
+			t.Errorf("ListOIDCProviderConfigs(PageSize(%d)) = nil; want = error", size)
+		}
+		if _, err := client.ListSAMLProviderConfigs(context.Background()).PageSize(size).Next(); err == nil {
+			t.Errorf("ListSAMLProviderConfigs(PageSize(%d)) = nil; want = error", size)
+		}
+	}
+}
@@ -0,0 +1,192 @@
+// Copyright 2017 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+const (
+	defaultInitialBackoff = time.Second
+	defaultMaxBackoff     = 32 * time.Second
+
+	// minRateFraction is the lowest fraction of the configured QPS to which the rate of requests is
+	// reduced when the quota of the project is exceeded.
+	minRateFraction = 1.0 / 16
+	// rateRecoveryFraction is the fraction of the configured QPS by which the rate of requests is
+	// increased after each successful request, until the configured QPS is reached again.
+	rateRecoveryFraction = 1.0 / 20
+)
+
+// RateLimit configures the client-side rate limiting of the requests sent by a Client to the Identity
+// Toolkit service, which enforces per-project quotas on user management operations.
+type RateLimit struct {
+	// QPS is the maximum sustained number of requests per second. Zero disables rate limiting, in
+	// which case only retries apply.
+	QPS float64
+
+	// Burst is the number of requests that may be sent at once, after a period of inactivity.
+	// Defaults to 1.
+	Burst int
+
+	// MaxRetries is the number of times a request rejected because the quota of the project was
+	// exceeded is retried. Zero disables retries.
+	MaxRetries int
+
+	// InitialBackoff is the delay before the first retry of a request. It is doubled for each
+	// subsequent retry, up to MaxBackoff. They default to 1 and 32 seconds respectively.
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+}
+
+// WithRateLimit returns a copy of the Client that limits the rate of its requests to the Identity
+// Toolkit service, and retries the requests rejected because the quota of the project was exceeded.
+// The Client on which it is called is not modified.
+//
+// The rate limit is adaptive: each rejected request halves the rate of requests, down to a sixteenth
+// of limit.QPS, and each successful request then raises it gradually back to limit.QPS. The limit is
+// shared by the returned Client and the clients derived from it, such as tenant clients, so that they
+// draw from the same quota. Requests wait for their turn until their context is done.
+func (c *Client) WithRateLimit(limit *RateLimit) (*Client, error) {
+	if limit == nil {
+		return nil, errors.New("rate limit must not be nil")
+	}
+	if limit.QPS < 0 || limit.Burst < 0 || limit.MaxRetries < 0 || limit.InitialBackoff < 0 ||
+		limit.MaxBackoff < 0 {
+		return nil, errors.New("rate limit parameters must not be negative")
+	}
+	l := &rateLimiter{
+		qps:            limit.QPS,
+		rate:           limit.QPS,
+		burst:          float64(limit.Burst),
+		maxRetries:     limit.MaxRetries,
+		initialBackoff: limit.InitialBackoff,
+		maxBackoff:     limit.MaxBackoff,
+	}
+	if l.burst == 0 {
+		l.burst = 1
+	}
+	l.tokens = l.burst
+	if l.initialBackoff == 0 {
+		l.initialBackoff = defaultInitialBackoff
+	}
+	if l.maxBackoff == 0 {
+		l.maxBackoff = defaultMaxBackoff
+	}
+
+	copied := *c
+	copied.limiter = l
+	return &copied, nil
+}
+
+// rateLimiter is an adaptive token bucket, which refills at a rate between a sixteenth of qps and qps.
+type rateLimiter struct {
+	qps            float64
+	burst          float64
+	maxRetries     int
+	initialBackoff time.Duration
+	maxBackoff     time.Duration
+
+	mutex  sync.Mutex
+	rate   float64
+	tokens float64
+	last   time.Time
+}
+
+// do calls f once a token is available, and calls it again after a backoff delay if it fails because
+// the quota of the project was exceeded, up to the maximum number of retries.
+func (l *rateLimiter) do(ctx context.Context, f func() error) error {
+	backoff := l.initialBackoff
+	for attempt := 0; ; attempt++ {
+		if err := sleep(ctx, l.reserve()); err != nil {
+			return err
+		}
+		err := f()
+		if !IsQuotaExceeded(err) {
+			l.adjust(false)
+			return err
+		}
+		l.adjust(true)
+		if attempt == l.maxRetries {
+			return err
+		}
+		if err := sleep(ctx, backoff); err != nil {
+			return err
+		}
+		if backoff *= 2; backoff > l.maxBackoff {
+			backoff = l.maxBackoff
+		}
+	}
+}
+
+// reserve takes a token from the bucket, and returns how long to wait until the token is available.
+func (l *rateLimiter) reserve() time.Duration {
+	if l.qps == 0 {
+		return 0
+	}
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	now := time.Now()
+	if !l.last.IsZero() {
+		l.tokens += now.Sub(l.last).Seconds() * l.rate
+		if l.tokens > l.burst {
+			l.tokens = l.burst
+		}
+	}
+	l.last = now
+	l.tokens--
+	if l.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-l.tokens / l.rate * float64(time.Second))
+}
+
+// adjust halves the rate of the bucket when the quota of the project is exceeded, and raises it
+// towards qps otherwise.
+func (l *rateLimiter) adjust(exceeded bool) {
+	if l.qps == 0 {
+		return
+	}
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if exceeded {
+		l.rate /= 2
+		if floor := l.qps * minRateFraction; l.rate < floor {
+			l.rate = floor
+		}
+	} else if l.rate < l.qps {
+		l.rate += l.qps * rateRecoveryFraction
+		if l.rate > l.qps {
+			l.rate = l.qps
+		}
+	}
+}
+
+// sleep waits for the given duration, or until the context is done.
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	select {
+	case <-time.After(d):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,179 @@
+// Copyright 2017 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+// quotaServer returns a Client whose requests are rejected with a quota error until the given number
+// of requests has been received, and the counter of received requests.
+func quotaServer(t *testing.T, rejected int, limit *RateLimit) (*Client, func() int, func()) {
+	var mutex sync.Mutex
+	count := 0
+	resp := testGetUserResponse(t)
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		count++
+		n := count
+		mutex.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		if n <= rejected {
+			w.WriteHeader(http.StatusTooManyRequests)
+			w.Write([]byte(`{"error": {"message": "QUOTA_EXCEEDED : Exceeded quota for getting account information."}}`))
+			return
+		}
+		w.Write(resp)
+	}))
+	c := *client
+	c.userEndpoint = s.URL
+	limited, err := c.WithRateLimit(limit)
+	if err != nil {
+		t.Fatal(err)
+	}
+	counter := func() int {
+		mutex.Lock()
+		defer mutex.Unlock()
+		return count
+	}
+	return limited, counter, s.Close
+}
+
+func TestWithRateLimitRetry(t *testing.T) {
+	c, count, done := quotaServer(t, 2, &RateLimit{MaxRetries: 2, InitialBackoff: time.Millisecond})
+	defer done()
+
+	user, err := c.GetUser(context.Background(), "testuser")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.UID != "testuser" {
+		t.Errorf("GetUser() = %q; want = %q", user.UID, "testuser")
+	}
+	if n := count(); n != 3 {
+		t.Errorf("Requests = %d; want = 3", n)
+	}
+}
+
+func TestWithRateLimitRetriesExhausted(t *testing.T) {
+	c, count, done := quotaServer(t, 5, &RateLimit{MaxRetries: 1, InitialBackoff: time.Millisecond})
+	defer done()
+
+	if _, err := c.GetUser(context.Background(), "testuser"); !IsQuotaExceeded(err) {
+		t.Errorf("GetUser() = %v; want = quota-exceeded error", err)
+	}
+	if n := count(); n != 2 {
+		t.Errorf("Requests = %d; want = 2", n)
+	}
+}
+
+func TestWithRateLimitNoRetryOnOtherErrors(t *testing.T) {
+	s := echoServer([]byte(`{"error": {"message": "USER_NOT_FOUND"}}`), t)
+	defer s.Close()
+	s.Status = http.StatusBadRequest
+
+	c, err := s.Client.WithRateLimit(&RateLimit{MaxRetries: 3, InitialBackoff: time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Client.limiter != nil {
+		t.Errorf("WithRateLimit() modified the original Client")
+	}
+	if _, err := c.GetUser(context.Background(), "testuser"); !IsUserNotFound(err) {
+		t.Errorf("GetUser() = %v; want = user-not-found error", err)
+	}
+	if len(s.Req) != 1 {
+		t.Errorf("Requests = %d; want = 1", len(s.Req))
+	}
+}
+
+func TestWithRateLimitQPS(t *testing.T) {
+	c, count, done := quotaServer(t, 0, &RateLimit{QPS: 50, Burst: 2})
+	defer done()
+
+	start := time.Now()
+	for i := 0; i < 4; i++ {
+		if _, err := c.GetUser(context.Background(), "testuser"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// The first two requests use the burst, and the other two wait 20ms each.
+	if elapsed := time.Since(start); elapsed < 40*time.Millisecond {
+		t.Errorf("GetUser() x 4 took %v; want >= 40ms", elapsed)
+	}
+	if n := count(); n != 4 {
+		t.Errorf("Requests = %d; want = 4", n)
+	}
+}
+
+func TestWithRateLimitContextDone(t *testing.T) {
+	c, count, done := quotaServer(t, 5, &RateLimit{MaxRetries: 3, InitialBackoff: time.Hour})
+	defer done()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := c.GetUser(ctx, "testuser"); err != context.DeadlineExceeded {
+		t.Errorf("GetUser() = %v; want = %v", err, context.DeadlineExceeded)
+	}
+	if n := count(); n != 1 {
+		t.Errorf("Requests = %d; want = 1", n)
+	}
+}
+
+func TestRateLimiterAdjust(t *testing.T) {
+	l := &rateLimiter{qps: 160, rate: 160}
+	l.adjust(true)
+	if l.rate != 80 {
+		t.Errorf("rate = %v; want = 80", l.rate)
+	}
+	for i := 0; i < 10; i++ {
+		l.adjust(true)
+	}
+	if l.rate != 10 {
+		t.Errorf("rate = %v; want = 10", l.rate)
+	}
+	l.adjust(false)
+	if l.rate != 18 {
+		t.Errorf("rate = %v; want = 18", l.rate)
+	}
+	for i := 0; i < 100; i++ {
+		l.adjust(false)
+	}
+	if l.rate != 160 {
+		t.Errorf("rate = %v; want = 160", l.rate)
+	}
+}
+
+func TestWithRateLimitInvalid(t *testing.T) {
+	cases := []*RateLimit{
+		nil,
+		{QPS: -1},
+		{Burst: -1},
+		{MaxRetries: -1},
+		{InitialBackoff: -time.Second},
+		{MaxBackoff: -time.Second},
+	}
+	for _, tc := range cases {
+		if c, err := client.WithRateLimit(tc); c != nil || err == nil {
+			t.Errorf("WithRateLimit(%v) = (%v, %v); want = (nil, error)", tc, c, err)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+// Copyright 2017 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+const (
+	recaptchaEmailPasswordStateKey = "recaptchaConfig.emailPasswordEnforcementState"
+	recaptchaPhoneStateKey         = "recaptchaConfig.phoneEnforcementState"
+	recaptchaManagedRulesKey       = "recaptchaConfig.managedRules"
+	recaptchaAccountDefenderKey    = "recaptchaConfig.useAccountDefender"
+)
+
+// RecaptchaEnforcementState is the enforcement state of reCAPTCHA Enterprise bot protection for a
+// sign-in provider.
+type RecaptchaEnforcementState string
+
+const (
+	// RecaptchaEnforcementOff disables reCAPTCHA verification.
+	RecaptchaEnforcementOff RecaptchaEnforcementState = "OFF"
+
+	// RecaptchaEnforcementAudit assesses requests, and records the results in the reCAPTCHA metrics,
+	// without rejecting any requests.
+	RecaptchaEnforcementAudit RecaptchaEnforcementState = "AUDIT"
+
+	// RecaptchaEnforcementEnforce assesses requests, and rejects the requests matched by the managed
+	// rules.
+	RecaptchaEnforcementEnforce RecaptchaEnforcementState = "ENFORCE"
+)
+
+// RecaptchaAction is the action taken on requests matched by a reCAPTCHA managed rule.
+type RecaptchaAction string
+
+const (
+	// RecaptchaActionBlock rejects the matched requests.
+	RecaptchaActionBlock RecaptchaAction = "BLOCK"
+)
+
+// RecaptchaManagedRule takes an action on the requests whose reCAPTCHA score is at most EndScore.
+//
+// Scores range from 0.0, for requests most likely made by bots, to 1.0. EndScore must be a multiple of
+// 0.1 within that range.
+type RecaptchaManagedRule struct {
+	EndScore float64         `json:"endScore"`
+	Action   RecaptchaAction `json:"action"`
+}
+
+// RecaptchaConfig represents the reCAPTCHA Enterprise bot protection configuration of a project.
+//
+// EmailPasswordEnforcementState and PhoneEnforcementState are the enforcement states of the
+// email/password and phone sign-in providers respectively. Each is empty if reCAPTCHA has never been
+// configured for the provider.
+type RecaptchaConfig struct {
+	EmailPasswordEnforcementState RecaptchaEnforcementState
+	PhoneEnforcementState         RecaptchaEnforcementState
+	ManagedRules                  []*RecaptchaManagedRule
+	UseAccountDefender            bool
+}
+
+// recaptchaConfigResponse is the JSON representation of the RecaptchaConfig resource of the Identity
+// Toolkit service.
+type recaptchaConfigResponse struct {
+	EmailPasswordEnforcementState string                  `json:"emailPasswordEnforcementState"`
+	PhoneEnforcementState         string                  `json:"phoneEnforcementState"`
+	ManagedRules                  []*RecaptchaManagedRule `json:"managedRules"`
+	UseAccountDefender            bool                    `json:"useAccountDefender"`
+}
+
+func (r *recaptchaConfigResponse) makeRecaptchaConfig() *RecaptchaConfig {
+	return &RecaptchaConfig{
+		EmailPasswordEnforcementState: makeRecaptchaEnforcementState(r.EmailPasswordEnforcementState),
+		PhoneEnforcementState:         makeRecaptchaEnforcementState(r.PhoneEnforcementState),
+		ManagedRules:                  r.ManagedRules,
+		UseAccountDefender:            r.UseAccountDefender,
+	}
+}
+
+func makeRecaptchaEnforcementState(state string) RecaptchaEnforcementState {
+	if state == "RECAPTCHA_PROVIDER_ENFORCEMENT_STATE_UNSPECIFIED" {
+		return ""
+	}
+	return RecaptchaEnforcementState(state)
+}
+
+func validateRecaptchaEnforcementState(state RecaptchaEnforcementState) error {
+	switch state {
+	case RecaptchaEnforcementOff, RecaptchaEnforcementAudit, RecaptchaEnforcementEnforce:
+		return nil
+	}
+	return fmt.Errorf("invalid reCAPTCHA enforcement state: %q", state)
+}
+
+func validateRecaptchaManagedRules(rules []*RecaptchaManagedRule) error {
+	for _, r := range rules {
+		if r == nil {
+			return errors.New("reCAPTCHA managed rule must not be nil")
+		}
+		tenths := r.EndScore * 10
+		if r.EndScore < 0 || r.EndScore > 1 || math.Abs(tenths-math.Floor(tenths+0.5)) > 1e-9 {
+			return fmt.Errorf("invalid reCAPTCHA end score: %v; must be a multiple of 0.1 between 0 and 1",
+				r.EndScore)
+		}
+		if r.Action != RecaptchaActionBlock {
+			return fmt.Errorf("invalid reCAPTCHA action: %q", r.Action)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,245 @@
+// Copyright 2017 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// redacted replaces sensitive values in the string representations of the types of this package.
+const redacted = "REDACTED"
+
+// String returns a description of the Client that identifies its project and tenant, without the
+// service account email and private key of the Client.
+func (c *Client) String() string {
+	return fmt.Sprintf("auth.Client{projectID: %q, tenantID: %q}", c.projectID, c.tenantID)
+}
+
+// GoString returns the same redacted description as String, so that %#v does not reveal the private
+// key either.
+func (c *Client) GoString() string {
+	return c.String()
+}
+
+// String returns a description of the Token suitable for logging. The values of the claims of the
+// token are redacted, as they may hold personal data such as the email address of the user.
+func (t *Token) String() string {
+	return fmt.Sprintf("auth.Token{UID: %q, ProjectID: %q, Issuer: %q, Audience: %q, IssuedAt: %d, "+
+		"Expires: %d, Claims: %s}", t.UID, t.ProjectID, t.Issuer, t.Audience, t.IssuedAt, t.Expires,
+		redactClaims(t.Claims))
+}
+
+// GoString returns the same redacted description as String.
+func (t *Token) GoString() string {
+	return t.String()
+}
+
+// String returns a description of the UserInfo suitable for logging, in which the email address and
+// phone number are partially redacted, and the display name and photo URL are omitted.
+func (u *UserInfo) String() string {
+	return fmt.Sprintf("auth.UserInfo{ProviderID: %q, UID: %q, Email: %q, PhoneNumber: %q}",
+		u.ProviderID, u.UID, redactEmail(u.Email), redactPhone(u.PhoneNumber))
+}
+
+// GoString returns the same redacted description as String.
+func (u *UserInfo) GoString() string {
+	return u.String()
+}
+
+// String returns a description of the UserRecord suitable for logging. The email address and phone
+// number are partially redacted, the values of custom claims are redacted, and the password hash and
+// salt are omitted.
+func (u *UserRecord) String() string {
+	var info UserInfo
+	if u.UserInfo != nil {
+		info = *u.UserInfo
+	}
+	providers := make([]string, len(u.ProviderUserInfo))
+	for i, p := range u.ProviderUserInfo {
+		providers[i] = p.ProviderID
+	}
+	factors := 0
+	if u.MultiFactor != nil {
+		factors = len(u.MultiFactor.EnrolledFactors)
+	}
+	return fmt.Sprintf("auth.UserRecord{UID: %q, Email: %q, EmailVerified: %t, PhoneNumber: %q, "+
+		"Disabled: %t, Providers: %q, CustomClaims: %s, EnrolledFactors: %d}", info.UID,
+		redactEmail(info.Email), u.EmailVerified, redactPhone(info.PhoneNumber), u.Disabled, providers,
+		redactClaims(u.CustomClaims), factors)
+}
+
+// GoString returns the same redacted description as String, so that %#v does not reveal the password
+// hash and salt either.
+func (u *UserRecord) GoString() string {
+	return u.String()
+}
+
+// String returns a description of the OIDCProviderConfig suitable for logging, in which the client
+// secret is redacted.
+func (config *OIDCProviderConfig) String() string {
+	return fmt.Sprintf("auth.OIDCProviderConfig{ID: %q, DisplayName: %q, Enabled: %t, ClientID: %q, "+
+		"Issuer: %q, ClientSecret: %s, CodeResponseType: %t, IDTokenResponseType: %t}", config.ID,
+		config.DisplayName, config.Enabled, config.ClientID, config.Issuer, redactSecret(config.ClientSecret),
+		config.CodeResponseType, config.IDTokenResponseType)
+}
+
+// GoString returns the same redacted description as String.
+func (config *OIDCProviderConfig) GoString() string {
+	return config.String()
+}
+
+// String returns a description of the OIDCProviderConfigToCreate that only lists the names of the
+// fields set on it, as it may hold a client secret.
+func (config *OIDCProviderConfigToCreate) String() string {
+	return fmt.Sprintf("auth.OIDCProviderConfigToCreate{ID: %q, Fields: %q}", config.id,
+		fieldNames(config.params))
+}
+
+// GoString returns the same redacted description as String.
+func (config *OIDCProviderConfigToCreate) GoString() string {
+	return config.String()
+}
+
+// String returns a description of the OIDCProviderConfigToUpdate that only lists the names of the
+// fields set on it, as it may hold a client secret.
+func (config *OIDCProviderConfigToUpdate) String() string {
+	return fmt.Sprintf("auth.OIDCProviderConfigToUpdate{Fields: %q}", fieldNames(config.params))
+}
+
+// GoString returns the same redacted description as String.
+func (config *OIDCProviderConfigToUpdate) GoString() string {
+	return config.String()
+}
+
+// String returns a description of the UserToImport suitable for logging, which holds its UID and the
+// names of the other fields set on it, without their values, such as the password hash and salt.
+func (u *UserToImport) String() string {
+	uid, _ := u.params["localId"].(string)
+	var fields []string
+	for _, f := range fieldNames(u.params) {
+		if f != "localId" {
+			fields = append(fields, f)
+		}
+	}
+	return fmt.Sprintf("auth.UserToImport{UID: %q, Fields: %q}", uid, fields)
+}
+
+// GoString returns the same redacted description as String.
+func (u *UserToImport) GoString() string {
+	return u.String()
+}
+
+// UserSummary is a description of a user that is safe to display in support tools. The email address
+// and phone number are partially redacted, and only the names of the custom claims are kept.
+//
+// Timestamps are in milliseconds since epoch, and are 0 when unknown.
+type UserSummary struct {
+	UID                string   `json:"uid"`
+	Email              string   `json:"email,omitempty"`
+	EmailVerified      bool     `json:"emailVerified"`
+	PhoneNumber        string   `json:"phoneNumber,omitempty"`
+	Providers          []string `json:"providers"`
+	CreationTimestamp  int64    `json:"creationTimestamp,omitempty"`
+	LastLogInTimestamp int64    `json:"lastLogInTimestamp,omitempty"`
+	Disabled           bool     `json:"disabled"`
+	ClaimNames         []string `json:"claimNames"`
+	EnrolledFactors    int      `json:"enrolledFactors"`
+}
+
+// Summary returns a redacted summary of the user, which omits the display name, photo URL and password
+// hash of the user, as well as the values of its custom claims.
+func (u *UserRecord) Summary() *UserSummary {
+	summary := &UserSummary{
+		EmailVerified: u.EmailVerified,
+		Providers:     make([]string, len(u.ProviderUserInfo)),
+		Disabled:      u.Disabled,
+		ClaimNames:    make([]string, 0, len(u.CustomClaims)),
+	}
+	if u.UserInfo != nil {
+		summary.UID = u.UID
+		summary.Email = redactEmail(u.Email)
+		summary.PhoneNumber = redactPhone(u.PhoneNumber)
+	}
+	for i, p := range u.ProviderUserInfo {
+		summary.Providers[i] = p.ProviderID
+	}
+	if u.UserMetadata != nil {
+		summary.CreationTimestamp = u.UserMetadata.CreationTimestamp
+		summary.LastLogInTimestamp = u.UserMetadata.LastLogInTimestamp
+	}
+	for k := range u.CustomClaims {
+		summary.ClaimNames = append(summary.ClaimNames, k)
+	}
+	sort.Strings(summary.ClaimNames)
+	if u.MultiFactor != nil {
+		summary.EnrolledFactors = len(u.MultiFactor.EnrolledFactors)
+	}
+	return summary
+}
+
+// redactEmail keeps the first character of the local part and the domain of an email address, such as
+// "j***@example.com".
+func redactEmail(email string) string {
+	if email == "" {
+		return ""
+	}
+	at := strings.LastIndex(email, "@")
+	if at < 1 {
+		return redacted
+	}
+	return email[:1] + "***" + email[at:]
+}
+
+// redactPhone keeps the last two digits of a phone number, such as "+*********00".
+func redactPhone(phone string) string {
+	prefix := ""
+	if strings.HasPrefix(phone, "+") {
+		prefix, phone = "+", phone[1:]
+	}
+	if len(phone) <= 2 {
+		return prefix + strings.Repeat("*", len(phone))
+	}
+	return prefix + strings.Repeat("*", len(phone)-2) + phone[len(phone)-2:]
+}
+
+// redactSecret hides the value of a secret, only revealing whether it is set.
+func redactSecret(secret string) string {
+	if secret == "" {
+		return `""`
+	}
+	return redacted
+}
+
+// fieldNames returns the sorted names of the fields set in the given request parameters.
+func fieldNames(params map[string]interface{}) []string {
+	names := make([]string, 0, len(params))
+	for k := range params {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// redactClaims lists the names of the given claims, with their values redacted.
+func redactClaims(claims map[string]interface{}) string {
+	keys := make([]string, 0, len(claims))
+	for k := range claims {
+		keys = append(keys, fmt.Sprintf("%q: %s", k, redacted))
+	}
+	sort.Strings(keys)
+	return "{" + strings.Join(keys, ", ") + "}"
+}
@@ -0,0 +1,227 @@
+// Copyright 2017 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestClientString(t *testing.T) {
+	c := *client
+	c.email = "service-account@mock-project-id.iam.gserviceaccount.com"
+	c.tenantID = "tenant1"
+	want := `auth.Client{projectID: "mock-project-id", tenantID: "tenant1"}`
+	for _, format := range []string{"%v", "%+v", "%#v", "%s"} {
+		if got := fmt.Sprintf(format, &c); got != want {
+			t.Errorf("Sprintf(%q) = %s; want = %s", format, got, want)
+		}
+	}
+}
+
+func TestTokenString(t *testing.T) {
+	token := &Token{
+		Issuer:    "https://securetoken.google.com/mock-project-id",
+		Audience:  "mock-project-id",
+		Expires:   2000,
+		IssuedAt:  1000,
+		Subject:   "user1",
+		UID:       "user1",
+		ProjectID: "mock-project-id",
+		Claims:    map[string]interface{}{"email": "user1@example.com", "admin": true},
+	}
+	want := `auth.Token{UID: "user1", ProjectID: "mock-project-id", ` +
+		`Issuer: "https://securetoken.google.com/mock-project-id", Audience: "mock-project-id", ` +
+		`IssuedAt: 1000, Expires: 2000, Claims: {"admin": REDACTED, "email": REDACTED}}`
+	if got := fmt.Sprintf("%v", token); got != want {
+		t.Errorf("String() = %s; want = %s", got, want)
+	}
+}
+
+func TestUserRecordString(t *testing.T) {
+	user := *testUser
+	want := `auth.UserRecord{UID: "testuser", Email: "t***@example.com", EmailVerified: true, ` +
+		`PhoneNumber: "+********90", Disabled: false, Providers: ["password" "phone"], ` +
+		`CustomClaims: {"admin": REDACTED, "package": REDACTED}, EnrolledFactors: 2}`
+	got := fmt.Sprintf("%v", &user)
+	if got != want {
+		t.Errorf("String() = %s; want = %s", got, want)
+	}
+	for _, secret := range []string{"testuser@example.com", "+1234567890", "gold", user.PasswordHash} {
+		if strings.Contains(got, secret) {
+			t.Errorf("String() = %s; want %q redacted", got, secret)
+		}
+	}
+
+	if got := fmt.Sprintf("%v", &UserRecord{}); !strings.HasPrefix(got, `auth.UserRecord{UID: ""`) {
+		t.Errorf("String() = %s; want = empty user record", got)
+	}
+}
+
+func TestUserRecordSummary(t *testing.T) {
+	want := &UserSummary{
+		UID:                "testuser",
+		Email:              "t***@example.com",
+		EmailVerified:      true,
+		PhoneNumber:        "+********90",
+		Providers:          []string{"password", "phone"},
+		CreationTimestamp:  testUser.UserMetadata.CreationTimestamp,
+		LastLogInTimestamp: testUser.UserMetadata.LastLogInTimestamp,
+		ClaimNames:         []string{"admin", "package"},
+		EnrolledFactors:    2,
+	}
+	summary := testUser.Summary()
+	if !reflect.DeepEqual(summary, want) {
+		t.Errorf("Summary() = %#v; want = %#v", summary, want)
+	}
+
+	b, err := json.Marshal(summary)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, secret := range []string{"testuser@example.com", "+1234567890", "gold", testUser.PasswordHash} {
+		if strings.Contains(string(b), secret) {
+			t.Errorf("Summary() = %s; want %q redacted", b, secret)
+		}
+	}
+}
+
+func TestEmptyUserRecordSummary(t *testing.T) {
+	want := &UserSummary{Providers: []string{}, ClaimNames: []string{}}
+	if summary := (&UserRecord{}).Summary(); !reflect.DeepEqual(summary, want) {
+		t.Errorf("Summary() = %#v; want = %#v", summary, want)
+	}
+}
+
+func TestUserInfoString(t *testing.T) {
+	info := &UserInfo{
+		ProviderID:  "google.com",
+		UID:         "g1",
+		Email:       "user@gmail.com",
+		PhoneNumber: "+15555550100",
+		DisplayName: "User",
+	}
+	want := `auth.UserInfo{ProviderID: "google.com", UID: "g1", Email: "u***@gmail.com", PhoneNumber: "+*********00"}`
+	if got := fmt.Sprintf("%v", info); got != want {
+		t.Errorf("String() = %s; want = %s", got, want)
+	}
+}
+
+func TestRedact(t *testing.T) {
+	emails := map[string]string{
+		"":                "",
+		"a@example.com":   "a***@example.com",
+		"not-an-email":    redacted,
+		"@example.com":    redacted,
+		"a@b@example.com": "a***@example.com",
+	}
+	for email, want := range emails {
+		if got := redactEmail(email); got != want {
+			t.Errorf("redactEmail(%q) = %q; want = %q", email, got, want)
+		}
+	}
+	phones := map[string]string{
+		"":             "",
+		"+1":           "+*",
+		"12":           "**",
+		"+15555550100": "+*********00",
+	}
+	for phone, want := range phones {
+		if got := redactPhone(phone); got != want {
+			t.Errorf("redactPhone(%q) = %q; want = %q", phone, got, want)
+		}
+	}
+}
+
+// redactFormats are the verbs with which the redacted types are checked not to leak secrets.
+var redactFormats = []string{"%v", "%+v", "%#v", "%s"}
+
+func TestUserRecordGoString(t *testing.T) {
+	user := *testUser
+	user.PasswordHash = "cGFzc3dvcmRoYXNo"
+	user.PasswordSalt = "c2FsdA=="
+	for _, format := range redactFormats {
+		got := fmt.Sprintf(format, &user)
+		if !strings.HasPrefix(got, `auth.UserRecord{UID: "testuser"`) {
+			t.Errorf("Sprintf(%q) = %s; want = redacted user record", format, got)
+		}
+		for _, secret := range []string{user.PasswordHash, user.PasswordSalt, "testuser@example.com"} {
+			if strings.Contains(got, secret) {
+				t.Errorf("Sprintf(%q) = %s; want %q redacted", format, got, secret)
+			}
+		}
+	}
+}
+
+func TestOIDCProviderConfigString(t *testing.T) {
+	config := &OIDCProviderConfig{
+		ID:               "oidc.provider",
+		DisplayName:      "OIDC",
+		Enabled:          true,
+		ClientID:         "CLIENT_ID",
+		Issuer:           "https://oidc.com/issuer",
+		ClientSecret:     "CLIENT_SECRET",
+		CodeResponseType: true,
+	}
+	want := `auth.OIDCProviderConfig{ID: "oidc.provider", DisplayName: "OIDC", Enabled: true, ` +
+		`ClientID: "CLIENT_ID", Issuer: "https://oidc.com/issuer", ClientSecret: REDACTED, ` +
+		`CodeResponseType: true, IDTokenResponseType: false}`
+	for _, format := range redactFormats {
+		if got := fmt.Sprintf(format, config); got != want {
+			t.Errorf("Sprintf(%q) = %s; want = %s", format, got, want)
+		}
+	}
+	if got := (&OIDCProviderConfig{}).String(); !strings.Contains(got, `ClientSecret: ""`) {
+		t.Errorf("String() = %s; want empty client secret", got)
+	}
+
+	create := (&OIDCProviderConfigToCreate{}).ID("oidc.provider").ClientID("CLIENT_ID").
+		ClientSecret("CLIENT_SECRET")
+	update := (&OIDCProviderConfigToUpdate{}).ClientSecret("CLIENT_SECRET")
+	cases := []struct {
+		value interface{}
+		want  string
+	}{
+		{create, `auth.OIDCProviderConfigToCreate{ID: "oidc.provider", Fields: ["clientId" "clientSecret"]}`},
+		{update, `auth.OIDCProviderConfigToUpdate{Fields: ["clientSecret"]}`},
+	}
+	for _, tc := range cases {
+		for _, format := range redactFormats {
+			if got := fmt.Sprintf(format, tc.value); got != tc.want {
+				t.Errorf("Sprintf(%q) = %s; want = %s", format, got, tc.want)
+			}
+		}
+	}
+}
+
+func TestUserToImportString(t *testing.T) {
+	user := (&UserToImport{}).UID("user1").Email("user1@example.com").
+		PasswordHash([]byte("passwordhash")).PasswordSalt([]byte("salt"))
+	want := `auth.UserToImport{UID: "user1", Fields: ["email" "passwordHash" "salt"]}`
+	for _, format := range redactFormats {
+		got := fmt.Sprintf(format, user)
+		if got != want {
+			t.Errorf("Sprintf(%q) = %s; want = %s", format, got, want)
+		}
+		for _, secret := range []string{"passwordhash", "cGFzc3dvcmRoYXNo", "c2FsdA"} {
+			if strings.Contains(got, secret) {
+				t.Errorf("Sprintf(%q) = %s; want %q redacted", format, got, secret)
+			}
+		}
+	}
+}
@@ -0,0 +1,89 @@
+// Copyright 2017 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"container/list"
+	"errors"
+	"sync"
+)
+
+// TenantClientCache lazily creates the TenantClients of the tenants of a project, and caches up to a
+// maximum number of them, evicting the least recently used client when the cache is full.
+//
+// All the TenantClients of a cache are derived from the same Client, and hence share its HTTP client,
+// the public keys used by TenantClient.VerifyIDToken, and its key pins, whichever tenant the tokens are
+// verified for. A TenantClientCache is safe for concurrent use by multiple goroutines.
+type TenantClientCache struct {
+	tm      *TenantManager
+	size    int
+	mutex   sync.Mutex
+	lru     *list.List
+	entries map[string]*list.Element
+}
+
+// NewTenantClientCache creates a TenantClientCache that holds the TenantClients of up to size tenants.
+func (tm *TenantManager) NewTenantClientCache(size int) (*TenantClientCache, error) {
+	if size < 1 {
+		return nil, errors.New("size must be at least 1")
+	}
+	return &TenantClientCache{
+		tm:      tm,
+		size:    size,
+		lru:     list.New(),
+		entries: make(map[string]*list.Element),
+	}, nil
+}
+
+// Get returns the TenantClient of the tenant with the given ID, creating it as by
+// TenantManager.AuthForTenant if it is not cached.
+func (c *TenantClientCache) Get(tenantID string) (*TenantClient, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if e, ok := c.entries[tenantID]; ok {
+		c.lru.MoveToFront(e)
+		return e.Value.(*TenantClient), nil
+	}
+
+	tc, err := c.tm.AuthForTenant(tenantID)
+	if err != nil {
+		return nil, err
+	}
+	c.entries[tenantID] = c.lru.PushFront(tc)
+	if c.lru.Len() > c.size {
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		delete(c.entries, oldest.Value.(*TenantClient).TenantID())
+	}
+	return tc, nil
+}
+
+// Remove evicts the TenantClient of the tenant with the given ID from the cache, if any, such as after
+// the tenant has been deleted.
+func (c *TenantClientCache) Remove(tenantID string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if e, ok := c.entries[tenantID]; ok {
+		c.lru.Remove(e)
+		delete(c.entries, tenantID)
+	}
+}
+
+// Len returns the number of TenantClients in the cache.
+func (c *TenantClientCache) Len() int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.lru.Len()
+}
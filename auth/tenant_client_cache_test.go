@@ -0,0 +1,100 @@
+// Copyright 2017 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func TestTenantClientCache(t *testing.T) {
+	cache, err := client.TenantManager().NewTenantClientCache(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tc1, err := cache.Get("tenant1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tc1.TenantID() != "tenant1" {
+		t.Errorf("TenantID() = %q; want = %q", tc1.TenantID(), "tenant1")
+	}
+	if tc1.client.ks != client.ks || tc1.client.pins != client.pins {
+		t.Errorf("TenantClient does not share the key source and pins of its Client")
+	}
+	if tc, _ := cache.Get("tenant1"); tc != tc1 {
+		t.Errorf("Get() = %p; want = cached client %p", tc, tc1)
+	}
+
+	tc2, _ := cache.Get("tenant2")
+	cache.Get("tenant1")
+	cache.Get("tenant3") // evicts tenant2, the least recently used
+	if cache.Len() != 2 {
+		t.Errorf("Len() = %d; want = 2", cache.Len())
+	}
+	if tc, _ := cache.Get("tenant1"); tc != tc1 {
+		t.Errorf("Get(tenant1) = %p; want = cached client %p", tc, tc1)
+	}
+	if tc, _ := cache.Get("tenant2"); tc == tc2 {
+		t.Errorf("Get(tenant2) returned an evicted client")
+	}
+
+	cache.Remove("tenant1")
+	cache.Remove("unknown")
+	if tc, _ := cache.Get("tenant1"); tc == tc1 {
+		t.Errorf("Get(tenant1) returned a removed client")
+	}
+}
+
+func TestTenantClientCacheConcurrent(t *testing.T) {
+	cache, err := client.TenantManager().NewTenantClientCache(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			tenantID := fmt.Sprintf("tenant%d", i%20)
+			tc, err := cache.Get(tenantID)
+			if err != nil || tc.TenantID() != tenantID {
+				t.Errorf("Get(%q) = (%v, %v); want = (%q, nil)", tenantID, tc, err, tenantID)
+			}
+		}(i)
+	}
+	wg.Wait()
+	if cache.Len() != 10 {
+		t.Errorf("Len() = %d; want = 10", cache.Len())
+	}
+}
+
+func TestTenantClientCacheInvalid(t *testing.T) {
+	if cache, err := client.TenantManager().NewTenantClientCache(0); cache != nil || err == nil {
+		t.Errorf("NewTenantClientCache(0) = (%v, %v); want = (nil, error)", cache, err)
+	}
+	cache, err := client.TenantManager().NewTenantClientCache(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tc, err := cache.Get(""); tc != nil || err == nil {
+		t.Errorf("Get(\"\") = (%v, %v); want = (nil, error)", tc, err)
+	}
+	if cache.Len() != 0 {
+		t.Errorf("Len() = %d; want = 0", cache.Len())
+	}
+}
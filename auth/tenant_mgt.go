@@ -0,0 +1,518 @@
+// Copyright 2017 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/context"
+	"google.golang.org/api/iterator"
+
+	"firebase.google.com/go/internal"
+)
+
+// maxListTenantsResults is the maximum number of tenants that can be retrieved in one page.
+const maxListTenantsResults = 1000
+
+const (
+	tenantDisplayNameKey     = "displayName"
+	allowPasswordSignUpKey   = "allowPasswordSignup"
+	enableEmailLinkSignInKey = "enableEmailLinkSignin"
+	enableAnonymousUsersKey  = "enableAnonymousUser"
+	tenantMFAProviderKey     = "mfaConfig.providerConfigs"
+)
+
+// tenantDisplayNamePattern matches valid tenant display names: 4 to 20 characters, consisting of
+// letters, digits and hyphens, and starting with a letter.
+var tenantDisplayNamePattern = regexp.MustCompile("^[a-zA-Z][a-zA-Z0-9-]{3,19}$")
+
+// Tenant represents a tenant in a multi-tenant application.
+//
+// Multi-tenancy support requires Google Cloud Identity Platform (GCIP). To learn more about GCIP,
+// including pricing and features, see https://cloud.google.com/identity-platform.
+//
+// Before multi-tenancy can be used in a Google Cloud Identity Platform project, tenants must be
+// enabled in that project via the Cloud Console UI.
+type Tenant struct {
+	ID                    string
+	DisplayName           string
+	AllowPasswordSignUp   bool
+	EnableEmailLinkSignIn bool
+	EnableAnonymousUsers  bool
+	MultiFactor           *MultiFactorConfig
+}
+
+// TenantToCreate represents the options used to create a new tenant. The tenant ID is assigned by the
+// server.
+type TenantToCreate struct {
+	params nestedMap
+}
+
+func (t *TenantToCreate) set(key string, value interface{}) *TenantToCreate {
+	if t.params == nil {
+		t.params = make(nestedMap)
+	}
+	t.params.Set(key, value)
+	return t
+}
+
+// DisplayName sets the display name of the new tenant.
+func (t *TenantToCreate) DisplayName(name string) *TenantToCreate {
+	return t.set(tenantDisplayNameKey, name)
+}
+
+// AllowPasswordSignUp enables or disables email sign-in provider for the new tenant.
+func (t *TenantToCreate) AllowPasswordSignUp(allow bool) *TenantToCreate {
+	return t.set(allowPasswordSignUpKey, allow)
+}
+
+// EnableEmailLinkSignIn enables or disables email link sign-in for the new tenant.
+func (t *TenantToCreate) EnableEmailLinkSignIn(enable bool) *TenantToCreate {
+	return t.set(enableEmailLinkSignInKey, enable)
+}
+
+// EnableAnonymousUsers enables or disables anonymous authentication for the new tenant.
+func (t *TenantToCreate) EnableAnonymousUsers(enable bool) *TenantToCreate {
+	return t.set(enableAnonymousUsersKey, enable)
+}
+
+// TOTPMultiFactor enables or disables TOTP second factors for the new tenant, and sets the number of
+// adjacent time intervals for which TOTP codes are accepted, between 0 and 10.
+func (t *TenantToCreate) TOTPMultiFactor(enabled bool, adjacentIntervals int) *TenantToCreate {
+	return t.set(tenantMFAProviderKey, totpProviderConfigs(enabled, adjacentIntervals))
+}
+
+// TenantToUpdate represents the options used to update an existing tenant.
+//
+// Only the settings explicitly specified on a TenantToUpdate are changed.
+type TenantToUpdate struct {
+	params nestedMap
+}
+
+func (t *TenantToUpdate) set(key string, value interface{}) *TenantToUpdate {
+	if t.params == nil {
+		t.params = make(nestedMap)
+	}
+	t.params.Set(key, value)
+	return t
+}
+
+// DisplayName updates the display name of the tenant.
+func (t *TenantToUpdate) DisplayName(name string) *TenantToUpdate {
+	return t.set(tenantDisplayNameKey, name)
+}
+
+// AllowPasswordSignUp enables or disables email sign-in provider for the tenant.
+func (t *TenantToUpdate) AllowPasswordSignUp(allow bool) *TenantToUpdate {
+	return t.set(allowPasswordSignUpKey, allow)
+}
+
+// EnableEmailLinkSignIn enables or disables email link sign-in for the tenant.
+func (t *TenantToUpdate) EnableEmailLinkSignIn(enable bool) *TenantToUpdate {
+	return t.set(enableEmailLinkSignInKey, enable)
+}
+
+// EnableAnonymousUsers enables or disables anonymous authentication for the tenant.
+func (t *TenantToUpdate) EnableAnonymousUsers(enable bool) *TenantToUpdate {
+	return t.set(enableAnonymousUsersKey, enable)
+}
+
+// TOTPMultiFactor enables or disables TOTP second factors for the tenant, and sets the number of
+// adjacent time intervals for which TOTP codes are accepted, between 0 and 10.
+func (t *TenantToUpdate) TOTPMultiFactor(enabled bool, adjacentIntervals int) *TenantToUpdate {
+	return t.set(tenantMFAProviderKey, totpProviderConfigs(enabled, adjacentIntervals))
+}
+
+// TenantManager is the interface used to manage tenants in a multi-tenant application.
+//
+// This supports creating, updating, listing, deleting the tenants of a Firebase project.
+type TenantManager struct {
+	client *Client
+}
+
+// TenantManager returns the TenantManager of the project.
+func (c *Client) TenantManager() *TenantManager {
+	return &TenantManager{client: c}
+}
+
+// AuthForTenant returns a TenantClient that performs operations scoped to the tenant with the given ID.
+//
+// AuthForTenant does not check whether the tenant exists. Operations of the returned TenantClient fail
+// if it does not.
+func (tm *TenantManager) AuthForTenant(tenantID string) (*TenantClient, error) {
+	if tenantID == "" {
+		return nil, errors.New("tenantID must not be empty")
+	}
+	scoped := *tm.client
+	scoped.tenantID = tenantID
+	return &TenantClient{client: &scoped}, nil
+}
+
+// Tenant returns the tenant with the given ID.
+//
+// If no tenant exists for the given ID, Tenant returns an error for which IsTenantNotFound returns
+// true.
+func (tm *TenantManager) Tenant(ctx context.Context, tenantID string) (*Tenant, error) {
+	if tenantID == "" {
+		return nil, errors.New("tenantID must not be empty")
+	}
+	base, err := tm.client.projectResourcesURL("tenants")
+	if err != nil {
+		return nil, err
+	}
+
+	var parsed tenantResponse
+	if err := tm.client.makeRequest(ctx, http.MethodGet, base+"/"+url.PathEscape(tenantID), nil, &parsed); err != nil {
+		return nil, err
+	}
+	return parsed.makeTenant(), nil
+}
+
+// CreateTenant creates a new tenant with the given options, and returns the resulting tenant.
+func (tm *TenantManager) CreateTenant(ctx context.Context, tenant *TenantToCreate) (*Tenant, error) {
+	if tenant == nil {
+		return nil, errors.New("tenant must not be nil")
+	}
+	if err := validateTenantParams(tenant.params); err != nil {
+		return nil, err
+	}
+	base, err := tm.client.projectResourcesURL("tenants")
+	if err != nil {
+		return nil, err
+	}
+
+	body := tenant.params
+	if body == nil {
+		body = make(nestedMap)
+	}
+	var parsed tenantResponse
+	if err := tm.client.makeRequest(ctx, http.MethodPost, base, body, &parsed); err != nil {
+		return nil, err
+	}
+	return parsed.makeTenant(), nil
+}
+
+// UpdateTenant updates an existing tenant with the given options, and returns the resulting tenant.
+func (tm *TenantManager) UpdateTenant(ctx context.Context, tenantID string,
+	tenant *TenantToUpdate) (*Tenant, error) {
+	if tenantID == "" {
+		return nil, errors.New("tenantID must not be empty")
+	}
+	if tenant == nil || len(tenant.params) == 0 {
+		return nil, errors.New("tenant must not be nil or empty")
+	}
+	if err := validateTenantParams(tenant.params); err != nil {
+		return nil, err
+	}
+	base, err := tm.client.projectResourcesURL("tenants")
+	if err != nil {
+		return nil, err
+	}
+	mask := tenant.params.UpdateMask()
+	endpoint := fmt.Sprintf("%s/%s?updateMask=%s", base, url.PathEscape(tenantID),
+		url.QueryEscape(strings.Join(mask, ",")))
+
+	var parsed tenantResponse
+	if err := tm.client.makeRequest(ctx, http.MethodPatch, endpoint, tenant.params, &parsed); err != nil {
+		return nil, err
+	}
+	return parsed.makeTenant(), nil
+}
+
+// DeleteTenant deletes the tenant with the given ID.
+func (tm *TenantManager) DeleteTenant(ctx context.Context, tenantID string) error {
+	if tenantID == "" {
+		return errors.New("tenantID must not be empty")
+	}
+	base, err := tm.client.projectResourcesURL("tenants")
+	if err != nil {
+		return err
+	}
+
+	var parsed map[string]interface{}
+	return tm.client.makeRequest(ctx, http.MethodDelete, base+"/"+url.PathEscape(tenantID), nil, &parsed)
+}
+
+// TenantClient performs ID token verification, user management, user import and email action link
+// operations scoped to a specific tenant.
+//
+// ID tokens are only accepted if they were issued for the tenant. Users looked up, updated, deleted or
+// imported through a TenantClient are the users of the tenant, and email action links generated through
+// it act on the accounts of the tenant. Use TenantManager.AuthForTenant to obtain a TenantClient.
+type TenantClient struct {
+	client *Client
+}
+
+// TenantID returns the ID of the tenant to which the TenantClient is scoped.
+func (tc *TenantClient) TenantID() string {
+	return tc.client.tenantID
+}
+
+// VerifyIDToken verifies the signature and payload of the provided ID token, as Client.VerifyIDToken,
+// and additionally checks that the token was issued for the tenant.
+//
+// If the token was issued for another tenant, or for no tenant, VerifyIDToken returns an error for
+// which IsTenantIDMismatch returns true.
+func (tc *TenantClient) VerifyIDToken(idToken string) (*Token, error) {
+	p, err := tc.client.VerifyIDToken(idToken)
+	if err != nil {
+		return nil, err
+	}
+	if tid := tokenTenantID(p); tid != tc.client.tenantID {
+		return nil, internal.Errorf(tenantIDMismatch,
+			"ID token has invalid tenant. Expected %q but got %q", tc.client.tenantID, tid)
+	}
+	return p, nil
+}
+
+// tokenTenantID returns the tenant of the given token, held in the "tenant" field of its "firebase"
+// claim, or an empty string if the token was not issued for a tenant.
+func tokenTenantID(p *Token) string {
+	firebase, _ := p.Claims["firebase"].(map[string]interface{})
+	tid, _ := firebase["tenant"].(string)
+	return tid
+}
+
+// VerifyIDTokenAndCheckRevoked verifies the provided ID token as VerifyIDToken, and additionally checks
+// that the token has not been revoked, and that the user account it belongs to is still active in the
+// tenant. See Client.VerifyIDTokenAndCheckRevoked for details.
+func (tc *TenantClient) VerifyIDTokenAndCheckRevoked(ctx context.Context, idToken string) (*Token, error) {
+	p, err := tc.VerifyIDToken(idToken)
+	if err != nil {
+		return nil, err
+	}
+	return tc.client.checkRevoked(ctx, p)
+}
+
+// GetUser gets the tenant user with the given user ID. See Client.GetUser for details.
+func (tc *TenantClient) GetUser(ctx context.Context, uid string) (*UserRecord, error) {
+	return tc.client.GetUser(ctx, uid)
+}
+
+// GetUsers gets the tenant users corresponding to the given identifiers. See Client.GetUsers for
+// details.
+func (tc *TenantClient) GetUsers(ctx context.Context, identifiers []UserIdentifier) (*GetUsersResult, error) {
+	return tc.client.GetUsers(ctx, identifiers)
+}
+
+// UpdateUser updates an existing tenant user. See Client.UpdateUser for details.
+func (tc *TenantClient) UpdateUser(ctx context.Context, uid string, user *UserToUpdate) (*UserRecord, error) {
+	return tc.client.UpdateUser(ctx, uid, user)
+}
+
+// SetCustomUserClaims sets the custom claims of a tenant user. See Client.SetCustomUserClaims for
+// details.
+func (tc *TenantClient) SetCustomUserClaims(ctx context.Context, uid string,
+	customClaims map[string]interface{}) error {
+	return tc.client.SetCustomUserClaims(ctx, uid, customClaims)
+}
+
+// DisableUserAndRevokeTokens disables a tenant user, and revokes its refresh tokens. See
+// Client.DisableUserAndRevokeTokens for details.
+func (tc *TenantClient) DisableUserAndRevokeTokens(ctx context.Context, uid string) (*UserRecord, error) {
+	return tc.client.DisableUserAndRevokeTokens(ctx, uid)
+}
+
+// RevokeRefreshTokens revokes all refresh tokens of a tenant user, without disabling it. See
+// Client.RevokeRefreshTokens for details.
+func (tc *TenantClient) RevokeRefreshTokens(ctx context.Context, uid string) error {
+	return tc.client.RevokeRefreshTokens(ctx, uid)
+}
+
+// DeleteUser deletes the tenant user with the given user ID.
+func (tc *TenantClient) DeleteUser(ctx context.Context, uid string) error {
+	return tc.client.DeleteUser(ctx, uid)
+}
+
+// DeleteUsers deletes the tenant users with the given user IDs. See Client.DeleteUsers for details.
+func (tc *TenantClient) DeleteUsers(ctx context.Context, uids []string) (*DeleteUsersResult, error) {
+	return tc.client.DeleteUsers(ctx, uids)
+}
+
+// ImportUsers imports an array of users to the tenant. See Client.ImportUsers for details.
+func (tc *TenantClient) ImportUsers(ctx context.Context, users []*UserToImport,
+	opts ...UserImportOption) (*ImportUsersResult, error) {
+	return tc.client.ImportUsers(ctx, users, opts...)
+}
+
+// ImportUsersFromReader imports the user accounts read from r to the tenant. See
+// Client.ImportUsersFromReader for details.
+func (tc *TenantClient) ImportUsersFromReader(ctx context.Context, r io.Reader,
+	progress func(*ImportUsersResult), opts ...UserImportOption) (*ImportUsersResult, error) {
+	return tc.client.ImportUsersFromReader(ctx, r, progress, opts...)
+}
+
+// EmailVerificationLink generates the out-of-band email action link for email verification flows for
+// the specified email address of a tenant user.
+func (tc *TenantClient) EmailVerificationLink(ctx context.Context, email string) (string, error) {
+	return tc.client.EmailVerificationLink(ctx, email)
+}
+
+// EmailVerificationLinkWithSettings generates the out-of-band email action link for email verification
+// flows for the specified email address of a tenant user, using the action code settings provided.
+func (tc *TenantClient) EmailVerificationLinkWithSettings(ctx context.Context, email string,
+	settings *ActionCodeSettings) (string, error) {
+	return tc.client.EmailVerificationLinkWithSettings(ctx, email, settings)
+}
+
+// PasswordResetLink generates the out-of-band email action link for password reset flows for the
+// specified email address of a tenant user.
+func (tc *TenantClient) PasswordResetLink(ctx context.Context, email string) (string, error) {
+	return tc.client.PasswordResetLink(ctx, email)
+}
+
+// PasswordResetLinkWithSettings generates the out-of-band email action link for password reset flows
+// for the specified email address of a tenant user, using the action code settings provided.
+func (tc *TenantClient) PasswordResetLinkWithSettings(ctx context.Context, email string,
+	settings *ActionCodeSettings) (string, error) {
+	return tc.client.PasswordResetLinkWithSettings(ctx, email, settings)
+}
+
+// EmailSignInLink generates the out-of-band email action link for email link sign-in flows for the
+// specified email address of a tenant user, using the action code settings provided.
+func (tc *TenantClient) EmailSignInLink(ctx context.Context, email string,
+	settings *ActionCodeSettings) (string, error) {
+	return tc.client.EmailSignInLink(ctx, email, settings)
+}
+
+// SignInWithEmailLink completes an email link sign-in on behalf of a tenant user. See
+// Client.SignInWithEmailLink for details.
+func (tc *TenantClient) SignInWithEmailLink(ctx context.Context, email,
+	link string) (*EmailLinkSignInResult, error) {
+	return tc.client.SignInWithEmailLink(ctx, email, link)
+}
+
+// VerifyPassword checks that the given password is the password of the tenant user with the given email
+// address. See Client.VerifyPassword for details.
+func (tc *TenantClient) VerifyPassword(ctx context.Context, email, password string) (string, error) {
+	return tc.client.VerifyPassword(ctx, email, password)
+}
+
+// TenantIterator is an iterator over the tenants of a project.
+//
+// TenantIterator is compatible with the google.golang.org/api/iterator package.
+type TenantIterator struct {
+	tm       *TenantManager
+	ctx      context.Context
+	nextFunc func() error
+	pageInfo *iterator.PageInfo
+	tenants  []*Tenant
+}
+
+// ListTenants returns an iterator over the tenants of the project.
+//
+// Tenants are fetched from the server in pages of up to 1000 tenants, as the iterator advances.
+func (tm *TenantManager) ListTenants(ctx context.Context) *TenantIterator {
+	it := &TenantIterator{
+		tm:  tm,
+		ctx: ctx,
+	}
+	it.pageInfo, it.nextFunc = iterator.NewPageInfo(
+		it.fetch,
+		func() int { return len(it.tenants) },
+		func() interface{} { b := it.tenants; it.tenants = nil; return b })
+	it.pageInfo.MaxSize = maxListTenantsResults
+	return it
+}
+
+// PageSize sets the number of tenants retrieved from the server in each page. It must be between 1
+// and 1000. PageSize should be called before the first call to Next.
+func (it *TenantIterator) PageSize(size int) *TenantIterator {
+	it.pageInfo.MaxSize = size
+	return it
+}
+
+// PageInfo supports pagination. See the google.golang.org/api/iterator package for details.
+func (it *TenantIterator) PageInfo() *iterator.PageInfo {
+	return it.pageInfo
+}
+
+// Next returns the next Tenant. Its second return value is iterator.Done if there are no more results.
+// Once Next returns iterator.Done, all subsequent calls will return iterator.Done.
+func (it *TenantIterator) Next() (*Tenant, error) {
+	if err := it.nextFunc(); err != nil {
+		return nil, err
+	}
+	tenant := it.tenants[0]
+	it.tenants = it.tenants[1:]
+	return tenant, nil
+}
+
+func (it *TenantIterator) fetch(pageSize int, pageToken string) (string, error) {
+	if pageSize < 1 || pageSize > maxListTenantsResults {
+		return "", fmt.Errorf("page size must be between 1 and %d", maxListTenantsResults)
+	}
+	base, err := it.tm.client.projectResourcesURL("tenants")
+	if err != nil {
+		return "", err
+	}
+
+	query := url.Values{}
+	query.Set("pageSize", strconv.Itoa(pageSize))
+	if pageToken != "" {
+		query.Set("pageToken", pageToken)
+	}
+	var parsed struct {
+		Tenants       []*tenantResponse `json:"tenants"`
+		NextPageToken string            `json:"nextPageToken"`
+	}
+	endpoint := fmt.Sprintf("%s?%s", base, query.Encode())
+	if err := it.tm.client.makeRequest(it.ctx, http.MethodGet, endpoint, nil, &parsed); err != nil {
+		return "", err
+	}
+	for _, tenant := range parsed.Tenants {
+		it.tenants = append(it.tenants, tenant.makeTenant())
+	}
+	return parsed.NextPageToken, nil
+}
+
+// tenantResponse is the JSON representation of the Tenant resource of the Identity Toolkit service.
+type tenantResponse struct {
+	Name                  string                    `json:"name"`
+	DisplayName           string                    `json:"displayName"`
+	AllowPasswordSignUp   bool                      `json:"allowPasswordSignup"`
+	EnableEmailLinkSignIn bool                      `json:"enableEmailLinkSignin"`
+	EnableAnonymousUsers  bool                      `json:"enableAnonymousUser"`
+	MFAConfig             multiFactorConfigResponse `json:"mfaConfig"`
+}
+
+func (r *tenantResponse) makeTenant() *Tenant {
+	return &Tenant{
+		ID:                    extractResourceID(r.Name),
+		DisplayName:           r.DisplayName,
+		AllowPasswordSignUp:   r.AllowPasswordSignUp,
+		EnableEmailLinkSignIn: r.EnableEmailLinkSignIn,
+		EnableAnonymousUsers:  r.EnableAnonymousUsers,
+		MultiFactor:           r.MFAConfig.makeMultiFactorConfig(),
+	}
+}
+
+func validateTenantParams(params nestedMap) error {
+	if val, ok := params.Get(tenantDisplayNameKey); ok {
+		name := val.(string)
+		if !tenantDisplayNamePattern.MatchString(name) {
+			return fmt.Errorf("invalid tenant display name: %q; display names must be 4 to 20 characters "+
+				"long, start with a letter, and consist of letters, digits and hyphens", name)
+		}
+	}
+	return validateTOTPProviderConfigs(params, tenantMFAProviderKey)
+}
@@ -0,0 +1,515 @@
+// Copyright 2017 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/context"
+	"google.golang.org/api/iterator"
+)
+
+const tenantResponseJSON = `{
+	"name": "projects/mock-project-id/tenants/tenantID",
+	"displayName": "Test-Tenant",
+	"allowPasswordSignup": true,
+	"enableEmailLinkSignin": true,
+	"enableAnonymousUser": false,
+	"mfaConfig": {
+		"providerConfigs": [{"state": "DISABLED", "totpProviderConfig": {"adjacentIntervals": 3}}]
+	}
+}`
+
+var testTenant = &Tenant{
+	ID:                    "tenantID",
+	DisplayName:           "Test-Tenant",
+	AllowPasswordSignUp:   true,
+	EnableEmailLinkSignIn: true,
+	EnableAnonymousUsers:  false,
+	MultiFactor: &MultiFactorConfig{
+		TOTPEnabled:           false,
+		TOTPAdjacentIntervals: 3,
+	},
+}
+
+func TestTenant(t *testing.T) {
+	s := echoServer([]byte(tenantResponseJSON), t)
+	defer s.Close()
+
+	tenant, err := s.Client.TenantManager().Tenant(context.Background(), "tenantID")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(tenant, testTenant) {
+		t.Errorf("Tenant() = %#v; want = %#v", tenant, testTenant)
+	}
+
+	req := s.Req[0]
+	if req.Method != http.MethodGet {
+		t.Errorf("Method = %q; want = %q", req.Method, http.MethodGet)
+	}
+	checkRequest(t, s, "/projects/mock-project-id/tenants/tenantID", nil)
+}
+
+func TestTenantEmptyID(t *testing.T) {
+	tenant, err := client.TenantManager().Tenant(context.Background(), "")
+	if tenant != nil || err == nil {
+		t.Errorf("Tenant('') = (%v, %v); want = (nil, error)", tenant, err)
+	}
+}
+
+func TestTenantNotFound(t *testing.T) {
+	s := echoServer([]byte(`{"error": {"message": "TENANT_NOT_FOUND"}}`), t)
+	defer s.Close()
+	s.Status = http.StatusNotFound
+
+	tenant, err := s.Client.TenantManager().Tenant(context.Background(), "tenantID")
+	if tenant != nil || err == nil {
+		t.Errorf("Tenant() = (%v, %v); want = (nil, error)", tenant, err)
+	}
+	if !IsTenantNotFound(err) {
+		t.Errorf("IsTenantNotFound(%v) = false; want = true", err)
+	}
+}
+
+func TestCreateTenant(t *testing.T) {
+	s := echoServer([]byte(tenantResponseJSON), t)
+	defer s.Close()
+
+	options := (&TenantToCreate{}).
+		DisplayName("Test-Tenant").
+		AllowPasswordSignUp(true).
+		EnableEmailLinkSignIn(true).
+		EnableAnonymousUsers(false)
+	tenant, err := s.Client.TenantManager().CreateTenant(context.Background(), options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(tenant, testTenant) {
+		t.Errorf("CreateTenant() = %#v; want = %#v", tenant, testTenant)
+	}
+
+	req := s.Req[0]
+	if req.Method != http.MethodPost {
+		t.Errorf("Method = %q; want = %q", req.Method, http.MethodPost)
+	}
+	want := map[string]interface{}{
+		"displayName":           "Test-Tenant",
+		"allowPasswordSignup":   true,
+		"enableEmailLinkSignin": true,
+		"enableAnonymousUser":   false,
+	}
+	checkRequest(t, s, "/projects/mock-project-id/tenants", want)
+}
+
+func TestCreateTenantMinimal(t *testing.T) {
+	s := echoServer([]byte(tenantResponseJSON), t)
+	defer s.Close()
+
+	if _, err := s.Client.TenantManager().CreateTenant(context.Background(), &TenantToCreate{}); err != nil {
+		t.Fatal(err)
+	}
+	checkRequest(t, s, "/projects/mock-project-id/tenants", map[string]interface{}{})
+}
+
+func TestCreateTenantInvalid(t *testing.T) {
+	cases := []*TenantToCreate{
+		nil,
+		(&TenantToCreate{}).DisplayName(""),
+		(&TenantToCreate{}).DisplayName("abc"),
+		(&TenantToCreate{}).DisplayName("1-tenant"),
+		(&TenantToCreate{}).DisplayName("invalid_tenant"),
+		(&TenantToCreate{}).DisplayName("a-very-long-tenant-name"),
+		(&TenantToCreate{}).TOTPMultiFactor(true, -1),
+		(&TenantToCreate{}).TOTPMultiFactor(true, maxTOTPAdjacentIntervals+1),
+	}
+	for _, tc := range cases {
+		tenant, err := client.TenantManager().CreateTenant(context.Background(), tc)
+		if tenant != nil || err == nil {
+			t.Errorf("CreateTenant(%v) = (%v, %v); want = (nil, error)", tc, tenant, err)
+		}
+	}
+}
+
+func TestUpdateTenant(t *testing.T) {
+	s := echoServer([]byte(tenantResponseJSON), t)
+	defer s.Close()
+
+	options := (&TenantToUpdate{}).
+		DisplayName("Test-Tenant").
+		EnableAnonymousUsers(false)
+	tenant, err := s.Client.TenantManager().UpdateTenant(context.Background(), "tenantID", options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(tenant, testTenant) {
+		t.Errorf("UpdateTenant() = %#v; want = %#v", tenant, testTenant)
+	}
+
+	req := s.Req[0]
+	if req.Method != http.MethodPatch {
+		t.Errorf("Method = %q; want = %q", req.Method, http.MethodPatch)
+	}
+	wantMask := "displayName,enableAnonymousUser"
+	if mask := req.URL.Query().Get("updateMask"); mask != wantMask {
+		t.Errorf("updateMask = %q; want = %q", mask, wantMask)
+	}
+	want := map[string]interface{}{
+		"displayName":         "Test-Tenant",
+		"enableAnonymousUser": false,
+	}
+	checkRequest(t, s, "/projects/mock-project-id/tenants/tenantID", want)
+}
+
+func TestUpdateTenantTOTPMultiFactor(t *testing.T) {
+	s := echoServer([]byte(tenantResponseJSON), t)
+	defer s.Close()
+
+	options := (&TenantToUpdate{}).TOTPMultiFactor(false, 3)
+	if _, err := s.Client.TenantManager().UpdateTenant(context.Background(), "tenantID", options); err != nil {
+		t.Fatal(err)
+	}
+
+	if mask := s.Req[0].URL.Query().Get("updateMask"); mask != "mfaConfig.providerConfigs" {
+		t.Errorf("updateMask = %q; want = %q", mask, "mfaConfig.providerConfigs")
+	}
+	want := map[string]interface{}{
+		"mfaConfig": map[string]interface{}{
+			"providerConfigs": []interface{}{
+				map[string]interface{}{
+					"state":              "DISABLED",
+					"totpProviderConfig": map[string]interface{}{"adjacentIntervals": float64(3)},
+				},
+			},
+		},
+	}
+	checkRequest(t, s, "/projects/mock-project-id/tenants/tenantID", want)
+}
+
+func TestUpdateTenantInvalid(t *testing.T) {
+	tm := client.TenantManager()
+	valid := (&TenantToUpdate{}).AllowPasswordSignUp(true)
+	if tenant, err := tm.UpdateTenant(context.Background(), "", valid); tenant != nil || err == nil {
+		t.Errorf("UpdateTenant('') = (%v, %v); want = (nil, error)", tenant, err)
+	}
+	cases := []*TenantToUpdate{
+		nil,
+		{},
+		(&TenantToUpdate{}).DisplayName("abc"),
+		(&TenantToUpdate{}).TOTPMultiFactor(true, 11),
+	}
+	for _, tc := range cases {
+		if tenant, err := tm.UpdateTenant(context.Background(), "tenantID", tc); tenant != nil || err == nil {
+			t.Errorf("UpdateTenant(%v) = (%v, %v); want = (nil, error)", tc, tenant, err)
+		}
+	}
+}
+
+func TestDeleteTenant(t *testing.T) {
+	s := echoServer([]byte("{}"), t)
+	defer s.Close()
+
+	if err := s.Client.TenantManager().DeleteTenant(context.Background(), "tenantID"); err != nil {
+		t.Fatal(err)
+	}
+
+	req := s.Req[0]
+	if req.Method != http.MethodDelete {
+		t.Errorf("Method = %q; want = %q", req.Method, http.MethodDelete)
+	}
+	checkRequest(t, s, "/projects/mock-project-id/tenants/tenantID", nil)
+}
+
+func TestDeleteTenantEmptyID(t *testing.T) {
+	if err := client.TenantManager().DeleteTenant(context.Background(), ""); err == nil {
+		t.Errorf("DeleteTenant('') = nil; want = error")
+	}
+}
+
+func TestTenantIDEscaped(t *testing.T) {
+	s := echoServer([]byte(tenantResponseJSON), t)
+	defer s.Close()
+
+	const tenantID = "tenant/../other?x"
+	tm := s.Client.TenantManager()
+	ctx := context.Background()
+	if _, err := tm.Tenant(ctx, tenantID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tm.UpdateTenant(ctx, tenantID, (&TenantToUpdate{}).DisplayName("Test-Tenant")); err != nil {
+		t.Fatal(err)
+	}
+	if err := tm.DeleteTenant(ctx, tenantID); err != nil {
+		t.Fatal(err)
+	}
+	tc, err := tm.AuthForTenant(tenantID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tc.DeleteUser(ctx, "testuser"); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		"/projects/mock-project-id/tenants/tenant%2F..%2Fother%3Fx",
+		"/projects/mock-project-id/tenants/tenant%2F..%2Fother%3Fx",
+		"/projects/mock-project-id/tenants/tenant%2F..%2Fother%3Fx",
+		"/projects/mock-project-id/tenants/tenant%2F..%2Fother%3Fx/accounts:delete",
+	}
+	if len(s.Req) != len(want) {
+		t.Fatalf("Requests = %d; want = %d", len(s.Req), len(want))
+	}
+	for i, w := range want {
+		if got := s.Req[i].URL.EscapedPath(); got != w {
+			t.Errorf("Path = %q; want = %q", got, w)
+		}
+	}
+}
+
+func TestListTenants(t *testing.T) {
+	resp := `{
+		"tenants": [` + tenantResponseJSON + `],
+		"nextPageToken": "page3"
+	}`
+	s := echoServer([]byte(resp), t)
+	defer s.Close()
+
+	pager := iterator.NewPager(s.Client.TenantManager().ListTenants(context.Background()), 10, "page2")
+	var tenants []*Tenant
+	token, err := pager.NextPage(&tenants)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tenants) != 1 || !reflect.DeepEqual(tenants[0], testTenant) || token != "page3" {
+		t.Errorf("NextPage() = (%v, %q); want = ([%v], %q)", tenants, token, testTenant, "page3")
+	}
+	if q := s.Req[0].URL.RawQuery; q != "pageSize=10&pageToken=page2" {
+		t.Errorf("Query = %q; want = %q", q, "pageSize=10&pageToken=page2")
+	}
+	checkRequest(t, s, "/projects/mock-project-id/tenants", nil)
+}
+
+func TestListTenantsInvalidPageSize(t *testing.T) {
+	for _, size := range []int{0, maxListTenantsResults + 1} {
+		it := client.TenantManager().ListTenants(context.Background()).PageSize(size)
+		if _, err := it.Next(); err == nil {
+			t.Errorf("ListTenants(PageSize(%d)) = nil; want = error", size)
+		}
+	}
+}
+
+func TestAuthForTenant(t *testing.T) {
+	tc, err := client.TenantManager().AuthForTenant("tenantID")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tc.TenantID() != "tenantID" {
+		t.Errorf("TenantID() = %q; want = %q", tc.TenantID(), "tenantID")
+	}
+	if client.tenantID != "" {
+		t.Errorf("AuthForTenant() modified the tenant of the parent client: %q", client.tenantID)
+	}
+}
+
+func TestAuthForTenantEmptyID(t *testing.T) {
+	tc, err := client.TenantManager().AuthForTenant("")
+	if tc != nil || err == nil {
+		t.Errorf("AuthForTenant('') = (%v, %v); want = (nil, error)", tc, err)
+	}
+}
+
+func TestTenantVerifyIDToken(t *testing.T) {
+	tc, err := client.TenantManager().AuthForTenant("tenantID")
+	if err != nil {
+		t.Fatal(err)
+	}
+	token := getIDToken(mockIDTokenPayload{"firebase": map[string]interface{}{"tenant": "tenantID"}})
+	ft, err := tc.VerifyIDToken(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ft.UID != "1234567890" {
+		t.Errorf("UID = %q; want = %q", ft.UID, "1234567890")
+	}
+
+	cases := []struct {
+		name  string
+		token string
+	}{
+		{"OtherTenant", getIDToken(mockIDTokenPayload{"firebase": map[string]interface{}{"tenant": "other"}})},
+		{"NoTenant", testIDToken},
+	}
+	for _, c := range cases {
+		if ft, err := tc.VerifyIDToken(c.token); ft != nil || !IsTenantIDMismatch(err) {
+			t.Errorf("VerifyIDToken(%s) = (%v, %v); want = (nil, tenant-id-mismatch error)", c.name, ft, err)
+		}
+	}
+}
+
+func TestTenantVerifyIDTokenAndCheckRevoked(t *testing.T) {
+	s := echoServer(testGetUserResponse(t), t)
+	defer s.Close()
+	s.Client.ks = client.ks
+
+	tc, err := s.Client.TenantManager().AuthForTenant("tenantID")
+	if err != nil {
+		t.Fatal(err)
+	}
+	token := getIDToken(mockIDTokenPayload{"firebase": map[string]interface{}{"tenant": "tenantID"}})
+	if _, err := tc.VerifyIDTokenAndCheckRevoked(context.Background(), token); err != nil {
+		t.Fatal(err)
+	}
+	checkRequest(t, s, "/projects/mock-project-id/tenants/tenantID/accounts:lookup", map[string]interface{}{
+		"localId": []interface{}{"1234567890"},
+	})
+
+	if _, err := tc.VerifyIDTokenAndCheckRevoked(context.Background(), testIDToken); !IsTenantIDMismatch(err) {
+		t.Errorf("VerifyIDTokenAndCheckRevoked(NoTenant) = %v; want = tenant-id-mismatch error", err)
+	}
+}
+
+func TestTenantUserManagement(t *testing.T) {
+	s := echoServer(testGetUserResponse(t), t)
+	defer s.Close()
+
+	tc, err := s.Client.TenantManager().AuthForTenant("tenantID")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	cases := []struct {
+		name string
+		path string
+		call func() error
+	}{
+		{"GetUser", "accounts:lookup", func() error {
+			_, err := tc.GetUser(ctx, "testuser")
+			return err
+		}},
+		{"GetUsers", "accounts:lookup", func() error {
+			_, err := tc.GetUsers(ctx, []UserIdentifier{UIDIdentifier{UID: "testuser"}})
+			return err
+		}},
+		{"SetCustomUserClaims", "accounts:update", func() error {
+			return tc.SetCustomUserClaims(ctx, "testuser", map[string]interface{}{"admin": true})
+		}},
+		{"DeleteUser", "accounts:delete", func() error {
+			return tc.DeleteUser(ctx, "testuser")
+		}},
+		{"DeleteUsers", "accounts:batchDelete", func() error {
+			_, err := tc.DeleteUsers(ctx, []string{"testuser"})
+			return err
+		}},
+	}
+	for _, c := range cases {
+		if err := c.call(); err != nil {
+			t.Errorf("%s() = %v", c.name, err)
+			continue
+		}
+		checkRequest(t, s, "/projects/mock-project-id/tenants/tenantID/"+c.path, nil)
+	}
+}
+
+func TestTenantImportUsers(t *testing.T) {
+	s := echoServer([]byte("{}"), t)
+	defer s.Close()
+
+	tc, err := s.Client.TenantManager().AuthForTenant("tenantID")
+	if err != nil {
+		t.Fatal(err)
+	}
+	users := []*UserToImport{(&UserToImport{}).UID("user1")}
+	result, err := tc.ImportUsers(context.Background(), users)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.SuccessCount != 1 || result.FailureCount != 0 {
+		t.Errorf("ImportUsers() = %#v; want = {SuccessCount: 1}", result)
+	}
+	checkRequest(t, s, "/projects/mock-project-id/tenants/tenantID/accounts:batchCreate", nil)
+}
+
+func TestTenantImportUsersFromReader(t *testing.T) {
+	s := echoServer([]byte("{}"), t)
+	defer s.Close()
+
+	tc, err := s.Client.TenantManager().AuthForTenant("tenantID")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := strings.NewReader(`{"localId": "user1"}` + "\n")
+	result, err := tc.ImportUsersFromReader(context.Background(), r, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.SuccessCount != 1 {
+		t.Errorf("ImportUsersFromReader() = %#v; want = {SuccessCount: 1}", result)
+	}
+	checkRequest(t, s, "/projects/mock-project-id/tenants/tenantID/accounts:batchCreate", nil)
+}
+
+func TestTenantEmailActionLinks(t *testing.T) {
+	s := echoServer([]byte(testActionLinkJSON), t)
+	defer s.Close()
+
+	tc, err := s.Client.TenantManager().AuthForTenant("tenantID")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	cases := []struct {
+		name        string
+		requestType string
+		generate    func() (string, error)
+	}{
+		{"EmailVerificationLink", "VERIFY_EMAIL", func() (string, error) {
+			return tc.EmailVerificationLink(ctx, testEmail)
+		}},
+		{"EmailVerificationLinkWithSettings", "VERIFY_EMAIL", func() (string, error) {
+			return tc.EmailVerificationLinkWithSettings(ctx, testEmail, testActionCodeSettings)
+		}},
+		{"PasswordResetLink", "PASSWORD_RESET", func() (string, error) {
+			return tc.PasswordResetLink(ctx, testEmail)
+		}},
+		{"PasswordResetLinkWithSettings", "PASSWORD_RESET", func() (string, error) {
+			return tc.PasswordResetLinkWithSettings(ctx, testEmail, testActionCodeSettings)
+		}},
+		{"EmailSignInLink", "EMAIL_SIGNIN", func() (string, error) {
+			return tc.EmailSignInLink(ctx, testEmail, testActionCodeSettings)
+		}},
+	}
+	for _, c := range cases {
+		link, err := c.generate()
+		if err != nil {
+			t.Errorf("%s() = %v", c.name, err)
+			continue
+		}
+		if link != testActionLink {
+			t.Errorf("%s() = %q; want = %q", c.name, link, testActionLink)
+		}
+		checkRequest(t, s, "/projects/mock-project-id/tenants/tenantID/accounts:sendOobCode", nil)
+		var body map[string]interface{}
+		if err := json.Unmarshal(s.Rbody, &body); err != nil {
+			t.Fatal(err)
+		}
+		if body["requestType"] != c.requestType {
+			t.Errorf("%s() requestType = %v; want = %q", c.name, body["requestType"], c.requestType)
+		}
+	}
+}
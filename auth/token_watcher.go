@@ -0,0 +1,166 @@
+// Copyright 2017 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// WatchEvent is an event delivered by a TokenWatcher.
+type WatchEvent int
+
+const (
+	// RefreshNeeded indicates that the watched token is about to expire, and the client should be asked
+	// to re-authenticate with a fresh ID token.
+	RefreshNeeded WatchEvent = iota
+
+	// Expired indicates that the watched token has expired, and the grace window has elapsed without a
+	// fresh token being supplied. The connection should be terminated.
+	Expired
+)
+
+func (e WatchEvent) String() string {
+	switch e {
+	case RefreshNeeded:
+		return "RefreshNeeded"
+	case Expired:
+		return "Expired"
+	default:
+		return fmt.Sprintf("WatchEvent(%d)", int(e))
+	}
+}
+
+// TokenWatcher tracks the expiry of a verified ID token on behalf of a long-lived connection, such as
+// a WebSocket or a streaming RPC.
+//
+// A TokenWatcher delivers a RefreshNeeded event on C when the token enters its refresh window, and an
+// Expired event when the token has expired and the grace window has also elapsed. Calling Refresh with
+// a newly verified token restarts the tracking. Each event is delivered at most once per token.
+type TokenWatcher struct {
+	// C is the channel on which watch events are delivered.
+	C <-chan WatchEvent
+
+	c       chan WatchEvent
+	window  time.Duration
+	grace   time.Duration
+	mutex   sync.Mutex
+	uid     string
+	gen     int
+	timer   *time.Timer
+	stopped bool
+}
+
+// NewTokenWatcher creates a new TokenWatcher for the given verified ID token.
+//
+// The refreshWindow specifies how long before the token expiry the RefreshNeeded event is delivered.
+// The grace duration specifies how long after the token expiry the connection is allowed to remain
+// open, before the Expired event is delivered.
+func NewTokenWatcher(token *Token, refreshWindow, grace time.Duration) (*TokenWatcher, error) {
+	if token == nil {
+		return nil, errors.New("token must not be nil")
+	}
+	if refreshWindow < 0 || grace < 0 {
+		return nil, errors.New("refresh window and grace durations must not be negative")
+	}
+	c := make(chan WatchEvent, 2)
+	w := &TokenWatcher{
+		C:      c,
+		c:      c,
+		window: refreshWindow,
+		grace:  grace,
+		uid:    token.UID,
+	}
+	w.schedule(token)
+	return w, nil
+}
+
+// Refresh replaces the watched token with a newly verified ID token, and resets the watch events. Any
+// undelivered events for the previous token are discarded.
+//
+// The new token must belong to the same user as the token the TokenWatcher was created with.
+func (w *TokenWatcher) Refresh(token *Token) error {
+	if token == nil {
+		return errors.New("token must not be nil")
+	}
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if w.stopped {
+		return errors.New("token watcher has been stopped")
+	}
+	if token.UID != w.uid {
+		return fmt.Errorf("token belongs to user %q; want: %q", token.UID, w.uid)
+	}
+	w.stopTimers()
+	for len(w.c) > 0 {
+		<-w.c
+	}
+	w.scheduleLocked(token)
+	return nil
+}
+
+// Stop stops the TokenWatcher. No events are delivered after Stop returns.
+func (w *TokenWatcher) Stop() {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	w.stopped = true
+	w.stopTimers()
+}
+
+func (w *TokenWatcher) schedule(token *Token) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	w.scheduleLocked(token)
+}
+
+func (w *TokenWatcher) scheduleLocked(token *Token) {
+	w.gen++
+	gen := w.gen
+	exp := time.Unix(token.Expires, 0)
+	w.timer = time.AfterFunc(exp.Add(-w.window).Sub(clk.Now()), func() {
+		w.mutex.Lock()
+		defer w.mutex.Unlock()
+		if !w.deliverLocked(gen, RefreshNeeded) {
+			return
+		}
+		// Scheduled only after RefreshNeeded, so that the events are always delivered in order.
+		w.timer = time.AfterFunc(exp.Add(w.grace).Sub(clk.Now()), func() {
+			w.mutex.Lock()
+			defer w.mutex.Unlock()
+			w.deliverLocked(gen, Expired)
+		})
+	})
+}
+
+func (w *TokenWatcher) stopTimers() {
+	if w.timer != nil {
+		w.timer.Stop()
+		w.timer = nil
+	}
+	w.gen++
+}
+
+func (w *TokenWatcher) deliverLocked(gen int, e WatchEvent) bool {
+	if w.stopped || gen != w.gen {
+		return false
+	}
+	select {
+	case w.c <- e:
+	default:
+	}
+	return true
+}
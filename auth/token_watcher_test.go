@@ -0,0 +1,156 @@
+// Copyright 2017 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"testing"
+	"time"
+)
+
+const watchTimeout = 5 * time.Second
+
+func expectEvent(t *testing.T, w *TokenWatcher, want WatchEvent) {
+	select {
+	case e := <-w.C:
+		if e != want {
+			t.Errorf("Event = %v; want = %v", e, want)
+		}
+	case <-time.After(watchTimeout):
+		t.Fatalf("no event received; want = %v", want)
+	}
+}
+
+func TestTokenWatcher(t *testing.T) {
+	exp := int64(1000)
+	window := 10 * time.Second
+	grace := 5 * time.Second
+	// Start 50ms before the refresh window begins.
+	clk = &mockClock{now: time.Unix(exp, 0).Add(-window).Add(-50 * time.Millisecond)}
+	defer func() {
+		clk = &systemClock{}
+	}()
+
+	w, err := NewTokenWatcher(&Token{UID: "uid1", Expires: exp}, window, grace)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Stop()
+
+	expectEvent(t, w, RefreshNeeded)
+	select {
+	case e := <-w.C:
+		t.Errorf("Event = %v; want = none", e)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestTokenWatcherExpired(t *testing.T) {
+	exp := int64(1000)
+	grace := 5 * time.Second
+	// Start 50ms before the grace window ends.
+	clk = &mockClock{now: time.Unix(exp, 0).Add(grace).Add(-50 * time.Millisecond)}
+	defer func() {
+		clk = &systemClock{}
+	}()
+
+	w, err := NewTokenWatcher(&Token{UID: "uid1", Expires: exp}, time.Minute, grace)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Stop()
+
+	expectEvent(t, w, RefreshNeeded)
+	expectEvent(t, w, Expired)
+}
+
+func TestTokenWatcherRefresh(t *testing.T) {
+	exp := int64(1000)
+	clk = &mockClock{now: time.Unix(exp, 0)}
+	defer func() {
+		clk = &systemClock{}
+	}()
+
+	w, err := NewTokenWatcher(&Token{UID: "uid1", Expires: exp}, time.Minute, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Stop()
+	expectEvent(t, w, RefreshNeeded)
+
+	if err := w.Refresh(&Token{UID: "uid1", Expires: exp + 3600}); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case e := <-w.C:
+		t.Errorf("Event = %v; want = none", e)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestTokenWatcherRefreshError(t *testing.T) {
+	w, err := NewTokenWatcher(&Token{UID: "uid1", Expires: time.Now().Unix() + 3600}, time.Minute, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := w.Refresh(nil); err == nil {
+		t.Error("Refresh(nil) = nil; want = error")
+	}
+	if err := w.Refresh(&Token{UID: "uid2"}); err == nil {
+		t.Error("Refresh(otherUser) = nil; want = error")
+	}
+	w.Stop()
+	if err := w.Refresh(&Token{UID: "uid1"}); err == nil {
+		t.Error("Refresh(afterStop) = nil; want = error")
+	}
+}
+
+func TestTokenWatcherStop(t *testing.T) {
+	exp := int64(1000)
+	clk = &mockClock{now: time.Unix(exp, 0).Add(-50 * time.Millisecond)}
+	defer func() {
+		clk = &systemClock{}
+	}()
+
+	w, err := NewTokenWatcher(&Token{UID: "uid1", Expires: exp}, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Stop()
+	select {
+	case e := <-w.C:
+		t.Errorf("Event = %v; want = none", e)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestNewTokenWatcherError(t *testing.T) {
+	token := &Token{UID: "uid1"}
+	cases := []struct {
+		name   string
+		token  *Token
+		window time.Duration
+		grace  time.Duration
+	}{
+		{"NilToken", nil, 0, 0},
+		{"NegativeWindow", token, -1, 0},
+		{"NegativeGrace", token, 0, -1},
+	}
+	for _, tc := range cases {
+		if w, err := NewTokenWatcher(tc.token, tc.window, tc.grace); w != nil || err == nil {
+			t.Errorf("NewTokenWatcher(%q) = (%v, %v); want = (nil, error)", tc.name, w, err)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+// Copyright 2017 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"golang.org/x/net/context"
+)
+
+// userCheckpoint is the JSON representation of a checkpoint of a UserIterator.
+type userCheckpoint struct {
+	PageToken string `json:"pageToken,omitempty"`
+	PageSize  int    `json:"pageSize"`
+	Offset    int    `json:"offset,omitempty"`
+}
+
+// Checkpoint returns an opaque string that identifies the position of the iterator, from which a new
+// iterator can resume the listing with ResumeListUsers, for instance after a crash.
+//
+// Unlike the page tokens of the google.golang.org/api/iterator package, checkpoints account for the
+// users of the current page that have not been returned by Next yet, so that a resumed listing neither
+// skips nor repeats users. Checkpoint returns an empty string once all users have been returned.
+// Checkpoints are safe to store and to pass between processes.
+func (it *UserIterator) Checkpoint() string {
+	cp := &userCheckpoint{PageSize: it.pageInfo.MaxSize}
+	switch {
+	case !it.started:
+		cp.PageToken, cp.Offset = it.pageInfo.Token, it.skip
+	case len(it.users) > 0:
+		cp.PageToken, cp.PageSize, cp.Offset = it.pageToken, it.pageSize, it.offset
+	case it.pageInfo.Token == "":
+		return ""
+	default:
+		cp.PageToken = it.pageInfo.Token
+	}
+	b, _ := json.Marshal(cp)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// ResumeListUsers returns an iterator over the user accounts of the project, starting from the
+// position identified by a checkpoint previously returned by UserIterator.Checkpoint.
+//
+// The page size of the original iterator is restored from the checkpoint, and must not be changed.
+// If the original iterator had a filter, the same filter must be set on the returned iterator before
+// the first call to Next.
+func (c *Client) ResumeListUsers(ctx context.Context, checkpoint string) (*UserIterator, error) {
+	b, err := base64.RawURLEncoding.DecodeString(checkpoint)
+	if err != nil {
+		return nil, fmt.Errorf("malformed checkpoint: %v", err)
+	}
+	var cp userCheckpoint
+	if err := json.Unmarshal(b, &cp); err != nil {
+		return nil, fmt.Errorf("malformed checkpoint: %v", err)
+	}
+	if cp.PageSize < 1 || cp.PageSize > maxListUsersResults || cp.Offset < 0 {
+		return nil, errors.New("malformed checkpoint: invalid page size or offset")
+	}
+
+	it := c.ListUsers(ctx)
+	it.pageInfo.Token = cp.PageToken
+	it.pageInfo.MaxSize = cp.PageSize
+	it.skip = cp.Offset
+	return it, nil
+}
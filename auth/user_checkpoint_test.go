@@ -0,0 +1,131 @@
+// Copyright 2017 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"reflect"
+	"testing"
+
+	"golang.org/x/net/context"
+	"google.golang.org/api/iterator"
+)
+
+var checkpointPages = map[string]string{
+	"": `{
+		"users": [{"localId": "uid1"}, {"localId": "uid2"}, {"localId": "uid3"}],
+		"nextPageToken": "page2"
+	}`,
+	"page2": `{
+		"users": [{"localId": "uid4"}, {"localId": "uid5"}]
+	}`,
+}
+
+func nextUIDs(t *testing.T, it *UserIterator, n int) []string {
+	var uids []string
+	for n < 0 || len(uids) < n {
+		user, err := it.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		uids = append(uids, user.UID)
+	}
+	return uids
+}
+
+func TestUserIteratorCheckpoint(t *testing.T) {
+	s := newPagedUsersServer(t, checkpointPages, nil)
+	defer s.Close()
+
+	cases := []struct {
+		consumed int
+		want     []string
+	}{
+		{0, []string{"uid1", "uid2", "uid3", "uid4", "uid5"}},
+		{1, []string{"uid2", "uid3", "uid4", "uid5"}},
+		{3, []string{"uid4", "uid5"}},
+		{4, []string{"uid5"}},
+	}
+	for _, tc := range cases {
+		it := s.Client.ListUsers(context.Background()).PageSize(3)
+		nextUIDs(t, it, tc.consumed)
+		checkpoint := it.Checkpoint()
+		if checkpoint == "" {
+			t.Fatalf("Checkpoint(%d) = %q; want = non-empty", tc.consumed, checkpoint)
+		}
+
+		s.Queries = nil
+		resumed, err := s.Client.ResumeListUsers(context.Background(), checkpoint)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := nextUIDs(t, resumed, -1); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("ResumeListUsers(%d) = %v; want = %v", tc.consumed, got, tc.want)
+		}
+		for _, q := range s.Queries {
+			if q != "maxResults=3" && q != "maxResults=3&nextPageToken=page2" {
+				t.Errorf("ResumeListUsers(%d) query = %q; want page size 3", tc.consumed, q)
+			}
+		}
+	}
+}
+
+func TestUserIteratorCheckpointDone(t *testing.T) {
+	s := newPagedUsersServer(t, checkpointPages, nil)
+	defer s.Close()
+
+	it := s.Client.ListUsers(context.Background())
+	nextUIDs(t, it, -1)
+	if checkpoint := it.Checkpoint(); checkpoint != "" {
+		t.Errorf("Checkpoint() = %q; want = %q", checkpoint, "")
+	}
+}
+
+func TestUserIteratorCheckpointChained(t *testing.T) {
+	s := newPagedUsersServer(t, checkpointPages, nil)
+	defer s.Close()
+
+	it := s.Client.ListUsers(context.Background()).PageSize(3)
+	var uids []string
+	for checkpoint := it.Checkpoint(); checkpoint != ""; checkpoint = it.Checkpoint() {
+		resumed, err := s.Client.ResumeListUsers(context.Background(), checkpoint)
+		if err != nil {
+			t.Fatal(err)
+		}
+		it = resumed
+		uids = append(uids, nextUIDs(t, it, 2)...)
+	}
+	if want := []string{"uid1", "uid2", "uid3", "uid4", "uid5"}; !reflect.DeepEqual(uids, want) {
+		t.Errorf("ResumeListUsers() = %v; want = %v", uids, want)
+	}
+}
+
+func TestResumeListUsersInvalid(t *testing.T) {
+	cases := []string{
+		"",
+		"not base64!",
+		"bm90IGpzb24",
+		"eyJwYWdlU2l6ZSI6MH0",                  // {"pageSize":0}
+		"eyJwYWdlU2l6ZSI6MTAwMX0",              // {"pageSize":1001}
+		"eyJwYWdlU2l6ZSI6MTAsIm9mZnNldCI6LTF9", // {"pageSize":10,"offset":-1}
+	}
+	for _, tc := range cases {
+		if it, err := client.ResumeListUsers(context.Background(), tc); it != nil || err == nil {
+			t.Errorf("ResumeListUsers(%q) = (%v, %v); want = (nil, error)", tc, it, err)
+		}
+	}
+}
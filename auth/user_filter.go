@@ -0,0 +1,79 @@
+// Copyright 2017 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import "time"
+
+// UserFilter selects the user accounts returned by a UserIterator. A user matches the filter if it
+// matches all the fields of the filter that are set.
+type UserFilter struct {
+	// Disabled, if not nil, selects the users whose accounts are disabled when true, or enabled when
+	// false.
+	Disabled *bool
+
+	// ProviderID, if not empty, selects the users linked to the given identity provider, such as
+	// "password", "phone" or "google.com".
+	ProviderID string
+
+	// CreatedAfter and CreatedBefore, if not zero, select the users created at or after, and before,
+	// the given times respectively.
+	CreatedAfter  time.Time
+	CreatedBefore time.Time
+
+	// Match, if not nil, selects the users for which it returns true. It is called last, and only for
+	// users that match the other fields of the filter.
+	Match func(user *UserRecord) bool
+}
+
+// Filter restricts the users returned by the iterator to those that match the given filter. Pass nil
+// to return all users. Filter should be called before the first call to Next.
+//
+// The user listing API of the server does not support filtering by these attributes. Hence every page
+// of users is still fetched from the server, and the users that do not match the filter are discarded
+// as soon as the page is received, without being buffered by the iterator. As a result, the pages
+// returned by iterator.Pager may hold fewer users than the page size, and may be empty.
+func (it *UserIterator) Filter(filter *UserFilter) *UserIterator {
+	it.filter = filter
+	return it
+}
+
+func (f *UserFilter) matches(u *UserRecord) bool {
+	if f.Disabled != nil && *f.Disabled != u.Disabled {
+		return false
+	}
+	if f.ProviderID != "" && !hasProvider(u, f.ProviderID) {
+		return false
+	}
+	var created time.Time
+	if u.UserMetadata != nil {
+		created = time.Unix(0, u.UserMetadata.CreationTimestamp*int64(time.Millisecond))
+	}
+	if !f.CreatedAfter.IsZero() && created.Before(f.CreatedAfter) {
+		return false
+	}
+	if !f.CreatedBefore.IsZero() && !created.Before(f.CreatedBefore) {
+		return false
+	}
+	return f.Match == nil || f.Match(u)
+}
+
+func hasProvider(u *UserRecord, providerID string) bool {
+	for _, p := range u.ProviderUserInfo {
+		if p != nil && p.ProviderID == providerID {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,104 @@
+// Copyright 2017 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+	"google.golang.org/api/iterator"
+)
+
+var filterPages = map[string]string{
+	"": `{
+		"users": [
+			{"localId": "uid1", "disabled": true, "createdAt": "1000000"},
+			{"localId": "uid2", "createdAt": "2000000", "providerUserInfo": [{"providerId": "google.com", "rawId": "g2"}]}
+		],
+		"nextPageToken": "page2"
+	}`,
+	"page2": `{
+		"users": [{"localId": "uid3", "disabled": true, "createdAt": "3000000"}],
+		"nextPageToken": "page3"
+	}`,
+	"page3": `{
+		"users": [
+			{"localId": "uid4", "disabled": true, "createdAt": "4000000", "providerUserInfo": [{"providerId": "google.com", "rawId": "g4"}]}
+		]
+	}`,
+}
+
+func filteredUIDs(t *testing.T, filter *UserFilter) []string {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(filterPages[r.URL.Query().Get("nextPageToken")]))
+	}))
+	defer s.Close()
+	c := *client
+	c.userEndpoint = s.URL
+
+	it := c.ListUsers(context.Background()).Filter(filter)
+	var uids []string
+	for {
+		user, err := it.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		uids = append(uids, user.UID)
+	}
+	return uids
+}
+
+func TestListUsersFilter(t *testing.T) {
+	disabled := true
+	enabled := false
+	cases := []struct {
+		name   string
+		filter *UserFilter
+		want   []string
+	}{
+		{"nil", nil, []string{"uid1", "uid2", "uid3", "uid4"}},
+		{"empty", &UserFilter{}, []string{"uid1", "uid2", "uid3", "uid4"}},
+		{"disabled", &UserFilter{Disabled: &disabled}, []string{"uid1", "uid3", "uid4"}},
+		{"enabled", &UserFilter{Disabled: &enabled}, []string{"uid2"}},
+		{"provider", &UserFilter{ProviderID: "google.com"}, []string{"uid2", "uid4"}},
+		{"createdAfter", &UserFilter{CreatedAfter: time.Unix(3000, 0)}, []string{"uid3", "uid4"}},
+		{"createdBefore", &UserFilter{CreatedBefore: time.Unix(3000, 0)}, []string{"uid1", "uid2"}},
+		{
+			"combined",
+			&UserFilter{Disabled: &disabled, ProviderID: "google.com", CreatedAfter: time.Unix(2000, 0)},
+			[]string{"uid4"},
+		},
+		{
+			"match",
+			&UserFilter{Disabled: &disabled, Match: func(u *UserRecord) bool { return !strings.HasSuffix(u.UID, "1") }},
+			[]string{"uid3", "uid4"},
+		},
+		{"none", &UserFilter{ProviderID: "github.com"}, nil},
+	}
+	for _, tc := range cases {
+		if got := filteredUIDs(t, tc.filter); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("ListUsers(%s) = %v; want = %v", tc.name, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+// Copyright 2017 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"time"
+
+	"golang.org/x/net/context"
+	"google.golang.org/api/iterator"
+)
+
+// UserMetrics summarizes the user accounts of a project, for usage and cost reporting.
+//
+// A user is active within a period if it signed in, or refreshed an ID token, during that period.
+// DailyActiveUsers and MonthlyActiveUsers count the users active within the last 24 hours and the last
+// 30 days respectively. They approximate, but may differ from, the monthly active users billed by
+// Identity Platform, which counts the users active within each calendar month.
+//
+// ProviderCounts maps each sign-in provider ID, such as "password" or "google.com", to the number of
+// users linked to it. AnonymousUsers is the number of users without any email address, phone number or
+// linked provider. The user records do not tell anonymous users apart from users signed in with custom
+// tokens, so AnonymousUsers also counts the latter.
+type UserMetrics struct {
+	TotalUsers         int
+	DisabledUsers      int
+	AnonymousUsers     int
+	DailyActiveUsers   int
+	MonthlyActiveUsers int
+	ProviderCounts     map[string]int
+}
+
+// UserMetrics computes the UserMetrics of the project.
+//
+// The metrics are computed from all the user accounts, listed with ListUsers. Hence UserMetrics makes
+// one request per 1000 users, and should not be called more often than needed for reporting.
+func (c *Client) UserMetrics(ctx context.Context) (*UserMetrics, error) {
+	now := clk.Now()
+	dayStart := now.Add(-24*time.Hour).UnixNano() / int64(time.Millisecond)
+	monthStart := now.Add(-30*24*time.Hour).UnixNano() / int64(time.Millisecond)
+
+	metrics := &UserMetrics{ProviderCounts: make(map[string]int)}
+	iter := c.ListUsers(ctx)
+	for {
+		user, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		metrics.TotalUsers++
+		if user.Disabled {
+			metrics.DisabledUsers++
+		}
+		if hasNoIdentity(user) {
+			metrics.AnonymousUsers++
+		}
+		for _, p := range user.ProviderUserInfo {
+			metrics.ProviderCounts[p.ProviderID]++
+		}
+		if active := lastActivity(user); active >= dayStart {
+			metrics.DailyActiveUsers++
+			metrics.MonthlyActiveUsers++
+		} else if active >= monthStart {
+			metrics.MonthlyActiveUsers++
+		}
+	}
+	return metrics, nil
+}
+
+// lastActivity returns the time in milliseconds since epoch at which the user last signed in or
+// refreshed an ID token, or 0 if unknown.
+func lastActivity(user *UserRecord) int64 {
+	if user.UserMetadata == nil {
+		return 0
+	}
+	if user.UserMetadata.LastRefreshTimestamp > user.UserMetadata.LastLogInTimestamp {
+		return user.UserMetadata.LastRefreshTimestamp
+	}
+	return user.UserMetadata.LastLogInTimestamp
+}
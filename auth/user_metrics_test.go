@@ -0,0 +1,89 @@
+// Copyright 2017 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+// Users active 1 hour, 10 days and 60 days before a clock at 100 days.
+const userMetricsResponse = `{
+	"users": [
+		{
+			"localId": "user1",
+			"email": "user1@example.com",
+			"lastLoginAt": "5184000000",
+			"lastRefreshAt": "1970-04-10T23:00:00Z",
+			"providerUserInfo": [{"providerId": "password"}, {"providerId": "google.com"}]
+		},
+		{
+			"localId": "user2",
+			"lastLoginAt": "7776000000",
+			"providerUserInfo": [{"providerId": "google.com"}]
+		},
+		{
+			"localId": "user3",
+			"phoneNumber": "+15555550100",
+			"disabled": true,
+			"lastLoginAt": "3456000000",
+			"providerUserInfo": [{"providerId": "phone"}]
+		},
+		{"localId": "anon1"}
+	]
+}`
+
+func TestUserMetrics(t *testing.T) {
+	s := echoServer([]byte(userMetricsResponse), t)
+	defer s.Close()
+	clk = &mockClock{now: time.Unix(100*24*3600, 0)}
+	defer func() {
+		clk = &systemClock{}
+	}()
+
+	metrics, err := s.Client.UserMetrics(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &UserMetrics{
+		TotalUsers:         4,
+		DisabledUsers:      1,
+		AnonymousUsers:     1,
+		DailyActiveUsers:   1,
+		MonthlyActiveUsers: 2,
+		ProviderCounts: map[string]int{
+			"password":   1,
+			"google.com": 2,
+			"phone":      1,
+		},
+	}
+	if !reflect.DeepEqual(metrics, want) {
+		t.Errorf("UserMetrics() = %#v; want = %#v", metrics, want)
+	}
+}
+
+func TestUserMetricsError(t *testing.T) {
+	s := echoServer([]byte(`{"error": {"message": "QUOTA_EXCEEDED"}}`), t)
+	defer s.Close()
+	s.Status = http.StatusTooManyRequests
+
+	if metrics, err := s.Client.UserMetrics(context.Background()); metrics != nil || !IsQuotaExceeded(err) {
+		t.Errorf("UserMetrics() = (%v, %v); want = (nil, quota-exceeded error)", metrics, err)
+	}
+}
@@ -0,0 +1,1115 @@
+// Copyright 2017 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"firebase.google.com/go/internal"
+
+	"golang.org/x/net/context"
+	"google.golang.org/api/iterator"
+)
+
+const idToolkitV1Endpoint = "https://identitytoolkit.googleapis.com/v1"
+
+// maxGetUsersBatchSize is the maximum number of identifiers that can be looked up in one GetUsers call.
+const maxGetUsersBatchSize = 100
+
+// maxDeleteUsersBatchSize is the maximum number of users that can be deleted in one DeleteUsers call.
+const maxDeleteUsersBatchSize = 1000
+
+// maxListUsersResults is the maximum number of users that can be retrieved in one page of ListUsers.
+const maxListUsersResults = 1000
+
+// maxClaimsPayloadSize is the maximum size of the serialized custom claims of a user, in bytes.
+const maxClaimsPayloadSize = 1000
+
+const (
+	configurationNotFound = "configuration-not-found"
+	emailAlreadyExists    = "email-already-exists"
+	idTokenRevoked        = "id-token-revoked"
+	invalidActionCode     = "invalid-action-code"
+	invalidCredential     = "invalid-credential"
+	passwordPolicyFailed  = "password-policy-failed"
+	quotaExceeded         = "quota-exceeded"
+	tenantIDMismatch      = "tenant-id-mismatch"
+	tenantNotFound        = "tenant-not-found"
+	unknown               = "unknown-error"
+	userDisabled          = "user-disabled"
+	userNotFound          = "user-not-found"
+)
+
+// serverError maps the error codes returned by the Identity Toolkit service to SDK error codes.
+var serverError = map[string]string{
+	"CONFIGURATION_NOT_FOUND":             configurationNotFound,
+	"EMAIL_EXISTS":                        emailAlreadyExists,
+	"EMAIL_NOT_FOUND":                     userNotFound,
+	"EXPIRED_OOB_CODE":                    invalidActionCode,
+	"INVALID_LOGIN_CREDENTIALS":           invalidCredential,
+	"INVALID_OOB_CODE":                    invalidActionCode,
+	"INVALID_PASSWORD":                    invalidCredential,
+	"PASSWORD_DOES_NOT_MEET_REQUIREMENTS": passwordPolicyFailed,
+	"QUOTA_EXCEEDED":                      quotaExceeded,
+	"TENANT_NOT_FOUND":                    tenantNotFound,
+	"TOO_MANY_ATTEMPTS_TRY_LATER":         quotaExceeded,
+	"USER_DISABLED":                       userDisabled,
+	"USER_NOT_FOUND":                      userNotFound,
+}
+
+// UserInfo is a collection of standard profile information for a user.
+type UserInfo struct {
+	DisplayName string `json:"displayName,omitempty"`
+	Email       string `json:"email,omitempty"`
+	PhoneNumber string `json:"phoneNumber,omitempty"`
+	PhotoURL    string `json:"photoUrl,omitempty"`
+	// In the ProviderUserInfo[] ProviderID can be a short domain name (e.g. google.com),
+	// or the identity of an OpenID identity provider.
+	// In UserRecord.UserInfo it will return the constant string "firebase".
+	ProviderID string `json:"providerId,omitempty"`
+	UID        string `json:"rawId,omitempty"`
+}
+
+// UserMetadata contains additional metadata associated with a user account.
+//
+// Timestamps are in milliseconds since epoch. PasswordUpdatedTimestamp is zero for users without a
+// password. LastRefreshTimestamp is the last time an ID token of the user was minted or refreshed,
+// which tracks the activity of signed in users more closely than LastLogInTimestamp. It is zero if the
+// user has never been active.
+type UserMetadata struct {
+	CreationTimestamp        int64
+	LastLogInTimestamp       int64
+	PasswordUpdatedTimestamp int64
+	LastRefreshTimestamp     int64
+}
+
+// UserRecord contains metadata associated with a Firebase user account.
+//
+// TokensValidAfterMillis is the time, in milliseconds since epoch, before which all the ID tokens of the
+// user are considered revoked. It is truncated to the closest second, and is updated whenever the
+// refresh tokens of the user are revoked, or the password of the user is changed.
+//
+// PasswordHash and PasswordSalt are the base64 encoded password hash and salt of the user, if the user
+// has a password. They are only returned to service accounts that are granted permission to read
+// password hashes, and hold the base64 encoding of "REDACTED" otherwise.
+type UserRecord struct {
+	*UserInfo
+	CustomClaims           map[string]interface{}
+	Disabled               bool
+	EmailVerified          bool
+	ProviderUserInfo       []*UserInfo
+	TokensValidAfterMillis int64
+	UserMetadata           *UserMetadata
+	MultiFactor            *MultiFactorSettings
+	PasswordHash           string
+	PasswordSalt           string
+}
+
+// IsConfigurationNotFound checks if the given error was due to a non-existing provider configuration.
+func IsConfigurationNotFound(err error) bool {
+	return internal.HasErrorCode(err, configurationNotFound)
+}
+
+// IsEmailAlreadyExists checks if the given error was due to an email address already in use by another
+// user.
+func IsEmailAlreadyExists(err error) bool {
+	return internal.HasErrorCode(err, emailAlreadyExists)
+}
+
+// IsInvalidActionCode checks if the given error was due to an email action code that is malformed,
+// expired or already used.
+func IsInvalidActionCode(err error) bool {
+	return internal.HasErrorCode(err, invalidActionCode)
+}
+
+// IsInvalidCredential checks if the given error was due to a password that does not match the user.
+//
+// Projects with email enumeration protection do not distinguish non-existing users from wrong
+// passwords, in which case IsInvalidCredential also returns true for non-existing users.
+func IsInvalidCredential(err error) bool {
+	return internal.HasErrorCode(err, invalidCredential)
+}
+
+// IsPasswordPolicyFailed checks if the given error was due to a new password that does not satisfy the
+// password policy of the project. Use PasswordPolicyFailures to obtain the requirements that the
+// password does not satisfy.
+func IsPasswordPolicyFailed(err error) bool {
+	return internal.HasErrorCode(err, passwordPolicyFailed)
+}
+
+// IsQuotaExceeded checks if the given error was due to the request quota of the project being exceeded.
+func IsQuotaExceeded(err error) bool {
+	return internal.HasErrorCode(err, quotaExceeded)
+}
+
+// IsIDTokenRevoked checks if the given error was due to a revoked ID token.
+func IsIDTokenRevoked(err error) bool {
+	return internal.HasErrorCode(err, idTokenRevoked)
+}
+
+// IsTenantIDMismatch checks if the given error was due to an ID token issued for another tenant than
+// the tenant of a TenantClient.
+func IsTenantIDMismatch(err error) bool {
+	return internal.HasErrorCode(err, tenantIDMismatch)
+}
+
+// IsTenantNotFound checks if the given error was due to a non-existing tenant.
+func IsTenantNotFound(err error) bool {
+	return internal.HasErrorCode(err, tenantNotFound)
+}
+
+// IsUserDisabled checks if the given error was due to a disabled user account.
+func IsUserDisabled(err error) bool {
+	return internal.HasErrorCode(err, userDisabled)
+}
+
+// IsUserNotFound checks if the given error was due to a non-existing user.
+func IsUserNotFound(err error) bool {
+	return internal.HasErrorCode(err, userNotFound)
+}
+
+// GetUser gets the user data corresponding to the specified user ID.
+//
+// If no user exists for the given ID, GetUser returns an error for which IsUserNotFound returns true.
+func (c *Client) GetUser(ctx context.Context, uid string) (*UserRecord, error) {
+	if err := validateUID(uid); err != nil {
+		return nil, err
+	}
+	users, err := c.lookupUsers(ctx, &lookupRequest{UIDs: []string{uid}})
+	if err != nil {
+		return nil, err
+	}
+	if len(users) == 0 {
+		return nil, internal.Errorf(userNotFound, "cannot find user from uid: %q", uid)
+	}
+	return users[0], nil
+}
+
+// UserIdentifier identifies a user to be looked up by GetUsers.
+//
+// UIDIdentifier, EmailIdentifier, PhoneIdentifier and ProviderIdentifier are the supported
+// implementations.
+type UserIdentifier interface {
+	validate() error
+	populate(req *lookupRequest)
+	matches(u *UserRecord) bool
+}
+
+// UIDIdentifier identifies a user by user ID.
+type UIDIdentifier struct {
+	UID string
+}
+
+func (id UIDIdentifier) validate() error {
+	return validateUID(id.UID)
+}
+
+func (id UIDIdentifier) populate(req *lookupRequest) {
+	req.UIDs = append(req.UIDs, id.UID)
+}
+
+func (id UIDIdentifier) matches(u *UserRecord) bool {
+	return id.UID == u.UID
+}
+
+// EmailIdentifier identifies a user by email address.
+type EmailIdentifier struct {
+	Email string
+}
+
+func (id EmailIdentifier) validate() error {
+	if id.Email == "" {
+		return errors.New("email must be a non-empty string")
+	}
+	return nil
+}
+
+func (id EmailIdentifier) populate(req *lookupRequest) {
+	req.Emails = append(req.Emails, id.Email)
+}
+
+func (id EmailIdentifier) matches(u *UserRecord) bool {
+	return strings.EqualFold(id.Email, u.Email)
+}
+
+// PhoneIdentifier identifies a user by phone number. The phone number is normalized as described in
+// NormalizePhoneNumber.
+type PhoneIdentifier struct {
+	PhoneNumber string
+}
+
+func (id PhoneIdentifier) validate() error {
+	_, err := NormalizePhoneNumber(id.PhoneNumber)
+	return err
+}
+
+func (id PhoneIdentifier) populate(req *lookupRequest) {
+	phone, _ := NormalizePhoneNumber(id.PhoneNumber)
+	req.PhoneNumbers = append(req.PhoneNumbers, phone)
+}
+
+func (id PhoneIdentifier) matches(u *UserRecord) bool {
+	phone, _ := NormalizePhoneNumber(id.PhoneNumber)
+	return phone == u.PhoneNumber
+}
+
+// ProviderIdentifier identifies a user by the user ID assigned to them by a federated identity
+// provider, such as google.com.
+type ProviderIdentifier struct {
+	ProviderID  string
+	ProviderUID string
+}
+
+func (id ProviderIdentifier) validate() error {
+	if id.ProviderID == "" {
+		return errors.New("provider id must be a non-empty string")
+	}
+	if id.ProviderUID == "" {
+		return errors.New("provider uid must be a non-empty string")
+	}
+	return nil
+}
+
+func (id ProviderIdentifier) populate(req *lookupRequest) {
+	req.FederatedUserIDs = append(req.FederatedUserIDs, &federatedUserID{
+		ProviderID: id.ProviderID,
+		RawID:      id.ProviderUID,
+	})
+}
+
+func (id ProviderIdentifier) matches(u *UserRecord) bool {
+	for _, p := range u.ProviderUserInfo {
+		if p.ProviderID == id.ProviderID && p.UID == id.ProviderUID {
+			return true
+		}
+	}
+	return false
+}
+
+// GetUsersResult is the result of the GetUsers function.
+type GetUsersResult struct {
+	// Users contains the user accounts that were found, in no particular order.
+	Users []*UserRecord
+	// NotFound contains the identifiers that did not match any user account.
+	NotFound []UserIdentifier
+}
+
+// GetUsers gets the user data corresponding to the specified identifiers.
+//
+// GetUsers accepts up to 100 identifiers of any supported type in a single call. Identifiers that do
+// not match any user account are reported in the NotFound field of the result, rather than as an error.
+// GetUsers does not guarantee any ordering of the returned users.
+func (c *Client) GetUsers(ctx context.Context, identifiers []UserIdentifier) (*GetUsersResult, error) {
+	if len(identifiers) == 0 {
+		return &GetUsersResult{}, nil
+	}
+	if len(identifiers) > maxGetUsersBatchSize {
+		return nil, fmt.Errorf("identifiers must not contain more than %d elements", maxGetUsersBatchSize)
+	}
+
+	req := &lookupRequest{}
+	for _, id := range identifiers {
+		if id == nil {
+			return nil, errors.New("identifiers must not contain nil elements")
+		}
+		if err := id.validate(); err != nil {
+			return nil, err
+		}
+		id.populate(req)
+	}
+
+	users, err := c.lookupUsers(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &GetUsersResult{Users: users}
+	for _, id := range identifiers {
+		found := false
+		for _, u := range users {
+			if id.matches(u) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			result.NotFound = append(result.NotFound, id)
+		}
+	}
+	return result, nil
+}
+
+// UserToUpdate is the parameter struct for the UpdateUser function.
+//
+// Only the attributes explicitly set on a UserToUpdate are changed. All other attributes of the user
+// account retain their current values. Setting DisplayName, PhotoURL or PhoneNumber to an empty string
+// removes the corresponding attribute from the user account.
+type UserToUpdate struct {
+	params map[string]interface{}
+}
+
+func (u *UserToUpdate) set(key string, value interface{}) *UserToUpdate {
+	if u.params == nil {
+		u.params = make(map[string]interface{})
+	}
+	u.params[key] = value
+	return u
+}
+
+// CustomClaims setter. Set to nil or an empty map to remove all custom claims from the user account.
+func (u *UserToUpdate) CustomClaims(claims map[string]interface{}) *UserToUpdate {
+	if claims == nil {
+		claims = make(map[string]interface{})
+	}
+	return u.set("customClaims", claims)
+}
+
+// Disabled setter.
+func (u *UserToUpdate) Disabled(disabled bool) *UserToUpdate {
+	return u.set("disableUser", disabled)
+}
+
+// DisplayName setter. Set to an empty string to remove the display name from the user account.
+func (u *UserToUpdate) DisplayName(name string) *UserToUpdate {
+	return u.set("displayName", name)
+}
+
+// Email setter.
+func (u *UserToUpdate) Email(email string) *UserToUpdate {
+	return u.set("email", email)
+}
+
+// EmailVerified setter.
+func (u *UserToUpdate) EmailVerified(verified bool) *UserToUpdate {
+	return u.set("emailVerified", verified)
+}
+
+// Password setter.
+func (u *UserToUpdate) Password(pw string) *UserToUpdate {
+	return u.set("password", pw)
+}
+
+// PhoneNumber setter. The phone number is normalized to E.164 format as described in
+// NormalizePhoneNumber. Set to an empty string to remove the phone number, and the phone sign-in
+// provider, from the user account.
+func (u *UserToUpdate) PhoneNumber(phone string) *UserToUpdate {
+	return u.set("phoneNumber", phone)
+}
+
+// PhotoURL setter. Set to an empty string to remove the photo URL from the user account.
+func (u *UserToUpdate) PhotoURL(url string) *UserToUpdate {
+	return u.set("photoUrl", url)
+}
+
+// ProvidersToDelete setter. Unlinks the identity providers with the given IDs, such as "google.com" or
+// "facebook.com", from the user account. The user can no longer sign in with an unlinked provider,
+// but the account and its other providers are retained.
+func (u *UserToUpdate) ProvidersToDelete(providerIDs []string) *UserToUpdate {
+	return u.set("deleteProvider", append([]string(nil), providerIDs...))
+}
+
+// MFASettings setter. Replaces the enrolled second factors of the user account with those specified.
+// Omit an enrolled factor to remove it, and set empty settings to remove all second factors. Phone
+// second factors without a UID are enrolled as new factors.
+func (u *UserToUpdate) MFASettings(settings MultiFactorSettings) *UserToUpdate {
+	return u.set("mfaSettings", &settings)
+}
+
+// validatedRequest validates the parameters set on the UserToUpdate, and builds the corresponding
+// accounts:update request for the specified user.
+func (u *UserToUpdate) validatedRequest(uid string) (map[string]interface{}, error) {
+	if err := validateUID(uid); err != nil {
+		return nil, err
+	}
+	if u == nil || len(u.params) == 0 {
+		return nil, errors.New("update parameters must not be nil or empty")
+	}
+
+	req := map[string]interface{}{"localId": uid}
+	var deleteAttrs []string
+	for k, v := range u.params {
+		req[k] = v
+	}
+	if name, ok := req["displayName"]; ok && name == "" {
+		delete(req, "displayName")
+		deleteAttrs = append(deleteAttrs, "DISPLAY_NAME")
+	}
+	if url, ok := req["photoUrl"]; ok && url == "" {
+		delete(req, "photoUrl")
+		deleteAttrs = append(deleteAttrs, "PHOTO_URL")
+	}
+	if len(deleteAttrs) > 0 {
+		req["deleteAttribute"] = deleteAttrs
+	}
+	if providers, ok := req["deleteProvider"]; ok {
+		for _, p := range providers.([]string) {
+			if p == "" {
+				return nil, errors.New("providers to delete must not contain empty strings")
+			}
+		}
+	}
+	if phone, ok := req["phoneNumber"]; ok {
+		if phone == "" {
+			delete(req, "phoneNumber")
+			providers, _ := req["deleteProvider"].([]string)
+			req["deleteProvider"] = appendProvider(providers, "phone")
+		} else {
+			normalized, err := NormalizePhoneNumber(phone.(string))
+			if err != nil {
+				return nil, err
+			}
+			req["phoneNumber"] = normalized
+		}
+	}
+	if email, ok := req["email"]; ok {
+		if err := validateEmail(email.(string)); err != nil {
+			return nil, err
+		}
+	}
+	if pw, ok := req["password"]; ok {
+		if err := validatePassword(pw.(string)); err != nil {
+			return nil, err
+		}
+	}
+	if claims, ok := req["customClaims"]; ok {
+		delete(req, "customClaims")
+		attrs, err := serializeCustomClaims(claims.(map[string]interface{}))
+		if err != nil {
+			return nil, err
+		}
+		req["customAttributes"] = attrs
+	}
+	if settings, ok := req["mfaSettings"]; ok {
+		delete(req, "mfaSettings")
+		enrollments, err := multiFactorEnrollments(settings.(*MultiFactorSettings))
+		if err != nil {
+			return nil, err
+		}
+		mfa := map[string]interface{}{}
+		if len(enrollments) > 0 {
+			mfa["enrollments"] = enrollments
+		}
+		req["mfa"] = mfa
+	}
+	return req, nil
+}
+
+// UpdateUser updates an existing user account with the specified properties, and returns the updated
+// user data.
+//
+// If no user exists for the given ID, UpdateUser returns an error for which IsUserNotFound returns true.
+func (c *Client) UpdateUser(ctx context.Context, uid string, user *UserToUpdate) (*UserRecord, error) {
+	if err := c.updateUser(ctx, uid, user); err != nil {
+		return nil, err
+	}
+	return c.GetUser(ctx, uid)
+}
+
+// SetCustomUserClaims sets additional claims on an existing user account.
+//
+// Custom claims set via this function can be used to define user roles and privilege levels. These
+// claims propagate to all the devices where the user is already signed in (after token expiration or
+// when token refresh is forced), and next time the user signs in. The claims can be accessed via the
+// user's ID token JWT. Reserved JWT claim names such as "sub" and "iss" cannot be used, and the claims
+// must not exceed 1000 bytes when serialized into JSON. Pass nil or an empty map to remove all custom
+// claims of the user.
+func (c *Client) SetCustomUserClaims(ctx context.Context, uid string, customClaims map[string]interface{}) error {
+	return c.updateUser(ctx, uid, (&UserToUpdate{}).CustomClaims(customClaims))
+}
+
+// maxDisableAttempts is the number of times DisableUserAndRevokeTokens and RevokeRefreshTokens attempt
+// to update a user.
+const maxDisableAttempts = 3
+
+// disableRetryDelay is the delay before the first retry of DisableUserAndRevokeTokens and
+// RevokeRefreshTokens, which doubles with each retry.
+var disableRetryDelay = 500 * time.Millisecond
+
+// DisableUserAndRevokeTokens disables the specified user account and revokes all its refresh tokens,
+// and returns the updated user data.
+//
+// Both changes are made by a single request, so that there is no window in which the user is disabled
+// while its refresh tokens are still valid, or the other way around. The user is then looked up to
+// confirm that it is disabled, and that its tokens are revoked. The request is retried up to two times
+// if it fails, or if the user is not in the expected state. ID tokens issued before the call remain
+// valid for VerifyIDToken until they expire, but are rejected by VerifyIDTokenAndCheckRevoked.
+//
+// If no user exists for the given ID, DisableUserAndRevokeTokens returns an error for which
+// IsUserNotFound returns true, without retrying.
+func (c *Client) DisableUserAndRevokeTokens(ctx context.Context, uid string) (*UserRecord, error) {
+	return c.revokeTokens(ctx, uid, true)
+}
+
+// RevokeRefreshTokens revokes all refresh tokens of the specified user account, without disabling it.
+//
+// The user is looked up to confirm that its tokens are revoked, and the request is retried in the same
+// way as by DisableUserAndRevokeTokens. ID tokens issued before the call remain valid for VerifyIDToken
+// until they expire, but are rejected by VerifyIDTokenAndCheckRevoked.
+func (c *Client) RevokeRefreshTokens(ctx context.Context, uid string) error {
+	_, err := c.revokeTokens(ctx, uid, false)
+	return err
+}
+
+// revokeTokens revokes the refresh tokens of the specified user, and disables it if disable is true.
+// It returns the user data, once it is confirmed to be in the expected state.
+func (c *Client) revokeTokens(ctx context.Context, uid string, disable bool) (*UserRecord, error) {
+	if err := validateUID(uid); err != nil {
+		return nil, err
+	}
+
+	var lastErr error
+	delay := disableRetryDelay
+	for attempt := 0; attempt < maxDisableAttempts; attempt++ {
+		if attempt > 0 {
+			if err := sleep(ctx, delay); err != nil {
+				return nil, err
+			}
+			delay *= 2
+		}
+
+		validSince := clk.Now().Unix()
+		req := map[string]interface{}{
+			"localId":    uid,
+			"validSince": strconv.FormatInt(validSince, 10),
+		}
+		if disable {
+			req["disableUser"] = true
+		}
+		var parsed struct {
+			UID string `json:"localId"`
+		}
+		if err := c.post(ctx, "/accounts:update", req, &parsed); err != nil {
+			if IsUserNotFound(err) {
+				return nil, err
+			}
+			lastErr = err
+			continue
+		}
+
+		user, err := c.GetUser(ctx, uid)
+		if err != nil {
+			if IsUserNotFound(err) {
+				return nil, err
+			}
+			lastErr = err
+			continue
+		}
+		if (user.Disabled || !disable) && user.TokensValidAfterMillis >= validSince*1000 {
+			return user, nil
+		}
+		if disable {
+			lastErr = fmt.Errorf("user %q is not disabled with revoked tokens after update", uid)
+		} else {
+			lastErr = fmt.Errorf("tokens of user %q are not revoked after update", uid)
+		}
+	}
+	return nil, lastErr
+}
+
+func (c *Client) updateUser(ctx context.Context, uid string, user *UserToUpdate) error {
+	req, err := user.validatedRequest(uid)
+	if err != nil {
+		return err
+	}
+	if claims, ok := user.params["customClaims"]; ok {
+		if err := c.saveClaimsHistory(ctx, uid, claims.(map[string]interface{})); err != nil {
+			return err
+		}
+	}
+
+	var parsed struct {
+		UID string `json:"localId"`
+	}
+	return c.post(ctx, "/accounts:update", req, &parsed)
+}
+
+// serializeCustomClaims validates the given custom claims, and serializes them into the JSON string
+// expected by the Identity Toolkit service.
+func serializeCustomClaims(claims map[string]interface{}) (string, error) {
+	for _, k := range reservedClaims {
+		if _, contains := claims[k]; contains {
+			return "", fmt.Errorf("claim %q is reserved and must not be set", k)
+		}
+	}
+	b, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	if len(b) > maxClaimsPayloadSize {
+		return "", fmt.Errorf("serialized custom claims must not exceed %d bytes", maxClaimsPayloadSize)
+	}
+	return string(b), nil
+}
+
+// DeleteUser deletes the user account corresponding to the specified user ID.
+//
+// If no user exists for the given ID, DeleteUser returns an error for which IsUserNotFound returns true.
+// Callers that only need to ensure that the account no longer exists may safely ignore such errors.
+func (c *Client) DeleteUser(ctx context.Context, uid string) error {
+	if err := validateUID(uid); err != nil {
+		return err
+	}
+	req := map[string]interface{}{"localId": uid}
+	var parsed struct {
+		Kind string `json:"kind"`
+	}
+	return c.post(ctx, "/accounts:delete", req, &parsed)
+}
+
+// DeleteUsersResult is the result of the DeleteUsers function.
+type DeleteUsersResult struct {
+	SuccessCount int
+	FailureCount int
+	Errors       []*DeleteUsersErrorInfo
+}
+
+// DeleteUsersErrorInfo describes the failure to delete one of the users in a DeleteUsers call.
+//
+// Index is the position of the failed user ID in the list passed to DeleteUsers.
+type DeleteUsersErrorInfo struct {
+	Index  int    `json:"index"`
+	UID    string `json:"localId"`
+	Reason string `json:"message"`
+}
+
+// DeleteUsers deletes the user accounts corresponding to the specified user IDs.
+//
+// DeleteUsers accepts up to 1000 user IDs in a single call. Users are deleted regardless of whether
+// their accounts are disabled, and user IDs that do not correspond to any existing account are counted
+// as successfully deleted. Failures to delete individual users are reported in the returned
+// DeleteUsersResult rather than as an error. DeleteUsers only returns an error when the batch as a
+// whole cannot be processed.
+func (c *Client) DeleteUsers(ctx context.Context, uids []string) (*DeleteUsersResult, error) {
+	if len(uids) == 0 {
+		return &DeleteUsersResult{}, nil
+	}
+	if len(uids) > maxDeleteUsersBatchSize {
+		return nil, fmt.Errorf("uids must not contain more than %d elements", maxDeleteUsersBatchSize)
+	}
+	for _, uid := range uids {
+		if err := validateUID(uid); err != nil {
+			return nil, err
+		}
+	}
+
+	req := map[string]interface{}{
+		"localIds": uids,
+		"force":    true,
+	}
+	var parsed struct {
+		Errors []*DeleteUsersErrorInfo `json:"errors"`
+	}
+	if err := c.post(ctx, "/accounts:batchDelete", req, &parsed); err != nil {
+		return nil, err
+	}
+	return &DeleteUsersResult{
+		SuccessCount: len(uids) - len(parsed.Errors),
+		FailureCount: len(parsed.Errors),
+		Errors:       parsed.Errors,
+	}, nil
+}
+
+// UserIterator is an iterator over the user accounts of a project.
+//
+// UserIterator is compatible with the google.golang.org/api/iterator package. Use iterator.NewPager to
+// retrieve users one page at a time, and to resume a listing from a previously returned page token.
+// Use Checkpoint and ResumeListUsers to resume a listing consumed with Next.
+type UserIterator struct {
+	client   *Client
+	ctx      context.Context
+	nextFunc func() error
+	pageInfo *iterator.PageInfo
+	users    []*UserRecord
+
+	prefetch bool
+	pending  *userPagePrefetch
+	filter   *UserFilter
+
+	// The position of the iterator, as reported by Checkpoint: the token and size of the page from
+	// which the buffered users were fetched, and the number of users of that page already returned or
+	// skipped.
+	started   bool
+	pageToken string
+	pageSize  int
+	offset    int
+	skip      int
+}
+
+// userPage is a page of user accounts fetched from the server.
+type userPage struct {
+	users         []*UserRecord
+	nextPageToken string
+	err           error
+}
+
+// userPagePrefetch is an in-flight fetch of the page identified by pageSize and pageToken.
+type userPagePrefetch struct {
+	pageSize  int
+	pageToken string
+	result    chan *userPage
+}
+
+// ListUsers returns an iterator over all the user accounts of the project.
+//
+// Users are fetched from the server in pages of up to 1000 accounts, as the iterator advances.
+func (c *Client) ListUsers(ctx context.Context) *UserIterator {
+	it := &UserIterator{
+		client: c,
+		ctx:    ctx,
+	}
+	it.pageInfo, it.nextFunc = iterator.NewPageInfo(
+		it.fetch,
+		func() int { return len(it.users) },
+		func() interface{} { b := it.users; it.users = nil; return b })
+	it.pageInfo.MaxSize = maxListUsersResults
+	return it
+}
+
+// PageSize sets the number of users retrieved from the server in each page. It must be between 1 and
+// 1000. PageSize should be called before the first call to Next.
+func (it *UserIterator) PageSize(size int) *UserIterator {
+	it.pageInfo.MaxSize = size
+	return it
+}
+
+// Prefetch enables or disables fetching the next page of users in the background, while the current
+// page is being consumed. This hides the latency of the server when listing a large number of users.
+// Errors encountered while prefetching are returned when the iterator advances to the page. Prefetch
+// should be called before the first call to Next. Cancel the context of the iterator to abort a
+// prefetch that is no longer needed.
+func (it *UserIterator) Prefetch(enabled bool) *UserIterator {
+	it.prefetch = enabled
+	return it
+}
+
+// PageInfo supports pagination. See the google.golang.org/api/iterator package for details.
+func (it *UserIterator) PageInfo() *iterator.PageInfo {
+	return it.pageInfo
+}
+
+// Next returns the next user account. Its second return value is iterator.Done if there are no more
+// results. Once Next returns iterator.Done, all subsequent calls will return iterator.Done.
+func (it *UserIterator) Next() (*UserRecord, error) {
+	if err := it.nextFunc(); err != nil {
+		return nil, err
+	}
+	user := it.users[0]
+	it.users = it.users[1:]
+	it.offset++
+	return user, nil
+}
+
+func (it *UserIterator) fetch(pageSize int, pageToken string) (string, error) {
+	var page *userPage
+	if p := it.pending; p != nil && p.pageSize == pageSize && p.pageToken == pageToken {
+		page = <-p.result
+	} else {
+		page = it.fetchPage(pageSize, pageToken)
+	}
+	it.pending = nil
+	if page.err != nil {
+		return "", page.err
+	}
+	it.started = true
+	it.pageToken, it.pageSize, it.offset = pageToken, pageSize, 0
+	users := page.users
+	if it.skip > 0 {
+		if it.skip > len(users) {
+			it.skip = len(users)
+		}
+		users, it.offset, it.skip = users[it.skip:], it.skip, 0
+	}
+	it.users = append(it.users, users...)
+
+	if it.prefetch && page.nextPageToken != "" {
+		p := &userPagePrefetch{
+			pageSize:  pageSize,
+			pageToken: page.nextPageToken,
+			result:    make(chan *userPage, 1),
+		}
+		go func() {
+			p.result <- it.fetchPage(p.pageSize, p.pageToken)
+		}()
+		it.pending = p
+	}
+	return page.nextPageToken, nil
+}
+
+// fetchPage fetches a page of users from the server. It does not modify the state of the iterator, so
+// that it can be called from a background goroutine.
+func (it *UserIterator) fetchPage(pageSize int, pageToken string) *userPage {
+	users, nextPageToken, err := it.client.fetchUsersPage(it.ctx, pageSize, pageToken)
+	if err != nil {
+		return &userPage{err: err}
+	}
+	page := &userPage{nextPageToken: nextPageToken}
+	for _, raw := range users {
+		var u userQueryResponse
+		if err := json.Unmarshal(raw, &u); err != nil {
+			return &userPage{err: err}
+		}
+		ur, err := u.makeUserRecord()
+		if err != nil {
+			return &userPage{err: err}
+		}
+		if it.filter == nil || it.filter.matches(ur) {
+			page.users = append(page.users, ur)
+		}
+	}
+	return page
+}
+
+// fetchUsersPage fetches a page of up to pageSize user accounts of the project from the server. It
+// returns the JSON representation of each account, which callers decode into the type they need, and
+// the token of the next page, which is empty on the last page.
+func (c *Client) fetchUsersPage(ctx context.Context, pageSize int,
+	pageToken string) ([]json.RawMessage, string, error) {
+	if pageSize < 1 || pageSize > maxListUsersResults {
+		return nil, "", fmt.Errorf("page size must be between 1 and %d", maxListUsersResults)
+	}
+	if c.projectID == "" {
+		return nil, "", errors.New("project id not available")
+	}
+
+	query := url.Values{}
+	query.Set("maxResults", strconv.Itoa(pageSize))
+	if pageToken != "" {
+		query.Set("nextPageToken", pageToken)
+	}
+	endpoint := fmt.Sprintf("%s/projects/%s/accounts:batchGet?%s", c.userEndpoint, c.projectID, query.Encode())
+
+	var parsed struct {
+		Users         []json.RawMessage `json:"users"`
+		NextPageToken string            `json:"nextPageToken"`
+	}
+	if err := c.makeRequest(ctx, http.MethodGet, endpoint, nil, &parsed); err != nil {
+		return nil, "", err
+	}
+	return parsed.Users, parsed.NextPageToken, nil
+}
+
+type federatedUserID struct {
+	ProviderID string `json:"providerId"`
+	RawID      string `json:"rawId"`
+}
+
+type lookupRequest struct {
+	UIDs             []string           `json:"localId,omitempty"`
+	Emails           []string           `json:"email,omitempty"`
+	PhoneNumbers     []string           `json:"phoneNumber,omitempty"`
+	FederatedUserIDs []*federatedUserID `json:"federatedUserId,omitempty"`
+}
+
+func (c *Client) lookupUsers(ctx context.Context, req *lookupRequest) ([]*UserRecord, error) {
+	var parsed struct {
+		Users []*userQueryResponse `json:"users"`
+	}
+	if err := c.post(ctx, "/accounts:lookup", req, &parsed); err != nil {
+		return nil, err
+	}
+
+	var users []*UserRecord
+	for _, u := range parsed.Users {
+		ur, err := u.makeUserRecord()
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, ur)
+	}
+	return users, nil
+}
+
+// userQueryResponse is the JSON representation of a user account returned by the Identity Toolkit
+// service.
+type userQueryResponse struct {
+	UID                string                     `json:"localId,omitempty"`
+	DisplayName        string                     `json:"displayName,omitempty"`
+	Email              string                     `json:"email,omitempty"`
+	PhoneNumber        string                     `json:"phoneNumber,omitempty"`
+	PhotoURL           string                     `json:"photoUrl,omitempty"`
+	CreationTimestamp  int64                      `json:"createdAt,string,omitempty"`
+	LastLogInTimestamp int64                      `json:"lastLoginAt,string,omitempty"`
+	PasswordHash       string                     `json:"passwordHash,omitempty"`
+	PasswordSalt       string                     `json:"salt,omitempty"`
+	PasswordUpdatedAt  float64                    `json:"passwordUpdatedAt,omitempty"`
+	LastRefreshAt      string                     `json:"lastRefreshAt,omitempty"`
+	CustomAttributes   string                     `json:"customAttributes,omitempty"`
+	ValidSinceSeconds  int64                      `json:"validSince,string,omitempty"`
+	Disabled           bool                       `json:"disabled,omitempty"`
+	EmailVerified      bool                       `json:"emailVerified,omitempty"`
+	ProviderUserInfo   []*UserInfo                `json:"providerUserInfo,omitempty"`
+	MFAInfo            []*multiFactorInfoResponse `json:"mfaInfo,omitempty"`
+}
+
+func (r *userQueryResponse) makeUserRecord() (*UserRecord, error) {
+	var customClaims map[string]interface{}
+	if r.CustomAttributes != "" {
+		if err := json.Unmarshal([]byte(r.CustomAttributes), &customClaims); err != nil {
+			return nil, err
+		}
+		if len(customClaims) == 0 {
+			customClaims = nil
+		}
+	}
+	mfa, err := makeMultiFactorSettings(r.MFAInfo)
+	if err != nil {
+		return nil, err
+	}
+	var lastRefresh int64
+	if r.LastRefreshAt != "" {
+		t, err := time.Parse(time.RFC3339Nano, r.LastRefreshAt)
+		if err != nil {
+			return nil, err
+		}
+		lastRefresh = t.UnixNano() / int64(time.Millisecond)
+	}
+
+	return &UserRecord{
+		UserInfo: &UserInfo{
+			DisplayName: r.DisplayName,
+			Email:       r.Email,
+			PhoneNumber: r.PhoneNumber,
+			PhotoURL:    r.PhotoURL,
+			ProviderID:  "firebase",
+			UID:         r.UID,
+		},
+		CustomClaims:           customClaims,
+		Disabled:               r.Disabled,
+		EmailVerified:          r.EmailVerified,
+		ProviderUserInfo:       r.ProviderUserInfo,
+		TokensValidAfterMillis: r.ValidSinceSeconds * 1000,
+		PasswordHash:           r.PasswordHash,
+		PasswordSalt:           r.PasswordSalt,
+		UserMetadata: &UserMetadata{
+			CreationTimestamp:        r.CreationTimestamp,
+			LastLogInTimestamp:       r.LastLogInTimestamp,
+			PasswordUpdatedTimestamp: int64(r.PasswordUpdatedAt),
+			LastRefreshTimestamp:     lastRefresh,
+		},
+		MultiFactor: mfa,
+	}, nil
+}
+
+// appendProvider appends the given provider ID to the list, unless it is already present.
+func appendProvider(providerIDs []string, providerID string) []string {
+	for _, p := range providerIDs {
+		if p == providerID {
+			return providerIDs
+		}
+	}
+	return append(providerIDs, providerID)
+}
+
+func validateUID(uid string) error {
+	if uid == "" {
+		return errors.New("uid must be a non-empty string")
+	}
+	if len(uid) > 128 {
+		return errors.New("uid string must not be longer than 128 characters")
+	}
+	return nil
+}
+
+func validateEmail(email string) error {
+	if email == "" {
+		return errors.New("email must be a non-empty string")
+	}
+	if parts := strings.Split(email, "@"); len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return fmt.Errorf("malformed email string: %q", email)
+	}
+	return nil
+}
+
+func validatePassword(pw string) error {
+	if len(pw) < 6 {
+		return errors.New("password must be a string at least 6 characters long")
+	}
+	return nil
+}
+
+// post sends a JSON request to the specified path of the Identity Toolkit user management API, and
+// unmarshals the JSON response into the variable pointed by v. The request is scoped to the tenant of
+// the Client, if any.
+func (c *Client) post(ctx context.Context, path string, body interface{}, v interface{}) error {
+	if c.projectID == "" {
+		return errors.New("project id not available")
+	}
+	endpoint := fmt.Sprintf("%s/projects/%s", c.userEndpoint, c.projectID)
+	if c.tenantID != "" {
+		endpoint = fmt.Sprintf("%s/tenants/%s", endpoint, url.PathEscape(c.tenantID))
+	}
+	return c.makeRequest(ctx, http.MethodPost, endpoint+path, body, v)
+}
+
+// makeRequest sends a request to the Identity Toolkit service, and unmarshals the JSON response into
+// the variable pointed by v. Error responses are converted into FirebaseError values.
+//
+// If the Client has a rate limit, the request waits for its turn, and is retried when the quota of the
+// project is exceeded.
+func (c *Client) makeRequest(ctx context.Context, method, url string, body interface{}, v interface{}) error {
+	if c.limiter != nil {
+		return c.limiter.do(ctx, func() error {
+			return c.sendRequest(ctx, method, url, body, v)
+		})
+	}
+	return c.sendRequest(ctx, method, url, body, v)
+}
+
+func (c *Client) sendRequest(ctx context.Context, method, url string, body interface{}, v interface{}) error {
+	resp, err := c.hc.Do(ctx, &internal.Request{
+		Method: method,
+		URL:    url,
+		Body:   body,
+	})
+	if err != nil {
+		return err
+	}
+	if resp.Status != http.StatusOK {
+		return handleServerError(resp)
+	}
+	return json.Unmarshal(resp.Body, v)
+}
+
+// handleServerError converts an error response from the Identity Toolkit service into a
+// FirebaseError.
+//
+// Error responses carry a message of the form "CODE" or "CODE : details". The code is mapped to
+// one of the SDK error codes defined in serverError.
+func handleServerError(resp *internal.Response) error {
+	var parsed struct {
+		Error struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	json.Unmarshal(resp.Body, &parsed) // ignore any json parse errors at this level
+
+	msg := parsed.Error.Message
+	serverCode := strings.TrimSpace(strings.SplitN(msg, ":", 2)[0])
+	code, ok := serverError[serverCode]
+	if !ok {
+		code = unknown
+	}
+	if msg == "" {
+		msg = string(resp.Body)
+	}
+	return internal.Errorf(code, "http error status: %d; reason: %s", resp.Status, msg)
+}
@@ -0,0 +1,1084 @@
+// Copyright 2017 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"firebase.google.com/go/internal"
+
+	"golang.org/x/net/context"
+	"google.golang.org/api/iterator"
+)
+
+var testUser = &UserRecord{
+	UserInfo: &UserInfo{
+		UID:         "testuser",
+		Email:       "testuser@example.com",
+		PhoneNumber: "+1234567890",
+		DisplayName: "Test User",
+		PhotoURL:    "http://www.example.com/testuser/photo.png",
+		ProviderID:  "firebase",
+	},
+	Disabled:      false,
+	EmailVerified: true,
+	ProviderUserInfo: []*UserInfo{
+		{
+			ProviderID:  "password",
+			DisplayName: "Test User",
+			PhotoURL:    "http://www.example.com/testuser/photo.png",
+			Email:       "testuser@example.com",
+			UID:         "testuid",
+		}, {
+			ProviderID:  "phone",
+			PhoneNumber: "+1234567890",
+			UID:         "testuid",
+		},
+	},
+	TokensValidAfterMillis: 1494364393000,
+	UserMetadata: &UserMetadata{
+		CreationTimestamp:        1234567890000,
+		LastLogInTimestamp:       1233211232000,
+		PasswordUpdatedTimestamp: 1494364393000,
+		LastRefreshTimestamp:     1494364393123,
+	},
+	CustomClaims: map[string]interface{}{"admin": true, "package": "gold"},
+	PasswordHash: "passwordhash",
+	PasswordSalt: "salt===",
+	MultiFactor: &MultiFactorSettings{
+		EnrolledFactors: []*MultiFactorInfo{
+			{
+				UID:                 "phoneFactor",
+				DisplayName:         "Work phone",
+				EnrollmentTimestamp: 1577836800000,
+				FactorID:            "phone",
+				PhoneNumber:         "+15555550101",
+			},
+			{
+				UID:                 "totpFactor",
+				DisplayName:         "Authenticator",
+				EnrollmentTimestamp: 1580515200500,
+				FactorID:            "totp",
+			},
+		},
+	},
+}
+
+func TestGetUser(t *testing.T) {
+	s := echoServer(testGetUserResponse(t), t)
+	defer s.Close()
+
+	user, err := s.Client.GetUser(context.Background(), "testuser")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(user, testUser) {
+		t.Errorf("GetUser() = %#v; want = %#v", user, testUser)
+	}
+
+	want := map[string]interface{}{"localId": []interface{}{"testuser"}}
+	checkRequest(t, s, "/projects/mock-project-id/accounts:lookup", want)
+}
+
+func TestGetUserInvalidUID(t *testing.T) {
+	cases := []string{"", strings.Repeat("a", 129)}
+	for _, uid := range cases {
+		user, err := client.GetUser(context.Background(), uid)
+		if user != nil || err == nil {
+			t.Errorf("GetUser(%q) = (%v, %v); want = (nil, error)", uid, user, err)
+		}
+	}
+}
+
+func TestGetNonExistingUser(t *testing.T) {
+	s := echoServer([]byte(`{"users": []}`), t)
+	defer s.Close()
+
+	user, err := s.Client.GetUser(context.Background(), "ignored_id")
+	if user != nil || !IsUserNotFound(err) {
+		t.Errorf("GetUser() = (%v, %v); want = (nil, user-not-found error)", user, err)
+	}
+}
+
+func TestGetUserHTTPError(t *testing.T) {
+	resp := `{"error": {"code": 400, "message": "USER_NOT_FOUND"}}`
+	s := echoServer([]byte(resp), t)
+	defer s.Close()
+	s.Status = http.StatusBadRequest
+
+	user, err := s.Client.GetUser(context.Background(), "ignored_id")
+	if user != nil || !IsUserNotFound(err) {
+		t.Errorf("GetUser() = (%v, %v); want = (nil, user-not-found error)", user, err)
+	}
+}
+
+func TestGetUserUnknownHTTPError(t *testing.T) {
+	resp := `{"error": {"code": 500, "message": "INTERNAL_ERROR : something went wrong"}}`
+	s := echoServer([]byte(resp), t)
+	defer s.Close()
+	s.Status = http.StatusInternalServerError
+
+	user, err := s.Client.GetUser(context.Background(), "ignored_id")
+	if user != nil || err == nil || IsUserNotFound(err) {
+		t.Fatalf("GetUser() = (%v, %v); want = (nil, error)", user, err)
+	}
+	if !internal.HasErrorCode(err, unknown) {
+		t.Errorf("GetUser() err = %v; want = unknown-error", err)
+	}
+}
+
+func TestGetUserInvalidCustomClaims(t *testing.T) {
+	resp := `{"users": [{"localId": "testuser", "customAttributes": "invalid"}]}`
+	s := echoServer([]byte(resp), t)
+	defer s.Close()
+
+	user, err := s.Client.GetUser(context.Background(), "testuser")
+	if user != nil || err == nil {
+		t.Errorf("GetUser() = (%v, %v); want = (nil, error)", user, err)
+	}
+}
+
+func TestGetUserInvalidLastRefreshTime(t *testing.T) {
+	resp := `{"users": [{"localId": "testuser", "lastRefreshAt": "invalid"}]}`
+	s := echoServer([]byte(resp), t)
+	defer s.Close()
+
+	user, err := s.Client.GetUser(context.Background(), "testuser")
+	if user != nil || err == nil {
+		t.Errorf("GetUser() = (%v, %v); want = (nil, error)", user, err)
+	}
+}
+
+func TestGetUserNoProjectID(t *testing.T) {
+	c, err := NewClient(context.Background(), &internal.AuthConfig{Opts: testOpts})
+	if err != nil {
+		t.Fatal(err)
+	}
+	user, err := c.GetUser(context.Background(), "testuser")
+	if user != nil || err == nil {
+		t.Errorf("GetUser() = (%v, %v); want = (nil, error)", user, err)
+	}
+}
+
+func testGetUserResponse(t *testing.T) []byte {
+	b, err := ioutil.ReadFile("../testdata/get_user.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func checkRequest(t *testing.T, s *mockAuthServer, wantPath string, wantBody map[string]interface{}) {
+	if len(s.Req) == 0 {
+		t.Fatal("no requests received by the mock server")
+	}
+	req := s.Req[len(s.Req)-1]
+	if req.URL.Path != wantPath {
+		t.Errorf("Path = %q; want = %q", req.URL.Path, wantPath)
+	}
+	if wantBody == nil {
+		return
+	}
+	var got map[string]interface{}
+	if err := json.Unmarshal(s.Rbody, &got); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, wantBody) {
+		t.Errorf("Body = %v; want = %v", got, wantBody)
+	}
+}
+
+type mockAuthServer struct {
+	Resp   []byte
+	Header map[string]string
+	Status int
+	Req    []*http.Request
+	Rbody  []byte
+	Srv    *httptest.Server
+	Client *Client
+}
+
+// echoServer takes either a []byte or a value that can be JSON-serialized, and starts an HTTP
+// server that responds to all requests with that content.
+func echoServer(resp interface{}, t *testing.T) *mockAuthServer {
+	var b []byte
+	var err error
+	switch v := resp.(type) {
+	case []byte:
+		b = v
+	default:
+		b, err = json.Marshal(resp)
+		if err != nil {
+			t.Fatal("marshaling error")
+		}
+	}
+	s := &mockAuthServer{Resp: b}
+
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close()
+		reqBody, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		s.Req = append(s.Req, r)
+		s.Rbody = reqBody
+		for k, v := range s.Header {
+			w.Header().Set(k, v)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if s.Status != 0 {
+			w.WriteHeader(s.Status)
+		}
+		w.Write(s.Resp)
+	}
+	s.Srv = httptest.NewServer(http.HandlerFunc(handler))
+
+	authClient, err := NewClient(context.Background(), &internal.AuthConfig{
+		Opts:      testOpts,
+		ProjectID: "mock-project-id",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	authClient.userEndpoint = s.Srv.URL
+	authClient.projectMgtEndpoint = s.Srv.URL
+	s.Client = authClient
+	return s
+}
+
+func (s *mockAuthServer) Close() {
+	s.Srv.Close()
+}
+
+// pagedUsersServer is a mock Identity Toolkit server that lists users in pages, keyed by page token.
+// It records the query of each request to list users.
+type pagedUsersServer struct {
+	Queries []string
+	Srv     *httptest.Server
+	Client  *Client
+}
+
+// newPagedUsersServer starts a pagedUsersServer that serves the given pages. Requests other than
+// listing users are passed to handler, which may be nil if the test makes no such requests.
+func newPagedUsersServer(t *testing.T, pages map[string]string, handler http.HandlerFunc) *pagedUsersServer {
+	s := &pagedUsersServer{}
+	s.Srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.Method == http.MethodGet && r.URL.Path == "/projects/mock-project-id/accounts:batchGet" {
+			s.Queries = append(s.Queries, r.URL.RawQuery)
+			token := r.URL.Query().Get("nextPageToken")
+			page, ok := pages[token]
+			if !ok {
+				t.Errorf("Page token = %q; want = one of the mock pages", token)
+			}
+			w.Write([]byte(page))
+			return
+		}
+		if handler == nil {
+			t.Errorf("Request = %s %s; want = list users", r.Method, r.URL.Path)
+			return
+		}
+		handler(w, r)
+	}))
+
+	c := *client
+	c.userEndpoint = s.Srv.URL
+	s.Client = &c
+	return s
+}
+
+func (s *pagedUsersServer) Close() {
+	s.Srv.Close()
+}
+
+func TestGetUsers(t *testing.T) {
+	resp := `{
+		"users": [
+			{"localId": "uid1"},
+			{"localId": "uid2", "email": "user2@example.com"},
+			{"localId": "uid3", "phoneNumber": "+15555550003"},
+			{"localId": "uid4", "providerUserInfo": [{"providerId": "google.com", "rawId": "google_uid4"}]}
+		]
+	}`
+	s := echoServer([]byte(resp), t)
+	defer s.Close()
+
+	identifiers := []UserIdentifier{
+		UIDIdentifier{"uid1"},
+		EmailIdentifier{"USER2@example.com"},
+		PhoneIdentifier{"+15555550003"},
+		ProviderIdentifier{"google.com", "google_uid4"},
+		UIDIdentifier{"uid_not_found"},
+		ProviderIdentifier{"facebook.com", "google_uid4"},
+	}
+	result, err := s.Client.GetUsers(context.Background(), identifiers)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Users) != 4 {
+		t.Errorf("GetUsers() = %d users; want = 4", len(result.Users))
+	}
+	wantNotFound := []UserIdentifier{
+		UIDIdentifier{"uid_not_found"},
+		ProviderIdentifier{"facebook.com", "google_uid4"},
+	}
+	if !reflect.DeepEqual(result.NotFound, wantNotFound) {
+		t.Errorf("GetUsers().NotFound = %v; want = %v", result.NotFound, wantNotFound)
+	}
+
+	want := map[string]interface{}{
+		"localId":     []interface{}{"uid1", "uid_not_found"},
+		"email":       []interface{}{"USER2@example.com"},
+		"phoneNumber": []interface{}{"+15555550003"},
+		"federatedUserId": []interface{}{
+			map[string]interface{}{"providerId": "google.com", "rawId": "google_uid4"},
+			map[string]interface{}{"providerId": "facebook.com", "rawId": "google_uid4"},
+		},
+	}
+	checkRequest(t, s, "/projects/mock-project-id/accounts:lookup", want)
+}
+
+func TestGetUsersEmpty(t *testing.T) {
+	result, err := client.GetUsers(context.Background(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Users) != 0 || len(result.NotFound) != 0 {
+		t.Errorf("GetUsers(nil) = %v; want = empty result", result)
+	}
+}
+
+func TestGetUsersNoneFound(t *testing.T) {
+	s := echoServer([]byte(`{}`), t)
+	defer s.Close()
+
+	identifiers := []UserIdentifier{UIDIdentifier{"uid1"}, EmailIdentifier{"user@example.com"}}
+	result, err := s.Client.GetUsers(context.Background(), identifiers)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Users) != 0 || !reflect.DeepEqual(result.NotFound, identifiers) {
+		t.Errorf("GetUsers() = %v; want = all not found", result)
+	}
+}
+
+func TestGetUsersInvalidIdentifiers(t *testing.T) {
+	tooMany := make([]UserIdentifier, maxGetUsersBatchSize+1)
+	for i := range tooMany {
+		tooMany[i] = UIDIdentifier{fmt.Sprintf("uid%d", i)}
+	}
+	cases := [][]UserIdentifier{
+		tooMany,
+		{nil},
+		{UIDIdentifier{""}},
+		{UIDIdentifier{strings.Repeat("a", 129)}},
+		{EmailIdentifier{""}},
+		{PhoneIdentifier{""}},
+		{ProviderIdentifier{"", "uid"}},
+		{ProviderIdentifier{"google.com", ""}},
+	}
+	for _, tc := range cases {
+		result, err := client.GetUsers(context.Background(), tc)
+		if result != nil || err == nil {
+			t.Errorf("GetUsers(%v) = (%v, %v); want = (nil, error)", tc, result, err)
+		}
+	}
+}
+
+func TestUpdateUser(t *testing.T) {
+	s := echoServer(testGetUserResponse(t), t)
+	defer s.Close()
+
+	user, err := s.Client.UpdateUser(context.Background(), "testuser", (&UserToUpdate{}).Disabled(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(user, testUser) {
+		t.Errorf("UpdateUser() = %#v; want = %#v", user, testUser)
+	}
+	if len(s.Req) != 2 {
+		t.Fatalf("requests = %d; want = 2", len(s.Req))
+	}
+	wantPath := "/projects/mock-project-id/accounts:update"
+	if s.Req[0].URL.Path != wantPath {
+		t.Errorf("Path = %q; want = %q", s.Req[0].URL.Path, wantPath)
+	}
+	checkRequest(t, s, "/projects/mock-project-id/accounts:lookup", nil)
+}
+
+func TestUpdateUserRequest(t *testing.T) {
+	cases := []struct {
+		update *UserToUpdate
+		want   map[string]interface{}
+	}{
+		{
+			(&UserToUpdate{}).
+				DisplayName("New Name").
+				Email("new@example.com").
+				EmailVerified(true).
+				Password("secret123").
+				PhoneNumber("+15555550100").
+				PhotoURL("http://www.example.com/photo.png").
+				Disabled(false),
+			map[string]interface{}{
+				"localId":       "testuser",
+				"displayName":   "New Name",
+				"email":         "new@example.com",
+				"emailVerified": true,
+				"password":      "secret123",
+				"phoneNumber":   "+15555550100",
+				"photoUrl":      "http://www.example.com/photo.png",
+				"disableUser":   false,
+			},
+		},
+		{
+			(&UserToUpdate{}).DisplayName("").PhotoURL(""),
+			map[string]interface{}{
+				"localId":         "testuser",
+				"deleteAttribute": []string{"DISPLAY_NAME", "PHOTO_URL"},
+			},
+		},
+		{
+			(&UserToUpdate{}).PhoneNumber(""),
+			map[string]interface{}{
+				"localId":        "testuser",
+				"deleteProvider": []string{"phone"},
+			},
+		},
+		{
+			(&UserToUpdate{}).ProvidersToDelete([]string{"google.com", "facebook.com"}),
+			map[string]interface{}{
+				"localId":        "testuser",
+				"deleteProvider": []string{"google.com", "facebook.com"},
+			},
+		},
+		{
+			(&UserToUpdate{}).ProvidersToDelete([]string{"google.com"}).PhoneNumber(""),
+			map[string]interface{}{
+				"localId":        "testuser",
+				"deleteProvider": []string{"google.com", "phone"},
+			},
+		},
+		{
+			(&UserToUpdate{}).ProvidersToDelete([]string{"phone"}).PhoneNumber(""),
+			map[string]interface{}{
+				"localId":        "testuser",
+				"deleteProvider": []string{"phone"},
+			},
+		},
+		{
+			(&UserToUpdate{}).PhoneNumber("+1 (555) 555-0100"),
+			map[string]interface{}{
+				"localId":     "testuser",
+				"phoneNumber": "+15555550100",
+			},
+		},
+		{
+			(&UserToUpdate{}).MFASettings(MultiFactorSettings{}),
+			map[string]interface{}{
+				"localId": "testuser",
+				"mfa":     map[string]interface{}{},
+			},
+		},
+	}
+	for _, tc := range cases {
+		got, err := tc.update.validatedRequest("testuser")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("validatedRequest() = %v; want = %v", got, tc.want)
+		}
+	}
+}
+
+func TestUpdateUserMFASettings(t *testing.T) {
+	settings := MultiFactorSettings{
+		EnrolledFactors: []*MultiFactorInfo{
+			testUser.MultiFactor.EnrolledFactors[1],
+			{
+				DisplayName: "Home phone",
+				FactorID:    "phone",
+				PhoneNumber: "+15555550102",
+			},
+		},
+	}
+	req, err := (&UserToUpdate{}).MFASettings(settings).validatedRequest("testuser")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := json.Marshal(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got map[string]interface{}
+	if err := json.Unmarshal(b, &got); err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]interface{}{
+		"localId": "testuser",
+		"mfa": map[string]interface{}{
+			"enrollments": []interface{}{
+				map[string]interface{}{
+					"mfaEnrollmentId": "totpFactor",
+					"displayName":     "Authenticator",
+					"totpInfo":        map[string]interface{}{},
+					"enrolledAt":      "2020-02-01T00:00:00.5Z",
+				},
+				map[string]interface{}{
+					"displayName": "Home phone",
+					"phoneInfo":   "+15555550102",
+				},
+			},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("validatedRequest() = %v; want = %v", got, want)
+	}
+}
+
+func TestGetUserInvalidMFAInfo(t *testing.T) {
+	cases := []string{
+		`{"users": [{"localId": "testuser", "mfaInfo": [{"mfaEnrollmentId": "unknown"}]}]}`,
+		`{"users": [{"localId": "testuser", "mfaInfo": [{"phoneInfo": "+15555550101", "enrolledAt": "invalid"}]}]}`,
+	}
+	for _, resp := range cases {
+		s := echoServer([]byte(resp), t)
+		user, err := s.Client.GetUser(context.Background(), "testuser")
+		if user != nil || err == nil {
+			t.Errorf("GetUser() = (%v, %v); want = (nil, error)", user, err)
+		}
+		s.Close()
+	}
+}
+
+func TestUpdateUserInvalid(t *testing.T) {
+	cases := []struct {
+		uid    string
+		update *UserToUpdate
+	}{
+		{"", (&UserToUpdate{}).Disabled(true)},
+		{strings.Repeat("a", 129), (&UserToUpdate{}).Disabled(true)},
+		{"testuser", nil},
+		{"testuser", &UserToUpdate{}},
+		{"testuser", (&UserToUpdate{}).Email("")},
+		{"testuser", (&UserToUpdate{}).Email("not-an-email")},
+		{"testuser", (&UserToUpdate{}).Password("short")},
+		{"testuser", (&UserToUpdate{}).PhoneNumber("1234567890")},
+		{"testuser", (&UserToUpdate{}).ProvidersToDelete([]string{"google.com", ""})},
+		{"testuser", (&UserToUpdate{}).MFASettings(MultiFactorSettings{
+			EnrolledFactors: []*MultiFactorInfo{nil},
+		})},
+		{"testuser", (&UserToUpdate{}).MFASettings(MultiFactorSettings{
+			EnrolledFactors: []*MultiFactorInfo{{FactorID: "phone", PhoneNumber: "5555550101"}},
+		})},
+		{"testuser", (&UserToUpdate{}).MFASettings(MultiFactorSettings{
+			EnrolledFactors: []*MultiFactorInfo{{FactorID: "totp"}},
+		})},
+		{"testuser", (&UserToUpdate{}).MFASettings(MultiFactorSettings{
+			EnrolledFactors: []*MultiFactorInfo{{UID: "factor", FactorID: "email"}},
+		})},
+	}
+	for _, tc := range cases {
+		user, err := client.UpdateUser(context.Background(), tc.uid, tc.update)
+		if user != nil || err == nil {
+			t.Errorf("UpdateUser(%q) = (%v, %v); want = (nil, error)", tc.uid, user, err)
+		}
+	}
+}
+
+func TestUpdateNonExistingUser(t *testing.T) {
+	resp := `{"error": {"code": 400, "message": "USER_NOT_FOUND"}}`
+	s := echoServer([]byte(resp), t)
+	defer s.Close()
+	s.Status = http.StatusBadRequest
+
+	user, err := s.Client.UpdateUser(context.Background(), "ignored_id", (&UserToUpdate{}).Disabled(true))
+	if user != nil || !IsUserNotFound(err) {
+		t.Errorf("UpdateUser() = (%v, %v); want = (nil, user-not-found error)", user, err)
+	}
+}
+
+func TestDeleteUser(t *testing.T) {
+	s := echoServer([]byte(`{"kind": "identitytoolkit#DeleteAccountResponse"}`), t)
+	defer s.Close()
+
+	if err := s.Client.DeleteUser(context.Background(), "testuser"); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{"localId": "testuser"}
+	checkRequest(t, s, "/projects/mock-project-id/accounts:delete", want)
+}
+
+func TestDeleteUserInvalidUID(t *testing.T) {
+	cases := []string{"", strings.Repeat("a", 129)}
+	for _, uid := range cases {
+		if err := client.DeleteUser(context.Background(), uid); err == nil {
+			t.Errorf("DeleteUser(%q) = nil; want = error", uid)
+		}
+	}
+}
+
+func TestDeleteNonExistingUser(t *testing.T) {
+	resp := `{"error": {"code": 400, "message": "USER_NOT_FOUND"}}`
+	s := echoServer([]byte(resp), t)
+	defer s.Close()
+	s.Status = http.StatusBadRequest
+
+	if err := s.Client.DeleteUser(context.Background(), "ignored_id"); !IsUserNotFound(err) {
+		t.Errorf("DeleteUser() = %v; want = user-not-found error", err)
+	}
+}
+
+func TestDeleteUsers(t *testing.T) {
+	resp := `{
+		"errors": [
+			{"index": 1, "localId": "uid2", "message": "NOT_DISABLED : Disable the account before batch deletion."}
+		]
+	}`
+	s := echoServer([]byte(resp), t)
+	defer s.Close()
+
+	result, err := s.Client.DeleteUsers(context.Background(), []string{"uid1", "uid2", "uid3"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &DeleteUsersResult{
+		SuccessCount: 2,
+		FailureCount: 1,
+		Errors: []*DeleteUsersErrorInfo{
+			{
+				Index:  1,
+				UID:    "uid2",
+				Reason: "NOT_DISABLED : Disable the account before batch deletion.",
+			},
+		},
+	}
+	if !reflect.DeepEqual(result, want) {
+		t.Errorf("DeleteUsers() = %#v; want = %#v", result, want)
+	}
+
+	wantBody := map[string]interface{}{
+		"localIds": []interface{}{"uid1", "uid2", "uid3"},
+		"force":    true,
+	}
+	checkRequest(t, s, "/projects/mock-project-id/accounts:batchDelete", wantBody)
+}
+
+func TestDeleteUsersEmpty(t *testing.T) {
+	result, err := client.DeleteUsers(context.Background(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.SuccessCount != 0 || result.FailureCount != 0 || len(result.Errors) != 0 {
+		t.Errorf("DeleteUsers(nil) = %v; want = empty result", result)
+	}
+}
+
+func TestDeleteUsersInvalidUIDs(t *testing.T) {
+	tooMany := make([]string, maxDeleteUsersBatchSize+1)
+	for i := range tooMany {
+		tooMany[i] = fmt.Sprintf("uid%d", i)
+	}
+	cases := [][]string{
+		tooMany,
+		{"uid1", ""},
+		{strings.Repeat("a", 129)},
+	}
+	for _, tc := range cases {
+		result, err := client.DeleteUsers(context.Background(), tc)
+		if result != nil || err == nil {
+			t.Errorf("DeleteUsers(%d uids) = (%v, %v); want = (nil, error)", len(tc), result, err)
+		}
+	}
+}
+
+func TestListUsers(t *testing.T) {
+	pages := map[string]string{
+		"": `{
+			"users": [{"localId": "uid1"}, {"localId": "uid2"}],
+			"nextPageToken": "page2"
+		}`,
+		"page2": `{
+			"users": [{"localId": "uid3"}]
+		}`,
+	}
+	s := newPagedUsersServer(t, pages, nil)
+	defer s.Close()
+
+	it := s.Client.ListUsers(context.Background()).PageSize(2)
+	var uids []string
+	for {
+		user, err := it.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		uids = append(uids, user.UID)
+	}
+
+	wantUIDs := []string{"uid1", "uid2", "uid3"}
+	if !reflect.DeepEqual(uids, wantUIDs) {
+		t.Errorf("ListUsers() = %v; want = %v", uids, wantUIDs)
+	}
+	wantQueries := []string{"maxResults=2", "maxResults=2&nextPageToken=page2"}
+	if !reflect.DeepEqual(s.Queries, wantQueries) {
+		t.Errorf("ListUsers() queries = %v; want = %v", s.Queries, wantQueries)
+	}
+}
+
+func TestListUsersPrefetch(t *testing.T) {
+	pages := map[string]string{
+		"": `{
+			"users": [{"localId": "uid1"}, {"localId": "uid2"}],
+			"nextPageToken": "page2"
+		}`,
+		"page2": `{
+			"users": [{"localId": "uid3"}]
+		}`,
+	}
+	requested := make(chan string, 2)
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := r.URL.Query().Get("nextPageToken")
+		requested <- token
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(pages[token]))
+	}))
+	defer s.Close()
+	c := *client
+	c.userEndpoint = s.URL
+
+	it := c.ListUsers(context.Background()).PageSize(2).Prefetch(true)
+	user, err := it.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.UID != "uid1" {
+		t.Errorf("Next() = %q; want = %q", user.UID, "uid1")
+	}
+	for _, want := range []string{"", "page2"} {
+		select {
+		case token := <-requested:
+			if token != want {
+				t.Errorf("Requested page = %q; want = %q", token, want)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Page %q not requested", want)
+		}
+	}
+
+	uids := []string{user.UID}
+	for {
+		user, err := it.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		uids = append(uids, user.UID)
+	}
+	if want := []string{"uid1", "uid2", "uid3"}; !reflect.DeepEqual(uids, want) {
+		t.Errorf("ListUsers() = %v; want = %v", uids, want)
+	}
+	select {
+	case token := <-requested:
+		t.Errorf("Unexpected request for page %q", token)
+	default:
+	}
+}
+
+func TestListUsersPrefetchError(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Query().Get("nextPageToken") == "" {
+			w.Write([]byte(`{"users": [{"localId": "uid1"}], "nextPageToken": "page2"}`))
+			return
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"error": {"message": "INTERNAL_ERROR"}}`))
+	}))
+	defer s.Close()
+	c := *client
+	c.userEndpoint = s.URL
+
+	it := c.ListUsers(context.Background()).Prefetch(true)
+	if user, err := it.Next(); err != nil || user.UID != "uid1" {
+		t.Fatalf("Next() = (%v, %v); want = (uid1, nil)", user, err)
+	}
+	if user, err := it.Next(); user != nil || err == nil || err == iterator.Done {
+		t.Errorf("Next() = (%v, %v); want = (nil, error)", user, err)
+	}
+}
+
+func TestListUsersPager(t *testing.T) {
+	resp := `{
+		"users": [{"localId": "uid3"}],
+		"nextPageToken": "page3"
+	}`
+	s := echoServer([]byte(resp), t)
+	defer s.Close()
+
+	pager := iterator.NewPager(s.Client.ListUsers(context.Background()), 10, "page2")
+	var users []*UserRecord
+	token, err := pager.NextPage(&users)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(users) != 1 || users[0].UID != "uid3" || token != "page3" {
+		t.Errorf("NextPage() = (%v, %q); want = ([uid3], %q)", users, token, "page3")
+	}
+
+	req := s.Req[0]
+	if req.Method != http.MethodGet {
+		t.Errorf("Method = %q; want = %q", req.Method, http.MethodGet)
+	}
+	checkRequest(t, s, "/projects/mock-project-id/accounts:batchGet", nil)
+	if got := req.URL.RawQuery; got != "maxResults=10&nextPageToken=page2" {
+		t.Errorf("Query = %q; want = %q", got, "maxResults=10&nextPageToken=page2")
+	}
+}
+
+func TestListUsersInvalidPageSize(t *testing.T) {
+	for _, size := range []int{0, maxListUsersResults + 1} {
+		user, err := client.ListUsers(context.Background()).PageSize(size).Next()
+		if user != nil || err == nil || err == iterator.Done {
+			t.Errorf("Next() with PageSize(%d) = (%v, %v); want = (nil, error)", size, user, err)
+		}
+	}
+}
+
+func TestListUsersHTTPError(t *testing.T) {
+	resp := `{"error": {"code": 500, "message": "INTERNAL_ERROR"}}`
+	s := echoServer([]byte(resp), t)
+	defer s.Close()
+	s.Status = http.StatusInternalServerError
+
+	it := s.Client.ListUsers(context.Background())
+	user, err := it.Next()
+	if user != nil || err == nil || err == iterator.Done {
+		t.Errorf("Next() = (%v, %v); want = (nil, error)", user, err)
+	}
+}
+
+func TestSetCustomUserClaims(t *testing.T) {
+	cases := []struct {
+		claims map[string]interface{}
+		want   string
+	}{
+		{map[string]interface{}{"admin": true, "package": "gold"}, `{"admin":true,"package":"gold"}`},
+		{map[string]interface{}{}, "{}"},
+		{nil, "{}"},
+	}
+	s := echoServer([]byte(`{"localId": "testuser"}`), t)
+	defer s.Close()
+
+	for _, tc := range cases {
+		if err := s.Client.SetCustomUserClaims(context.Background(), "testuser", tc.claims); err != nil {
+			t.Fatal(err)
+		}
+		want := map[string]interface{}{
+			"localId":          "testuser",
+			"customAttributes": tc.want,
+		}
+		checkRequest(t, s, "/projects/mock-project-id/accounts:update", want)
+	}
+}
+
+func TestSetCustomUserClaimsInvalid(t *testing.T) {
+	cases := []map[string]interface{}{
+		{"sub": "not allowed"},
+		{"admin": true, "iss": "not allowed"},
+		{"large": strings.Repeat("a", maxClaimsPayloadSize)},
+		{"unsupported": func() {}},
+	}
+	for _, tc := range cases {
+		if err := client.SetCustomUserClaims(context.Background(), "testuser", tc); err == nil {
+			t.Errorf("SetCustomUserClaims(%v) = nil; want = error", tc)
+		}
+	}
+	if err := client.SetCustomUserClaims(context.Background(), "", nil); err == nil {
+		t.Error("SetCustomUserClaims(\"\") = nil; want = error")
+	}
+}
+
+// disableServer serves accounts:update requests, failing the first updateFailures of them, and
+// lookups of a user whose disabled state and valid since time are set by the last update, unless
+// ignoreUpdates is set.
+func disableServer(t *testing.T, updateFailures int, ignoreUpdates bool) (*Client, *[]map[string]interface{}, func()) {
+	var updates []map[string]interface{}
+	user := map[string]interface{}{"localId": "testuser", "validSince": "100"}
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/projects/mock-project-id/accounts:update":
+			var req map[string]interface{}
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				t.Error(err)
+			}
+			updates = append(updates, req)
+			if len(updates) <= updateFailures {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				w.Write([]byte(`{"error": {"message": "INTERNAL_ERROR"}}`))
+				return
+			}
+			if !ignoreUpdates {
+				user["disabled"] = req["disableUser"]
+				user["validSince"] = req["validSince"]
+			}
+			w.Write([]byte(`{"localId": "testuser"}`))
+		case "/projects/mock-project-id/accounts:lookup":
+			b, _ := json.Marshal(map[string]interface{}{"users": []interface{}{user}})
+			w.Write(b)
+		default:
+			t.Errorf("Path = %q; want = accounts:update or accounts:lookup", r.URL.Path)
+		}
+	}))
+	c := *client
+	c.userEndpoint = s.URL
+	return &c, &updates, s.Close
+}
+
+func TestDisableUserAndRevokeTokens(t *testing.T) {
+	c, updates, done := disableServer(t, 1, false)
+	defer done()
+	clk = &mockClock{now: time.Unix(1000, 0)}
+	delay := disableRetryDelay
+	disableRetryDelay = time.Millisecond
+	defer func() {
+		clk = &systemClock{}
+		disableRetryDelay = delay
+	}()
+
+	user, err := c.DisableUserAndRevokeTokens(context.Background(), "testuser")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !user.Disabled || user.TokensValidAfterMillis != 1000000 {
+		t.Errorf("DisableUserAndRevokeTokens() = (Disabled: %v, TokensValidAfterMillis: %d); want = (true, 1000000)",
+			user.Disabled, user.TokensValidAfterMillis)
+	}
+	want := map[string]interface{}{
+		"localId":     "testuser",
+		"disableUser": true,
+		"validSince":  "1000",
+	}
+	if len(*updates) != 2 {
+		t.Fatalf("Updates = %d; want = 2", len(*updates))
+	}
+	for _, got := range *updates {
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("Update = %v; want = %v", got, want)
+		}
+	}
+}
+
+func TestDisableUserAndRevokeTokensNotApplied(t *testing.T) {
+	c, updates, done := disableServer(t, 0, true)
+	defer done()
+	delay := disableRetryDelay
+	disableRetryDelay = time.Millisecond
+	defer func() {
+		disableRetryDelay = delay
+	}()
+
+	if user, err := c.DisableUserAndRevokeTokens(context.Background(), "testuser"); user != nil || err == nil {
+		t.Errorf("DisableUserAndRevokeTokens() = (%v, %v); want = (nil, error)", user, err)
+	}
+	if len(*updates) != maxDisableAttempts {
+		t.Errorf("Updates = %d; want = %d", len(*updates), maxDisableAttempts)
+	}
+}
+
+func TestDisableUserAndRevokeTokensUserNotFound(t *testing.T) {
+	s := echoServer([]byte(`{"error": {"message": "USER_NOT_FOUND"}}`), t)
+	defer s.Close()
+	s.Status = http.StatusBadRequest
+
+	if user, err := s.Client.DisableUserAndRevokeTokens(context.Background(), "testuser"); user != nil || !IsUserNotFound(err) {
+		t.Errorf("DisableUserAndRevokeTokens() = (%v, %v); want = (nil, user-not-found error)", user, err)
+	}
+	if len(s.Req) != 1 {
+		t.Errorf("Requests = %d; want = 1", len(s.Req))
+	}
+}
+
+func TestRevokeRefreshTokens(t *testing.T) {
+	c, updates, done := disableServer(t, 1, false)
+	defer done()
+	clk = &mockClock{now: time.Unix(1000, 0)}
+	delay := disableRetryDelay
+	disableRetryDelay = time.Millisecond
+	defer func() {
+		clk = &systemClock{}
+		disableRetryDelay = delay
+	}()
+
+	if err := c.RevokeRefreshTokens(context.Background(), "testuser"); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{
+		"localId":    "testuser",
+		"validSince": "1000",
+	}
+	if len(*updates) != 2 {
+		t.Fatalf("Updates = %d; want = 2", len(*updates))
+	}
+	for _, got := range *updates {
+		if _, ok := got["disableUser"]; ok {
+			t.Errorf("Update = %v; want no disableUser", got)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("Update = %v; want = %v", got, want)
+		}
+	}
+}
+
+func TestRevokeRefreshTokensNotApplied(t *testing.T) {
+	c, updates, done := disableServer(t, 0, true)
+	defer done()
+	delay := disableRetryDelay
+	disableRetryDelay = time.Millisecond
+	defer func() {
+		disableRetryDelay = delay
+	}()
+
+	if err := c.RevokeRefreshTokens(context.Background(), "testuser"); err == nil {
+		t.Errorf("RevokeRefreshTokens() = nil; want = error")
+	}
+	if len(*updates) != maxDisableAttempts {
+		t.Errorf("Updates = %d; want = %d", len(*updates), maxDisableAttempts)
+	}
+}
+
+func TestDisableUserAndRevokeTokensInvalid(t *testing.T) {
+	if user, err := client.DisableUserAndRevokeTokens(context.Background(), ""); user != nil || err == nil {
+		t.Errorf("DisableUserAndRevokeTokens(\"\") = (%v, %v); want = (nil, error)", user, err)
+	}
+	if err := client.RevokeRefreshTokens(context.Background(), ""); err == nil {
+		t.Errorf("RevokeRefreshTokens(\"\") = nil; want = error")
+	}
+}
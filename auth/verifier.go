@@ -0,0 +1,77 @@
+// Copyright 2017 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"errors"
+	"fmt"
+)
+
+// MultiProjectVerifier verifies Firebase ID tokens issued for any one of a set of Firebase projects.
+//
+// MultiProjectVerifier is meant for services that accept ID tokens from several Firebase projects. Unlike
+// Client, it does not require any credentials, since ID tokens are verified using public keys. The project
+// for which a verified token was issued is reported in the ProjectID field of the returned Token.
+type MultiProjectVerifier struct {
+	ks     keySource
+	accept func(projectID string) bool
+}
+
+// NewMultiProjectVerifier creates a MultiProjectVerifier that accepts ID tokens issued for any of the
+// specified Firebase projects.
+func NewMultiProjectVerifier(projectIDs ...string) (*MultiProjectVerifier, error) {
+	if len(projectIDs) == 0 {
+		return nil, errors.New("at least one project id must be specified")
+	}
+	allowed := make(map[string]bool)
+	for _, pid := range projectIDs {
+		if pid == "" {
+			return nil, errors.New("project id must be a non-empty string")
+		}
+		allowed[pid] = true
+	}
+	return NewMultiProjectVerifierFunc(func(projectID string) bool {
+		return allowed[projectID]
+	})
+}
+
+// NewMultiProjectVerifierFunc creates a MultiProjectVerifier that accepts ID tokens issued for any
+// Firebase project, for which the accept function returns true.
+//
+// The accept function may be called concurrently from multiple goroutines.
+func NewMultiProjectVerifierFunc(accept func(projectID string) bool) (*MultiProjectVerifier, error) {
+	if accept == nil {
+		return nil, errors.New("accept function must not be nil")
+	}
+	return &MultiProjectVerifier{
+		ks:     newHTTPKeySource(googleCertURL),
+		accept: accept,
+	}, nil
+}
+
+// VerifyIDToken verifies the signature and payload of the provided ID token.
+//
+// VerifyIDToken performs the same checks as Client.VerifyIDToken, except that the token may be issued
+// for any of the projects accepted by this MultiProjectVerifier. The ProjectID field of the returned
+// Token indicates which project matched.
+func (v *MultiProjectVerifier) VerifyIDToken(idToken string) (*Token, error) {
+	return verifyIDToken(idToken, v.ks, func(aud string) (string, error) {
+		if aud == "" || !v.accept(aud) {
+			return "", fmt.Errorf("ID token has invalid 'aud' (audience) claim. Project %q is not accepted "+
+				"by this verifier. %s", aud, verifyTokenMsg)
+		}
+		return issuerPrefix + aud, nil
+	})
+}
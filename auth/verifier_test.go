@@ -0,0 +1,108 @@
+// Copyright 2017 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"testing"
+)
+
+func projectToken(pid string) string {
+	return getIDToken(mockIDTokenPayload{
+		"aud": pid,
+		"iss": "https://securetoken.google.com/" + pid,
+	})
+}
+
+func TestMultiProjectVerifier(t *testing.T) {
+	v, err := NewMultiProjectVerifier("project-1", "project-2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	v.ks = client.ks
+
+	for _, pid := range []string{"project-1", "project-2"} {
+		ft, err := v.VerifyIDToken(projectToken(pid))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ft.ProjectID != pid {
+			t.Errorf("ProjectID = %q; want: %q", ft.ProjectID, pid)
+		}
+		if ft.UID != ft.Subject {
+			t.Errorf("UID = %q; Sub = %q; want UID = Sub", ft.UID, ft.Subject)
+		}
+	}
+}
+
+func TestMultiProjectVerifierFunc(t *testing.T) {
+	v, err := NewMultiProjectVerifierFunc(func(pid string) bool {
+		return pid == "project-1"
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	v.ks = client.ks
+
+	ft, err := v.VerifyIDToken(projectToken("project-1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ft.ProjectID != "project-1" {
+		t.Errorf("ProjectID = %q; want: %q", ft.ProjectID, "project-1")
+	}
+}
+
+func TestMultiProjectVerifierError(t *testing.T) {
+	v, err := NewMultiProjectVerifier("project-1", "project-2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	v.ks = client.ks
+
+	cases := []struct {
+		name  string
+		token string
+	}{
+		{"UnknownProject", projectToken("project-3")},
+		{"MismatchedIssuer", getIDToken(mockIDTokenPayload{
+			"aud": "project-1",
+			"iss": "https://securetoken.google.com/project-2",
+		})},
+		{"EmptyAudience", projectToken("")},
+		{"EmptyToken", ""},
+	}
+	for _, tc := range cases {
+		if _, err := v.VerifyIDToken(tc.token); err == nil {
+			t.Errorf("VerifyIDToken(%q) = nil; want error", tc.name)
+		}
+	}
+}
+
+func TestNewMultiProjectVerifierError(t *testing.T) {
+	cases := [][]string{
+		nil,
+		{""},
+		{"project-1", ""},
+	}
+	for _, tc := range cases {
+		if v, err := NewMultiProjectVerifier(tc...); v != nil || err == nil {
+			t.Errorf("NewMultiProjectVerifier(%v) = (%v, %v); want: (nil, error)", tc, v, err)
+		}
+	}
+
+	if v, err := NewMultiProjectVerifierFunc(nil); v != nil || err == nil {
+		t.Errorf("NewMultiProjectVerifierFunc(nil) = (%v, %v); want: (nil, error)", v, err)
+	}
+}
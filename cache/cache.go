@@ -0,0 +1,86 @@
+// Copyright 2017 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package cache defines the interface of the caches used by the Firebase Admin SDK, along with an
+// in-memory implementation.
+//
+// By default each SDK instance caches data such as the public keys used to verify ID tokens on its own.
+// Supplying a Cache backed by a shared store, such as Redis or Memcached, allows a fleet of servers to
+// share the cached data.
+package cache
+
+import (
+	"sync"
+	"time"
+)
+
+// Cache is a key-value store with per-entry expiry.
+//
+// Implementations must be safe for concurrent use by multiple goroutines. A Cache is only an
+// optimization: implementations backed by a remote store should treat failures as cache misses, rather
+// than reporting them to the SDK.
+type Cache interface {
+	// Get returns the value stored under the given key. Its second return value is false if there is
+	// no such value, or it has expired.
+	Get(key string) ([]byte, bool)
+
+	// Set stores the value under the given key. The value expires after the given TTL. A TTL of zero
+	// or less indicates that the value does not expire.
+	Set(key string, value []byte, ttl time.Duration)
+}
+
+var now = time.Now
+
+type entry struct {
+	value  []byte
+	expiry time.Time
+}
+
+type memoryCache struct {
+	mutex   sync.Mutex
+	entries map[string]*entry
+}
+
+// NewMemoryCache creates a new Cache that stores values in memory.
+//
+// The returned Cache can be shared by several SDK instances within the same process.
+func NewMemoryCache() Cache {
+	return &memoryCache{
+		entries: make(map[string]*entry),
+	}
+}
+
+func (m *memoryCache) Get(key string) ([]byte, bool) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	e, ok := m.entries[key]
+	if !ok {
+		return nil, false
+	}
+	if !e.expiry.IsZero() && !now().Before(e.expiry) {
+		delete(m.entries, key)
+		return nil, false
+	}
+	return e.value, true
+}
+
+func (m *memoryCache) Set(key string, value []byte, ttl time.Duration) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	e := &entry{value: append([]byte(nil), value...)}
+	if ttl > 0 {
+		e.expiry = now().Add(ttl)
+	}
+	m.entries[key] = e
+}
@@ -0,0 +1,57 @@
+// Copyright 2017 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMemoryCache(t *testing.T) {
+	current := time.Unix(1000, 0)
+	now = func() time.Time { return current }
+	defer func() {
+		now = time.Now
+	}()
+
+	c := NewMemoryCache()
+	if v, ok := c.Get("key"); v != nil || ok {
+		t.Errorf("Get() = (%q, %v); want = (nil, false)", v, ok)
+	}
+
+	c.Set("key", []byte("value"), 10*time.Second)
+	c.Set("forever", []byte("value"), 0)
+	if v, ok := c.Get("key"); string(v) != "value" || !ok {
+		t.Errorf("Get() = (%q, %v); want = (%q, true)", v, ok, "value")
+	}
+
+	current = current.Add(10 * time.Second)
+	if v, ok := c.Get("key"); v != nil || ok {
+		t.Errorf("Get() after expiry = (%q, %v); want = (nil, false)", v, ok)
+	}
+	if v, ok := c.Get("forever"); string(v) != "value" || !ok {
+		t.Errorf("Get() = (%q, %v); want = (%q, true)", v, ok, "value")
+	}
+}
+
+func TestMemoryCacheCopiesValue(t *testing.T) {
+	c := NewMemoryCache()
+	b := []byte("value")
+	c.Set("key", b, 0)
+	b[0] = 'V'
+	if v, _ := c.Get("key"); string(v) != "value" {
+		t.Errorf("Get() = %q; want = %q", v, "value")
+	}
+}
@@ -0,0 +1,74 @@
+// Copyright 2017 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cache_test
+
+import (
+	"log"
+	"time"
+
+	"firebase.google.com/go"
+
+	"golang.org/x/net/context"
+)
+
+// redisClient is the subset of a Redis client API used by redisCache. Clients such as
+// github.com/go-redis/redis can be adapted to it with a few lines of code.
+type redisClient interface {
+	Get(key string) ([]byte, error)
+	Set(key string, value []byte, expiration time.Duration) error
+}
+
+// redisCache is a cache.Cache backed by Redis, which allows several servers to share cached data.
+type redisCache struct {
+	client redisClient
+	prefix string
+}
+
+func (r *redisCache) Get(key string) ([]byte, bool) {
+	b, err := r.client.Get(r.prefix + key)
+	if err != nil {
+		// Treat Redis errors as cache misses.
+		return nil, false
+	}
+	return b, true
+}
+
+func (r *redisCache) Set(key string, value []byte, ttl time.Duration) {
+	if ttl < 0 {
+		ttl = 0
+	}
+	if err := r.client.Set(r.prefix+key, value, ttl); err != nil {
+		log.Printf("failed to write to redis: %v", err)
+	}
+}
+
+func newRedisClient() redisClient {
+	// Connect to Redis here.
+	return nil
+}
+
+func Example_redis() {
+	shared := &redisCache{
+		client: newRedisClient(),
+		prefix: "firebase:",
+	}
+	app, err := firebase.NewApp(context.Background(), &firebase.Config{Cache: shared})
+	if err != nil {
+		log.Fatal(err)
+	}
+	if _, err := app.Auth(); err != nil {
+		log.Fatal(err)
+	}
+}
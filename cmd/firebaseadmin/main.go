@@ -0,0 +1,251 @@
+// Copyright 2017 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Command firebaseadmin is a command-line utility for common Firebase admin operations, built on the
+// Firebase Admin SDK.
+//
+// Usage:
+//
+//	firebaseadmin [-credentials file] [-project id] <command> [arguments]
+//
+// The commands are:
+//
+//	user get <uid>                   Prints the user account with the given user ID.
+//	user disable <uid>               Disables the user account with the given user ID.
+//	user enable <uid>                Enables the user account with the given user ID.
+//	user delete <uid>                Deletes the user account with the given user ID.
+//	claims set <uid> <json>          Sets the custom claims of a user to the given JSON object.
+//	token verify [-revoked] <token>  Verifies an ID token, and prints its claims.
+//
+// If no credentials file is specified, Google application default credentials are used. Results are
+// printed to standard output as JSON.
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	firebase "firebase.google.com/go"
+	"firebase.google.com/go/auth"
+
+	"golang.org/x/net/context"
+	"google.golang.org/api/option"
+)
+
+// authClient is the subset of auth.Client used by the commands.
+type authClient interface {
+	GetUser(ctx context.Context, uid string) (*auth.UserRecord, error)
+	UpdateUser(ctx context.Context, uid string, user *auth.UserToUpdate) (*auth.UserRecord, error)
+	DeleteUser(ctx context.Context, uid string) error
+	SetCustomUserClaims(ctx context.Context, uid string, customClaims map[string]interface{}) error
+	VerifyIDToken(idToken string) (*auth.Token, error)
+	VerifyIDTokenAndCheckRevoked(ctx context.Context, idToken string) (*auth.Token, error)
+}
+
+type command func(ctx context.Context, client authClient, args []string, out io.Writer) error
+
+var commands = map[string]command{
+	"user get":     getUser,
+	"user disable": setUserDisabled(true),
+	"user enable":  setUserDisabled(false),
+	"user delete":  deleteUser,
+	"claims set":   setClaims,
+	"token verify": verifyToken,
+}
+
+func main() {
+	credentials := flag.String("credentials", "", "path to a service account or refresh token JSON file")
+	project := flag.String("project", "", "Firebase project ID")
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: firebaseadmin [-credentials file] [-project id] <command> [arguments]")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	ctx := context.Background()
+	client, err := newAuthClient(ctx, *credentials, *project)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "firebaseadmin: %v\n", err)
+		os.Exit(1)
+	}
+	if err := run(ctx, client, flag.Args(), os.Stdout); err != nil {
+		fmt.Fprintf(os.Stderr, "firebaseadmin: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func newAuthClient(ctx context.Context, credentials, project string) (*auth.Client, error) {
+	var opts []option.ClientOption
+	if credentials != "" {
+		opts = append(opts, option.WithCredentialsFile(credentials))
+	}
+	var config *firebase.Config
+	if project != "" {
+		config = &firebase.Config{ProjectID: project}
+	}
+	app, err := firebase.NewApp(ctx, config, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return app.Auth()
+}
+
+// run looks up the command named by the first two arguments, and runs it with the remaining arguments.
+func run(ctx context.Context, client authClient, args []string, out io.Writer) error {
+	if len(args) < 2 {
+		return errors.New("no command specified")
+	}
+	name := args[0] + " " + args[1]
+	cmd, ok := commands[name]
+	if !ok {
+		return fmt.Errorf("unknown command: %q", name)
+	}
+	return cmd(ctx, client, args[2:], out)
+}
+
+func getUser(ctx context.Context, client authClient, args []string, out io.Writer) error {
+	if len(args) != 1 {
+		return errors.New("usage: user get <uid>")
+	}
+	user, err := client.GetUser(ctx, args[0])
+	if err != nil {
+		return err
+	}
+	return printJSON(out, newUserOutput(user))
+}
+
+func setUserDisabled(disabled bool) command {
+	return func(ctx context.Context, client authClient, args []string, out io.Writer) error {
+		if len(args) != 1 {
+			return errors.New("usage: user disable|enable <uid>")
+		}
+		user, err := client.UpdateUser(ctx, args[0], (&auth.UserToUpdate{}).Disabled(disabled))
+		if err != nil {
+			return err
+		}
+		return printJSON(out, newUserOutput(user))
+	}
+}
+
+func deleteUser(ctx context.Context, client authClient, args []string, out io.Writer) error {
+	if len(args) != 1 {
+		return errors.New("usage: user delete <uid>")
+	}
+	return client.DeleteUser(ctx, args[0])
+}
+
+func setClaims(ctx context.Context, client authClient, args []string, out io.Writer) error {
+	if len(args) != 2 {
+		return errors.New("usage: claims set <uid> <json>")
+	}
+	var claims map[string]interface{}
+	if err := json.Unmarshal([]byte(args[1]), &claims); err != nil {
+		return fmt.Errorf("claims must be a JSON object: %v", err)
+	}
+	return client.SetCustomUserClaims(ctx, args[0], claims)
+}
+
+func verifyToken(ctx context.Context, client authClient, args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("token verify", flag.ContinueOnError)
+	fs.SetOutput(out)
+	revoked := fs.Bool("revoked", false, "also check whether the token has been revoked")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return errors.New("usage: token verify [-revoked] <token>")
+	}
+
+	var token *auth.Token
+	var err error
+	if *revoked {
+		token, err = client.VerifyIDTokenAndCheckRevoked(ctx, fs.Arg(0))
+	} else {
+		token, err = client.VerifyIDToken(fs.Arg(0))
+	}
+	if err != nil {
+		return err
+	}
+	return printJSON(out, token.Claims)
+}
+
+type providerOutput struct {
+	ProviderID  string `json:"providerId"`
+	UID         string `json:"uid"`
+	DisplayName string `json:"displayName,omitempty"`
+	Email       string `json:"email,omitempty"`
+	PhoneNumber string `json:"phoneNumber,omitempty"`
+	PhotoURL    string `json:"photoUrl,omitempty"`
+}
+
+// userOutput is the printed form of a user account. It leaves out the password hash and salt of the
+// user, so that they are never written to the terminal or to logs.
+type userOutput struct {
+	UID                    string                 `json:"uid"`
+	DisplayName            string                 `json:"displayName,omitempty"`
+	Email                  string                 `json:"email,omitempty"`
+	EmailVerified          bool                   `json:"emailVerified"`
+	PhoneNumber            string                 `json:"phoneNumber,omitempty"`
+	PhotoURL               string                 `json:"photoUrl,omitempty"`
+	Disabled               bool                   `json:"disabled"`
+	CustomClaims           map[string]interface{} `json:"customClaims,omitempty"`
+	Providers              []*providerOutput      `json:"providers,omitempty"`
+	TokensValidAfterMillis int64                  `json:"tokensValidAfterMillis,omitempty"`
+	CreationTimestamp      int64                  `json:"creationTimestamp,omitempty"`
+	LastLogInTimestamp     int64                  `json:"lastLogInTimestamp,omitempty"`
+}
+
+func newUserOutput(u *auth.UserRecord) *userOutput {
+	out := &userOutput{
+		EmailVerified:          u.EmailVerified,
+		Disabled:               u.Disabled,
+		CustomClaims:           u.CustomClaims,
+		TokensValidAfterMillis: u.TokensValidAfterMillis,
+	}
+	if u.UserInfo != nil {
+		out.UID = u.UID
+		out.DisplayName = u.DisplayName
+		out.Email = u.Email
+		out.PhoneNumber = u.PhoneNumber
+		out.PhotoURL = u.PhotoURL
+	}
+	for _, p := range u.ProviderUserInfo {
+		out.Providers = append(out.Providers, &providerOutput{
+			ProviderID:  p.ProviderID,
+			UID:         p.UID,
+			DisplayName: p.DisplayName,
+			Email:       p.Email,
+			PhoneNumber: p.PhoneNumber,
+			PhotoURL:    p.PhotoURL,
+		})
+	}
+	if u.UserMetadata != nil {
+		out.CreationTimestamp = u.UserMetadata.CreationTimestamp
+		out.LastLogInTimestamp = u.UserMetadata.LastLogInTimestamp
+	}
+	return out
+}
+
+func printJSON(out io.Writer, v interface{}) error {
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(out, string(b))
+	return err
+}
@@ -0,0 +1,153 @@
+// Copyright 2017 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"firebase.google.com/go/auth"
+
+	"golang.org/x/net/context"
+)
+
+type mockAuthClient struct {
+	calls  []string
+	claims map[string]interface{}
+	user   *auth.UserToUpdate
+}
+
+func (m *mockAuthClient) GetUser(ctx context.Context, uid string) (*auth.UserRecord, error) {
+	m.calls = append(m.calls, "GetUser:"+uid)
+	if uid == "missing" {
+		return nil, errors.New("user not found")
+	}
+	return &auth.UserRecord{
+		UserInfo:     &auth.UserInfo{UID: uid, Email: "user@example.com"},
+		PasswordHash: "aGFzaA==",
+		PasswordSalt: "c2FsdA==",
+	}, nil
+}
+
+func (m *mockAuthClient) UpdateUser(ctx context.Context, uid string,
+	user *auth.UserToUpdate) (*auth.UserRecord, error) {
+	m.calls = append(m.calls, "UpdateUser:"+uid)
+	m.user = user
+	return &auth.UserRecord{UserInfo: &auth.UserInfo{UID: uid}}, nil
+}
+
+func (m *mockAuthClient) DeleteUser(ctx context.Context, uid string) error {
+	m.calls = append(m.calls, "DeleteUser:"+uid)
+	return nil
+}
+
+func (m *mockAuthClient) SetCustomUserClaims(ctx context.Context, uid string,
+	customClaims map[string]interface{}) error {
+	m.calls = append(m.calls, "SetCustomUserClaims:"+uid)
+	m.claims = customClaims
+	return nil
+}
+
+func (m *mockAuthClient) VerifyIDToken(idToken string) (*auth.Token, error) {
+	m.calls = append(m.calls, "VerifyIDToken:"+idToken)
+	return &auth.Token{Claims: map[string]interface{}{"sub": "uid1"}}, nil
+}
+
+func (m *mockAuthClient) VerifyIDTokenAndCheckRevoked(ctx context.Context, idToken string) (*auth.Token, error) {
+	m.calls = append(m.calls, "VerifyIDTokenAndCheckRevoked:"+idToken)
+	return &auth.Token{Claims: map[string]interface{}{"sub": "uid1"}}, nil
+}
+
+func TestRun(t *testing.T) {
+	cases := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"user", "get", "uid1"}, "GetUser:uid1"},
+		{[]string{"user", "disable", "uid1"}, "UpdateUser:uid1"},
+		{[]string{"user", "enable", "uid1"}, "UpdateUser:uid1"},
+		{[]string{"user", "delete", "uid1"}, "DeleteUser:uid1"},
+		{[]string{"claims", "set", "uid1", `{"admin": true}`}, "SetCustomUserClaims:uid1"},
+		{[]string{"token", "verify", "token1"}, "VerifyIDToken:token1"},
+		{[]string{"token", "verify", "-revoked", "token1"}, "VerifyIDTokenAndCheckRevoked:token1"},
+	}
+	for _, tc := range cases {
+		client := &mockAuthClient{}
+		var out bytes.Buffer
+		if err := run(context.Background(), client, tc.args, &out); err != nil {
+			t.Errorf("run(%v) = %v; want = nil", tc.args, err)
+			continue
+		}
+		if len(client.calls) != 1 || client.calls[0] != tc.want {
+			t.Errorf("run(%v) calls = %v; want = [%s]", tc.args, client.calls, tc.want)
+		}
+	}
+}
+
+func TestRunOutput(t *testing.T) {
+	var out bytes.Buffer
+	if err := run(context.Background(), &mockAuthClient{}, []string{"user", "get", "uid1"}, &out); err != nil {
+		t.Fatal(err)
+	}
+	var got map[string]interface{}
+	if err := json.Unmarshal(out.Bytes(), &got); err != nil {
+		t.Fatalf("output is not JSON: %q", out.String())
+	}
+	if got["uid"] != "uid1" || got["email"] != "user@example.com" {
+		t.Errorf("user = %v; want = uid1 with email", got)
+	}
+	for _, secret := range []string{"aGFzaA==", "c2FsdA=="} {
+		if strings.Contains(out.String(), secret) {
+			t.Errorf("output = %s; want no password hash or salt", out.String())
+		}
+	}
+	if _, ok := got["rawId"]; ok {
+		t.Errorf("output = %s; want no rawId", out.String())
+	}
+}
+
+func TestRunClaims(t *testing.T) {
+	client := &mockAuthClient{}
+	args := []string{"claims", "set", "uid1", `{"admin": true, "level": 2}`}
+	if err := run(context.Background(), client, args, &bytes.Buffer{}); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{"admin": true, "level": float64(2)}
+	if !reflect.DeepEqual(client.claims, want) {
+		t.Errorf("claims = %v; want = %v", client.claims, want)
+	}
+}
+
+func TestRunErrors(t *testing.T) {
+	cases := [][]string{
+		nil,
+		{"user"},
+		{"user", "unknown", "uid1"},
+		{"user", "get"},
+		{"user", "get", "missing"},
+		{"claims", "set", "uid1"},
+		{"claims", "set", "uid1", "not json"},
+		{"token", "verify"},
+	}
+	for _, args := range cases {
+		if err := run(context.Background(), &mockAuthClient{}, args, &bytes.Buffer{}); err == nil {
+			t.Errorf("run(%v) = nil; want = error", args)
+		}
+	}
+}
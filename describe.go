@@ -0,0 +1,174 @@
+// Copyright 2017 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package firebase
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+
+	"golang.org/x/net/context"
+)
+
+// ProjectDescription is a snapshot of the resources of a Firebase project, as reported by the Firebase
+// management APIs.
+type ProjectDescription struct {
+	ProjectID            string
+	ProjectNumber        string
+	DisplayName          string
+	LocationID           string
+	DefaultStorageBucket string
+	DefaultHostingSite   string
+	DatabaseInstances    []*DatabaseInstance
+	Apps                 []*AppMetadata
+}
+
+// DatabaseInstance describes a Realtime Database instance of a Firebase project.
+type DatabaseInstance struct {
+	Name        string
+	DatabaseURL string
+	Type        string
+	State       string
+}
+
+// AppMetadata describes an Android, iOS or web app registered with a Firebase project.
+type AppMetadata struct {
+	AppID       string
+	DisplayName string
+	Platform    string
+	Namespace   string
+	State       string
+}
+
+// Describe returns a snapshot of the resources of the Firebase project of the App.
+//
+// The project details, the Realtime Database instances and the registered apps of the project are
+// fetched from the Firebase management APIs, which requires the credential of the App to be authorized
+// to view the project. Describe also resolves the project number of the App, as WarmUp does.
+func (a *App) Describe(ctx context.Context) (*ProjectDescription, error) {
+	if a.projectID == "" {
+		return nil, errors.New("project id not available")
+	}
+
+	var project struct {
+		ProjectID     string `json:"projectId"`
+		ProjectNumber string `json:"projectNumber"`
+		DisplayName   string `json:"displayName"`
+		Resources     struct {
+			HostingSite   string `json:"hostingSite"`
+			StorageBucket string `json:"storageBucket"`
+			LocationID    string `json:"locationId"`
+		} `json:"resources"`
+	}
+	if err := a.get(ctx, fmt.Sprintf("%s/projects/%s", a.mgtEndpoint, a.projectID), &project); err != nil {
+		return nil, err
+	}
+	if project.ProjectNumber != "" {
+		a.setProjectNumber(project.ProjectNumber)
+	}
+
+	desc := &ProjectDescription{
+		ProjectID:            project.ProjectID,
+		ProjectNumber:        project.ProjectNumber,
+		DisplayName:          project.DisplayName,
+		LocationID:           project.Resources.LocationID,
+		DefaultStorageBucket: project.Resources.StorageBucket,
+		DefaultHostingSite:   project.Resources.HostingSite,
+	}
+
+	dbURL := fmt.Sprintf("%s/projects/%s/locations/-/instances", a.dbMgtEndpoint, a.projectID)
+	err := a.listAll(ctx, dbURL, func(page []byte) (string, error) {
+		var parsed struct {
+			Instances []struct {
+				Name        string `json:"name"`
+				DatabaseURL string `json:"databaseUrl"`
+				Type        string `json:"type"`
+				State       string `json:"state"`
+			} `json:"instances"`
+			NextPageToken string `json:"nextPageToken"`
+		}
+		if err := json.Unmarshal(page, &parsed); err != nil {
+			return "", err
+		}
+		for _, inst := range parsed.Instances {
+			desc.DatabaseInstances = append(desc.DatabaseInstances, &DatabaseInstance{
+				Name:        inst.Name,
+				DatabaseURL: inst.DatabaseURL,
+				Type:        inst.Type,
+				State:       inst.State,
+			})
+		}
+		return parsed.NextPageToken, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	appsURL := fmt.Sprintf("%s/projects/%s:searchApps", a.mgtEndpoint, a.projectID)
+	err = a.listAll(ctx, appsURL, func(page []byte) (string, error) {
+		var parsed struct {
+			Apps []struct {
+				AppID       string `json:"appId"`
+				DisplayName string `json:"displayName"`
+				Platform    string `json:"platform"`
+				Namespace   string `json:"namespace"`
+				State       string `json:"state"`
+			} `json:"apps"`
+			NextPageToken string `json:"nextPageToken"`
+		}
+		if err := json.Unmarshal(page, &parsed); err != nil {
+			return "", err
+		}
+		for _, app := range parsed.Apps {
+			desc.Apps = append(desc.Apps, &AppMetadata{
+				AppID:       app.AppID,
+				DisplayName: app.DisplayName,
+				Platform:    app.Platform,
+				Namespace:   app.Namespace,
+				State:       app.State,
+			})
+		}
+		return parsed.NextPageToken, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return desc, nil
+}
+
+// listAll fetches all the pages of a paginated list API, passing each page to the parse function,
+// which returns the token of the next page.
+func (a *App) listAll(ctx context.Context, endpoint string, parse func(page []byte) (string, error)) error {
+	var pageToken string
+	for {
+		u := endpoint
+		if pageToken != "" {
+			u = fmt.Sprintf("%s?pageToken=%s", endpoint, url.QueryEscape(pageToken))
+		}
+		var page json.RawMessage
+		if err := a.get(ctx, u, &page); err != nil {
+			return err
+		}
+		next, err := parse(page)
+		if err != nil {
+			return err
+		}
+		if next == "" {
+			return nil
+		}
+		pageToken = next
+	}
+}
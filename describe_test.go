@@ -0,0 +1,157 @@
+// Copyright 2017 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package firebase
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"golang.org/x/net/context"
+	"google.golang.org/api/option"
+)
+
+func TestDescribe(t *testing.T) {
+	responses := map[string]string{
+		"/projects/mock-project-id": `{
+			"projectId": "mock-project-id",
+			"projectNumber": "1234567890",
+			"displayName": "Mock Project",
+			"resources": {
+				"hostingSite": "mock-project-id",
+				"storageBucket": "mock-project-id.appspot.com",
+				"locationId": "us-central"
+			}
+		}`,
+		"/projects/mock-project-id/locations/-/instances": `{
+			"instances": [{
+				"name": "projects/1234567890/locations/us-central1/instances/mock-project-id",
+				"databaseUrl": "https://mock-project-id.firebaseio.com",
+				"type": "DEFAULT_DATABASE",
+				"state": "ACTIVE"
+			}]
+		}`,
+		"/projects/mock-project-id:searchApps": `{
+			"apps": [{
+				"appId": "1:1234567890:android:abc",
+				"displayName": "Android App",
+				"platform": "ANDROID",
+				"namespace": "com.example.android",
+				"state": "ACTIVE"
+			}],
+			"nextPageToken": "page2"
+		}`,
+		"/projects/mock-project-id:searchApps?page2": `{
+			"apps": [{
+				"appId": "1:1234567890:web:def",
+				"displayName": "Web App",
+				"platform": "WEB",
+				"state": "ACTIVE"
+			}]
+		}`,
+	}
+	service := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.URL.Path
+		if token := r.URL.Query().Get("pageToken"); token != "" {
+			key += "?" + token
+		}
+		resp, ok := responses[key]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(resp))
+	}))
+	defer service.Close()
+
+	ctx := context.Background()
+	ts := &testTokenSource{AccessToken: "mock-token-from-custom"}
+	app, err := NewApp(ctx, &Config{ProjectID: "mock-project-id"}, option.WithTokenSource(ts))
+	if err != nil {
+		t.Fatal(err)
+	}
+	app.mgtEndpoint = service.URL
+	app.dbMgtEndpoint = service.URL
+
+	desc, err := app.Describe(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &ProjectDescription{
+		ProjectID:            "mock-project-id",
+		ProjectNumber:        "1234567890",
+		DisplayName:          "Mock Project",
+		LocationID:           "us-central",
+		DefaultStorageBucket: "mock-project-id.appspot.com",
+		DefaultHostingSite:   "mock-project-id",
+		DatabaseInstances: []*DatabaseInstance{
+			{
+				Name:        "projects/1234567890/locations/us-central1/instances/mock-project-id",
+				DatabaseURL: "https://mock-project-id.firebaseio.com",
+				Type:        "DEFAULT_DATABASE",
+				State:       "ACTIVE",
+			},
+		},
+		Apps: []*AppMetadata{
+			{
+				AppID:       "1:1234567890:android:abc",
+				DisplayName: "Android App",
+				Platform:    "ANDROID",
+				Namespace:   "com.example.android",
+				State:       "ACTIVE",
+			},
+			{
+				AppID:       "1:1234567890:web:def",
+				DisplayName: "Web App",
+				Platform:    "WEB",
+				State:       "ACTIVE",
+			},
+		},
+	}
+	if !reflect.DeepEqual(desc, want) {
+		t.Errorf("Describe() = %#v; want: %#v", desc, want)
+	}
+	if pn := app.ProjectNumber(); pn != "1234567890" {
+		t.Errorf("ProjectNumber() = %q; want: %q", pn, "1234567890")
+	}
+}
+
+func TestDescribeError(t *testing.T) {
+	service := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/projects/mock-project-id" {
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"projectId": "mock-project-id", "projectNumber": "1234567890"}`))
+			return
+		}
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(`{"error": {"code": 403, "message": "permission denied"}}`))
+	}))
+	defer service.Close()
+
+	ctx := context.Background()
+	ts := &testTokenSource{AccessToken: "mock-token-from-custom"}
+	app, err := NewApp(ctx, &Config{ProjectID: "mock-project-id"}, option.WithTokenSource(ts))
+	if err != nil {
+		t.Fatal(err)
+	}
+	app.mgtEndpoint = service.URL
+	app.dbMgtEndpoint = service.URL
+
+	if desc, err := app.Describe(ctx); desc != nil || err == nil {
+		t.Errorf("Describe() = (%v, %v); want: (nil, error)", desc, err)
+	}
+}
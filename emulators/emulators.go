@@ -0,0 +1,325 @@
+// Copyright 2017 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package emulators starts and stops the Firebase emulator suite for integration tests.
+//
+// The emulators are run by the Firebase CLI, which must be installed separately. A typical test
+// starts the suite once in TestMain, and sets the environment variables returned by Env before
+// creating any clients:
+//
+//	suite := &emulators.Suite{ProjectID: "demo-project", Emulators: []string{emulators.Auth}}
+//	if err := suite.Start(ctx); err != nil {
+//		log.Fatal(err)
+//	}
+//	if err := suite.SetEnv(); err != nil {
+//		log.Fatal(err)
+//	}
+//	code := m.Run()
+//	suite.Stop()
+//	os.Exit(code)
+//
+// Of the clients of this SDK, only the Auth client is routed to its emulator, when it is created
+// while FIREBASE_AUTH_EMULATOR_HOST is set. The SDK has no Database or Firestore clients: the
+// FIREBASE_DATABASE_EMULATOR_HOST and FIRESTORE_EMULATOR_HOST variables are only honored by other
+// libraries that read them, such as the Cloud Firestore client for Go. Clients that do not read these
+// variables keep talking to production services, so tests should use a "demo-" project ID.
+package emulators
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+// Names of the emulators supported by Suite.
+const (
+	Auth      = "auth"
+	Database  = "database"
+	Firestore = "firestore"
+)
+
+const (
+	defaultCLIPath     = "firebase"
+	defaultHubAddr     = "localhost:4400"
+	defaultStopTimeout = 10 * time.Second
+	pollInterval       = 250 * time.Millisecond
+)
+
+// envVars maps each emulator to the environment variable that points clients at it.
+var envVars = map[string]string{
+	Auth:      "FIREBASE_AUTH_EMULATOR_HOST",
+	Database:  "FIREBASE_DATABASE_EMULATOR_HOST",
+	Firestore: "FIRESTORE_EMULATOR_HOST",
+}
+
+// Suite is a Firebase emulator suite run by the Firebase CLI.
+//
+// A Suite must not be copied after it has been started.
+type Suite struct {
+	// ProjectID is the project the emulators serve. Project IDs starting with "demo-" never reach
+	// production services.
+	ProjectID string
+
+	// Emulators lists the emulators to start. Defaults to Auth, Database and Firestore.
+	Emulators []string
+
+	// CLIPath is the path of the Firebase CLI executable. Defaults to "firebase", looked up in PATH.
+	CLIPath string
+
+	// Dir is the working directory of the CLI, in which it looks for firebase.json. Defaults to the
+	// current directory.
+	Dir string
+
+	// HubAddr is the host:port of the emulator hub, which reports the emulators that are running.
+	// Defaults to "localhost:4400", the default of the Firebase CLI.
+	HubAddr string
+
+	// StopTimeout is how long Stop waits for the CLI to exit, before killing it. Defaults to 10
+	// seconds.
+	StopTimeout time.Duration
+
+	// Stdout and Stderr receive the output of the CLI. Output is discarded if nil.
+	Stdout io.Writer
+	Stderr io.Writer
+
+	mutex   sync.Mutex
+	cmd     *exec.Cmd
+	exited  chan struct{}
+	exitErr error
+	hosts   map[string]string
+}
+
+// Start starts the emulators, and blocks until they are ready to serve requests, or the context is
+// done. The emulators are stopped if they fail to become ready.
+func (s *Suite) Start(ctx context.Context) error {
+	if s.ProjectID == "" {
+		return errors.New("project id must not be empty")
+	}
+	for _, e := range s.emulators() {
+		if _, ok := envVars[e]; !ok {
+			return fmt.Errorf("unsupported emulator: %q", e)
+		}
+	}
+
+	s.mutex.Lock()
+	if s.cmd != nil {
+		s.mutex.Unlock()
+		return errors.New("emulator suite already started")
+	}
+	cli := s.CLIPath
+	if cli == "" {
+		cli = defaultCLIPath
+	}
+	path, err := exec.LookPath(cli)
+	if err != nil {
+		s.mutex.Unlock()
+		return fmt.Errorf("firebase CLI not found: %v", err)
+	}
+	cmd := exec.Command(path, "emulators:start",
+		"--project", s.ProjectID,
+		"--only", strings.Join(s.emulators(), ","))
+	cmd.Dir = s.Dir
+	cmd.Stdout = s.Stdout
+	cmd.Stderr = s.Stderr
+	if err := cmd.Start(); err != nil {
+		s.mutex.Unlock()
+		return err
+	}
+	s.cmd = cmd
+	s.exited = make(chan struct{})
+	go func() {
+		err := cmd.Wait()
+		s.mutex.Lock()
+		s.exitErr = err
+		s.mutex.Unlock()
+		close(s.exited)
+	}()
+	s.mutex.Unlock()
+
+	if err := s.WaitReady(ctx); err != nil {
+		s.Stop()
+		return err
+	}
+	return nil
+}
+
+// WaitReady blocks until all the emulators of the Suite are reported running by the emulator hub, or
+// the context is done.
+//
+// WaitReady may also be used without Start, to wait for emulators started by another process, such
+// as "firebase emulators:exec".
+func (s *Suite) WaitReady(ctx context.Context) error {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for {
+		hosts, err := s.fetchHosts(ctx)
+		if err == nil {
+			s.mutex.Lock()
+			s.hosts = hosts
+			s.mutex.Unlock()
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("emulators not ready: %v", err)
+		case <-s.exitedChan():
+			return fmt.Errorf("firebase CLI exited before emulators were ready: %v", s.exitError())
+		case <-ticker.C:
+		}
+	}
+}
+
+// Env returns the environment variables that advertise the running emulators to the clients that
+// read them, along with GCLOUD_PROJECT, which sets the project of Apps initialized without a project
+// ID. Env returns nil if the emulators are not ready. See the package documentation for the clients
+// that are routed to the emulators.
+func (s *Suite) Env() map[string]string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.hosts == nil {
+		return nil
+	}
+	env := map[string]string{"GCLOUD_PROJECT": s.ProjectID}
+	for e, host := range s.hosts {
+		env[envVars[e]] = host
+	}
+	return env
+}
+
+// SetEnv sets the environment variables returned by Env in the current process. It only affects
+// clients created afterwards.
+func (s *Suite) SetEnv() error {
+	env := s.Env()
+	if env == nil {
+		return errors.New("emulators not ready")
+	}
+	keys := make([]string, 0, len(env))
+	for k := range env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if err := os.Setenv(k, env[k]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Stop stops the emulators started by Start, and waits for the Firebase CLI to exit.
+//
+// The CLI is first interrupted, so that it can shut the emulators down gracefully, and killed if it
+// does not exit within StopTimeout. Stop is a no-op if the Suite has not been started.
+func (s *Suite) Stop() error {
+	s.mutex.Lock()
+	cmd, exited := s.cmd, s.exited
+	s.mutex.Unlock()
+	if cmd == nil {
+		return nil
+	}
+
+	timeout := s.StopTimeout
+	if timeout <= 0 {
+		timeout = defaultStopTimeout
+	}
+	select {
+	case <-exited:
+	default:
+		if err := cmd.Process.Signal(os.Interrupt); err != nil {
+			cmd.Process.Kill()
+		}
+		select {
+		case <-exited:
+		case <-time.After(timeout):
+			cmd.Process.Kill()
+			<-exited
+		}
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.cmd = nil
+	s.hosts = nil
+	return nil
+}
+
+func (s *Suite) emulators() []string {
+	if len(s.Emulators) == 0 {
+		return []string{Auth, Database, Firestore}
+	}
+	return s.Emulators
+}
+
+func (s *Suite) exitedChan() <-chan struct{} {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.exited
+}
+
+func (s *Suite) exitError() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.exitErr
+}
+
+// fetchHosts queries the emulator hub for the running emulators, and returns the host:port of each
+// emulator of the Suite. It returns an error if any of the emulators is not running yet.
+func (s *Suite) fetchHosts(ctx context.Context) (map[string]string, error) {
+	addr := s.HubAddr
+	if addr == "" {
+		addr = defaultHubAddr
+	}
+	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("http://%s/emulators", addr), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected http status code from emulator hub: %d", resp.StatusCode)
+	}
+
+	var running map[string]struct {
+		Host string `json:"host"`
+		Port int    `json:"port"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&running); err != nil {
+		return nil, err
+	}
+	hosts := make(map[string]string)
+	for _, e := range s.emulators() {
+		info, ok := running[e]
+		if !ok {
+			return nil, fmt.Errorf("emulator not running: %q", e)
+		}
+		hosts[e] = net.JoinHostPort(info.Host, strconv.Itoa(info.Port))
+	}
+	return hosts, nil
+}
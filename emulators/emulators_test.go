@@ -0,0 +1,206 @@
+// Copyright 2017 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package emulators
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+const hubResponse = `{
+	"hub": {"name": "hub", "host": "localhost", "port": 4400},
+	"auth": {"name": "auth", "host": "127.0.0.1", "port": 9099},
+	"database": {"name": "database", "host": "127.0.0.1", "port": 9000},
+	"firestore": {"name": "firestore", "host": "::1", "port": 8080}
+}`
+
+func hubServer(resp string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/emulators" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(resp))
+	}))
+}
+
+func hubAddr(s *httptest.Server) string {
+	return strings.TrimPrefix(s.URL, "http://")
+}
+
+// fakeCLI creates an executable that stands in for the Firebase CLI, and runs until it is
+// interrupted.
+func fakeCLI(t *testing.T) string {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake CLI requires a POSIX shell")
+	}
+	dir, err := ioutil.TempDir("", "emulators")
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "firebase")
+	if err := ioutil.WriteFile(path, []byte("#!/bin/sh\nexec sleep 30\n"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestWaitReady(t *testing.T) {
+	hub := hubServer(hubResponse)
+	defer hub.Close()
+
+	s := &Suite{ProjectID: "demo-project", HubAddr: hubAddr(hub)}
+	if env := s.Env(); env != nil {
+		t.Errorf("Env() before ready = %v; want = nil", env)
+	}
+	if err := s.WaitReady(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]string{
+		"GCLOUD_PROJECT":                  "demo-project",
+		"FIREBASE_AUTH_EMULATOR_HOST":     "127.0.0.1:9099",
+		"FIREBASE_DATABASE_EMULATOR_HOST": "127.0.0.1:9000",
+		"FIRESTORE_EMULATOR_HOST":         "[::1]:8080",
+	}
+	if env := s.Env(); !reflect.DeepEqual(env, want) {
+		t.Errorf("Env() = %v; want = %v", env, want)
+	}
+}
+
+func TestWaitReadySubset(t *testing.T) {
+	hub := hubServer(`{"auth": {"host": "localhost", "port": 9099}}`)
+	defer hub.Close()
+
+	s := &Suite{ProjectID: "demo-project", Emulators: []string{Auth}, HubAddr: hubAddr(hub)}
+	if err := s.WaitReady(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"GCLOUD_PROJECT":              "demo-project",
+		"FIREBASE_AUTH_EMULATOR_HOST": "localhost:9099",
+	}
+	if env := s.Env(); !reflect.DeepEqual(env, want) {
+		t.Errorf("Env() = %v; want = %v", env, want)
+	}
+}
+
+func TestWaitReadyTimeout(t *testing.T) {
+	hub := hubServer(`{"auth": {"host": "localhost", "port": 9099}}`)
+	defer hub.Close()
+
+	s := &Suite{ProjectID: "demo-project", HubAddr: hubAddr(hub)}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := s.WaitReady(ctx); err == nil {
+		t.Errorf("WaitReady() = nil; want = error")
+	}
+	if env := s.Env(); env != nil {
+		t.Errorf("Env() = %v; want = nil", env)
+	}
+}
+
+func TestSetEnv(t *testing.T) {
+	hub := hubServer(`{"auth": {"host": "localhost", "port": 9099}}`)
+	defer hub.Close()
+
+	s := &Suite{ProjectID: "demo-project", Emulators: []string{Auth}, HubAddr: hubAddr(hub)}
+	if err := s.SetEnv(); err == nil {
+		t.Errorf("SetEnv() before ready = nil; want = error")
+	}
+	if err := s.WaitReady(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	old := os.Getenv("FIREBASE_AUTH_EMULATOR_HOST")
+	defer os.Setenv("FIREBASE_AUTH_EMULATOR_HOST", old)
+	oldProject := os.Getenv("GCLOUD_PROJECT")
+	defer os.Setenv("GCLOUD_PROJECT", oldProject)
+
+	if err := s.SetEnv(); err != nil {
+		t.Fatal(err)
+	}
+	if host := os.Getenv("FIREBASE_AUTH_EMULATOR_HOST"); host != "localhost:9099" {
+		t.Errorf("FIREBASE_AUTH_EMULATOR_HOST = %q; want = %q", host, "localhost:9099")
+	}
+	if pid := os.Getenv("GCLOUD_PROJECT"); pid != "demo-project" {
+		t.Errorf("GCLOUD_PROJECT = %q; want = %q", pid, "demo-project")
+	}
+}
+
+func TestStartStop(t *testing.T) {
+	hub := hubServer(hubResponse)
+	defer hub.Close()
+
+	s := &Suite{ProjectID: "demo-project", CLIPath: fakeCLI(t), HubAddr: hubAddr(hub)}
+	defer os.RemoveAll(filepath.Dir(s.CLIPath))
+	if err := s.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Start(context.Background()); err == nil {
+		t.Errorf("Start() when started = nil; want = error")
+	}
+	if env := s.Env(); env == nil {
+		t.Errorf("Env() = nil; want = non-nil")
+	}
+
+	if err := s.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	if env := s.Env(); env != nil {
+		t.Errorf("Env() after Stop() = %v; want = nil", env)
+	}
+	if err := s.Stop(); err != nil {
+		t.Errorf("Stop() when stopped = %v; want = nil", err)
+	}
+}
+
+func TestStartCLIExits(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("requires a POSIX shell")
+	}
+	hub := hubServer(`{}`)
+	defer hub.Close()
+
+	s := &Suite{ProjectID: "demo-project", CLIPath: "false", HubAddr: hubAddr(hub)}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := s.Start(ctx); err == nil || !strings.Contains(err.Error(), "exited") {
+		t.Errorf("Start() = %v; want = CLI exited error", err)
+	}
+}
+
+func TestStartInvalid(t *testing.T) {
+	cases := []*Suite{
+		{},
+		{ProjectID: "demo-project", Emulators: []string{"functions"}},
+		{ProjectID: "demo-project", CLIPath: "no-such-firebase-cli"},
+	}
+	for _, s := range cases {
+		if err := s.Start(context.Background()); err == nil {
+			t.Errorf("Start(%v) = nil; want = error", s)
+		}
+	}
+}
@@ -18,10 +18,19 @@
 package firebase
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+
 	"firebase.google.com/go/auth"
+	"firebase.google.com/go/cache"
 	"firebase.google.com/go/internal"
+	"firebase.google.com/go/messaging"
 
 	"os"
+	"time"
 
 	"golang.org/x/net/context"
 	"golang.org/x/oauth2/google"
@@ -37,26 +46,179 @@ var firebaseScopes = []string{
 // Version of the Firebase Go Admin SDK.
 const Version = "1.0.0"
 
+const (
+	firebaseMgtEndpoint = "https://firebase.googleapis.com/v1beta1"
+	databaseMgtEndpoint = "https://firebasedatabase.googleapis.com/v1beta"
+)
+
 // An App holds configuration and state common to all Firebase services that are exposed from the SDK.
 type App struct {
-	ctx       context.Context
-	creds     *google.DefaultCredentials
-	projectID string
-	opts      []option.ClientOption
+	ctx            context.Context
+	creds          *google.DefaultCredentials
+	projectID      string
+	keyGracePeriod time.Duration
+	strict         bool
+	accepted       []string
+	cache          cache.Cache
+	opts           []option.ClientOption
+	mgtEndpoint    string
+	dbMgtEndpoint  string
+
+	authOnce   sync.Once
+	authClient *auth.Client
+	authErr    error
+
+	mutex         sync.Mutex
+	projectNumber string
 }
 
 // Config represents the configuration used to initialize an App.
 type Config struct {
 	ProjectID string
+
+	// AuthKeyGracePeriod is how long the Auth service may continue to verify ID tokens using cached
+	// public keys after they have expired, when the keys cannot be refreshed due to an error. Defaults
+	// to zero, in which case tokens fail verification as soon as the cached keys expire and cannot be
+	// refreshed.
+	AuthKeyGracePeriod time.Duration
+
+	// StrictVerification disables all leniency in ID token verification. ID tokens are verified with
+	// no clock skew allowance, and fail verification as soon as the cached public keys expire and
+	// cannot be refreshed. The public keys are always fetched from Google, and never read from or
+	// written to Cache. StrictVerification cannot be combined with a non-zero AuthKeyGracePeriod.
+	StrictVerification bool
+
+	// AuthAcceptedProjectIDs lists Firebase projects, in addition to the project of the App, whose ID
+	// tokens are accepted by the Auth service. This allows a single service to verify ID tokens issued
+	// for several projects, such as while migrating users from a staging to a production project.
+	AuthAcceptedProjectIDs []string
+
+	// Cache is used to store data that can be shared by several App instances, such as the public keys
+	// used to verify ID tokens and the project number. Use a Cache backed by a shared store to share
+	// this data across a fleet of servers. If not specified, each App caches data on its own. The
+	// public keys are not shared when StrictVerification is enabled.
+	Cache cache.Cache
+}
+
+// String returns a description of the App that identifies its project, without its credentials.
+func (a *App) String() string {
+	return fmt.Sprintf("firebase.App{projectID: %q}", a.projectID)
 }
 
 // Auth returns an instance of auth.Client.
+//
+// The same instance is returned on every call, so that cached state such as the public keys used to
+// verify ID tokens is shared by all users of the App.
 func (a *App) Auth() (*auth.Client, error) {
-	conf := &internal.AuthConfig{
-		Creds:     a.creds,
+	a.authOnce.Do(func() {
+		conf := &internal.AuthConfig{
+			Opts:               a.opts,
+			Creds:              a.creds,
+			ProjectID:          a.projectID,
+			KeyGracePeriod:     a.keyGracePeriod,
+			StrictVerification: a.strict,
+			AcceptedProjectIDs: a.accepted,
+			Cache:              a.cache,
+		}
+		a.authClient, a.authErr = auth.NewClient(a.ctx, conf)
+	})
+	return a.authClient, a.authErr
+}
+
+// Messaging returns an instance of messaging.Client.
+func (a *App) Messaging() (*messaging.Client, error) {
+	conf := &internal.MessagingConfig{
+		Opts:      a.opts,
 		ProjectID: a.projectID,
 	}
-	return auth.NewClient(conf)
+	return messaging.NewClient(a.ctx, conf)
+}
+
+// WarmUp prepares the App to serve requests without first-call latency.
+//
+// WarmUp obtains an OAuth2 access token from the credential of the App, resolves the project number of
+// the Firebase project, and fetches the public keys used to verify ID tokens. It blocks until all of
+// these steps have completed, or the context is done. A nil error indicates that the SDK is ready, and
+// hence WarmUp can be used as a readiness signal, for instance to delay routing traffic to a server
+// until warm-up completes.
+func (a *App) WarmUp(ctx context.Context) error {
+	if a.creds != nil && a.creds.TokenSource != nil {
+		if _, err := a.creds.TokenSource.Token(); err != nil {
+			return fmt.Errorf("failed to obtain access token: %v", err)
+		}
+	}
+	if err := a.resolveProjectNumber(ctx); err != nil {
+		return err
+	}
+	client, err := a.Auth()
+	if err != nil {
+		return err
+	}
+	return client.WarmUp(ctx)
+}
+
+// ProjectNumber returns the project number of the Firebase project, as resolved by WarmUp.
+//
+// ProjectNumber returns an empty string if WarmUp has not completed successfully.
+func (a *App) ProjectNumber() string {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	return a.projectNumber
+}
+
+func (a *App) resolveProjectNumber(ctx context.Context) error {
+	if a.ProjectNumber() != "" {
+		return nil
+	}
+	if a.projectID == "" {
+		return errors.New("project id not available")
+	}
+	cacheKey := "firebase.projectNumber:" + a.projectID
+	if a.cache != nil {
+		if b, ok := a.cache.Get(cacheKey); ok && len(b) > 0 {
+			a.setProjectNumber(string(b))
+			return nil
+		}
+	}
+
+	var parsed struct {
+		ProjectNumber string `json:"projectNumber"`
+	}
+	if err := a.get(ctx, fmt.Sprintf("%s/projects/%s", a.mgtEndpoint, a.projectID), &parsed); err != nil {
+		return err
+	}
+	if parsed.ProjectNumber == "" {
+		return fmt.Errorf("project number not available for project: %q", a.projectID)
+	}
+	if a.cache != nil {
+		a.cache.Set(cacheKey, []byte(parsed.ProjectNumber), 0)
+	}
+	a.setProjectNumber(parsed.ProjectNumber)
+	return nil
+}
+
+// get sends an authorized GET request to the specified URL of a Google API, and unmarshals the JSON
+// response into the variable pointed by v.
+func (a *App) get(ctx context.Context, url string, v interface{}) error {
+	hc, _, err := transport.NewHTTPClient(ctx, a.opts...)
+	if err != nil {
+		return err
+	}
+	client := &internal.HTTPClient{Client: hc}
+	resp, err := client.Do(ctx, &internal.Request{
+		Method: http.MethodGet,
+		URL:    url,
+	})
+	if err != nil {
+		return err
+	}
+	return resp.Unmarshal(http.StatusOK, v)
+}
+
+func (a *App) setProjectNumber(pn string) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	a.projectNumber = pn
 }
 
 // NewApp creates a new App from the provided config and client options.
@@ -64,6 +226,10 @@ func (a *App) Auth() (*auth.Client, error) {
 // If the client options contain a valid credential (a service account file, a refresh token file or an
 // oauth2.TokenSource) the App will be authenticated using that credential. Otherwise, NewApp attempts to
 // authenticate the App with Google application default credentials.
+//
+// Credentials may also be supplied as in-memory JSON using option.WithCredentialsJSON, in which case the
+// SDK never reads credentials from the file system. Service account credentials are validated by NewApp,
+// so that a malformed credential is reported here, rather than when a service is first used.
 func NewApp(ctx context.Context, config *Config, opts ...option.ClientOption) (*App, error) {
 	o := []option.ClientOption{option.WithScopes(firebaseScopes...)}
 	o = append(o, opts...)
@@ -72,6 +238,9 @@ func NewApp(ctx context.Context, config *Config, opts ...option.ClientOption) (*
 	if err != nil {
 		return nil, err
 	}
+	if err := validateCredentials(creds); err != nil {
+		return nil, err
+	}
 
 	var pid string
 	if config != nil && config.ProjectID != "" {
@@ -82,10 +251,82 @@ func NewApp(ctx context.Context, config *Config, opts ...option.ClientOption) (*
 		pid = os.Getenv("GCLOUD_PROJECT")
 	}
 
+	var grace time.Duration
+	var strict bool
+	var accepted []string
+	var c cache.Cache
+	if config != nil {
+		grace = config.AuthKeyGracePeriod
+		strict = config.StrictVerification
+		accepted = append(accepted, config.AuthAcceptedProjectIDs...)
+		c = config.Cache
+	}
+
 	return &App{
-		ctx:       ctx,
-		creds:     creds,
-		projectID: pid,
-		opts:      o,
+		ctx:            ctx,
+		creds:          creds,
+		projectID:      pid,
+		keyGracePeriod: grace,
+		strict:         strict,
+		accepted:       accepted,
+		cache:          c,
+		opts:           o,
+		mgtEndpoint:    firebaseMgtEndpoint,
+		dbMgtEndpoint:  databaseMgtEndpoint,
 	}, nil
 }
+
+// DeprecationEvent describes an invocation of a deprecated API or legacy endpoint of the SDK.
+//
+// File and Line identify the call site in the application code that invoked the deprecated API, and
+// Replacement names the API that should be used instead, if any.
+type DeprecationEvent struct {
+	API         string
+	Replacement string
+	File        string
+	Line        int
+}
+
+// SetDeprecationHook registers a function that is called whenever the application invokes a deprecated
+// API of the SDK. This helps locating and migrating call sites before the deprecated APIs are removed.
+// The hook may be called concurrently from multiple goroutines. Pass nil to stop reporting.
+func SetDeprecationHook(hook func(*DeprecationEvent)) {
+	if hook == nil {
+		internal.SetDeprecationHook(nil)
+		return
+	}
+	internal.SetDeprecationHook(func(e *internal.DeprecationEvent) {
+		hook(&DeprecationEvent{
+			API:         e.API,
+			Replacement: e.Replacement,
+			File:        e.File,
+			Line:        e.Line,
+		})
+	})
+}
+
+// validateCredentials checks that a service account credential contains the client email and the RSA
+// private key required for signing custom tokens. Other types of credentials are not checked.
+func validateCredentials(creds *google.DefaultCredentials) error {
+	if creds == nil || len(creds.JSON) == 0 {
+		return nil
+	}
+	var svcAcct struct {
+		Type        string `json:"type"`
+		ClientEmail string `json:"client_email"`
+		PrivateKey  string `json:"private_key"`
+	}
+	if err := json.Unmarshal(creds.JSON, &svcAcct); err != nil {
+		return err
+	}
+	if svcAcct.Type != "service_account" {
+		return nil
+	}
+	if svcAcct.ClientEmail == "" {
+		return errors.New("service account credential must contain a client_email")
+	}
+	if _, err := internal.ParsePrivateKey(svcAcct.PrivateKey); err != nil {
+		return fmt.Errorf("service account credential contains an invalid private_key: %v", err)
+	}
+	return nil
+}
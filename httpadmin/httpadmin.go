@@ -0,0 +1,304 @@
+// Copyright 2017 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package httpadmin contains HTTP handlers that expose common Firebase admin operations, so that they
+// can be mounted directly into internal admin dashboards.
+//
+// Every request is first passed to a Guard, which must authorize the caller. The handlers perform
+// privileged operations with the credentials of the SDK, and must never be exposed without a Guard
+// that restricts access to trusted administrators.
+package httpadmin
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"firebase.google.com/go/auth"
+	"firebase.google.com/go/messaging"
+
+	"golang.org/x/net/context"
+)
+
+const (
+	usersPath          = "/users/"
+	testMessagePath    = "/messages/test"
+	maxTestMessageSize = 64 * 1024
+)
+
+// Guard authorizes an incoming admin request. It returns a non-nil error to reject the request.
+type Guard func(r *http.Request) error
+
+type userManager interface {
+	GetUser(ctx context.Context, uid string) (*auth.UserRecord, error)
+	UpdateUser(ctx context.Context, uid string, user *auth.UserToUpdate) (*auth.UserRecord, error)
+	RevokeRefreshTokens(ctx context.Context, uid string) error
+}
+
+type messageSender interface {
+	Send(ctx context.Context, message *messaging.Message) (string, error)
+	SendDryRun(ctx context.Context, message *messaging.Message) (string, error)
+}
+
+// Handler is an http.Handler that serves the admin endpoints.
+//
+// Handler serves the following endpoints, relative to the path it is mounted at (use http.StripPrefix
+// to mount it under a prefix):
+//
+//	GET  /users/{uid}            Looks up the user account with the given user ID.
+//	POST /users/{uid}/disable    Disables the user account, and returns the updated account.
+//	POST /users/{uid}/revoke     Revokes all refresh tokens of the user account, without disabling
+//	                             it, and returns the updated account.
+//	POST /messages/test          Sends a test notification to a registration token. Only served if a
+//	                             messaging client is set with WithMessaging.
+//
+// The body of a test message request is a JSON object with the token, title and body of the
+// notification, an optional data object, and an optional dryRun flag, in which case the message is
+// validated but not delivered.
+//
+// Responses are JSON objects. Requests rejected by the Guard receive a 403 response.
+type Handler struct {
+	users    userManager
+	messages messageSender
+	guard    Guard
+}
+
+// NewHandler creates a new Handler that performs admin operations using the given auth.Client.
+func NewHandler(client *auth.Client, guard Guard) (*Handler, error) {
+	if client == nil {
+		return nil, errors.New("auth client must not be nil")
+	}
+	if guard == nil {
+		return nil, errors.New("guard must not be nil")
+	}
+	return &Handler{
+		users: client,
+		guard: guard,
+	}, nil
+}
+
+// WithMessaging returns a copy of the Handler that also serves the test message endpoint, sending
+// messages with the given messaging.Client. The Handler on which it is called is not modified.
+func (h *Handler) WithMessaging(client *messaging.Client) *Handler {
+	copied := *h
+	copied.messages = nil
+	if client != nil {
+		copied.messages = client
+	}
+	return &copied
+}
+
+// ServeHTTP implements the http.Handler interface.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if err := h.guard(r); err != nil {
+		writeError(w, http.StatusForbidden, err)
+		return
+	}
+
+	if r.URL.Path == testMessagePath && h.messages != nil {
+		if !allowMethod(w, r, http.MethodPost) {
+			return
+		}
+		h.sendTestMessage(w, r)
+		return
+	}
+
+	if !strings.HasPrefix(r.URL.Path, usersPath) {
+		writeError(w, http.StatusNotFound, errors.New("unknown admin endpoint"))
+		return
+	}
+	segments := strings.Split(strings.TrimPrefix(r.URL.Path, usersPath), "/")
+	uid := segments[0]
+	if uid == "" || len(segments) > 2 {
+		writeError(w, http.StatusNotFound, errors.New("unknown admin endpoint"))
+		return
+	}
+
+	if len(segments) == 1 {
+		if allowMethod(w, r, http.MethodGet) {
+			h.getUser(w, r, uid)
+		}
+		return
+	}
+	switch segments[1] {
+	case "disable":
+		if allowMethod(w, r, http.MethodPost) {
+			h.disableUser(w, r, uid)
+		}
+	case "revoke":
+		if allowMethod(w, r, http.MethodPost) {
+			h.revokeUser(w, r, uid)
+		}
+	default:
+		writeError(w, http.StatusNotFound, errors.New("unknown admin endpoint"))
+	}
+}
+
+// allowMethod reports whether the request uses the given method, and writes a 405 response if not.
+func allowMethod(w http.ResponseWriter, r *http.Request, method string) bool {
+	if r.Method == method {
+		return true
+	}
+	w.Header().Set("Allow", method)
+	writeError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+	return false
+}
+
+func (h *Handler) getUser(w http.ResponseWriter, r *http.Request, uid string) {
+	u, err := h.users.GetUser(r.Context(), uid)
+	if err != nil {
+		writeAuthError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, newUserResponse(u))
+}
+
+func (h *Handler) disableUser(w http.ResponseWriter, r *http.Request, uid string) {
+	u, err := h.users.UpdateUser(r.Context(), uid, (&auth.UserToUpdate{}).Disabled(true))
+	if err != nil {
+		writeAuthError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, newUserResponse(u))
+}
+
+func (h *Handler) revokeUser(w http.ResponseWriter, r *http.Request, uid string) {
+	if err := h.users.RevokeRefreshTokens(r.Context(), uid); err != nil {
+		writeAuthError(w, err)
+		return
+	}
+	h.getUser(w, r, uid)
+}
+
+type testMessageRequest struct {
+	Token  string            `json:"token"`
+	Title  string            `json:"title"`
+	Body   string            `json:"body"`
+	Data   map[string]string `json:"data"`
+	DryRun bool              `json:"dryRun"`
+}
+
+type testMessageResponse struct {
+	MessageID string `json:"messageId"`
+	DryRun    bool   `json:"dryRun"`
+}
+
+func (h *Handler) sendTestMessage(w http.ResponseWriter, r *http.Request) {
+	var req testMessageRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxTestMessageSize)).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, errors.New("malformed test message request"))
+		return
+	}
+	if req.Token == "" {
+		writeError(w, http.StatusBadRequest, errors.New("token must not be empty"))
+		return
+	}
+
+	msg := &messaging.Message{
+		Token: req.Token,
+		Data:  req.Data,
+		Notification: &messaging.Notification{
+			Title: req.Title,
+			Body:  req.Body,
+		},
+	}
+	send := h.messages.Send
+	if req.DryRun {
+		send = h.messages.SendDryRun
+	}
+	id, err := send(r.Context(), msg)
+	if err != nil {
+		writeMessagingError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, &testMessageResponse{MessageID: id, DryRun: req.DryRun})
+}
+
+type providerResponse struct {
+	ProviderID  string `json:"providerId"`
+	UID         string `json:"uid"`
+	DisplayName string `json:"displayName,omitempty"`
+	Email       string `json:"email,omitempty"`
+	PhoneNumber string `json:"phoneNumber,omitempty"`
+	PhotoURL    string `json:"photoUrl,omitempty"`
+}
+
+type userResponse struct {
+	UID                string                 `json:"uid"`
+	DisplayName        string                 `json:"displayName,omitempty"`
+	Email              string                 `json:"email,omitempty"`
+	EmailVerified      bool                   `json:"emailVerified"`
+	PhoneNumber        string                 `json:"phoneNumber,omitempty"`
+	PhotoURL           string                 `json:"photoUrl,omitempty"`
+	Disabled           bool                   `json:"disabled"`
+	CustomClaims       map[string]interface{} `json:"customClaims,omitempty"`
+	Providers          []*providerResponse    `json:"providers,omitempty"`
+	CreationTimestamp  int64                  `json:"creationTimestamp,omitempty"`
+	LastLogInTimestamp int64                  `json:"lastLogInTimestamp,omitempty"`
+}
+
+func newUserResponse(u *auth.UserRecord) *userResponse {
+	resp := &userResponse{
+		UID:           u.UID,
+		DisplayName:   u.DisplayName,
+		Email:         u.Email,
+		EmailVerified: u.EmailVerified,
+		PhoneNumber:   u.PhoneNumber,
+		PhotoURL:      u.PhotoURL,
+		Disabled:      u.Disabled,
+		CustomClaims:  u.CustomClaims,
+	}
+	for _, p := range u.ProviderUserInfo {
+		resp.Providers = append(resp.Providers, &providerResponse{
+			ProviderID:  p.ProviderID,
+			UID:         p.UID,
+			DisplayName: p.DisplayName,
+			Email:       p.Email,
+			PhoneNumber: p.PhoneNumber,
+			PhotoURL:    p.PhotoURL,
+		})
+	}
+	if u.UserMetadata != nil {
+		resp.CreationTimestamp = u.UserMetadata.CreationTimestamp
+		resp.LastLogInTimestamp = u.UserMetadata.LastLogInTimestamp
+	}
+	return resp
+}
+
+func writeAuthError(w http.ResponseWriter, err error) {
+	if auth.IsUserNotFound(err) {
+		writeError(w, http.StatusNotFound, err)
+	} else {
+		writeError(w, http.StatusInternalServerError, err)
+	}
+}
+
+func writeMessagingError(w http.ResponseWriter, err error) {
+	if messaging.IsInvalidArgument(err) || messaging.IsRegistrationTokenNotRegistered(err) {
+		writeError(w, http.StatusBadRequest, err)
+	} else {
+		writeError(w, http.StatusInternalServerError, err)
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
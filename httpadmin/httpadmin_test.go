@@ -0,0 +1,306 @@
+// Copyright 2017 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package httpadmin
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"firebase.google.com/go/auth"
+	"firebase.google.com/go/messaging"
+
+	"golang.org/x/net/context"
+)
+
+type mockUserManager struct {
+	users map[string]*auth.UserRecord
+	err   error
+	calls []string
+}
+
+func (m *mockUserManager) GetUser(ctx context.Context, uid string) (*auth.UserRecord, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	return m.users[uid], nil
+}
+
+func (m *mockUserManager) UpdateUser(ctx context.Context, uid string,
+	user *auth.UserToUpdate) (*auth.UserRecord, error) {
+	m.calls = append(m.calls, "UpdateUser:"+uid)
+	if m.err != nil {
+		return nil, m.err
+	}
+	return &auth.UserRecord{UserInfo: &auth.UserInfo{UID: uid}, Disabled: true}, nil
+}
+
+func (m *mockUserManager) RevokeRefreshTokens(ctx context.Context, uid string) error {
+	m.calls = append(m.calls, "RevokeRefreshTokens:"+uid)
+	return m.err
+}
+
+type mockMessageSender struct {
+	messages []*messaging.Message
+	dryRun   []bool
+	err      error
+}
+
+func (m *mockMessageSender) Send(ctx context.Context, message *messaging.Message) (string, error) {
+	return m.send(message, false)
+}
+
+func (m *mockMessageSender) SendDryRun(ctx context.Context, message *messaging.Message) (string, error) {
+	return m.send(message, true)
+}
+
+func (m *mockMessageSender) send(message *messaging.Message, dryRun bool) (string, error) {
+	m.messages = append(m.messages, message)
+	m.dryRun = append(m.dryRun, dryRun)
+	if m.err != nil {
+		return "", m.err
+	}
+	return "projects/mock-project-id/messages/msg1", nil
+}
+
+func allowAll(r *http.Request) error {
+	return nil
+}
+
+func newTestHandler(m *mockUserManager, guard Guard) *Handler {
+	return &Handler{users: m, guard: guard}
+}
+
+func serve(h http.Handler, method, path string) *httptest.ResponseRecorder {
+	return serveBody(h, method, path, "")
+}
+
+func serveBody(h http.Handler, method, path, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestGetUser(t *testing.T) {
+	m := &mockUserManager{users: map[string]*auth.UserRecord{
+		"uid1": {
+			UserInfo: &auth.UserInfo{UID: "uid1", Email: "user1@example.com"},
+			ProviderUserInfo: []*auth.UserInfo{
+				{ProviderID: "password", UID: "user1@example.com"},
+			},
+			UserMetadata: &auth.UserMetadata{CreationTimestamp: 1000},
+		},
+	}}
+	rec := serve(newTestHandler(m, allowAll), http.MethodGet, "/users/uid1")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Status = %d; want = %d", rec.Code, http.StatusOK)
+	}
+
+	var got userResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.UID != "uid1" || got.Email != "user1@example.com" || got.CreationTimestamp != 1000 {
+		t.Errorf("GetUser() = %#v; want = uid1", got)
+	}
+	if len(got.Providers) != 1 || got.Providers[0].ProviderID != "password" {
+		t.Errorf("Providers = %v; want = [password]", got.Providers)
+	}
+}
+
+func TestDisableUser(t *testing.T) {
+	m := &mockUserManager{}
+	rec := serve(newTestHandler(m, allowAll), http.MethodPost, "/users/uid1/disable")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Status = %d; want = %d", rec.Code, http.StatusOK)
+	}
+	if want := []string{"UpdateUser:uid1"}; !reflect.DeepEqual(m.calls, want) {
+		t.Errorf("calls = %v; want = %v", m.calls, want)
+	}
+
+	var got userResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.UID != "uid1" || !got.Disabled {
+		t.Errorf("DisableUser() = %#v; want = disabled uid1", got)
+	}
+}
+
+func TestRevokeTokens(t *testing.T) {
+	m := &mockUserManager{users: map[string]*auth.UserRecord{
+		"uid1": {UserInfo: &auth.UserInfo{UID: "uid1"}, TokensValidAfterMillis: 1000},
+	}}
+	rec := serve(newTestHandler(m, allowAll), http.MethodPost, "/users/uid1/revoke")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Status = %d; want = %d", rec.Code, http.StatusOK)
+	}
+	if want := []string{"RevokeRefreshTokens:uid1"}; !reflect.DeepEqual(m.calls, want) {
+		t.Errorf("calls = %v; want = %v", m.calls, want)
+	}
+
+	var got userResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.UID != "uid1" || got.Disabled {
+		t.Errorf("RevokeTokens() = %#v; want = enabled uid1", got)
+	}
+}
+
+func TestSendTestMessage(t *testing.T) {
+	for _, dryRun := range []bool{false, true} {
+		m := &mockMessageSender{}
+		h := newTestHandler(&mockUserManager{}, allowAll)
+		h.messages = m
+		body := `{"token": "device-token", "title": "Hello", "body": "World", "data": {"k": "v"}, "dryRun": false}`
+		if dryRun {
+			body = strings.Replace(body, `"dryRun": false`, `"dryRun": true`, 1)
+		}
+
+		rec := serveBody(h, http.MethodPost, "/messages/test", body)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Status = %d; want = %d", rec.Code, http.StatusOK)
+		}
+		var got testMessageResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+			t.Fatal(err)
+		}
+		want := testMessageResponse{MessageID: "projects/mock-project-id/messages/msg1", DryRun: dryRun}
+		if got != want {
+			t.Errorf("response = %#v; want = %#v", got, want)
+		}
+
+		wantMsg := &messaging.Message{
+			Token:        "device-token",
+			Data:         map[string]string{"k": "v"},
+			Notification: &messaging.Notification{Title: "Hello", Body: "World"},
+		}
+		if len(m.messages) != 1 || !reflect.DeepEqual(m.messages[0], wantMsg) || m.dryRun[0] != dryRun {
+			t.Errorf("sent = %v, dryRun = %v; want = [%v], [%t]", m.messages, m.dryRun, wantMsg, dryRun)
+		}
+	}
+}
+
+func TestSendTestMessageErrors(t *testing.T) {
+	cases := []struct {
+		name string
+		body string
+		err  error
+		want int
+	}{
+		{"MalformedBody", `not json`, nil, http.StatusBadRequest},
+		{"NoToken", `{"title": "Hello"}`, nil, http.StatusBadRequest},
+		{"BackendError", `{"token": "device-token"}`, errors.New("backend error"), http.StatusInternalServerError},
+	}
+	for _, tc := range cases {
+		h := newTestHandler(&mockUserManager{}, allowAll)
+		h.messages = &mockMessageSender{err: tc.err}
+		rec := serveBody(h, http.MethodPost, "/messages/test", tc.body)
+		if rec.Code != tc.want {
+			t.Errorf("%s: Status = %d; want = %d", tc.name, rec.Code, tc.want)
+		}
+	}
+}
+
+func TestWithMessaging(t *testing.T) {
+	h := newTestHandler(&mockUserManager{}, allowAll)
+	if rec := serve(h, http.MethodPost, "/messages/test"); rec.Code != http.StatusNotFound {
+		t.Errorf("Status = %d; want = %d", rec.Code, http.StatusNotFound)
+	}
+
+	withMsg := h.WithMessaging(&messaging.Client{})
+	if withMsg.messages == nil {
+		t.Errorf("WithMessaging().messages = nil; want = client")
+	}
+	if h.messages != nil {
+		t.Errorf("WithMessaging() modified the original Handler")
+	}
+	if withNil := withMsg.WithMessaging(nil); withNil.messages != nil {
+		t.Errorf("WithMessaging(nil).messages = %v; want = nil", withNil.messages)
+	}
+}
+
+func TestGuardRejection(t *testing.T) {
+	guard := func(r *http.Request) error {
+		return errors.New("not an admin")
+	}
+	m := &mockUserManager{}
+	h := newTestHandler(m, guard)
+	h.messages = &mockMessageSender{}
+	cases := []struct {
+		method string
+		path   string
+	}{
+		{http.MethodGet, "/users/uid1"},
+		{http.MethodPost, "/users/uid1/disable"},
+		{http.MethodPost, "/users/uid1/revoke"},
+		{http.MethodPost, "/messages/test"},
+	}
+	for _, tc := range cases {
+		rec := serveBody(h, tc.method, tc.path, `{"token": "device-token"}`)
+		if rec.Code != http.StatusForbidden {
+			t.Errorf("%s %s: Status = %d; want = %d", tc.method, tc.path, rec.Code, http.StatusForbidden)
+		}
+	}
+	if len(m.calls) != 0 || len(h.messages.(*mockMessageSender).messages) != 0 {
+		t.Errorf("rejected requests reached the backend")
+	}
+}
+
+func TestErrors(t *testing.T) {
+	cases := []struct {
+		name   string
+		method string
+		path   string
+		err    error
+		want   int
+	}{
+		{"UnknownPath", http.MethodGet, "/unknown", nil, http.StatusNotFound},
+		{"EmptyUID", http.MethodGet, "/users/", nil, http.StatusNotFound},
+		{"NestedPath", http.MethodGet, "/users/uid1/extra", nil, http.StatusNotFound},
+		{"DeepPath", http.MethodPost, "/users/uid1/disable/extra", nil, http.StatusNotFound},
+		{"BadMethod", http.MethodPost, "/users/uid1", nil, http.StatusMethodNotAllowed},
+		{"BadDisableMethod", http.MethodGet, "/users/uid1/disable", nil, http.StatusMethodNotAllowed},
+		{"BadRevokeMethod", http.MethodGet, "/users/uid1/revoke", nil, http.StatusMethodNotAllowed},
+		{"BackendError", http.MethodGet, "/users/uid1", errors.New("backend error"), http.StatusInternalServerError},
+		{"DisableError", http.MethodPost, "/users/uid1/disable", errors.New("backend error"),
+			http.StatusInternalServerError},
+	}
+	for _, tc := range cases {
+		m := &mockUserManager{err: tc.err}
+		rec := serve(newTestHandler(m, allowAll), tc.method, tc.path)
+		if rec.Code != tc.want {
+			t.Errorf("%s: Status = %d; want = %d", tc.name, rec.Code, tc.want)
+		}
+		if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+			t.Errorf("%s: Content-Type = %q; want = %q", tc.name, ct, "application/json")
+		}
+	}
+}
+
+func TestNewHandlerError(t *testing.T) {
+	if h, err := NewHandler(nil, allowAll); h != nil || err == nil {
+		t.Errorf("NewHandler(nil, guard) = (%v, %v); want = (nil, error)", h, err)
+	}
+	if h, err := NewHandler(&auth.Client{}, nil); h != nil || err == nil {
+		t.Errorf("NewHandler(client, nil) = (%v, %v); want = (nil, error)", h, err)
+	}
+}
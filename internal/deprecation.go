@@ -0,0 +1,64 @@
+// Copyright 2017 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package internal
+
+import (
+	"runtime"
+	"sync"
+)
+
+// DeprecationEvent describes an invocation of a deprecated API or legacy endpoint of the SDK.
+type DeprecationEvent struct {
+	API         string
+	Replacement string
+	File        string
+	Line        int
+}
+
+var (
+	hookMutex       sync.RWMutex
+	deprecationHook func(*DeprecationEvent)
+)
+
+// SetDeprecationHook registers the function to be called whenever a deprecated API is invoked. A nil
+// hook disables reporting.
+func SetDeprecationHook(hook func(*DeprecationEvent)) {
+	hookMutex.Lock()
+	defer hookMutex.Unlock()
+	deprecationHook = hook
+}
+
+// ReportDeprecated reports the invocation of a deprecated API to the registered hook, if any.
+//
+// ReportDeprecated must be called directly from the deprecated API, so that the reported file and line
+// refer to the code that called the deprecated API.
+func ReportDeprecated(api, replacement string) {
+	hookMutex.RLock()
+	hook := deprecationHook
+	hookMutex.RUnlock()
+	if hook == nil {
+		return
+	}
+
+	e := &DeprecationEvent{
+		API:         api,
+		Replacement: replacement,
+	}
+	if _, file, line, ok := runtime.Caller(2); ok {
+		e.File = file
+		e.Line = line
+	}
+	hook(e)
+}
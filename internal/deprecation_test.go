@@ -0,0 +1,52 @@
+// Copyright 2017 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package internal
+
+import (
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func deprecatedAPI() {
+	ReportDeprecated("internal.deprecatedAPI", "internal.newAPI")
+}
+
+func TestReportDeprecated(t *testing.T) {
+	var events []*DeprecationEvent
+	SetDeprecationHook(func(e *DeprecationEvent) {
+		events = append(events, e)
+	})
+	defer SetDeprecationHook(nil)
+
+	_, _, line, _ := runtime.Caller(0)
+	deprecatedAPI()
+
+	if len(events) != 1 {
+		t.Fatalf("events = %d; want = 1", len(events))
+	}
+	e := events[0]
+	if e.API != "internal.deprecatedAPI" || e.Replacement != "internal.newAPI" {
+		t.Errorf("event = (%q, %q); want = (%q, %q)", e.API, e.Replacement, "internal.deprecatedAPI", "internal.newAPI")
+	}
+	if filepath.Base(e.File) != "deprecation_test.go" || e.Line != line+1 {
+		t.Errorf("call site = %s:%d; want = deprecation_test.go:%d", e.File, e.Line, line+1)
+	}
+}
+
+func TestReportDeprecatedNoHook(t *testing.T) {
+	SetDeprecationHook(nil)
+	deprecatedAPI()
+}
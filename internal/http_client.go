@@ -0,0 +1,104 @@
+// Copyright 2017 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package internal
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+
+	"golang.org/x/net/context"
+	"golang.org/x/net/context/ctxhttp"
+)
+
+// HTTPClient is a convenient API to make HTTP calls.
+//
+// This API handles the repetitive tasks such as entity serialization and deserialization involved in
+// making HTTP calls, while enforcing that an explicit context is used per request.
+type HTTPClient struct {
+	Client *http.Client
+}
+
+// Request contains all the parameters required to construct an outgoing HTTP request.
+//
+// If Body is not nil, it is serialized as JSON and sent as the request entity. Header holds any
+// additional headers to send with the request.
+type Request struct {
+	Method string
+	URL    string
+	Body   interface{}
+	Header http.Header
+}
+
+// Response contains information extracted from an HTTP response.
+type Response struct {
+	Status int
+	Header http.Header
+	Body   []byte
+}
+
+// Do executes the given Request, and returns a Response.
+//
+// Do only returns an error if the request could not be sent, or the response could not be read. Callers
+// are expected to inspect the status of the returned Response.
+func (c *HTTPClient) Do(ctx context.Context, r *Request) (*Response, error) {
+	var body io.Reader
+	if r.Body != nil {
+		b, err := json.Marshal(r.Body)
+		if err != nil {
+			return nil, err
+		}
+		body = bytes.NewBuffer(b)
+	}
+
+	req, err := http.NewRequest(r.Method, r.URL, body)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range r.Header {
+		req.Header[k] = v
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := ctxhttp.Do(ctx, c.Client, req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	return &Response{
+		Status: resp.StatusCode,
+		Header: resp.Header,
+		Body:   b,
+	}, nil
+}
+
+// Unmarshal checks if the Response has the given HTTP status code, and if so unmarshals the response
+// body into the variable pointed by v.
+func (r *Response) Unmarshal(want int, v interface{}) error {
+	if r.Status != want {
+		return fmt.Errorf("http error status: %d; reason: %s", r.Status, string(r.Body))
+	}
+	return json.Unmarshal(r.Body, v)
+}
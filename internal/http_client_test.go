@@ -0,0 +1,120 @@
+// Copyright 2017 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package internal
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"golang.org/x/net/context"
+)
+
+func TestHTTPClientDo(t *testing.T) {
+	var method, contentType, body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		method = r.Method
+		contentType = r.Header.Get("Content-Type")
+		body = string(b)
+		w.Write([]byte(`{"name": "result"}`))
+	}))
+	defer server.Close()
+
+	c := &HTTPClient{Client: http.DefaultClient}
+	resp, err := c.Do(context.Background(), &Request{
+		Method: http.MethodPost,
+		URL:    server.URL,
+		Body:   map[string]string{"key": "value"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if method != http.MethodPost {
+		t.Errorf("Method = %q; want = %q", method, http.MethodPost)
+	}
+	if contentType != "application/json" {
+		t.Errorf("Content-Type = %q; want = %q", contentType, "application/json")
+	}
+	if body != `{"key":"value"}` {
+		t.Errorf("Body = %q; want = %q", body, `{"key":"value"}`)
+	}
+
+	var parsed struct {
+		Name string `json:"name"`
+	}
+	if err := resp.Unmarshal(http.StatusOK, &parsed); err != nil {
+		t.Fatal(err)
+	}
+	if parsed.Name != "result" {
+		t.Errorf("Name = %q; want = %q", parsed.Name, "result")
+	}
+}
+
+func TestHTTPClientHeader(t *testing.T) {
+	var header string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header.Get("X-Custom-Header")
+		w.Write([]byte("{}"))
+	}))
+	defer server.Close()
+
+	c := &HTTPClient{Client: http.DefaultClient}
+	_, err := c.Do(context.Background(), &Request{
+		Method: http.MethodGet,
+		URL:    server.URL,
+		Header: http.Header{"X-Custom-Header": []string{"value"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if header != "value" {
+		t.Errorf("X-Custom-Header = %q; want = %q", header, "value")
+	}
+}
+
+func TestHTTPClientErrorStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("server error"))
+	}))
+	defer server.Close()
+
+	c := &HTTPClient{Client: http.DefaultClient}
+	resp, err := c.Do(context.Background(), &Request{Method: http.MethodGet, URL: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Status != http.StatusInternalServerError {
+		t.Errorf("Status = %d; want = %d", resp.Status, http.StatusInternalServerError)
+	}
+	var v interface{}
+	if err := resp.Unmarshal(http.StatusOK, &v); err == nil {
+		t.Error("Unmarshal() = nil; want = error")
+	}
+}
+
+func TestHTTPClientContextCancel(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	c := &HTTPClient{Client: http.DefaultClient}
+	if resp, err := c.Do(ctx, &Request{Method: http.MethodGet, URL: server.URL}); resp != nil || err == nil {
+		t.Errorf("Do() = (%v, %v); want = (nil, error)", resp, err)
+	}
+}
@@ -16,11 +16,60 @@
 package internal
 
 import (
+	"fmt"
+	"time"
+
+	"firebase.google.com/go/cache"
+
 	"golang.org/x/oauth2/google"
+	"google.golang.org/api/option"
 )
 
 // AuthConfig represents the configuration of Firebase Auth service.
 type AuthConfig struct {
-	Creds     *google.DefaultCredentials
+	Opts               []option.ClientOption
+	Creds              *google.DefaultCredentials
+	ProjectID          string
+	KeyGracePeriod     time.Duration
+	StrictVerification bool
+	AcceptedProjectIDs []string
+	Cache              cache.Cache
+}
+
+// MessagingConfig represents the configuration of Firebase Cloud Messaging service.
+type MessagingConfig struct {
+	Opts      []option.ClientOption
 	ProjectID string
 }
+
+// HashConfig represents a hash algorithm configuration used to generate password hashes.
+type HashConfig map[string]interface{}
+
+// FirebaseError is an error type containing an error code string.
+type FirebaseError struct {
+	Code   string
+	String string
+}
+
+func (fe *FirebaseError) Error() string {
+	return fe.String
+}
+
+// HasErrorCode checks if the given error contains a specific error code.
+func HasErrorCode(err error, code string) bool {
+	fe, ok := err.(*FirebaseError)
+	return ok && fe.Code == code
+}
+
+// Error creates a new FirebaseError from the specified error code and message.
+func Error(code string, msg string) *FirebaseError {
+	return &FirebaseError{
+		Code:   code,
+		String: msg,
+	}
+}
+
+// Errorf creates a new FirebaseError from the specified error code and message.
+func Errorf(code string, msg string, args ...interface{}) *FirebaseError {
+	return Error(code, fmt.Sprintf(msg, args...))
+}
@@ -0,0 +1,587 @@
+// Copyright 2017 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package messaging contains functions for sending messages to devices with Firebase Cloud Messaging
+// (FCM).
+package messaging
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"firebase.google.com/go/internal"
+
+	"golang.org/x/net/context"
+	"google.golang.org/api/transport"
+)
+
+const (
+	messagingEndpoint = "https://fcm.googleapis.com/v1"
+	iidEndpoint       = "https://iid.googleapis.com"
+)
+
+const (
+	// maxMessages is the maximum number of messages that can be sent in one SendEach call.
+	maxMessages = 500
+
+	// sendEachConcurrency is the maximum number of messages sent in parallel by SendEach.
+	sendEachConcurrency = 50
+)
+
+const (
+	internalError                  = "internal-error"
+	invalidArgument                = "invalid-argument"
+	messageRateExceeded            = "message-rate-exceeded"
+	mismatchedCredential           = "mismatched-credential"
+	registrationTokenNotRegistered = "registration-token-not-registered"
+	serverUnavailable              = "server-unavailable"
+	thirdPartyAuthError            = "third-party-auth-error"
+	tooManyTopics                  = "too-many-topics"
+	unknown                        = "unknown-error"
+)
+
+// fcmError maps the error codes returned by the FCM service, and the canonical error statuses of
+// Google APIs, to SDK error codes.
+var fcmError = map[string]string{
+	"INTERNAL":               internalError,
+	"INVALID_ARGUMENT":       invalidArgument,
+	"NOT_FOUND":              registrationTokenNotRegistered,
+	"PERMISSION_DENIED":      mismatchedCredential,
+	"QUOTA_EXCEEDED":         messageRateExceeded,
+	"RESOURCE_EXHAUSTED":     messageRateExceeded,
+	"SENDER_ID_MISMATCH":     mismatchedCredential,
+	"THIRD_PARTY_AUTH_ERROR": thirdPartyAuthError,
+	"UNAVAILABLE":            serverUnavailable,
+	"UNREGISTERED":           registrationTokenNotRegistered,
+}
+
+// colorPattern matches colors in the #rrggbb format, and lightColorPattern additionally matches colors
+// in the #rrggbbaa format.
+var (
+	colorPattern      = regexp.MustCompile("^#[0-9a-fA-F]{6}$")
+	lightColorPattern = regexp.MustCompile("^#[0-9a-fA-F]{6}([0-9a-fA-F]{2})?$")
+)
+
+// topicPattern matches valid topic names, with or without the "/topics/" prefix.
+var topicPattern = regexp.MustCompile("^(/topics/)?[a-zA-Z0-9-_.~%]+$")
+
+// Client is the interface for the Firebase Cloud Messaging service.
+type Client struct {
+	hc          *internal.HTTPClient
+	fcmEndpoint string
+	iidEndpoint string
+	projectID   string
+}
+
+// Message to be sent via Firebase Cloud Messaging.
+//
+// Message contains payload data and the target of the message. Exactly one of Token, Topic or
+// Condition must be set. Topic may be specified with or without the "/topics/" prefix.
+type Message struct {
+	Data         map[string]string `json:"data,omitempty"`
+	Notification *Notification     `json:"notification,omitempty"`
+	Android      *AndroidConfig    `json:"android,omitempty"`
+	Token        string            `json:"token,omitempty"`
+	Topic        string            `json:"-"`
+	Condition    string            `json:"condition,omitempty"`
+}
+
+// MarshalJSON marshals a Message into the JSON representation of the FCM service, in which the topic
+// has no "/topics/" prefix.
+func (m *Message) MarshalJSON() ([]byte, error) {
+	type messageInternal Message
+	temp := &struct {
+		BareTopic string `json:"topic,omitempty"`
+		*messageInternal
+	}{
+		BareTopic:       strings.TrimPrefix(m.Topic, "/topics/"),
+		messageInternal: (*messageInternal)(m),
+	}
+	return json.Marshal(temp)
+}
+
+// Notification is the basic notification template to use across all platforms.
+type Notification struct {
+	Title string `json:"title,omitempty"`
+	Body  string `json:"body,omitempty"`
+}
+
+// maxAndroidTTL is the maximum time for which FCM stores messages for Android devices that are
+// offline.
+const maxAndroidTTL = 4 * 7 * 24 * time.Hour
+
+// AndroidConfig contains messaging options specific to the Android platform.
+//
+// Priority is either "normal" or "high", and defaults to "normal". TTL is how long FCM stores the
+// message if the device is offline, of at most 4 weeks, which is also the default. Messages with the
+// same CollapseKey replace each other while they are stored. If RestrictedPackageName is set, the
+// message is only delivered to the app with the given package name. If Data is set, it replaces the
+// Data of the Message entirely on Android devices; the two maps are not merged.
+type AndroidConfig struct {
+	CollapseKey           string               `json:"collapse_key,omitempty"`
+	Priority              string               `json:"priority,omitempty"`
+	TTL                   *time.Duration       `json:"-"`
+	RestrictedPackageName string               `json:"restricted_package_name,omitempty"`
+	Data                  map[string]string    `json:"data,omitempty"`
+	Notification          *AndroidNotification `json:"notification,omitempty"`
+}
+
+// MarshalJSON marshals an AndroidConfig into the JSON representation of the FCM service, in which the
+// TTL is a duration string such as "3.5s".
+func (a *AndroidConfig) MarshalJSON() ([]byte, error) {
+	var ttl string
+	if a.TTL != nil {
+		ttl = durationString(*a.TTL)
+	}
+	type androidInternal AndroidConfig
+	temp := &struct {
+		TTL string `json:"ttl,omitempty"`
+		*androidInternal
+	}{
+		TTL:             ttl,
+		androidInternal: (*androidInternal)(a),
+	}
+	return json.Marshal(temp)
+}
+
+// AndroidNotification is a notification to send to Android devices, whose fields take precedence over
+// those of the Notification of the Message.
+//
+// TitleLocKey and BodyLocKey are the keys of string resources of the app, used to localize the title
+// and the body. The corresponding LocArgs are the values of the format specifiers of the localized
+// strings, and may only be set along with their key.
+//
+// Color is the color of the notification icon, in the #rrggbb format. Notifications with the same Tag
+// replace each other in the notification drawer. ClickAction is the intent action started when the
+// user clicks the notification. ChannelID is the ID of the notification channel of the app through
+// which the notification is shown, on Android O and later.
+//
+// VibrateTimings alternately specifies how long the device vibrates and pauses, starting with a pause.
+// NotificationCount is the number of items represented by the notification, shown on the app icon
+// badge by some launchers.
+type AndroidNotification struct {
+	Title                 string                        `json:"title,omitempty"`
+	Body                  string                        `json:"body,omitempty"`
+	Sound                 string                        `json:"sound,omitempty"`
+	TitleLocKey           string                        `json:"title_loc_key,omitempty"`
+	TitleLocArgs          []string                      `json:"title_loc_args,omitempty"`
+	BodyLocKey            string                        `json:"body_loc_key,omitempty"`
+	BodyLocArgs           []string                      `json:"body_loc_args,omitempty"`
+	Icon                  string                        `json:"icon,omitempty"`
+	Color                 string                        `json:"color,omitempty"`
+	Tag                   string                        `json:"tag,omitempty"`
+	ClickAction           string                        `json:"click_action,omitempty"`
+	ChannelID             string                        `json:"channel_id,omitempty"`
+	Visibility            AndroidNotificationVisibility `json:"visibility,omitempty"`
+	LightSettings         *LightSettings                `json:"light_settings,omitempty"`
+	DefaultLightSettings  bool                          `json:"default_light_settings,omitempty"`
+	VibrateTimings        []time.Duration               `json:"-"`
+	DefaultVibrateTimings bool                          `json:"default_vibrate_timings,omitempty"`
+	NotificationCount     *int                          `json:"notification_count,omitempty"`
+}
+
+// MarshalJSON marshals an AndroidNotification into the JSON representation of the FCM service, in
+// which the vibrate timings are duration strings such as "3.5s".
+func (n *AndroidNotification) MarshalJSON() ([]byte, error) {
+	var timings []string
+	for _, t := range n.VibrateTimings {
+		timings = append(timings, durationString(t))
+	}
+	type notificationInternal AndroidNotification
+	temp := &struct {
+		VibrateTimings []string `json:"vibrate_timings,omitempty"`
+		*notificationInternal
+	}{
+		VibrateTimings:       timings,
+		notificationInternal: (*notificationInternal)(n),
+	}
+	return json.Marshal(temp)
+}
+
+// AndroidNotificationVisibility specifies how much of a notification is shown on the lock screen.
+type AndroidNotificationVisibility string
+
+const (
+	// VisibilityPrivate shows the notification on the lock screen, but hides its sensitive content.
+	VisibilityPrivate AndroidNotificationVisibility = "PRIVATE"
+
+	// VisibilityPublic shows the notification in full on the lock screen.
+	VisibilityPublic AndroidNotificationVisibility = "PUBLIC"
+
+	// VisibilitySecret does not show any part of the notification on the lock screen.
+	VisibilitySecret AndroidNotificationVisibility = "SECRET"
+)
+
+// LightSettings specifies how the notification LED of a device blinks for a notification.
+//
+// Color is the color of the LED, in the #rrggbb or #rrggbbaa format. The LED is alternately on for
+// LightOnDuration and off for LightOffDuration. All the fields are required.
+type LightSettings struct {
+	Color            string
+	LightOnDuration  time.Duration
+	LightOffDuration time.Duration
+}
+
+// MarshalJSON marshals LightSettings into the JSON representation of the FCM service, in which the
+// color is made of red, green, blue and alpha components between 0 and 1.
+func (l *LightSettings) MarshalJSON() ([]byte, error) {
+	color, err := parseColor(l.Color)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(map[string]interface{}{
+		"color":              color,
+		"light_on_duration":  durationString(l.LightOnDuration),
+		"light_off_duration": durationString(l.LightOffDuration),
+	})
+}
+
+// parseColor converts a #rrggbb or #rrggbbaa color into its components, as fractions of 1.
+func parseColor(s string) (map[string]float64, error) {
+	if !lightColorPattern.MatchString(s) {
+		return nil, fmt.Errorf("color must be in the #rrggbb or #rrggbbaa format: %q", s)
+	}
+	if len(s) == 7 {
+		s += "ff"
+	}
+	color := make(map[string]float64)
+	for i, c := range []string{"red", "green", "blue", "alpha"} {
+		v, err := strconv.ParseUint(s[1+2*i:3+2*i], 16, 8)
+		if err != nil {
+			return nil, err
+		}
+		color[c] = float64(v) / 255
+	}
+	return color, nil
+}
+
+// durationString formats a duration as expected by the FCM service, in seconds with up to nine
+// fractional digits, such as "3.5s".
+func durationString(d time.Duration) string {
+	seconds := int64(d / time.Second)
+	nanos := int64(d % time.Second)
+	if nanos > 0 {
+		return strings.TrimRight(fmt.Sprintf("%d.%09d", seconds, nanos), "0") + "s"
+	}
+	return fmt.Sprintf("%ds", seconds)
+}
+
+// NewClient creates a new instance of the Firebase Cloud Messaging Client.
+//
+// This function can only be invoked from within the SDK. Client applications should access the
+// Messaging service through firebase.App.
+func NewClient(ctx context.Context, c *internal.MessagingConfig) (*Client, error) {
+	if c.ProjectID == "" {
+		return nil, errors.New("project id is required to access Firebase Cloud Messaging client")
+	}
+	hc, _, err := transport.NewHTTPClient(ctx, c.Opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &Client{
+		hc:          &internal.HTTPClient{Client: hc},
+		fcmEndpoint: messagingEndpoint,
+		iidEndpoint: iidEndpoint,
+		projectID:   c.ProjectID,
+	}, nil
+}
+
+// Send sends a Message to Firebase Cloud Messaging.
+//
+// The Message must specify exactly one of Token, Topic and Condition fields. FCM will customize the
+// message for each target platform based on the arguments specified in the Message. Send returns the
+// name of the message, of the form "projects/<project-id>/messages/<message-id>", which identifies
+// the message in the FCM service.
+func (c *Client) Send(ctx context.Context, message *Message) (string, error) {
+	return c.send(ctx, message, false)
+}
+
+// SendDryRun sends a Message to Firebase Cloud Messaging in the dry run (validation only) mode.
+//
+// The message is validated by FCM, including its payload and the format of its target, but it is not
+// delivered to any device. This allows testing messages, for instance in CI pipelines, without
+// notifying users. Errors are reported as by Send.
+func (c *Client) SendDryRun(ctx context.Context, message *Message) (string, error) {
+	return c.send(ctx, message, true)
+}
+
+// SendResponse describes the outcome of sending one of the messages of a SendEach call.
+//
+// If Success is true, MessageID holds the name of the message as returned by Send. Otherwise Error
+// holds the error that caused the message not to be sent.
+type SendResponse struct {
+	Success   bool
+	MessageID string
+	Error     error
+}
+
+// BatchResponse describes the outcome of a SendEach call.
+//
+// Responses holds one SendResponse per message, in the order in which the messages were specified.
+type BatchResponse struct {
+	SuccessCount int
+	FailureCount int
+	Responses    []*SendResponse
+}
+
+// SendEach sends each of the given messages to Firebase Cloud Messaging, as if by Send.
+//
+// Up to 500 messages can be sent in one call. All the messages are validated before any is sent, and
+// SendEach returns an error without sending anything if any message is invalid. The messages are then
+// sent in parallel, up to 50 at a time. Failures to send individual messages are reported in the
+// returned BatchResponse rather than as an error.
+func (c *Client) SendEach(ctx context.Context, messages []*Message) (*BatchResponse, error) {
+	return c.sendEach(ctx, messages, false)
+}
+
+// SendEachDryRun sends each of the given messages to Firebase Cloud Messaging in the dry run
+// (validation only) mode, as if by SendDryRun. See SendEach for details.
+func (c *Client) SendEachDryRun(ctx context.Context, messages []*Message) (*BatchResponse, error) {
+	return c.sendEach(ctx, messages, true)
+}
+
+func (c *Client) sendEach(ctx context.Context, messages []*Message, dryRun bool) (*BatchResponse, error) {
+	if len(messages) == 0 {
+		return nil, errors.New("messages must not be nil or empty")
+	}
+	if len(messages) > maxMessages {
+		return nil, fmt.Errorf("messages must not contain more than %d elements", maxMessages)
+	}
+	for i, m := range messages {
+		if err := validateMessage(m); err != nil {
+			return nil, fmt.Errorf("invalid message at index %d: %v", i, err)
+		}
+	}
+
+	responses := make([]*SendResponse, len(messages))
+	var wg sync.WaitGroup
+	work := make(chan int)
+	for w := 0; w < sendEachConcurrency && w < len(messages); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range work {
+				name, err := c.send(ctx, messages[i], dryRun)
+				responses[i] = &SendResponse{Success: err == nil, MessageID: name, Error: err}
+			}
+		}()
+	}
+	for i := range messages {
+		work <- i
+	}
+	close(work)
+	wg.Wait()
+
+	br := &BatchResponse{Responses: responses}
+	for _, r := range responses {
+		if r.Success {
+			br.SuccessCount++
+		} else {
+			br.FailureCount++
+		}
+	}
+	return br, nil
+}
+
+func (c *Client) send(ctx context.Context, message *Message, dryRun bool) (string, error) {
+	if err := validateMessage(message); err != nil {
+		return "", err
+	}
+	request := map[string]interface{}{
+		"message": message,
+	}
+	if dryRun {
+		request["validate_only"] = true
+	}
+	var parsed struct {
+		Name string `json:"name"`
+	}
+	if err := c.post(ctx, request, &parsed); err != nil {
+		return "", err
+	}
+	return parsed.Name, nil
+}
+
+func (c *Client) post(ctx context.Context, body interface{}, v interface{}) error {
+	resp, err := c.hc.Do(ctx, &internal.Request{
+		Method: http.MethodPost,
+		URL:    fmt.Sprintf("%s/projects/%s/messages:send", c.fcmEndpoint, c.projectID),
+		Body:   body,
+	})
+	if err != nil {
+		return err
+	}
+	if resp.Status != http.StatusOK {
+		return handleServerError(resp)
+	}
+	return json.Unmarshal(resp.Body, v)
+}
+
+// handleServerError converts an error response from the FCM service into a FirebaseError.
+//
+// The FCM specific error code is carried in the details of the response, if any. Otherwise the error
+// code is derived from the canonical status of the response.
+func handleServerError(resp *internal.Response) error {
+	var parsed struct {
+		Error struct {
+			Status  string `json:"status"`
+			Message string `json:"message"`
+			Details []struct {
+				Type      string `json:"@type"`
+				ErrorCode string `json:"errorCode"`
+			} `json:"details"`
+		} `json:"error"`
+	}
+	json.Unmarshal(resp.Body, &parsed) // ignore any json parse errors at this level
+
+	serverCode := parsed.Error.Status
+	for _, d := range parsed.Error.Details {
+		if d.Type == "type.googleapis.com/google.firebase.fcm.v1.FcmError" && d.ErrorCode != "" {
+			serverCode = d.ErrorCode
+		}
+	}
+	code, ok := fcmError[serverCode]
+	if !ok {
+		code = unknown
+	}
+	msg := parsed.Error.Message
+	if msg == "" {
+		msg = string(resp.Body)
+	}
+	return internal.Errorf(code, "http error status: %d; reason: %s", resp.Status, msg)
+}
+
+func validateMessage(message *Message) error {
+	if message == nil {
+		return errors.New("message must not be nil")
+	}
+
+	targets := 0
+	for _, t := range []string{message.Token, message.Topic, message.Condition} {
+		if t != "" {
+			targets++
+		}
+	}
+	if targets != 1 {
+		return errors.New("exactly one of token, topic or condition must be specified")
+	}
+	if message.Topic != "" && !topicPattern.MatchString(message.Topic) {
+		return fmt.Errorf("malformed topic name: %q", message.Topic)
+	}
+	return validateAndroidConfig(message.Android)
+}
+
+func validateAndroidConfig(config *AndroidConfig) error {
+	if config == nil {
+		return nil
+	}
+	if config.Priority != "" && config.Priority != "normal" && config.Priority != "high" {
+		return fmt.Errorf("android priority must be 'normal' or 'high': %q", config.Priority)
+	}
+	if config.TTL != nil && (*config.TTL < 0 || *config.TTL > maxAndroidTTL) {
+		return fmt.Errorf("android ttl must be between 0 and 4 weeks: %v", *config.TTL)
+	}
+	return validateAndroidNotification(config.Notification)
+}
+
+func validateAndroidNotification(notification *AndroidNotification) error {
+	if notification == nil {
+		return nil
+	}
+	if len(notification.TitleLocArgs) > 0 && notification.TitleLocKey == "" {
+		return errors.New("title_loc_key is required when specifying title_loc_args")
+	}
+	if len(notification.BodyLocArgs) > 0 && notification.BodyLocKey == "" {
+		return errors.New("body_loc_key is required when specifying body_loc_args")
+	}
+	if notification.Color != "" && !colorPattern.MatchString(notification.Color) {
+		return fmt.Errorf("color must be in the #rrggbb format: %q", notification.Color)
+	}
+	switch notification.Visibility {
+	case "", VisibilityPrivate, VisibilityPublic, VisibilitySecret:
+	default:
+		return fmt.Errorf("invalid notification visibility: %q", notification.Visibility)
+	}
+	if l := notification.LightSettings; l != nil {
+		if !lightColorPattern.MatchString(l.Color) {
+			return fmt.Errorf("light color must be in the #rrggbb or #rrggbbaa format: %q", l.Color)
+		}
+		if l.LightOnDuration <= 0 || l.LightOffDuration <= 0 {
+			return errors.New("light on and off durations must be positive")
+		}
+	}
+	for _, t := range notification.VibrateTimings {
+		if t < 0 {
+			return fmt.Errorf("vibrate timings must not be negative: %v", t)
+		}
+	}
+	if notification.NotificationCount != nil && *notification.NotificationCount < 0 {
+		return fmt.Errorf("notification count must not be negative: %d", *notification.NotificationCount)
+	}
+	return nil
+}
+
+// IsInternal checks if the given error was due to an internal server error.
+func IsInternal(err error) bool {
+	return internal.HasErrorCode(err, internalError)
+}
+
+// IsInvalidArgument checks if the given error was due to an invalid argument in the request, such as
+// a malformed registration token or an invalid message payload.
+func IsInvalidArgument(err error) bool {
+	return internal.HasErrorCode(err, invalidArgument)
+}
+
+// IsMessageRateExceeded checks if the given error was due to the sending rate limit of a device, a
+// topic or the project being exceeded.
+func IsMessageRateExceeded(err error) bool {
+	return internal.HasErrorCode(err, messageRateExceeded)
+}
+
+// IsMismatchedCredential checks if the given error was due to a registration token that belongs to a
+// different project than the credential used to send the message.
+func IsMismatchedCredential(err error) bool {
+	return internal.HasErrorCode(err, mismatchedCredential)
+}
+
+// IsRegistrationTokenNotRegistered checks if the given error was due to a registration token that is
+// no longer valid, such as after the app was uninstalled. Such tokens should be discarded.
+func IsRegistrationTokenNotRegistered(err error) bool {
+	return internal.HasErrorCode(err, registrationTokenNotRegistered)
+}
+
+// IsServerUnavailable checks if the given error was due to the FCM service being temporarily
+// unavailable.
+func IsServerUnavailable(err error) bool {
+	return internal.HasErrorCode(err, serverUnavailable)
+}
+
+// IsThirdPartyAuthError checks if the given error was due to the APNs certificate or web push auth key
+// of the project being invalid or missing.
+func IsThirdPartyAuthError(err error) bool {
+	return internal.HasErrorCode(err, thirdPartyAuthError)
+}
+
+// IsTooManyTopics checks if the given error was due to an app instance being subscribed to the maximum
+// number of topics.
+func IsTooManyTopics(err error) bool {
+	return internal.HasErrorCode(err, tooManyTopics)
+}
@@ -0,0 +1,563 @@
+// Copyright 2017 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package messaging
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"firebase.google.com/go/internal"
+
+	"golang.org/x/net/context"
+	"golang.org/x/oauth2"
+	"google.golang.org/api/option"
+)
+
+const testMessageName = "projects/test-project/messages/msg_id"
+
+var (
+	ttlWithNanos = 1500 * time.Millisecond
+	ttlSeconds   = 10 * time.Second
+	ttlNanos     = time.Nanosecond
+	negativeTTL  = -time.Second
+	excessiveTTL = 29 * 24 * time.Hour
+
+	notificationCount = 3
+	zeroCount         = 0
+	negativeCount     = -1
+)
+
+var testMessagingConfig = &internal.MessagingConfig{
+	ProjectID: "test-project",
+	Opts: []option.ClientOption{
+		option.WithTokenSource(&mockTokenSource{"test-token"}),
+	},
+}
+
+type mockTokenSource struct {
+	AccessToken string
+}
+
+func (ts *mockTokenSource) Token() (*oauth2.Token, error) {
+	return &oauth2.Token{AccessToken: ts.AccessToken}, nil
+}
+
+// mockFCMServer records the requests sent to it, and responds with Resp and Status.
+type mockFCMServer struct {
+	Resp   string
+	Status int
+	Req    []*http.Request
+	Rbody  []byte
+	Client *Client
+	srv    *httptest.Server
+}
+
+func (s *mockFCMServer) Close() {
+	s.srv.Close()
+}
+
+func fcmServer(t *testing.T, resp string) *mockFCMServer {
+	s := &mockFCMServer{Resp: resp}
+	s.srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close()
+		b, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		s.Req = append(s.Req, r)
+		s.Rbody = b
+		w.Header().Set("Content-Type", "application/json")
+		if s.Status != 0 {
+			w.WriteHeader(s.Status)
+		}
+		w.Write([]byte(s.Resp))
+	}))
+	client, err := NewClient(context.Background(), testMessagingConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.fcmEndpoint = s.srv.URL
+	s.Client = client
+	return s
+}
+
+func checkFCMRequest(t *testing.T, s *mockFCMServer, want map[string]interface{}) {
+	if len(s.Req) == 0 {
+		t.Fatal("no requests sent to the server")
+	}
+	r := s.Req[len(s.Req)-1]
+	if r.Method != http.MethodPost {
+		t.Errorf("Method = %q; want = %q", r.Method, http.MethodPost)
+	}
+	if r.URL.Path != "/projects/test-project/messages:send" {
+		t.Errorf("Path = %q; want = %q", r.URL.Path, "/projects/test-project/messages:send")
+	}
+	if h := r.Header.Get("Authorization"); h != "Bearer test-token" {
+		t.Errorf("Authorization = %q; want = %q", h, "Bearer test-token")
+	}
+	var got map[string]interface{}
+	if err := json.Unmarshal(s.Rbody, &got); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Body = %#v; want = %#v", got, want)
+	}
+}
+
+func TestNewClientNoProjectID(t *testing.T) {
+	conf := &internal.MessagingConfig{Opts: testMessagingConfig.Opts}
+	if c, err := NewClient(context.Background(), conf); c != nil || err == nil {
+		t.Errorf("NewClient() = (%v, %v); want = (nil, error)", c, err)
+	}
+}
+
+func TestSend(t *testing.T) {
+	cases := []struct {
+		name string
+		msg  *Message
+		want map[string]interface{}
+	}{
+		{
+			name: "token",
+			msg: &Message{
+				Token:        "test-token",
+				Data:         map[string]string{"k": "v"},
+				Notification: &Notification{Title: "t", Body: "b"},
+			},
+			want: map[string]interface{}{
+				"token":        "test-token",
+				"data":         map[string]interface{}{"k": "v"},
+				"notification": map[string]interface{}{"title": "t", "body": "b"},
+			},
+		},
+		{
+			name: "topic",
+			msg:  &Message{Topic: "news"},
+			want: map[string]interface{}{"topic": "news"},
+		},
+		{
+			name: "prefixed-topic",
+			msg:  &Message{Topic: "/topics/news"},
+			want: map[string]interface{}{"topic": "news"},
+		},
+		{
+			name: "condition",
+			msg:  &Message{Condition: "'a' in topics && 'b' in topics"},
+			want: map[string]interface{}{"condition": "'a' in topics && 'b' in topics"},
+		},
+		{
+			name: "android",
+			msg: &Message{
+				Token: "test-token",
+				Android: &AndroidConfig{
+					CollapseKey:           "ck",
+					Priority:              "high",
+					TTL:                   &ttlWithNanos,
+					RestrictedPackageName: "com.example.app",
+					Data:                  map[string]string{"k": "v"},
+					Notification: &AndroidNotification{
+						Title:        "t",
+						Body:         "b",
+						Sound:        "s",
+						TitleLocKey:  "tlk",
+						TitleLocArgs: []string{"t1", "t2"},
+						BodyLocKey:   "blk",
+						BodyLocArgs:  []string{"b1"},
+					},
+				},
+			},
+			want: map[string]interface{}{
+				"token": "test-token",
+				"android": map[string]interface{}{
+					"collapse_key":            "ck",
+					"priority":                "high",
+					"ttl":                     "1.5s",
+					"restricted_package_name": "com.example.app",
+					"data":                    map[string]interface{}{"k": "v"},
+					"notification": map[string]interface{}{
+						"title":          "t",
+						"body":           "b",
+						"sound":          "s",
+						"title_loc_key":  "tlk",
+						"title_loc_args": []interface{}{"t1", "t2"},
+						"body_loc_key":   "blk",
+						"body_loc_args":  []interface{}{"b1"},
+					},
+				},
+			},
+		},
+		{
+			name: "android-notification-appearance",
+			msg: &Message{
+				Topic: "news",
+				Android: &AndroidConfig{
+					Notification: &AndroidNotification{
+						Icon:        "ic_notification",
+						Color:       "#336699",
+						Tag:         "conversation-1",
+						ClickAction: "OPEN_CONVERSATION",
+						ChannelID:   "messages",
+						Visibility:  VisibilityPrivate,
+						LightSettings: &LightSettings{
+							Color:            "#ff000080",
+							LightOnDuration:  500 * time.Millisecond,
+							LightOffDuration: 2 * time.Second,
+						},
+						VibrateTimings:    []time.Duration{0, 500 * time.Millisecond, 250 * time.Millisecond},
+						NotificationCount: &notificationCount,
+					},
+				},
+			},
+			want: map[string]interface{}{
+				"topic": "news",
+				"android": map[string]interface{}{
+					"notification": map[string]interface{}{
+						"icon":         "ic_notification",
+						"color":        "#336699",
+						"tag":          "conversation-1",
+						"click_action": "OPEN_CONVERSATION",
+						"channel_id":   "messages",
+						"visibility":   "PRIVATE",
+						"light_settings": map[string]interface{}{
+							"color": map[string]interface{}{
+								"red":   float64(1),
+								"green": float64(0),
+								"blue":  float64(0),
+								"alpha": float64(128) / 255,
+							},
+							"light_on_duration":  "0.5s",
+							"light_off_duration": "2s",
+						},
+						"vibrate_timings":    []interface{}{"0s", "0.5s", "0.25s"},
+						"notification_count": float64(3),
+					},
+				},
+			},
+		},
+		{
+			name: "android-notification-defaults",
+			msg: &Message{
+				Topic: "news",
+				Android: &AndroidConfig{
+					Notification: &AndroidNotification{
+						DefaultLightSettings:  true,
+						DefaultVibrateTimings: true,
+						NotificationCount:     &zeroCount,
+					},
+				},
+			},
+			want: map[string]interface{}{
+				"topic": "news",
+				"android": map[string]interface{}{
+					"notification": map[string]interface{}{
+						"default_light_settings":  true,
+						"default_vibrate_timings": true,
+						"notification_count":      float64(0),
+					},
+				},
+			},
+		},
+		{
+			name: "android-ttl-seconds",
+			msg:  &Message{Topic: "news", Android: &AndroidConfig{TTL: &ttlSeconds}},
+			want: map[string]interface{}{
+				"topic":   "news",
+				"android": map[string]interface{}{"ttl": "10s"},
+			},
+		},
+		{
+			name: "android-ttl-nanos",
+			msg:  &Message{Topic: "news", Android: &AndroidConfig{TTL: &ttlNanos}},
+			want: map[string]interface{}{
+				"topic":   "news",
+				"android": map[string]interface{}{"ttl": "0.000000001s"},
+			},
+		},
+	}
+	for _, tc := range cases {
+		s := fcmServer(t, `{"name": "`+testMessageName+`"}`)
+		name, err := s.Client.Send(context.Background(), tc.msg)
+		if name != testMessageName || err != nil {
+			t.Errorf("Send(%s) = (%q, %v); want = (%q, nil)", tc.name, name, err, testMessageName)
+		}
+		checkFCMRequest(t, s, map[string]interface{}{"message": tc.want})
+		s.Close()
+	}
+}
+
+func TestSendDryRun(t *testing.T) {
+	s := fcmServer(t, `{"name": "projects/test-project/messages/fake_message_id"}`)
+	defer s.Close()
+
+	name, err := s.Client.SendDryRun(context.Background(), &Message{Topic: "news"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name != "projects/test-project/messages/fake_message_id" {
+		t.Errorf("SendDryRun() = %q; want = %q", name, "projects/test-project/messages/fake_message_id")
+	}
+	checkFCMRequest(t, s, map[string]interface{}{
+		"message":       map[string]interface{}{"topic": "news"},
+		"validate_only": true,
+	})
+}
+
+func TestSendDryRunError(t *testing.T) {
+	s := fcmServer(t, `{"error": {"status": "INVALID_ARGUMENT", "message": "Invalid registration token"}}`)
+	defer s.Close()
+	s.Status = http.StatusBadRequest
+
+	if name, err := s.Client.SendDryRun(context.Background(), &Message{Token: "token"}); name != "" || !IsInvalidArgument(err) {
+		t.Errorf("SendDryRun() = (%q, %v); want = (\"\", invalid-argument error)", name, err)
+	}
+	if name, err := s.Client.SendDryRun(context.Background(), &Message{}); name != "" || err == nil {
+		t.Errorf("SendDryRun(no-target) = (%q, %v); want = (\"\", error)", name, err)
+	}
+}
+
+func TestSendInvalidMessage(t *testing.T) {
+	cases := []struct {
+		name string
+		msg  *Message
+	}{
+		{"nil", nil},
+		{"no-target", &Message{}},
+		{"multiple-targets", &Message{Token: "token", Topic: "topic"}},
+		{"malformed-topic", &Message{Topic: "/topics/"}},
+		{"invalid-topic", &Message{Topic: "foo bar"}},
+		{"invalid-android-priority", &Message{Topic: "news", Android: &AndroidConfig{Priority: "urgent"}}},
+		{"negative-android-ttl", &Message{Topic: "news", Android: &AndroidConfig{TTL: &negativeTTL}}},
+		{"excessive-android-ttl", &Message{Topic: "news", Android: &AndroidConfig{TTL: &excessiveTTL}}},
+		{"android-title-loc-args", &Message{Topic: "news", Android: &AndroidConfig{
+			Notification: &AndroidNotification{TitleLocArgs: []string{"arg"}},
+		}}},
+		{"android-body-loc-args", &Message{Topic: "news", Android: &AndroidConfig{
+			Notification: &AndroidNotification{BodyLocArgs: []string{"arg"}},
+		}}},
+		{"android-color", &Message{Topic: "news", Android: &AndroidConfig{
+			Notification: &AndroidNotification{Color: "336699"},
+		}}},
+		{"android-visibility", &Message{Topic: "news", Android: &AndroidConfig{
+			Notification: &AndroidNotification{Visibility: "HIDDEN"},
+		}}},
+		{"android-light-color", &Message{Topic: "news", Android: &AndroidConfig{
+			Notification: &AndroidNotification{LightSettings: &LightSettings{
+				Color: "red", LightOnDuration: time.Second, LightOffDuration: time.Second,
+			}},
+		}}},
+		{"android-light-duration", &Message{Topic: "news", Android: &AndroidConfig{
+			Notification: &AndroidNotification{LightSettings: &LightSettings{Color: "#ff0000"}},
+		}}},
+		{"android-vibrate-timings", &Message{Topic: "news", Android: &AndroidConfig{
+			Notification: &AndroidNotification{VibrateTimings: []time.Duration{-time.Second}},
+		}}},
+		{"android-notification-count", &Message{Topic: "news", Android: &AndroidConfig{
+			Notification: &AndroidNotification{NotificationCount: &negativeCount},
+		}}},
+	}
+	client, err := NewClient(context.Background(), testMessagingConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range cases {
+		if name, err := client.Send(context.Background(), tc.msg); name != "" || err == nil {
+			t.Errorf("Send(%s) = (%q, %v); want = (\"\", error)", tc.name, name, err)
+		}
+	}
+}
+
+func TestSendError(t *testing.T) {
+	cases := []struct {
+		resp   string
+		status int
+		check  func(error) bool
+	}{
+		{
+			resp: `{"error": {"status": "NOT_FOUND", "message": "Requested entity was not found.", "details": [` +
+				`{"@type": "type.googleapis.com/google.firebase.fcm.v1.FcmError", "errorCode": "UNREGISTERED"}]}}`,
+			status: http.StatusNotFound,
+			check:  IsRegistrationTokenNotRegistered,
+		},
+		{
+			resp: `{"error": {"status": "INVALID_ARGUMENT", "message": "Invalid registration token", "details": [` +
+				`{"@type": "type.googleapis.com/google.firebase.fcm.v1.FcmError", "errorCode": "INVALID_ARGUMENT"}]}}`,
+			status: http.StatusBadRequest,
+			check:  IsInvalidArgument,
+		},
+		{
+			resp: `{"error": {"status": "PERMISSION_DENIED", "message": "Sender id mismatch", "details": [` +
+				`{"@type": "type.googleapis.com/google.firebase.fcm.v1.FcmError", "errorCode": "SENDER_ID_MISMATCH"}]}}`,
+			status: http.StatusForbidden,
+			check:  IsMismatchedCredential,
+		},
+		{
+			resp:   `{"error": {"status": "RESOURCE_EXHAUSTED", "message": "Quota exceeded"}}`,
+			status: http.StatusTooManyRequests,
+			check:  IsMessageRateExceeded,
+		},
+		{
+			resp:   `{"error": {"status": "UNAVAILABLE", "message": "Service unavailable"}}`,
+			status: http.StatusServiceUnavailable,
+			check:  IsServerUnavailable,
+		},
+		{
+			resp:   `{"error": {"status": "INTERNAL", "message": "Internal error"}}`,
+			status: http.StatusInternalServerError,
+			check:  IsInternal,
+		},
+		{
+			resp: `{"error": {"status": "UNAUTHENTICATED", "message": "Auth error", "details": [` +
+				`{"@type": "type.googleapis.com/google.firebase.fcm.v1.FcmError", "errorCode": "THIRD_PARTY_AUTH_ERROR"}]}}`,
+			status: http.StatusUnauthorized,
+			check:  IsThirdPartyAuthError,
+		},
+	}
+	for _, tc := range cases {
+		s := fcmServer(t, tc.resp)
+		s.Status = tc.status
+		name, err := s.Client.Send(context.Background(), &Message{Token: "token"})
+		if name != "" || !tc.check(err) {
+			t.Errorf("Send() = (%q, %v); want = (\"\", error matching %s)", name, err, tc.resp)
+		}
+		s.Close()
+	}
+}
+
+func TestSendUnknownError(t *testing.T) {
+	s := fcmServer(t, "not json")
+	defer s.Close()
+	s.Status = http.StatusInternalServerError
+
+	name, err := s.Client.Send(context.Background(), &Message{Token: "token"})
+	if name != "" || !internal.HasErrorCode(err, unknown) {
+		t.Errorf("Send() = (%q, %v); want = (\"\", unknown error)", name, err)
+	}
+	want := "http error status: 500; reason: not json"
+	if err.Error() != want {
+		t.Errorf("Send() = %q; want = %q", err.Error(), want)
+	}
+}
+
+func TestSendEach(t *testing.T) {
+	var mutex sync.Mutex
+	var tokens []string
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Message struct {
+				Token string `json:"token"`
+			} `json:"message"`
+			ValidateOnly bool `json:"validate_only"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Error(err)
+		}
+		if req.ValidateOnly {
+			t.Errorf("validate_only = true; want = false")
+		}
+		mutex.Lock()
+		tokens = append(tokens, req.Message.Token)
+		mutex.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		if req.Message.Token == "unregistered" {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error": {"status": "NOT_FOUND", "message": "not found"}}`))
+			return
+		}
+		w.Write([]byte(`{"name": "projects/test-project/messages/` + req.Message.Token + `"}`))
+	}))
+	defer s.Close()
+	client, err := NewClient(context.Background(), testMessagingConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.fcmEndpoint = s.URL
+
+	var messages []*Message
+	for i := 0; i < maxMessages; i++ {
+		token := fmt.Sprintf("token%d", i)
+		if i%100 == 0 {
+			token = "unregistered"
+		}
+		messages = append(messages, &Message{Token: token})
+	}
+	br, err := client.SendEach(context.Background(), messages)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if br.SuccessCount != 495 || br.FailureCount != 5 || len(br.Responses) != maxMessages {
+		t.Errorf("SendEach() = (%d, %d, %d); want = (495, 5, %d)",
+			br.SuccessCount, br.FailureCount, len(br.Responses), maxMessages)
+	}
+	for i, r := range br.Responses {
+		if i%100 == 0 {
+			if r.Success || r.MessageID != "" || !IsRegistrationTokenNotRegistered(r.Error) {
+				t.Errorf("Responses[%d] = %v; want = registration-token-not-registered error", i, r)
+			}
+		} else if want := fmt.Sprintf("projects/test-project/messages/token%d", i); !r.Success ||
+			r.MessageID != want || r.Error != nil {
+			t.Errorf("Responses[%d] = %v; want = %q", i, r, want)
+		}
+	}
+	if len(tokens) != maxMessages {
+		t.Errorf("Requests = %d; want = %d", len(tokens), maxMessages)
+	}
+}
+
+func TestSendEachDryRun(t *testing.T) {
+	s := fcmServer(t, `{"name": "projects/test-project/messages/fake_message_id"}`)
+	defer s.Close()
+
+	br, err := s.Client.SendEachDryRun(context.Background(), []*Message{{Topic: "news"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if br.SuccessCount != 1 || br.FailureCount != 0 {
+		t.Errorf("SendEachDryRun() = (%d, %d); want = (1, 0)", br.SuccessCount, br.FailureCount)
+	}
+	checkFCMRequest(t, s, map[string]interface{}{
+		"message":       map[string]interface{}{"topic": "news"},
+		"validate_only": true,
+	})
+}
+
+func TestSendEachInvalid(t *testing.T) {
+	tooMany := make([]*Message, maxMessages+1)
+	for i := range tooMany {
+		tooMany[i] = &Message{Token: "token"}
+	}
+	cases := map[string][]*Message{
+		"nil":             nil,
+		"empty":           {},
+		"too-many":        tooMany,
+		"invalid-message": {{Token: "token"}, {}},
+		"nil-message":     {{Token: "token"}, nil},
+	}
+	client, err := NewClient(context.Background(), testMessagingConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, messages := range cases {
+		if br, err := client.SendEach(context.Background(), messages); br != nil || err == nil {
+			t.Errorf("SendEach(%s) = (%v, %v); want = (nil, error)", name, br, err)
+		}
+	}
+}
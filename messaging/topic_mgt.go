@@ -0,0 +1,150 @@
+// Copyright 2017 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package messaging
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"firebase.google.com/go/internal"
+
+	"golang.org/x/net/context"
+)
+
+// maxTopicTokens is the maximum number of registration tokens that can be subscribed to, or
+// unsubscribed from, a topic in one call.
+const maxTopicTokens = 1000
+
+// iidError maps the error codes returned by the Instance ID service to SDK error codes.
+var iidError = map[string]string{
+	"INTERNAL":           internalError,
+	"INVALID_ARGUMENT":   invalidArgument,
+	"NOT_FOUND":          registrationTokenNotRegistered,
+	"RESOURCE_EXHAUSTED": messageRateExceeded,
+	"TOO_MANY_TOPICS":    tooManyTopics,
+	"UNAVAILABLE":        serverUnavailable,
+}
+
+// TopicManagementResponse is the result of a topic subscription or unsubscription request.
+type TopicManagementResponse struct {
+	SuccessCount int
+	FailureCount int
+	Errors       []*ErrorInfo
+}
+
+// ErrorInfo describes the failure to subscribe or unsubscribe one of the registration tokens of a
+// topic management request.
+//
+// Index is the position of the failed token in the list passed to SubscribeToTopic or
+// UnsubscribeFromTopic. Reason is the error code returned by the Instance ID service, such as
+// "NOT_FOUND" for tokens that are no longer valid, or "TOO_MANY_TOPICS" for app instances subscribed
+// to too many topics.
+type ErrorInfo struct {
+	Index  int
+	Reason string
+}
+
+// SubscribeToTopic subscribes a list of registration tokens to a topic.
+//
+// Up to 1000 tokens can be subscribed in one call. The topic name may be specified with or without the
+// "/topics/" prefix. Failures of individual tokens are reported in the returned
+// TopicManagementResponse rather than as an error.
+func (c *Client) SubscribeToTopic(ctx context.Context, tokens []string, topic string) (*TopicManagementResponse, error) {
+	return c.makeTopicManagementRequest(ctx, "/iid/v1:batchAdd", tokens, topic)
+}
+
+// UnsubscribeFromTopic unsubscribes a list of registration tokens from a topic. See SubscribeToTopic
+// for details.
+func (c *Client) UnsubscribeFromTopic(ctx context.Context, tokens []string, topic string) (*TopicManagementResponse, error) {
+	return c.makeTopicManagementRequest(ctx, "/iid/v1:batchRemove", tokens, topic)
+}
+
+func (c *Client) makeTopicManagementRequest(ctx context.Context, path string, tokens []string,
+	topic string) (*TopicManagementResponse, error) {
+	if len(tokens) == 0 {
+		return nil, errors.New("no tokens specified")
+	}
+	if len(tokens) > maxTopicTokens {
+		return nil, fmt.Errorf("tokens list must not contain more than %d items", maxTopicTokens)
+	}
+	for _, token := range tokens {
+		if token == "" {
+			return nil, errors.New("tokens list must not contain empty strings")
+		}
+	}
+	if topic == "" || !topicPattern.MatchString(topic) {
+		return nil, fmt.Errorf("malformed topic name: %q", topic)
+	}
+	if !strings.HasPrefix(topic, "/topics/") {
+		topic = "/topics/" + topic
+	}
+
+	resp, err := c.hc.Do(ctx, &internal.Request{
+		Method: http.MethodPost,
+		URL:    c.iidEndpoint + path,
+		Body: map[string]interface{}{
+			"to":                  topic,
+			"registration_tokens": tokens,
+		},
+		Header: http.Header{"access_token_auth": []string{"true"}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if resp.Status != http.StatusOK {
+		return nil, handleIIDError(resp)
+	}
+
+	var parsed struct {
+		Results []struct {
+			Error string `json:"error"`
+		} `json:"results"`
+	}
+	if err := json.Unmarshal(resp.Body, &parsed); err != nil {
+		return nil, err
+	}
+	result := &TopicManagementResponse{}
+	for i, r := range parsed.Results {
+		if r.Error == "" {
+			result.SuccessCount++
+		} else {
+			result.FailureCount++
+			result.Errors = append(result.Errors, &ErrorInfo{Index: i, Reason: r.Error})
+		}
+	}
+	return result, nil
+}
+
+// handleIIDError converts an error response from the Instance ID service into a FirebaseError. The
+// error code is carried in the error field of the response.
+func handleIIDError(resp *internal.Response) error {
+	var parsed struct {
+		Error string `json:"error"`
+	}
+	json.Unmarshal(resp.Body, &parsed) // ignore any json parse errors at this level
+
+	code, ok := iidError[parsed.Error]
+	if !ok {
+		code = unknown
+	}
+	msg := parsed.Error
+	if msg == "" {
+		msg = string(resp.Body)
+	}
+	return internal.Errorf(code, "http error status: %d; reason: %s", resp.Status, msg)
+}
@@ -0,0 +1,157 @@
+// Copyright 2017 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package messaging
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"golang.org/x/net/context"
+)
+
+// iidServer records the path and body of the last request sent to it, and responds with resp and
+// status.
+func iidServer(t *testing.T, resp string, status int) (*Client, *http.Request, *map[string]interface{}, func()) {
+	var req http.Request
+	var body map[string]interface{}
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req = *r
+		b, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := json.Unmarshal(b, &body); err != nil {
+			t.Error(err)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if status != 0 {
+			w.WriteHeader(status)
+		}
+		w.Write([]byte(resp))
+	}))
+	client, err := NewClient(context.Background(), testMessagingConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.iidEndpoint = s.URL
+	return client, &req, &body, s.Close
+}
+
+func TestSubscribeToTopic(t *testing.T) {
+	client, req, body, done := iidServer(t, `{"results": [{}, {"error": "NOT_FOUND"}, {}]}`, 0)
+	defer done()
+
+	resp, err := client.SubscribeToTopic(context.Background(), []string{"id1", "id2", "id3"}, "news")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &TopicManagementResponse{
+		SuccessCount: 2,
+		FailureCount: 1,
+		Errors:       []*ErrorInfo{{Index: 1, Reason: "NOT_FOUND"}},
+	}
+	if !reflect.DeepEqual(resp, want) {
+		t.Errorf("SubscribeToTopic() = %#v; want = %#v", resp, want)
+	}
+	if req.URL.Path != "/iid/v1:batchAdd" {
+		t.Errorf("Path = %q; want = %q", req.URL.Path, "/iid/v1:batchAdd")
+	}
+	if h := req.Header.Get("access_token_auth"); h != "true" {
+		t.Errorf("access_token_auth = %q; want = %q", h, "true")
+	}
+	wantBody := map[string]interface{}{
+		"to":                  "/topics/news",
+		"registration_tokens": []interface{}{"id1", "id2", "id3"},
+	}
+	if !reflect.DeepEqual(*body, wantBody) {
+		t.Errorf("Body = %#v; want = %#v", *body, wantBody)
+	}
+}
+
+func TestUnsubscribeFromTopic(t *testing.T) {
+	client, req, body, done := iidServer(t, `{"results": [{}]}`, 0)
+	defer done()
+
+	resp, err := client.UnsubscribeFromTopic(context.Background(), []string{"id1"}, "/topics/news")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.SuccessCount != 1 || resp.FailureCount != 0 || len(resp.Errors) != 0 {
+		t.Errorf("UnsubscribeFromTopic() = %#v; want = 1 success", resp)
+	}
+	if req.URL.Path != "/iid/v1:batchRemove" {
+		t.Errorf("Path = %q; want = %q", req.URL.Path, "/iid/v1:batchRemove")
+	}
+	if to := (*body)["to"]; to != "/topics/news" {
+		t.Errorf("to = %v; want = %q", to, "/topics/news")
+	}
+}
+
+func TestTopicManagementInvalid(t *testing.T) {
+	tooMany := make([]string, maxTopicTokens+1)
+	for i := range tooMany {
+		tooMany[i] = "token"
+	}
+	cases := []struct {
+		name   string
+		tokens []string
+		topic  string
+	}{
+		{"no-tokens", nil, "news"},
+		{"too-many-tokens", tooMany, "news"},
+		{"empty-token", []string{"id1", ""}, "news"},
+		{"no-topic", []string{"id1"}, ""},
+		{"malformed-topic", []string{"id1"}, "/topics/"},
+		{"invalid-topic", []string{"id1"}, "foo bar"},
+	}
+	client, err := NewClient(context.Background(), testMessagingConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range cases {
+		if resp, err := client.SubscribeToTopic(context.Background(), tc.tokens, tc.topic); resp != nil || err == nil {
+			t.Errorf("SubscribeToTopic(%s) = (%v, %v); want = (nil, error)", tc.name, resp, err)
+		}
+		if resp, err := client.UnsubscribeFromTopic(context.Background(), tc.tokens, tc.topic); resp != nil || err == nil {
+			t.Errorf("UnsubscribeFromTopic(%s) = (%v, %v); want = (nil, error)", tc.name, resp, err)
+		}
+	}
+}
+
+func TestTopicManagementError(t *testing.T) {
+	cases := []struct {
+		resp   string
+		status int
+		check  func(error) bool
+	}{
+		{`{"error": "INVALID_ARGUMENT"}`, http.StatusBadRequest, IsInvalidArgument},
+		{`{"error": "TOO_MANY_TOPICS"}`, http.StatusBadRequest, IsTooManyTopics},
+		{`{"error": "RESOURCE_EXHAUSTED"}`, http.StatusTooManyRequests, IsMessageRateExceeded},
+		{`{"error": "UNAVAILABLE"}`, http.StatusServiceUnavailable, IsServerUnavailable},
+		{`{"error": "INTERNAL"}`, http.StatusInternalServerError, IsInternal},
+	}
+	for _, tc := range cases {
+		client, _, _, done := iidServer(t, tc.resp, tc.status)
+		resp, err := client.SubscribeToTopic(context.Background(), []string{"id1"}, "news")
+		if resp != nil || !tc.check(err) {
+			t.Errorf("SubscribeToTopic() = (%v, %v); want = (nil, error matching %s)", resp, err, tc.resp)
+		}
+		done()
+	}
+}
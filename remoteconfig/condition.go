@@ -0,0 +1,384 @@
+// Copyright 2017 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package remoteconfig contains utilities for working with Firebase Remote Config templates.
+package remoteconfig
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var signalKeyPattern = regexp.MustCompile("^[a-zA-Z0-9_]{1,250}$")
+
+// Condition is a Remote Config condition expression, built from typed predicates that are validated
+// as the condition is constructed.
+//
+// Conditions are immutable. Any error detected while building a condition is carried along by all
+// the conditions built from it, and reported by Expression.
+type Condition struct {
+	expr string
+	err  error
+}
+
+func condition(expr string) *Condition {
+	return &Condition{expr: expr}
+}
+
+func invalid(format string, args ...interface{}) *Condition {
+	return &Condition{err: fmt.Errorf(format, args...)}
+}
+
+// Expression returns the expression string of the condition, as used in the conditions of a Remote
+// Config template, or the first error detected while building the condition.
+func (c *Condition) Expression() (string, error) {
+	if c.err != nil {
+		return "", c.err
+	}
+	if err := ValidateExpression(c.expr); err != nil {
+		return "", err
+	}
+	return c.expr, nil
+}
+
+// String returns the expression string of the condition, or a description of the error if the
+// condition is invalid.
+func (c *Condition) String() string {
+	if c.err != nil {
+		return fmt.Sprintf("invalid condition: %v", c.err)
+	}
+	return c.expr
+}
+
+// AppID returns a condition that matches the app with the given Firebase app ID.
+func AppID(id string) *Condition {
+	if err := validateLiteral("app id", id); err != nil {
+		return &Condition{err: err}
+	}
+	return condition(fmt.Sprintf("app.id == %s", quote(id)))
+}
+
+// DeviceOS returns a condition that matches devices running the given operating system, which must be
+// "ios" or "android".
+func DeviceOS(os string) *Condition {
+	if os != "ios" && os != "android" {
+		return invalid("device os must be %q or %q: %q", "ios", "android", os)
+	}
+	return condition(fmt.Sprintf("device.os == %s", quote(os)))
+}
+
+// DeviceLanguageIn returns a condition that matches devices whose language is one of the given
+// language tags, such as "en-US" or "fr".
+func DeviceLanguageIn(languages ...string) *Condition {
+	list, err := quoteList("device language", languages)
+	if err != nil {
+		return &Condition{err: err}
+	}
+	return condition(fmt.Sprintf("device.language in %s", list))
+}
+
+// PercentAtMost returns a condition that matches the given percentage of app instances, picked by a
+// random, per-instance percentile between 0 and 100.
+func PercentAtMost(percent float64) *Condition {
+	if err := validatePercent(percent); err != nil {
+		return &Condition{err: err}
+	}
+	return condition(fmt.Sprintf("percent <= %s", formatNumber(percent)))
+}
+
+// PercentBetween returns a condition that matches the app instances whose random percentile is greater
+// than low and at most high. Conditions over adjacent ranges split app instances into disjoint groups.
+func PercentBetween(low, high float64) *Condition {
+	if err := validatePercent(low); err != nil {
+		return &Condition{err: err}
+	}
+	if err := validatePercent(high); err != nil {
+		return &Condition{err: err}
+	}
+	if low >= high {
+		return invalid("lower percentage bound must be less than the upper bound: %v >= %v", low, high)
+	}
+	return condition(fmt.Sprintf("percent > %s && percent <= %s", formatNumber(low), formatNumber(high)))
+}
+
+// CustomSignal returns a builder for conditions on the custom signal with the given key, as set by the
+// client app.
+func CustomSignal(key string) *Signal {
+	return &Signal{key: key}
+}
+
+// Signal builds conditions on the value of a custom signal.
+type Signal struct {
+	key string
+}
+
+func (s *Signal) operand() (string, error) {
+	if !signalKeyPattern.MatchString(s.key) {
+		return "", fmt.Errorf("custom signal keys must consist of 1 to 250 letters, digits and "+
+			"underscores: %q", s.key)
+	}
+	return fmt.Sprintf("app.customSignal[%s]", quote(s.key)), nil
+}
+
+func (s *Signal) stringMatch(op string, values []string) *Condition {
+	operand, err := s.operand()
+	if err != nil {
+		return &Condition{err: err}
+	}
+	list, err := quoteList("custom signal value", values)
+	if err != nil {
+		return &Condition{err: err}
+	}
+	return condition(fmt.Sprintf("%s.%s(%s)", operand, op, list))
+}
+
+func (s *Signal) compare(op string, value float64) *Condition {
+	operand, err := s.operand()
+	if err != nil {
+		return &Condition{err: err}
+	}
+	return condition(fmt.Sprintf("%s %s %s", operand, op, formatNumber(value)))
+}
+
+// ExactlyMatches returns a condition that matches when the signal is equal to one of the given values.
+func (s *Signal) ExactlyMatches(values ...string) *Condition {
+	return s.stringMatch("exactlyMatches", values)
+}
+
+// Contains returns a condition that matches when the signal contains one of the given values.
+func (s *Signal) Contains(values ...string) *Condition {
+	return s.stringMatch("contains", values)
+}
+
+// LessThan returns a condition that matches when the numeric signal is less than the given value.
+func (s *Signal) LessThan(value float64) *Condition {
+	return s.compare("<", value)
+}
+
+// AtMost returns a condition that matches when the numeric signal is at most the given value.
+func (s *Signal) AtMost(value float64) *Condition {
+	return s.compare("<=", value)
+}
+
+// Equals returns a condition that matches when the numeric signal is equal to the given value.
+func (s *Signal) Equals(value float64) *Condition {
+	return s.compare("==", value)
+}
+
+// AtLeast returns a condition that matches when the numeric signal is at least the given value.
+func (s *Signal) AtLeast(value float64) *Condition {
+	return s.compare(">=", value)
+}
+
+// GreaterThan returns a condition that matches when the numeric signal is greater than the given value.
+func (s *Signal) GreaterThan(value float64) *Condition {
+	return s.compare(">", value)
+}
+
+// And returns a condition that matches when all of the given conditions match.
+func And(conditions ...*Condition) *Condition {
+	return combine("&&", conditions)
+}
+
+// Or returns a condition that matches when any of the given conditions match.
+func Or(conditions ...*Condition) *Condition {
+	return combine("||", conditions)
+}
+
+// Not returns a condition that matches when the given condition does not match.
+func Not(c *Condition) *Condition {
+	if c == nil {
+		return invalid("condition must not be nil")
+	}
+	if c.err != nil {
+		return c
+	}
+	return condition(fmt.Sprintf("!(%s)", c.expr))
+}
+
+func combine(op string, conditions []*Condition) *Condition {
+	if len(conditions) == 0 {
+		return invalid("at least one condition must be combined with %s", op)
+	}
+	var parts []string
+	for _, c := range conditions {
+		if c == nil {
+			return invalid("condition must not be nil")
+		}
+		if c.err != nil {
+			return c
+		}
+		parts = append(parts, "("+c.expr+")")
+	}
+	if len(parts) == 1 {
+		return conditions[0]
+	}
+	return condition(strings.Join(parts, " "+op+" "))
+}
+
+func validateLiteral(name, s string) error {
+	if s == "" {
+		return fmt.Errorf("%s must not be empty", name)
+	}
+	if strings.ContainsAny(s, "'\n") {
+		return fmt.Errorf("%s must not contain single quotes or line breaks: %q", name, s)
+	}
+	return nil
+}
+
+func validatePercent(p float64) error {
+	if p < 0 || p > 100 {
+		return fmt.Errorf("percentage must be between 0 and 100: %v", p)
+	}
+	return nil
+}
+
+func quote(s string) string {
+	return "'" + s + "'"
+}
+
+func quoteList(name string, values []string) (string, error) {
+	if len(values) == 0 {
+		return "", fmt.Errorf("at least one %s must be specified", name)
+	}
+	var quoted []string
+	for _, v := range values {
+		if err := validateLiteral(name, v); err != nil {
+			return "", err
+		}
+		quoted = append(quoted, quote(v))
+	}
+	return "[" + strings.Join(quoted, ", ") + "]", nil
+}
+
+func formatNumber(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+// knownOperands are the identifiers that may appear in a condition expression, apart from method
+// calls on custom signals.
+var knownOperands = map[string]bool{
+	"app.id":           true,
+	"app.version":      true,
+	"app.build":        true,
+	"app.audiences":    true,
+	"app.customSignal": true,
+	"device.os":        true,
+	"device.language":  true,
+	"device.country":   true,
+	"percent":          true,
+	"dateTime":         true,
+	"true":             true,
+	"false":            true,
+	"in":               true,
+}
+
+// ValidateExpression checks that a condition expression is well formed: string literals are
+// terminated, brackets are balanced, operators are supported, and identifiers refer to known condition
+// operands. It catches common mistakes in hand-written expressions before a template is published,
+// but does not check the types of operands.
+func ValidateExpression(expr string) error {
+	if strings.TrimSpace(expr) == "" {
+		return errors.New("expression must not be empty")
+	}
+
+	var stack []rune
+	closing := map[rune]rune{')': '(', ']': '['}
+	for i := 0; i < len(expr); {
+		ch := rune(expr[i])
+		switch {
+		case ch == ' ' || ch == '\t':
+			i++
+		case ch == '\'' || ch == '"':
+			end := strings.IndexRune(expr[i+1:], ch)
+			if end < 0 {
+				return fmt.Errorf("unterminated string literal at offset %d", i)
+			}
+			i += end + 2
+		case ch == '(' || ch == '[':
+			stack = append(stack, ch)
+			i++
+		case ch == ')' || ch == ']':
+			if len(stack) == 0 || stack[len(stack)-1] != closing[ch] {
+				return fmt.Errorf("unbalanced %q at offset %d", ch, i)
+			}
+			stack = stack[:len(stack)-1]
+			i++
+		case isIdentStart(ch):
+			j := i
+			for j < len(expr) && (isIdentStart(rune(expr[j])) || isDigit(rune(expr[j])) || expr[j] == '.') {
+				j++
+			}
+			ident := expr[i:j]
+			if !isKnownIdentifier(ident, expr[:i]) {
+				return fmt.Errorf("unknown identifier %q at offset %d", ident, i)
+			}
+			i = j
+		case isDigit(ch) || (ch == '-' && i+1 < len(expr) && isDigit(rune(expr[i+1]))):
+			// Numeric literals, which may be negative as in app.customSignal['score'] < -1.
+			i++
+			for i < len(expr) && (isDigit(rune(expr[i])) || expr[i] == '.') {
+				i++
+			}
+		default:
+			op := operatorAt(expr[i:])
+			if op == "" {
+				return fmt.Errorf("unexpected character %q at offset %d", ch, i)
+			}
+			i += len(op)
+		}
+	}
+	if len(stack) > 0 {
+		return fmt.Errorf("unclosed %q", stack[len(stack)-1])
+	}
+	return nil
+}
+
+// isKnownIdentifier reports whether ident is a known operand, or a method called on an operand.
+func isKnownIdentifier(ident, preceding string) bool {
+	if knownOperands[ident] {
+		return true
+	}
+	// Method calls such as app.customSignal['key'].contains([...]) or app.version.greaterThan(...)
+	// appear after a closing bracket or a known operand.
+	if strings.HasPrefix(ident, ".") {
+		return strings.HasSuffix(strings.TrimSpace(preceding), "]") || strings.HasSuffix(preceding, ")")
+	}
+	if idx := strings.LastIndex(ident, "."); idx > 0 {
+		return knownOperands[ident[:idx]]
+	}
+	return false
+}
+
+var operators = []string{"&&", "||", "==", "!=", "<=", ">=", "<", ">", "!", ","}
+
+func operatorAt(s string) string {
+	for _, op := range operators {
+		if strings.HasPrefix(s, op) {
+			return op
+		}
+	}
+	return ""
+}
+
+func isIdentStart(ch rune) bool {
+	return ch == '_' || ch == '.' || (ch >= 'a' && ch <= 'z') || (ch >= 'A' && ch <= 'Z')
+}
+
+func isDigit(ch rune) bool {
+	return ch >= '0' && ch <= '9'
+}
@@ -0,0 +1,130 @@
+// Copyright 2017 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package remoteconfig
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestConditions(t *testing.T) {
+	cases := []struct {
+		cond *Condition
+		want string
+	}{
+		{AppID("1:1234:ios:abcd"), "app.id == '1:1234:ios:abcd'"},
+		{DeviceOS("android"), "device.os == 'android'"},
+		{DeviceLanguageIn("en-US", "fr"), "device.language in ['en-US', 'fr']"},
+		{PercentAtMost(12.5), "percent <= 12.5"},
+		{PercentBetween(10, 20), "percent > 10 && percent <= 20"},
+		{CustomSignal("city").ExactlyMatches("Paris", "Rome"), "app.customSignal['city'].exactlyMatches(['Paris', 'Rome'])"},
+		{CustomSignal("plan").Contains("pro"), "app.customSignal['plan'].contains(['pro'])"},
+		{CustomSignal("level").LessThan(3), "app.customSignal['level'] < 3"},
+		{CustomSignal("level").AtMost(3), "app.customSignal['level'] <= 3"},
+		{CustomSignal("level").Equals(3), "app.customSignal['level'] == 3"},
+		{CustomSignal("level").AtLeast(3), "app.customSignal['level'] >= 3"},
+		{CustomSignal("level").GreaterThan(3.5), "app.customSignal['level'] > 3.5"},
+		{CustomSignal("score").LessThan(-1), "app.customSignal['score'] < -1"},
+		{CustomSignal("score").Equals(-0.5), "app.customSignal['score'] == -0.5"},
+		{CustomSignal("score").AtLeast(-10), "app.customSignal['score'] >= -10"},
+		{
+			And(DeviceOS("ios"), Or(DeviceLanguageIn("de"), Not(PercentAtMost(50)))),
+			"(device.os == 'ios') && ((device.language in ['de']) || (!(percent <= 50)))",
+		},
+		{And(DeviceOS("ios")), "device.os == 'ios'"},
+	}
+	for _, tc := range cases {
+		got, err := tc.cond.Expression()
+		if err != nil {
+			t.Errorf("Expression(%q) = %v", tc.want, err)
+			continue
+		}
+		if got != tc.want {
+			t.Errorf("Expression() = %q; want = %q", got, tc.want)
+		}
+		if tc.cond.String() != tc.want {
+			t.Errorf("String() = %q; want = %q", tc.cond.String(), tc.want)
+		}
+	}
+}
+
+func TestInvalidConditions(t *testing.T) {
+	cases := []*Condition{
+		AppID(""),
+		AppID("it's"),
+		DeviceOS("windows"),
+		DeviceLanguageIn(),
+		DeviceLanguageIn("en", ""),
+		PercentAtMost(-1),
+		PercentAtMost(101),
+		PercentBetween(20, 10),
+		PercentBetween(-5, 10),
+		CustomSignal("").Equals(1),
+		CustomSignal("invalid key").Equals(1),
+		CustomSignal("city").ExactlyMatches(),
+		And(),
+		Or(DeviceOS("ios"), nil),
+		And(DeviceOS("ios"), AppID("")),
+		Not(nil),
+		Not(AppID("")),
+	}
+	for _, c := range cases {
+		if expr, err := c.Expression(); expr != "" || err == nil {
+			t.Errorf("Expression() = (%q, %v); want = (\"\", error)", expr, err)
+		}
+		if !strings.HasPrefix(c.String(), "invalid condition: ") {
+			t.Errorf("String() = %q; want = invalid condition", c.String())
+		}
+	}
+}
+
+func TestValidateExpression(t *testing.T) {
+	cases := []string{
+		"true",
+		"app.id == '1:1234:ios:abcd' && device.country in ['US', 'CA']",
+		"percent('seed') <= 10",
+		"app.version.greaterThan(['1.2.0'])",
+		"dateTime >= dateTime('2024-01-01T00:00:00')",
+		"app.customSignal['city'].contains(['Paris']) || !(device.os == \"ios\")",
+		"app.customSignal['score'] > -2.5 && app.customSignal['score'] <= 10",
+	}
+	for _, expr := range cases {
+		if err := ValidateExpression(expr); err != nil {
+			t.Errorf("ValidateExpression(%q) = %v; want = nil", expr, err)
+		}
+	}
+}
+
+func TestValidateExpressionInvalid(t *testing.T) {
+	cases := []string{
+		"",
+		"   ",
+		"device.os == 'ios",
+		"(device.os == 'ios'",
+		"device.os == 'ios')",
+		"device.language in ['en')",
+		"device.platform == 'ios'",
+		"device.os = 'ios'",
+		"percent <= 10 & device.os == 'ios'",
+		"user.id == 'abc'",
+		"app.customSignal['score'] < -",
+		"app.customSignal['score'] < - 1",
+	}
+	for _, expr := range cases {
+		if err := ValidateExpression(expr); err == nil {
+			t.Errorf("ValidateExpression(%q) = nil; want = error", expr)
+		}
+	}
+}